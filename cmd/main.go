@@ -34,6 +34,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
@@ -72,6 +73,9 @@ func BuildRootCommand(opt *Options) (*cobra.Command, error) {
 		},
 	})
 
+	rootCmd.AddCommand(newMCPCommand())
+	rootCmd.AddCommand(newSessionCommand())
+
 	if err := opt.bindCLIFlags(rootCmd.Flags()); err != nil {
 		return nil, err
 	}
@@ -95,7 +99,24 @@ type Options struct {
 	MCPClient bool `json:"mcpClient,omitempty"`
 	// ExternalTools enables discovery and exposure of external MCP tools (only works with --mcp-server)
 	ExternalTools bool `json:"externalTools,omitempty"`
-	MaxIterations int  `json:"maxIterations,omitempty"`
+	// MCPServerAgent exposes the full agent as the ask_kubectl_ai tool (only works with --mcp-server).
+	// It uses the configured LLM provider and model, and declines commands that modify
+	// resources unless SkipPermissions is set.
+	MCPServerAgent bool `json:"mcpServerAgent,omitempty"`
+	MaxIterations  int  `json:"maxIterations,omitempty"`
+	// MCPReadOnly refuses MCP tool calls that may modify resources (only works with --mcp-server).
+	MCPReadOnly bool `json:"mcpReadOnly,omitempty"`
+	// MCPAllowVerbs and MCPDenyVerbs restrict the kubectl subcommands MCP clients may run.
+	MCPAllowVerbs []string `json:"mcpAllowVerbs,omitempty"`
+	MCPDenyVerbs  []string `json:"mcpDenyVerbs,omitempty"`
+	// MCPAllowNamespaces and MCPDenyNamespaces restrict the namespaces MCP clients may target.
+	MCPAllowNamespaces []string `json:"mcpAllowNamespaces,omitempty"`
+	MCPDenyNamespaces  []string `json:"mcpDenyNamespaces,omitempty"`
+	// MCPAuditLogPath is the file that every MCP tool call is recorded in.
+	MCPAuditLogPath string `json:"mcpAuditLogPath,omitempty"`
+	// MCPTenantsConfigPath is the multi-tenancy configuration of an HTTP MCP server: the clusters
+	// requests may select, impersonation and rate limits.
+	MCPTenantsConfigPath string `json:"mcpTenantsConfig,omitempty"`
 	// MCPServerMode is the mode of the MCP server. only works with --mcp-server.
 	MCPServerMode string `json:"mcpServerMode,omitempty"`
 	// Set the HTTP endpoint port for the MCP server when using HTTP transports like streamable-http.
@@ -103,17 +124,56 @@ type Options struct {
 	// KubeConfigPath is the path to the kubeconfig file.
 	// If not provided, the default kubeconfig path will be used.
 	KubeConfigPath string `json:"kubeConfigPath,omitempty"`
+	// KubeContext is the kubeconfig context to run against.
+	// If not provided, the kubeconfig's current context will be used.
+	KubeContext string `json:"kubeContext,omitempty"`
+	// Namespace is the namespace to run against.
+	// If not provided, the context's default namespace will be used.
+	Namespace string `json:"namespace,omitempty"`
 
 	PromptTemplateFilePath string   `json:"promptTemplateFilePath,omitempty"`
 	ExtraPromptPaths       []string `json:"extraPromptPaths,omitempty"`
 	TracePath              string   `json:"tracePath,omitempty"`
 	RemoveWorkDir          bool     `json:"removeWorkDir,omitempty"`
 	ToolConfigPaths        []string `json:"toolConfigPaths,omitempty"`
+	// EnableTools lists the built-in tools to enable. If empty, all built-in tools are enabled.
+	EnableTools []string `json:"enableTools,omitempty"`
+	// DisableTools lists tools (built-in or custom) that must never be made available,
+	// e.g. "bash" for a read-only deployment.
+	DisableTools []string `json:"disableTools,omitempty"`
+	// AllowedDirs are directories, in addition to the temporary working directory,
+	// that the file tools are allowed to read and write.
+	AllowedDirs []string `json:"allowedDirs,omitempty"`
 
 	// UIType is the type of user interface to use.
 	UIType ui.Type `json:"uiType,omitempty"`
 	// UIListenAddress is the address to listen for the web UI.
 	UIListenAddress string `json:"uiListenAddress,omitempty"`
+	// UIAuth is how users of the web UI authenticate: none, token, basic, oidc or proxy.
+	UIAuth string `json:"uiAuth,omitempty"`
+	// UIAuthTokenFile is the static token file of the token authentication.
+	UIAuthTokenFile string `json:"uiAuthTokenFile,omitempty"`
+	// UIAuthHtpasswdFile is the htpasswd file of the basic authentication.
+	UIAuthHtpasswdFile string `json:"uiAuthHtpasswdFile,omitempty"`
+	// UIOIDCIssuer, UIOIDCClientID and UIOIDCRedirectURL configure the OIDC authentication.
+	// The client secret is read from the KUBECTL_AI_UI_OIDC_CLIENT_SECRET environment variable.
+	UIOIDCIssuer      string   `json:"uiOIDCIssuer,omitempty"`
+	UIOIDCClientID    string   `json:"uiOIDCClientID,omitempty"`
+	UIOIDCRedirectURL string   `json:"uiOIDCRedirectURL,omitempty"`
+	UIOIDCScopes      []string `json:"uiOIDCScopes,omitempty"`
+	// UIAuthProxyUserHeader, UIAuthProxyGroupsHeader and UIAuthProxyTrustedCIDRs configure the
+	// authentication by a reverse proxy.
+	UIAuthProxyUserHeader   string   `json:"uiAuthProxyUserHeader,omitempty"`
+	UIAuthProxyGroupsHeader string   `json:"uiAuthProxyGroupsHeader,omitempty"`
+	UIAuthProxyTrustedCIDRs []string `json:"uiAuthProxyTrustedCIDRs,omitempty"`
+	// UIAllowedUsers and UIAllowedGroups restrict the web UI to some of the authenticated users.
+	UIAllowedUsers  []string `json:"uiAllowedUsers,omitempty"`
+	UIAllowedGroups []string `json:"uiAllowedGroups,omitempty"`
+	// UIAdminGroups are the groups of users who can use the web UI sessions of all users.
+	UIAdminGroups []string `json:"uiAdminGroups,omitempty"`
+	// UITLSCertFile and UITLSKeyFile serve the web UI over HTTPS.
+	UITLSCertFile string `json:"uiTLSCertFile,omitempty"`
+	UITLSKeyFile  string `json:"uiTLSKeyFile,omitempty"`
 
 	// SkipVerifySSL is a flag to skip verifying the SSL certificate of the LLM provider.
 	SkipVerifySSL bool `json:"skipVerifySSL,omitempty"`
@@ -126,6 +186,15 @@ type Options struct {
 
 	// ShowToolOutput is a flag to disable truncation of tool output in the terminal UI.
 	ShowToolOutput bool `json:"showToolOutput,omitempty"`
+
+	// LegacyCommands runs the commands typed without their slash, e.g. "clear" for /clear, as in earlier versions.
+	LegacyCommands bool `json:"legacyCommands,omitempty"`
+
+	// DisableRedaction stops secrets from being masked in tool output.
+	// Only intended for trusted (e.g. local) models.
+	DisableRedaction bool `json:"disableRedaction,omitempty"`
+	// RedactionPatterns are additional regular expressions to mask in tool output.
+	RedactionPatterns []string `json:"redactionPatterns,omitempty"`
 }
 
 var defaultToolConfigPaths = []string{
@@ -147,6 +216,15 @@ func (o *Options) InitDefaults() {
 	o.MCPClient = false
 	// by default, external tools are disabled (only works with --mcp-server)
 	o.ExternalTools = false
+	// by default, the agent is not exposed as an MCP tool (only works with --mcp-server)
+	o.MCPServerAgent = false
+	// by default, MCP clients may make any call, but must confirm calls that modify resources
+	o.MCPReadOnly = false
+	o.MCPAllowVerbs = []string{}
+	o.MCPDenyVerbs = []string{}
+	o.MCPAllowNamespaces = []string{}
+	o.MCPDenyNamespaces = []string{}
+	o.MCPAuditLogPath = filepath.Join(os.TempDir(), "kubectl-ai-mcp-audit.yaml")
 	// We now default to our strongest model (gemini-2.5-pro-exp-03-25) which supports tool use natively.
 	// so we don't need shim.
 	o.EnableToolUseShim = false
@@ -154,15 +232,29 @@ func (o *Options) InitDefaults() {
 	o.MCPServer = false
 	o.MaxIterations = 20
 	o.KubeConfigPath = ""
+	o.KubeContext = ""
+	o.Namespace = ""
 	o.PromptTemplateFilePath = ""
 	o.ExtraPromptPaths = []string{}
 	o.TracePath = filepath.Join(os.TempDir(), "kubectl-ai-trace.txt")
 	o.RemoveWorkDir = false
 	o.ToolConfigPaths = defaultToolConfigPaths
+	o.EnableTools = []string{}
+	o.DisableTools = []string{}
+	o.AllowedDirs = []string{}
 	// Default to terminal UI
 	o.UIType = ui.UITypeTerminal
 	// Default UI listen address for HTML UI
 	o.UIListenAddress = "localhost:8888"
+	// The web UI is not authenticated by default, as it only listens on localhost.
+	o.UIAuth = uiAuthNone
+	o.UIOIDCScopes = []string{"email", "profile"}
+	o.UIAuthProxyUserHeader = "X-Forwarded-User"
+	o.UIAuthProxyGroupsHeader = "X-Forwarded-Groups"
+	o.UIAuthProxyTrustedCIDRs = []string{}
+	o.UIAllowedUsers = []string{}
+	o.UIAllowedGroups = []string{}
+	o.UIAdminGroups = []string{}
 	// Default to not skipping SSL verification
 	o.SkipVerifySSL = false
 	// Default MCP server mode is stdio
@@ -178,6 +270,13 @@ func (o *Options) InitDefaults() {
 
 	// By default, hide tool outputs
 	o.ShowToolOutput = false
+
+	// By default, only queries starting with a slash are commands
+	o.LegacyCommands = false
+
+	// By default, mask secrets in tool output
+	o.DisableRedaction = false
+	o.RedactionPatterns = []string{}
 }
 
 func (o *Options) LoadConfiguration(b []byte) error {
@@ -239,6 +338,11 @@ func main() {
 	}()
 
 	if err := run(ctx); err != nil {
+		// Runs of the JSON UI report how they ended in their output, and with the exit code.
+		var exitErr *ui.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		// Don't print error if it's a context cancellation
 		if !errors.Is(err, context.Canceled) {
 			fmt.Fprintln(os.Stderr, err)
@@ -295,6 +399,8 @@ func run(ctx context.Context) error {
 func (opt *Options) bindCLIFlags(f *pflag.FlagSet) error {
 	f.IntVar(&opt.MaxIterations, "max-iterations", opt.MaxIterations, "maximum number of iterations agent will try before giving up")
 	f.StringVar(&opt.KubeConfigPath, "kubeconfig", opt.KubeConfigPath, "path to kubeconfig file")
+	f.StringVar(&opt.KubeContext, "context", opt.KubeContext, "kubeconfig context to use (default: the kubeconfig's current context)")
+	f.StringVar(&opt.Namespace, "namespace", opt.Namespace, "namespace to use (default: the context's namespace)")
 	f.StringVar(&opt.PromptTemplateFilePath, "prompt-template-file-path", opt.PromptTemplateFilePath, "path to custom prompt template file")
 	f.StringArrayVar(&opt.ExtraPromptPaths, "extra-prompt-paths", opt.ExtraPromptPaths, "extra prompt template paths")
 	f.StringVar(&opt.TracePath, "trace-path", opt.TracePath, "path to the trace file")
@@ -305,17 +411,46 @@ func (opt *Options) bindCLIFlags(f *pflag.FlagSet) error {
 	f.BoolVar(&opt.SkipPermissions, "skip-permissions", opt.SkipPermissions, "(dangerous) skip asking for confirmation before executing kubectl commands that modify resources")
 	f.BoolVar(&opt.MCPServer, "mcp-server", opt.MCPServer, "run in MCP server mode")
 	f.BoolVar(&opt.ExternalTools, "external-tools", opt.ExternalTools, "in MCP server mode, discover and expose external MCP tools")
+	f.BoolVar(&opt.MCPServerAgent, "mcp-server-agent", opt.MCPServerAgent, "in MCP server mode, expose the full agent as the ask_kubectl_ai tool, using the configured LLM provider and model")
+	f.BoolVar(&opt.MCPReadOnly, "mcp-read-only", opt.MCPReadOnly, "in MCP server mode, refuse tool calls that may modify resources")
+	f.StringSliceVar(&opt.MCPAllowVerbs, "mcp-allow-verbs", opt.MCPAllowVerbs, "in MCP server mode, the only kubectl subcommands clients may run, e.g. get,describe,logs")
+	f.StringSliceVar(&opt.MCPDenyVerbs, "mcp-deny-verbs", opt.MCPDenyVerbs, "in MCP server mode, kubectl subcommands clients may not run, e.g. delete,drain")
+	f.StringSliceVar(&opt.MCPAllowNamespaces, "mcp-allow-namespaces", opt.MCPAllowNamespaces, "in MCP server mode, the only namespaces kubectl commands may target")
+	f.StringSliceVar(&opt.MCPDenyNamespaces, "mcp-deny-namespaces", opt.MCPDenyNamespaces, "in MCP server mode, namespaces kubectl commands may not target, e.g. kube-system")
+	f.StringVar(&opt.MCPAuditLogPath, "mcp-audit-log", opt.MCPAuditLogPath, "in MCP server mode, file to record every tool call in")
+	f.StringVar(&opt.MCPTenantsConfigPath, "mcp-tenants-config", opt.MCPTenantsConfigPath, "in MCP server mode with an HTTP transport, file listing the clusters requests may select, impersonation and per-tenant rate limits")
 	f.StringArrayVar(&opt.ToolConfigPaths, "custom-tools-config", opt.ToolConfigPaths, "path to custom tools config file or directory")
+	f.StringSliceVar(&opt.EnableTools, "enable-tools", opt.EnableTools, "built-in tools to enable (default: all). Supported values: "+strings.Join(tools.BuiltinToolNames(), ", "))
+	f.StringSliceVar(&opt.DisableTools, "disable-tools", opt.DisableTools, "tools to disable, e.g. bash")
+	f.StringSliceVar(&opt.AllowedDirs, "allowed-dirs", opt.AllowedDirs, "additional directories the file tools may read and write (the working directory is always allowed)")
 	f.BoolVar(&opt.MCPClient, "mcp-client", opt.MCPClient, "enable MCP client mode to connect to external MCP servers")
-	f.StringVar(&opt.MCPServerMode, "mcp-server-mode", opt.MCPServerMode, "mode of the MCP server. Supported values: stdio, streamable-http")
-	f.IntVar(&opt.HTTPPort, "http-port", opt.HTTPPort, "port for the HTTP endpoint in MCP server mode (used with --mcp-server when --mcp-server-mode is streamable-http)")
+	f.StringVar(&opt.MCPServerMode, "mcp-server-mode", opt.MCPServerMode, "mode of the MCP server. Supported values: stdio, streamable-http, sse")
+	f.IntVar(&opt.HTTPPort, "http-port", opt.HTTPPort, "port for the HTTP endpoint in MCP server mode (used with --mcp-server when --mcp-server-mode is streamable-http or sse)")
 	f.BoolVar(&opt.EnableToolUseShim, "enable-tool-use-shim", opt.EnableToolUseShim, "enable tool use shim")
 	f.BoolVar(&opt.Quiet, "quiet", opt.Quiet, "run in non-interactive mode, requires a query to be provided as a positional argument")
 
-	f.Var(&opt.UIType, "ui-type", "user interface type to use. Supported values: terminal, web, tui.")
+	f.Var(&opt.UIType, "ui-type", "user interface type to use. Supported values: terminal, web, tui, json (one JSON object per line for scripts, implies --quiet).")
 	f.StringVar(&opt.UIListenAddress, "ui-listen-address", opt.UIListenAddress, "address to listen for the HTML UI.")
+	f.StringVar(&opt.UIAuth, "ui-auth", opt.UIAuth, "how users of the web UI authenticate. Supported values: none, token, basic, oidc, proxy")
+	f.StringVar(&opt.UIAuthTokenFile, "ui-auth-token-file", opt.UIAuthTokenFile, "with --ui-auth=token, CSV file of tokens in the format of the Kubernetes static token file: token,user,uid,\"group1,group2\"")
+	f.StringVar(&opt.UIAuthHtpasswdFile, "ui-auth-htpasswd-file", opt.UIAuthHtpasswdFile, "with --ui-auth=basic, htpasswd file with bcrypt password hashes (htpasswd -B)")
+	f.StringVar(&opt.UIOIDCIssuer, "ui-oidc-issuer", opt.UIOIDCIssuer, "with --ui-auth=oidc, URL of the OpenID Connect provider")
+	f.StringVar(&opt.UIOIDCClientID, "ui-oidc-client-id", opt.UIOIDCClientID, "with --ui-auth=oidc, client ID of the web UI; the client secret is read from $KUBECTL_AI_UI_OIDC_CLIENT_SECRET")
+	f.StringVar(&opt.UIOIDCRedirectURL, "ui-oidc-redirect-url", opt.UIOIDCRedirectURL, "with --ui-auth=oidc, external URL of the web UI's /oauth2/callback endpoint (default: derived from the requests)")
+	f.StringSliceVar(&opt.UIOIDCScopes, "ui-oidc-scopes", opt.UIOIDCScopes, "with --ui-auth=oidc, scopes to request in addition to openid")
+	f.StringVar(&opt.UIAuthProxyUserHeader, "ui-auth-proxy-user-header", opt.UIAuthProxyUserHeader, "with --ui-auth=proxy, header with the name of the user authenticated by the proxy")
+	f.StringVar(&opt.UIAuthProxyGroupsHeader, "ui-auth-proxy-groups-header", opt.UIAuthProxyGroupsHeader, "with --ui-auth=proxy, header with the comma-separated groups of the user")
+	f.StringSliceVar(&opt.UIAuthProxyTrustedCIDRs, "ui-auth-proxy-trusted-cidrs", opt.UIAuthProxyTrustedCIDRs, "with --ui-auth=proxy, addresses of the proxies whose headers are trusted, e.g. 10.0.0.0/8")
+	f.StringSliceVar(&opt.UIAllowedUsers, "ui-allowed-users", opt.UIAllowedUsers, "authenticated users allowed to use the web UI (default: all)")
+	f.StringSliceVar(&opt.UIAllowedGroups, "ui-allowed-groups", opt.UIAllowedGroups, "groups of authenticated users allowed to use the web UI (default: all)")
+	f.StringSliceVar(&opt.UIAdminGroups, "ui-admin-groups", opt.UIAdminGroups, "groups of authenticated users who can use the web UI sessions of all users, rather than only their own")
+	f.StringVar(&opt.UITLSCertFile, "ui-tls-cert-file", opt.UITLSCertFile, "certificate file to serve the web UI over HTTPS")
+	f.StringVar(&opt.UITLSKeyFile, "ui-tls-key-file", opt.UITLSKeyFile, "private key file to serve the web UI over HTTPS")
 	f.BoolVar(&opt.SkipVerifySSL, "skip-verify-ssl", opt.SkipVerifySSL, "skip verifying the SSL certificate of the LLM provider")
 	f.BoolVar(&opt.ShowToolOutput, "show-tool-output", opt.ShowToolOutput, "show tool output in the terminal UI")
+	f.BoolVar(&opt.LegacyCommands, "legacy-commands", opt.LegacyCommands, "also run commands typed without their slash, e.g. 'clear' for /clear")
+	f.BoolVar(&opt.DisableRedaction, "disable-redaction", opt.DisableRedaction, "(dangerous) send tool output to the model without masking secrets; only use with trusted models")
+	f.StringArrayVar(&opt.RedactionPatterns, "redaction-pattern", opt.RedactionPatterns, "additional regular expression to mask in tool output (a group named 'value' masks only that part of the match)")
 
 	f.StringVar(&opt.ResumeSession, "resume-session", opt.ResumeSession, "ID of session to resume (use 'latest' for the most recent session)")
 	f.BoolVar(&opt.NewSession, "new-session", opt.NewSession, "create a new session")
@@ -332,6 +467,26 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	if opt.ExternalTools && !opt.MCPServer {
 		return fmt.Errorf("--external-tools can only be used with --mcp-server")
 	}
+	if opt.MCPServerAgent && !opt.MCPServer {
+		return fmt.Errorf("--mcp-server-agent can only be used with --mcp-server")
+	}
+	switch opt.MCPServerMode {
+	case "stdio", "streamable-http", "sse":
+	default:
+		return fmt.Errorf("invalid --mcp-server-mode %q: must be stdio, streamable-http or sse", opt.MCPServerMode)
+	}
+	if err := validateWebUIOptions(opt); err != nil {
+		return err
+	}
+	if opt.MCPTenantsConfigPath != "" {
+		if !opt.MCPServer || opt.MCPServerMode == "stdio" {
+			return fmt.Errorf("--mcp-tenants-config can only be used with --mcp-server and --mcp-server-mode streamable-http or sse")
+		}
+		if opt.MCPServerAgent {
+			// The agent runs its own tool calls, which the multi-tenancy restrictions do not apply to.
+			return fmt.Errorf("--mcp-server-agent cannot be used with --mcp-tenants-config")
+		}
+	}
 
 	// resolve kubeconfig path with priority: flag/env > KUBECONFIG > default path
 	if err = resolveKubeConfigPath(&opt); err != nil {
@@ -353,7 +508,11 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		return handleDeleteSession(opt.DeleteSession)
 	}
 
-	if err := handleCustomTools(opt.ToolConfigPaths); err != nil {
+	toolset, err := newBuiltinToolset(opt)
+	if err != nil {
+		return err
+	}
+	if err := handleCustomTools(&toolset, opt.ToolConfigPaths); err != nil {
 		return fmt.Errorf("failed to process custom tools: %w", err)
 	}
 
@@ -373,12 +532,14 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 
 	klog.Info("Application started", "pid", os.Getpid())
 
-	var llmClient gollm.Client
-	if opt.SkipVerifySSL {
-		llmClient, err = gollm.NewClient(ctx, opt.ProviderID, gollm.WithSkipVerifySSL())
-	} else {
-		llmClient, err = gollm.NewClient(ctx, opt.ProviderID)
+	// newLLMClient creates a client for a provider, also when the user switches providers.
+	newLLMClient := func(ctx context.Context, providerID string) (gollm.Client, error) {
+		if opt.SkipVerifySSL {
+			return gollm.NewClient(ctx, providerID, gollm.WithSkipVerifySSL())
+		}
+		return gollm.NewClient(ctx, providerID)
 	}
+	llmClient, err := newLLMClient(ctx, opt.ProviderID)
 	if err != nil {
 		return fmt.Errorf("creating llm client: %w", err)
 	}
@@ -389,14 +550,15 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	var sessionManager *sessions.SessionManager
 
 	// TODO: Remove this when session persistence is default
-	if opt.NewSession || opt.ResumeSession != "" {
+	// The web UI hosts many sessions, so its sessions are always persisted.
+	if opt.NewSession || opt.ResumeSession != "" || opt.UIType == ui.UITypeWeb {
 		sessionManager, err = sessions.NewSessionManager()
 		if err != nil {
 			return fmt.Errorf("failed to create session manager: %w", err)
 		}
 
 		// Handle session creation or loading
-		if opt.NewSession {
+		if opt.NewSession || opt.ResumeSession == "" {
 			// Create a new session
 			meta := sessions.Metadata{
 				ProviderID: opt.ProviderID,
@@ -450,24 +612,39 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		defer recorder.Close()
 	}
 
-	k8sAgent := &agent.Agent{
-		Model:              opt.ModelID,
-		Provider:           opt.ProviderID,
-		Kubeconfig:         opt.KubeConfigPath,
-		LLM:                llmClient,
-		MaxIterations:      opt.MaxIterations,
-		PromptTemplateFile: opt.PromptTemplateFilePath,
-		ExtraPromptPaths:   opt.ExtraPromptPaths,
-		Tools:              tools.Default(),
-		Recorder:           recorder,
-		RemoveWorkDir:      opt.RemoveWorkDir,
-		SkipPermissions:    opt.SkipPermissions,
-		EnableToolUseShim:  opt.EnableToolUseShim,
-		MCPClientEnabled:   opt.MCPClient,
-		RunOnce:            opt.Quiet,
-		InitialQuery:       queryFromCmd,
-		ChatMessageStore:   chatStore,
+	redactor, err := newRedactor(opt)
+	if err != nil {
+		return err
+	}
+
+	// newAgent creates an agent for the conversation kept in chatStore.
+	newAgent := func(toolset tools.Tools, chatStore api.ChatMessageStore, initialQuery string) *agent.Agent {
+		return &agent.Agent{
+			Model:              opt.ModelID,
+			Provider:           opt.ProviderID,
+			Kubeconfig:         opt.KubeConfigPath,
+			KubeContext:        opt.KubeContext,
+			Namespace:          opt.Namespace,
+			AllowedDirs:        opt.AllowedDirs,
+			LLM:                llmClient,
+			NewLLMClient:       newLLMClient,
+			MaxIterations:      opt.MaxIterations,
+			PromptTemplateFile: opt.PromptTemplateFilePath,
+			ExtraPromptPaths:   opt.ExtraPromptPaths,
+			Tools:              toolset,
+			Recorder:           recorder,
+			RemoveWorkDir:      opt.RemoveWorkDir,
+			SkipPermissions:    opt.SkipPermissions,
+			Redactor:           redactor,
+			EnableToolUseShim:  opt.EnableToolUseShim,
+			MCPClientEnabled:   opt.MCPClient,
+			RunOnce:            opt.Quiet || opt.UIType == ui.UITypeJSON,
+			InitialQuery:       initialQuery,
+			ChatMessageStore:   chatStore,
+			LegacyCommands:     opt.LegacyCommands,
+		}
 	}
+	k8sAgent := newAgent(toolset, chatStore, queryFromCmd)
 
 	err = k8sAgent.Init(ctx)
 	if err != nil {
@@ -485,12 +662,34 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 			return fmt.Errorf("creating terminal UI: %w", err)
 		}
 	case ui.UITypeWeb:
-		userInterface, err = html.NewHTMLUserInterface(k8sAgent, opt.UIListenAddress, recorder)
+		// Every session of the web UI has an agent, with tools of its own.
+		newSessionAgent := func(ctx context.Context, store api.ChatMessageStore) (*agent.Agent, error) {
+			toolset, err := newBuiltinToolset(opt)
+			if err != nil {
+				return nil, err
+			}
+			if err := handleCustomTools(&toolset, opt.ToolConfigPaths); err != nil {
+				return nil, fmt.Errorf("failed to process custom tools: %w", err)
+			}
+			sessionAgent := newAgent(toolset, store, "")
+			sessionAgent.RunOnce = false
+			if err := sessionAgent.Init(ctx); err != nil {
+				return nil, fmt.Errorf("starting k8s agent: %w", err)
+			}
+			return sessionAgent, nil
+		}
+		webOptions, err := newWebUIOptions(ctx, opt)
+		if err != nil {
+			return err
+		}
+		userInterface, err = html.NewHTMLUserInterface(k8sAgent, webOptions, recorder, sessionManager, newSessionAgent)
 		if err != nil {
 			return fmt.Errorf("creating web UI: %w", err)
 		}
 	case ui.UITypeTUI:
 		userInterface = ui.NewTUI(k8sAgent)
+	case ui.UITypeJSON:
+		userInterface = ui.NewJSONUI(k8sAgent, os.Stdout)
 	default:
 		return fmt.Errorf("ui-type mode %q is not known", opt.UIType)
 	}
@@ -498,7 +697,31 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	return repl(ctx, queryFromCmd, userInterface, k8sAgent)
 }
 
-func handleCustomTools(toolConfigPaths []string) error {
+// newRedactor creates the redactor for tool output, or returns nil if redaction is disabled.
+func newRedactor(opt Options) (*redact.Redactor, error) {
+	if opt.DisableRedaction {
+		klog.Warning("Secret redaction is disabled; tool output is sent to the model as is")
+		return nil, nil
+	}
+	redactor, err := redact.New(opt.RedactionPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("creating redactor: %w", err)
+	}
+	return redactor, nil
+}
+
+// newBuiltinToolset creates a tool set with the built-in tools enabled by the options.
+func newBuiltinToolset(opt Options) (tools.Tools, error) {
+	var toolset tools.Tools
+	toolset.Init()
+	toolset.Disable(opt.DisableTools...)
+	if err := toolset.RegisterBuiltinTools(opt.EnableTools); err != nil {
+		return toolset, fmt.Errorf("registering built-in tools: %w", err)
+	}
+	return toolset, nil
+}
+
+func handleCustomTools(toolset *tools.Tools, toolConfigPaths []string) error {
 	// resolve tool config paths, and then load and register custom tools from config files and dirs
 	for _, path := range toolConfigPaths {
 		pathWithPlaceholdersExpanded := path
@@ -525,7 +748,7 @@ func handleCustomTools(toolConfigPaths []string) error {
 
 		klog.Infof("Attempting to load custom tools from processed path: %q (original value from config: %q)", cleanedPath, path)
 
-		if err := tools.LoadAndRegisterCustomTools(cleanedPath); err != nil {
+		if err := toolset.LoadAndRegisterCustomTools(cleanedPath); err != nil {
 			if errors.Is(err, os.ErrNotExist) && !slices.Contains(defaultToolConfigPaths, path) {
 				// user specified a directory that does not exist, we must error out
 				return fmt.Errorf("custom tools directory not found (original value: %q, processed path: %q)", path, cleanedPath)
@@ -670,10 +893,37 @@ func startMCPServer(ctx context.Context, opt Options) error {
 	if err := os.MkdirAll(workDir, 0o755); err != nil {
 		return fmt.Errorf("error creating work directory: %w", err)
 	}
-	mcpServer, err := newKubectlMCPServer(ctx, opt.KubeConfigPath, tools.Default(), workDir, opt.ExternalTools, opt.MCPServerMode, opt.HTTPPort)
+	toolset, err := newBuiltinToolset(opt)
+	if err != nil {
+		return err
+	}
+	mcpServer, err := newKubectlMCPServer(ctx, opt.KubeConfigPath, toolset, workDir, opt.ExternalTools, opt.MCPServerMode, opt.HTTPPort)
 	if err != nil {
 		return fmt.Errorf("creating mcp server: %w", err)
 	}
+	mcpServer.policy = newMCPPolicy(opt)
+	if opt.MCPTenantsConfigPath != "" {
+		tenancyConfig, err := loadMCPTenancyConfig(opt.MCPTenantsConfigPath)
+		if err != nil {
+			return err
+		}
+		mcpServer.tenancy = newMCPTenancy(tenancyConfig, workDir)
+	}
+	if opt.MCPAuditLogPath != "" {
+		audit, err := journal.NewAppendingFileRecorder(opt.MCPAuditLogPath)
+		if err != nil {
+			return fmt.Errorf("creating audit log: %w", err)
+		}
+		defer audit.Close()
+		mcpServer.audit = audit
+	}
+	if opt.MCPServerAgent {
+		cfg, err := newMCPAgentConfig(opt)
+		if err != nil {
+			return err
+		}
+		mcpServer.addAgentTool(cfg)
+	}
 	return mcpServer.Serve(ctx)
 }
 
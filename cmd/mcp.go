@@ -16,9 +16,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	mcpgo "github.com/mark3labs/mcp-go/mcp"
@@ -31,24 +35,34 @@ type kubectlMCPServer struct {
 	server        *server.MCPServer
 	tools         tools.Tools
 	workDir       string
-	mcpManager    *mcp.Manager // Add MCP manager for external tool calls
-	mcpServerMode string       // Server mode (e.g., "streamable-http", "stdio")
-	httpPort      int          // Port for HTTP-based server modes
+	mcpManager    *mcp.Manager     // Add MCP manager for external tool calls
+	mcpServerMode string           // Server mode (e.g., "streamable-http", "sse", "stdio")
+	httpPort      int              // Port for HTTP-based server modes
+	agentConfig   *mcpAgentConfig  // Set when the agent is exposed as the ask_kubectl_ai tool
+	policy        *mcpPolicy       // Decides which tool calls clients may make
+	audit         journal.Recorder // Records every tool call made by clients
+	tenancy       *mcpTenancy      // Set when HTTP requests select their cluster and identity
 }
 
 func newKubectlMCPServer(ctx context.Context, kubectlConfig string, tools tools.Tools, workDir string, exposeExternalTools bool, serverMode string, httpPort int) (*kubectlMCPServer, error) {
 	s := &kubectlMCPServer{
 		kubectlConfig: kubectlConfig,
 		workDir:       workDir,
-		server: server.NewMCPServer(
-			"kubectl-ai",
-			"0.0.1",
-			server.WithToolCapabilities(true),
-		),
 		tools:         tools,
 		mcpServerMode: serverMode,
 		httpPort:      httpPort,
+		policy:        &mcpPolicy{defaultNamespace: "default"},
+		audit:         &journal.LogRecorder{},
 	}
+	s.server = server.NewMCPServer(
+		"kubectl-ai",
+		"0.0.1",
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+		server.WithElicitation(),
+		server.WithToolHandlerMiddleware(s.auditToolCall),
+	)
 
 	// Add built-in tools
 	for _, tool := range s.tools.AllTools() {
@@ -64,6 +78,8 @@ func newKubectlMCPServer(ctx context.Context, kubectlConfig string, tools tools.
 		), s.handleToolCall)
 	}
 
+	s.addResourcesAndPrompts()
+
 	// Only discover external MCP tools if explicitly enabled
 	if exposeExternalTools {
 		// Initialize MCP manager to get client tools
@@ -131,12 +147,20 @@ func newKubectlMCPServer(ctx context.Context, kubectlConfig string, tools tools.
 					continue
 				}
 
-				// Add the tool to the server
-				s.server.AddTool(mcpgo.NewToolWithRawSchema(
+				// Add the tool to the server, telling clients whether it is read-only
+				mcpTool := mcpgo.NewToolWithRawSchema(
 					uniqueToolName,
 					schema.Description,
 					toolInputSchema,
-				), s.handleToolCall)
+				)
+				switch tool.ModifiesResource {
+				case "no":
+					mcpTool.Annotations.ReadOnlyHint = mcpgo.ToBoolPtr(true)
+				case "yes":
+					mcpTool.Annotations.ReadOnlyHint = mcpgo.ToBoolPtr(false)
+					mcpTool.Annotations.DestructiveHint = tool.DestructiveHint
+				}
+				s.server.AddTool(mcpTool, s.handleToolCall)
 
 				totalToolsRegistered++
 				klog.V(3).Infof("Registered tool: %s from server %s", uniqueToolName, serverName)
@@ -167,10 +191,17 @@ func (s *kubectlMCPServer) Serve(ctx context.Context) error {
 	case "streamable-http":
 		// Start the server in streamable HTTP mode
 		klog.Infof("Starting MCP server in streamable HTTP mode on port %d", s.httpPort)
-		httpServer := server.NewStreamableHTTPServer(s.server)
+		httpServer := server.NewStreamableHTTPServer(s.server, server.WithHTTPContextFunc(withHTTPRequest))
 		endpoint := fmt.Sprintf(":%d", s.httpPort)
 		klog.Infof("Listening for streamable HTTP connections on port %d", s.httpPort)
 		return httpServer.Start(endpoint)
+	case "sse":
+		// Start the server with the HTTP+SSE transport, for clients that do not support streamable HTTP
+		klog.Infof("Starting MCP server in SSE mode on port %d", s.httpPort)
+		sseServer := server.NewSSEServer(s.server, server.WithSSEContextFunc(withHTTPRequest))
+		endpoint := fmt.Sprintf(":%d", s.httpPort)
+		klog.Infof("Listening for SSE connections on port %d (events at /sse, messages at /message)", s.httpPort)
+		return sseServer.Start(endpoint)
 	default:
 		return server.ServeStdio(s.server)
 	}
@@ -179,6 +210,12 @@ func (s *kubectlMCPServer) Serve(ctx context.Context) error {
 func (s *kubectlMCPServer) handleToolCall(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
 	toolName := request.Params.Name
 
+	ctx, done, err := s.toolEnv(ctx)
+	if err != nil {
+		return s.authorize(ctx, deny("%v", err), ""), nil
+	}
+	defer done()
+
 	// First, try to find the tool in our built-in tools collection
 	builtinTool := s.tools.Lookup(toolName)
 	if builtinTool != nil {
@@ -204,10 +241,6 @@ func (s *kubectlMCPServer) handleToolCall(ctx context.Context, request mcpgo.Cal
 
 // handleBuiltinToolCall handles calls to built-in kubectl-ai tools
 func (s *kubectlMCPServer) handleBuiltinToolCall(ctx context.Context, request mcpgo.CallToolRequest, tool tools.Tool) (*mcpgo.CallToolResult, error) {
-	// Set up context for built-in tools
-	ctx = context.WithValue(ctx, tools.KubeconfigKey, s.kubectlConfig)
-	ctx = context.WithValue(ctx, tools.WorkDirKey, s.workDir)
-
 	// Convert arguments to the expected type
 	args, ok := request.Params.Arguments.(map[string]any)
 	if !ok {
@@ -222,6 +255,17 @@ func (s *kubectlMCPServer) handleBuiltinToolCall(ctx context.Context, request mc
 		}, nil
 	}
 
+	description := tool.Name()
+	if call, err := s.tools.ParseToolInvocation(ctx, tool.Name(), args); err == nil {
+		description = call.Description()
+	}
+	if risk := tools.ExplainRisk(ctx, tool, args); risk != "" {
+		description += "\n\n" + risk
+	}
+	if refused := s.authorize(ctx, s.checkBuiltin(ctx, tool, args), description); refused != nil {
+		return refused, nil
+	}
+
 	// Execute the built-in tool
 	result, err := tool.Run(ctx, args)
 	if err != nil {
@@ -236,23 +280,30 @@ func (s *kubectlMCPServer) handleBuiltinToolCall(ctx context.Context, request mc
 		}, nil
 	}
 
-	// Convert result to string
-	var resultStr string
-	switch v := result.(type) {
-	case string:
-		resultStr = v
-	default:
-		resultStr = fmt.Sprintf("%v", v)
+	return builtinToolResult(result)
+}
+
+// builtinToolResult converts the result of a built-in tool. Text results are returned as they are;
+// structured results (e.g. command output) are returned as JSON text and as structured content.
+func builtinToolResult(result any) (*mcpgo.CallToolResult, error) {
+	if text, ok := result.(string); ok {
+		return mcpgo.NewToolResultText(text), nil
 	}
 
-	return &mcpgo.CallToolResult{
-		Content: []mcpgo.Content{
-			mcpgo.TextContent{
-				Type: "text",
-				Text: resultStr,
-			},
-		},
-	}, nil
+	structured, err := tools.ToolResultToMap(result)
+	if err != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("converting tool result: %v", err)), nil
+	}
+	b, err := json.Marshal(structured)
+	if err != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("converting tool result to json: %v", err)), nil
+	}
+
+	callResult := mcpgo.NewToolResultStructured(structured, string(b))
+	if execResult, ok := result.(*tools.ExecResult); ok && execResult != nil {
+		callResult.IsError = execResult.Error != "" || execResult.ExitCode != 0
+	}
+	return callResult, nil
 }
 
 // handleExternalMCPToolCall handles calls to external MCP tools
@@ -275,6 +326,7 @@ func (s *kubectlMCPServer) handleExternalMCPToolCall(ctx context.Context, reques
 
 	var targetServerName string
 	var originalToolName string
+	var targetTool mcp.Tool
 
 	// Look for the tool by checking both original name and server-prefixed name
 	for serverName, tools := range serverTools {
@@ -283,6 +335,7 @@ func (s *kubectlMCPServer) handleExternalMCPToolCall(ctx context.Context, reques
 			if uniqueToolName == toolName {
 				targetServerName = serverName
 				originalToolName = tool.Name // Use the original tool name for the MCP call
+				targetTool = tool
 				break
 			}
 		}
@@ -344,8 +397,12 @@ func (s *kubectlMCPServer) handleExternalMCPToolCall(ctx context.Context, reques
 		}, nil
 	}
 
+	if refused := s.authorize(ctx, s.policy.checkExternal(targetTool), describeExternalCall(targetTool, toolArgs)); refused != nil {
+		return refused, nil
+	}
+
 	// Call the external MCP tool using the original tool name
-	result, err := client.CallTool(ctx, originalToolName, toolArgs)
+	result, err := client.CallToolResult(ctx, originalToolName, toolArgs)
 	if err != nil {
 		return &mcpgo.CallToolResult{
 			IsError: true,
@@ -358,13 +415,29 @@ func (s *kubectlMCPServer) handleExternalMCPToolCall(ctx context.Context, reques
 		}, nil
 	}
 
-	// Return successful result
+	// Pass the result through as it is, with all of its content
 	return &mcpgo.CallToolResult{
-		Content: []mcpgo.Content{
-			mcpgo.TextContent{
-				Type: "text",
-				Text: result,
-			},
-		},
+		Content:           result.Content,
+		StructuredContent: result.Structured,
+		IsError:           result.IsError,
 	}, nil
 }
+
+// describeExternalCall describes a call to a tool of an external MCP server, for confirmation,
+// with the trust level of the server and why the call may modify resources.
+func describeExternalCall(tool mcp.Tool, args map[string]any) string {
+	var parts []string
+	for k, v := range args {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(parts)
+	description := fmt.Sprintf("[MCP: %s] %s(%s)", tool.Server, tool.Name, strings.Join(parts, ", "))
+	if tool.Trust != "" {
+		description += fmt.Sprintf("\n\nThe MCP server %q has trust level %q", tool.Server, tool.Trust)
+		if tool.ModifiesResourceReason != "" {
+			description += "; " + tool.ModifiesResourceReason
+		}
+		description += "."
+	}
+	return description
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"k8s.io/klog/v2"
+)
+
+// askAgentToolName is the MCP tool that runs the full kubectl-ai agent.
+const askAgentToolName = "ask_kubectl_ai"
+
+// maxTranscriptOutput limits how much of each command's output is included in the transcript.
+const maxTranscriptOutput = 2000
+
+// mcpAgentConfig configures the agent that answers ask_kubectl_ai calls.
+type mcpAgentConfig struct {
+	// newLLMClient creates the LLM client used for a single call.
+	newLLMClient func(ctx context.Context) (gollm.Client, error)
+	model        string
+
+	maxIterations      int
+	promptTemplateFile string
+	extraPromptPaths   []string
+
+	// skipPermissions lets the agent run commands that modify resources, unless the
+	// server is read-only. Otherwise such commands are declined, and the model is told so.
+	skipPermissions bool
+	redactor        *redact.Redactor
+}
+
+// newMCPAgentConfig configures the ask_kubectl_ai agent from the command line options.
+func newMCPAgentConfig(opt Options) (*mcpAgentConfig, error) {
+	redactor, err := newRedactor(opt)
+	if err != nil {
+		return nil, err
+	}
+	return &mcpAgentConfig{
+		newLLMClient: func(ctx context.Context) (gollm.Client, error) {
+			if opt.SkipVerifySSL {
+				return gollm.NewClient(ctx, opt.ProviderID, gollm.WithSkipVerifySSL())
+			}
+			return gollm.NewClient(ctx, opt.ProviderID)
+		},
+		model:              opt.ModelID,
+		maxIterations:      opt.MaxIterations,
+		promptTemplateFile: opt.PromptTemplateFilePath,
+		extraPromptPaths:   opt.ExtraPromptPaths,
+		skipPermissions:    opt.SkipPermissions,
+		redactor:           redactor,
+	}, nil
+}
+
+// addAgentTool exposes the agent as the ask_kubectl_ai tool.
+func (s *kubectlMCPServer) addAgentTool(cfg *mcpAgentConfig) {
+	s.agentConfig = cfg
+	s.server.AddTool(mcpgo.NewTool(askAgentToolName,
+		mcpgo.WithDescription("Ask kubectl-ai to investigate or operate the Kubernetes cluster. "+
+			"It plans and runs the kubectl commands needed, and returns its answer along with a transcript of the commands it ran."),
+		mcpgo.WithString("query", mcpgo.Required(), mcpgo.Description("The question or task, in natural language.")),
+		mcpgo.WithString("namespace", mcpgo.Description("Namespace to work in. Defaults to the context's namespace.")),
+		mcpgo.WithString("context", mcpgo.Description("Kubeconfig context to work against. Defaults to the current context.")),
+	), s.handleAgentToolCall)
+}
+
+// agentTranscript collects what the agent did while answering a query.
+type agentTranscript struct {
+	answer   []string
+	commands []string
+	errors   []string
+}
+
+func (s *kubectlMCPServer) handleAgentToolCall(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcpgo.NewToolResultError(err.Error()), nil
+	}
+	cfg := s.agentConfig
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	llmClient, err := cfg.newLLMClient(ctx)
+	if err != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("creating llm client: %v", err)), nil
+	}
+	defer llmClient.Close()
+
+	k8sAgent := &agent.Agent{
+		Model:              cfg.model,
+		Kubeconfig:         s.kubectlConfig,
+		KubeContext:        request.GetString("context", ""),
+		Namespace:          request.GetString("namespace", ""),
+		LLM:                llmClient,
+		MaxIterations:      cfg.maxIterations,
+		PromptTemplateFile: cfg.promptTemplateFile,
+		ExtraPromptPaths:   cfg.extraPromptPaths,
+		Tools:              s.tools,
+		RemoveWorkDir:      true,
+		SkipPermissions:    cfg.skipPermissions && !s.policy.readOnly,
+		AuthorizeToolCall:  s.authorizeAgentToolCall(mcpCallerFromContext(ctx)),
+		Redactor:           cfg.redactor,
+		ChatMessageStore:   sessions.NewInMemoryChatStore(),
+	}
+	if err := k8sAgent.Init(ctx); err != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("starting agent: %v", err)), nil
+	}
+	defer k8sAgent.Close()
+
+	// The agent runs interactively, so that commands needing approval can be declined
+	// and the model can carry on, rather than the whole run failing.
+	if err := k8sAgent.Run(ctx, query); err != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("running agent: %v", err)), nil
+	}
+
+	var progressToken mcpgo.ProgressToken
+	if request.Params.Meta != nil {
+		progressToken = request.Params.Meta.ProgressToken
+	}
+	progress := 0
+	notify := func(message string) {
+		srv := server.ServerFromContext(ctx)
+		if progressToken == nil || srv == nil {
+			return
+		}
+		progress++
+		if err := srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": progressToken,
+			"progress":      progress,
+			"message":       message,
+		}); err != nil {
+			klog.V(2).Infof("failed to send progress notification: %v", err)
+		}
+	}
+
+	var transcript agentTranscript
+	pendingCommand := ""
+	for done := false; !done; {
+		var msg *api.Message
+		select {
+		case <-ctx.Done():
+			return mcpgo.NewToolResultError(fmt.Sprintf("agent cancelled: %v", ctx.Err())), nil
+		case v, ok := <-k8sAgent.Output:
+			if !ok {
+				done = true
+				continue
+			}
+			msg, _ = v.(*api.Message)
+		}
+		if msg == nil {
+			continue
+		}
+
+		switch msg.Type {
+		case api.MessageTypeText:
+			text, _ := msg.Payload.(string)
+			if msg.Source == api.MessageSourceModel {
+				transcript.answer = append(transcript.answer, text)
+			}
+			notify(text)
+		case api.MessageTypeError:
+			text, _ := msg.Payload.(string)
+			transcript.errors = append(transcript.errors, text)
+			notify(text)
+		case api.MessageTypeToolCallRequest:
+			pendingCommand, _ = msg.Payload.(string)
+			notify("Running: " + pendingCommand)
+		case api.MessageTypeToolCallResponse:
+			transcript.commands = append(transcript.commands, "$ "+pendingCommand+"\n"+transcriptOutput(msg.Payload))
+		case api.MessageTypeUserChoiceRequest:
+			// Only reached without skipPermissions: there is nobody to approve the commands.
+			choice, _ := msg.Payload.(*api.UserChoiceRequest)
+			if choice != nil {
+				transcript.errors = append(transcript.errors, "Declined, as approval is required: "+choice.Prompt)
+			}
+			notify("Declined commands that modify resources")
+			select {
+			case k8sAgent.Input <- &api.UserChoiceResponse{Choice: agent.ChoiceNo}:
+			case <-ctx.Done():
+				return mcpgo.NewToolResultError(fmt.Sprintf("agent cancelled: %v", ctx.Err())), nil
+			}
+		case api.MessageTypeUserInputRequest:
+			// The agent finished the query and wants the next one.
+			done = true
+		}
+	}
+
+	session := k8sAgent.Session()
+	text := formatAgentTranscript(transcript, session.KubeContext, session.Namespace)
+	if len(transcript.answer) == 0 && k8sAgent.LastErr() != nil {
+		return mcpgo.NewToolResultError(fmt.Sprintf("agent failed: %v\n\n%s", k8sAgent.LastErr(), text)), nil
+	}
+	return mcpgo.NewToolResultText(text), nil
+}
+
+// authorizeAgentToolCall returns a function that checks the tool calls of the ask_kubectl_ai agent
+// against the server policy, as for the calls clients make directly, and records them in the audit
+// journal. Calls that may modify resources are not confirmed with the client: the agent declines
+// them itself, unless the server runs with --skip-permissions.
+func (s *kubectlMCPServer) authorizeAgentToolCall(caller mcpCaller) func(ctx context.Context, tool tools.Tool, args map[string]any) error {
+	return func(ctx context.Context, tool tools.Tool, args map[string]any) error {
+		entry := &mcpAuditEntry{
+			Caller:    caller,
+			Via:       askAgentToolName,
+			Tool:      tool.Name(),
+			Arguments: auditArguments(args),
+			Decision:  auditAllowed,
+		}
+		defer s.writeAudit(ctx, entry)
+
+		// Commands that do not select a context or namespace run in those the agent was asked to work in.
+		checked := args
+		if command, ok := args["command"].(string); ok && (tool.Name() == "kubectl" || tool.Name() == "bash") {
+			kubeContext, _ := ctx.Value(tools.KubeContextKey).(string)
+			namespace, _ := ctx.Value(tools.NamespaceKey).(string)
+			checked = maps.Clone(args)
+			checked["command"] = tools.InjectKubeTarget(command, kubeContext, namespace)
+		}
+		if decision := s.policy.check(ctx, tool, checked); !decision.allowed {
+			entry.Decision, entry.Reason = auditDenied, decision.reason
+			return errors.New(decision.reason)
+		}
+		return nil
+	}
+}
+
+// formatAgentTranscript renders the agent's answer, followed by the commands it ran.
+func formatAgentTranscript(t agentTranscript, kubeContext, namespace string) string {
+	var sb strings.Builder
+	if len(t.answer) == 0 {
+		sb.WriteString("The agent did not produce an answer.")
+	} else {
+		sb.WriteString(strings.Join(t.answer, "\n\n"))
+	}
+	if len(t.errors) > 0 {
+		sb.WriteString("\n\nErrors:\n")
+		for _, e := range t.errors {
+			sb.WriteString("- " + e + "\n")
+		}
+	}
+	if len(t.commands) > 0 {
+		fmt.Fprintf(&sb, "\n\nCommands run against context %q, namespace %q:\n\n", kubeContext, namespace)
+		sb.WriteString(strings.Join(t.commands, "\n\n"))
+	}
+	return sb.String()
+}
+
+// transcriptOutput returns the readable part of a tool call response, truncated.
+func transcriptOutput(payload any) string {
+	var out string
+	switch p := payload.(type) {
+	case string:
+		out = p
+	case map[string]any:
+		for _, key := range []string{"content", "stdout", "stderr", "error"} {
+			if v, ok := p[key]; ok && v != "" {
+				out += fmt.Sprint(v)
+			}
+		}
+		if out == "" {
+			b, _ := json.Marshal(p)
+			out = string(b)
+		}
+	default:
+		out = fmt.Sprint(p)
+	}
+	if len(out) > maxTranscriptOutput {
+		// Cut at the start of a rune, so the output stays valid UTF-8.
+		n := maxTranscriptOutput
+		for n > 0 && !utf8.RuneStart(out[n]) {
+			n--
+		}
+		out = out[:n] + "\n... (output truncated)"
+	}
+	return strings.TrimRight(out, "\n")
+}
@@ -0,0 +1,403 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/pkg/browser"
+	"github.com/spf13/cobra"
+)
+
+// mcpLoginTimeout is how long we wait for the user to authorize kubectl-ai in the browser.
+const mcpLoginTimeout = 5 * time.Minute
+
+// newMCPCommand creates the mcp command, which manages the MCP servers kubectl-ai connects to in MCP client mode.
+func newMCPCommand() *cobra.Command {
+	var configPath string
+	mcpCmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Manage the MCP servers used in MCP client mode (--mcp-client)",
+		// The arguments are valid by the time this runs: errors from here on are not usage errors.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+		},
+	}
+	mcpCmd.PersistentFlags().StringVar(&configPath, "config", "", "path to the MCP config file (default: ~/.config/kubectl-ai/mcp.yaml)")
+
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the configured MCP servers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMCPList(cmd.OutOrStdout(), configPath)
+		},
+	})
+
+	mcpCmd.AddCommand(newMCPAddCommand(&configPath))
+
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "remove <server>",
+		Short: "Remove an MCP server from the config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMCPRemove(cmd.OutOrStdout(), configPath, args[0])
+		},
+	})
+
+	var testTimeout time.Duration
+	testCmd := &cobra.Command{
+		Use:   "test <server>",
+		Short: "Connect to an MCP server and list its tools, to check its configuration",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), testTimeout)
+			defer cancel()
+			return runMCPTest(ctx, cmd.OutOrStdout(), configPath, args[0])
+		},
+	}
+	testCmd.Flags().DurationVar(&testTimeout, "timeout", mcp.DefaultConnectionTimeout, "how long to wait for the server")
+	mcpCmd.AddCommand(testCmd)
+
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "tools [server...]",
+		Short: "Print the tools of MCP servers, as they are described to the LLM",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), mcp.DefaultConnectionTimeout)
+			defer cancel()
+			return runMCPTools(ctx, cmd.OutOrStdout(), configPath, args)
+		},
+	})
+
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "login <server>",
+		Short: "Log in to an MCP server using OAuth, and cache its tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), mcpLoginTimeout)
+			defer cancel()
+			return runMCPLogin(ctx, cmd.OutOrStdout(), configPath, args[0])
+		},
+	})
+
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "logout <server>",
+		Short: "Remove the cached OAuth tokens of an MCP server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMCPLogout(cmd.OutOrStdout(), args[0])
+		},
+	})
+
+	return mcpCmd
+}
+
+// newMCPAddCommand creates the mcp add command. The server is either remote (--url),
+// or a local command given after the server name.
+func newMCPAddCommand(configPath *string) *cobra.Command {
+	var server mcp.ServerConfig
+	var headers, env []string
+	addCmd := &cobra.Command{
+		Use:   "add <server> [-- command [args...]]",
+		Short: "Add an MCP server to the config file",
+		Example: `  # Add a local server, started by kubectl-ai
+  kubectl-ai mcp add filesystem -- npx -y @modelcontextprotocol/server-filesystem /tmp
+
+  # Add a remote server
+  kubectl-ai mcp add tickets --url https://tickets.example.com/mcp --header "Authorization=Bearer \${TICKETS_TOKEN}"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server.Name = args[0]
+			if len(args) > 1 {
+				if server.URL != "" {
+					return fmt.Errorf("specify either --url or a command, not both")
+				}
+				server.Command, server.Args = args[1], args[2:]
+			}
+			var err error
+			if server.Headers, err = parseKeyValues("header", headers); err != nil {
+				return err
+			}
+			if server.Env, err = parseKeyValues("env", env); err != nil {
+				return err
+			}
+			return runMCPAdd(cmd.OutOrStdout(), *configPath, server)
+		},
+	}
+	f := addCmd.Flags()
+	f.StringVar(&server.URL, "url", "", "URL of a remote MCP server")
+	f.StringVar(&server.Transport, "transport", "", "transport of the remote server: streamable-http (default) or sse")
+	f.StringArrayVar(&headers, "header", nil, "HTTP header sent to the remote server, as name=value (can be repeated); ${VAR} is replaced by the environment variable")
+	f.StringArrayVar(&env, "env", nil, "environment variable of the local server command, as name=value (can be repeated)")
+	f.IntVar(&server.Timeout, "timeout", 0, "timeout in seconds of requests to the remote server")
+	f.StringVar(&server.Trust, "trust", "", "trust level of the server: untrusted, standard (default) or trusted")
+	f.StringSliceVar(&server.IncludeTools, "include-tools", nil, "only use the tools matching these glob patterns")
+	f.StringSliceVar(&server.ExcludeTools, "exclude-tools", nil, "do not use the tools matching these glob patterns")
+	return addCmd
+}
+
+// parseKeyValues parses name=value flag values.
+func parseKeyValues(flag string, values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --%s %q: must be name=value", flag, v)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// mcpServerConfig returns the configuration of the named server in the MCP config file.
+func mcpServerConfig(configPath, name string) (mcp.ServerConfig, error) {
+	config, err := mcp.LoadConfig(configPath)
+	if err != nil {
+		return mcp.ServerConfig{}, fmt.Errorf("loading MCP config: %w", err)
+	}
+	server, ok := config.Server(name)
+	if !ok {
+		return mcp.ServerConfig{}, fmt.Errorf("MCP server %q is not configured", name)
+	}
+	return server, nil
+}
+
+// describeMCPServer returns the transport of a server, and the URL or command line used to reach it.
+func describeMCPServer(server mcp.ServerConfig) (transport, endpoint string) {
+	if server.URL == "" {
+		return "stdio", strings.Join(append([]string{server.Command}, server.Args...), " ")
+	}
+	if server.Transport == "" {
+		return mcp.TransportStreamableHTTP, server.URL
+	}
+	return server.Transport, server.URL
+}
+
+func runMCPList(out io.Writer, configPath string) error {
+	config, err := mcp.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading MCP config: %w", err)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTRANSPORT\tTRUST\tENDPOINT")
+	for _, server := range config.Servers {
+		transport, endpoint := describeMCPServer(server)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", server.Name, transport, server.TrustLevel(), endpoint)
+	}
+	return w.Flush()
+}
+
+func runMCPAdd(out io.Writer, configPath string, server mcp.ServerConfig) error {
+	config, err := mcp.LoadConfigForEdit(configPath)
+	if err != nil {
+		return fmt.Errorf("loading MCP config: %w", err)
+	}
+	if err := config.AddServer(server); err != nil {
+		return fmt.Errorf("adding MCP server %q: %w", server.Name, err)
+	}
+	if err := config.Save(configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added MCP server %q. Check it with \"kubectl-ai mcp test %s\".\n", server.Name, server.Name)
+	return nil
+}
+
+func runMCPRemove(out io.Writer, configPath, name string) error {
+	config, err := mcp.LoadConfigForEdit(configPath)
+	if err != nil {
+		return fmt.Errorf("loading MCP config: %w", err)
+	}
+	if err := config.RemoveServer(name); err != nil {
+		return err
+	}
+	if err := config.Save(configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed MCP server %q.\n", name)
+	return nil
+}
+
+func runMCPTest(ctx context.Context, out io.Writer, configPath, name string) error {
+	server, err := mcpServerConfig(configPath, name)
+	if err != nil {
+		return err
+	}
+	transport, endpoint := describeMCPServer(server)
+	fmt.Fprintf(out, "Testing MCP server %q (%s: %s)\n", name, transport, endpoint)
+
+	manager := mcp.NewManager(&mcp.Config{Servers: []mcp.ServerConfig{server}})
+	defer manager.Close()
+
+	start := time.Now()
+	if err := manager.ConnectAll(ctx); err != nil {
+		fmt.Fprintf(out, "  Failed to connect after %s: %v\n", time.Since(start).Round(time.Millisecond), err)
+		if hint := mcpErrorHint(ctx, err); hint != "" {
+			fmt.Fprintf(out, "  Hint: %s\n", hint)
+		}
+		return fmt.Errorf("connecting to MCP server %q failed", name)
+	}
+	fmt.Fprintf(out, "  Connected in %s\n", time.Since(start).Round(time.Millisecond))
+
+	client, _ := manager.GetClient(name)
+	start = time.Now()
+	serverTools, err := client.ListTools(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "  Failed to list tools after %s: %v\n", time.Since(start).Round(time.Millisecond), err)
+		if hint := mcpErrorHint(ctx, err); hint != "" {
+			fmt.Fprintf(out, "  Hint: %s\n", hint)
+		}
+		return fmt.Errorf("listing the tools of MCP server %q failed", name)
+	}
+	allowed := 0
+	for _, tool := range serverTools {
+		if server.ToolAllowed(tool.Name) {
+			allowed++
+		}
+	}
+	fmt.Fprintf(out, "  Listed %d tools in %s", len(serverTools), time.Since(start).Round(time.Millisecond))
+	if excluded := len(serverTools) - allowed; excluded > 0 {
+		fmt.Fprintf(out, " (%d excluded by include_tools/exclude_tools)", excluded)
+	}
+	fmt.Fprintln(out)
+	return nil
+}
+
+// mcpErrorHint suggests how to fix a failure to reach an MCP server.
+func mcpErrorHint(ctx context.Context, err error) string {
+	msg := err.Error()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded):
+		return "the server did not answer in time; check that it is running, or increase --timeout"
+	case strings.Contains(msg, "kubectl-ai mcp login"):
+		return ""
+	case strings.Contains(msg, "executable file not found"), strings.Contains(msg, "expanding command path"):
+		return "the server command is not installed, or not in your PATH"
+	case strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such host"):
+		return "check that the server is running, and that its URL is correct"
+	case strings.Contains(msg, "x509:"), strings.Contains(msg, "tls:"):
+		return "the TLS handshake failed; check the server's certificate, or set tls.ca_file (and tls.cert_file and tls.key_file for mTLS)"
+	case strings.Contains(msg, "401"), strings.Contains(msg, "403"):
+		return "the server rejected the credentials; check its auth, oauth or headers configuration"
+	case strings.Contains(msg, "404"), strings.Contains(msg, "405"):
+		return "check the server URL, and its transport (streamable-http servers usually end in /mcp, sse servers in /sse)"
+	}
+	return ""
+}
+
+func runMCPTools(ctx context.Context, out io.Writer, configPath string, names []string) error {
+	config, err := mcp.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading MCP config: %w", err)
+	}
+	if len(names) > 0 {
+		var servers []mcp.ServerConfig
+		for _, name := range names {
+			server, ok := config.Server(name)
+			if !ok {
+				return fmt.Errorf("MCP server %q is not configured", name)
+			}
+			servers = append(servers, server)
+		}
+		config = &mcp.Config{Servers: servers}
+	}
+
+	manager := mcp.NewManager(config)
+	defer manager.Close()
+	// Servers that fail to connect are reported below.
+	_ = manager.ConnectAll(ctx)
+
+	var failed []string
+	for _, state := range manager.ServerStates() {
+		fmt.Fprintf(out, "# %s\n", state.Name)
+		if !state.Connected {
+			fmt.Fprintf(out, "Not connected: %s\n\n", state.LastError)
+			failed = append(failed, state.Name)
+			continue
+		}
+		serverTools, err := manager.ListServerTools(ctx, state.Name)
+		if err != nil {
+			fmt.Fprintf(out, "%v\n\n", err)
+			failed = append(failed, state.Name)
+			continue
+		}
+		for _, tool := range serverTools {
+			def, err := tools.ConvertToolToGollm(&tool)
+			if err != nil {
+				return fmt.Errorf("converting tool %q of MCP server %q: %w", tool.Name, state.Name, err)
+			}
+			data, err := json.MarshalIndent(def, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling tool %q of MCP server %q: %w", tool.Name, state.Name, err)
+			}
+			fmt.Fprintf(out, "## %s (modifies resources: %s)\n%s\n\n", tool.Name, tool.ModifiesResource, data)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not list the tools of MCP servers %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func runMCPLogin(ctx context.Context, out io.Writer, configPath, name string) error {
+	serverCfg, err := mcpServerConfig(configPath, name)
+	if err != nil {
+		return err
+	}
+	cache, err := mcp.DefaultTokenCache()
+	if err != nil {
+		return err
+	}
+
+	openURL := func(url string) error {
+		fmt.Fprintf(out, "Opening the authorization page in your browser. If it does not open, visit:\n\n  %s\n\n", url)
+		// The user can still open the URL themselves.
+		_ = browser.OpenURL(url)
+		return nil
+	}
+	if err := mcp.Login(ctx, serverCfg, cache, openURL); err != nil {
+		return fmt.Errorf("logging in to MCP server %q: %w", name, err)
+	}
+	fmt.Fprintf(out, "Logged in to MCP server %q.\n", name)
+	return nil
+}
+
+func runMCPLogout(out io.Writer, name string) error {
+	cache, err := mcp.DefaultTokenCache()
+	if err != nil {
+		return err
+	}
+	removed, err := mcp.Logout(cache, name)
+	if err != nil {
+		return fmt.Errorf("logging out of MCP server %q: %w", name, err)
+	}
+	if !removed {
+		fmt.Fprintf(out, "Not logged in to MCP server %q.\n", name)
+		return nil
+	}
+	fmt.Fprintf(out, "Logged out of MCP server %q.\n", name)
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// runMCPCommand runs the mcp command with the given arguments, and returns its output.
+func runMCPCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := newMCPCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestMCPCommandAddListRemove(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mcp.yaml")
+	if err := (&mcp.Config{Servers: []mcp.ServerConfig{{Name: "local", Command: "server"}}}).Save(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runMCPCommand(t, "add", "remote", "--config", configPath,
+		"--url", "https://example.com/sse", "--transport", "sse",
+		"--header", "Authorization=Bearer ${TOKEN}", "--trust", "trusted", "--exclude-tools", "delete_*"); err != nil {
+		t.Fatalf("mcp add remote: %v", err)
+	}
+	if _, err := runMCPCommand(t, "add", "fs", "--config", configPath, "--env", "DEBUG=1", "--", "npx", "-y", "server-filesystem", "/tmp"); err != nil {
+		t.Fatalf("mcp add fs: %v", err)
+	}
+	if _, err := runMCPCommand(t, "add", "fs", "--config", configPath, "--url", "https://example.com/mcp"); err == nil {
+		t.Errorf("adding a duplicate server succeeded, want an error")
+	}
+	if _, err := runMCPCommand(t, "add", "bad", "--config", configPath, "--url", "https://example.com/mcp", "--trust", "maybe"); err == nil {
+		t.Errorf("adding an invalid server succeeded, want an error")
+	}
+
+	config, err := mcp.LoadConfigForEdit(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, _ := config.Server("remote")
+	if remote.Transport != mcp.TransportSSE || remote.Headers["Authorization"] != "Bearer ${TOKEN}" || remote.Trust != mcp.TrustTrusted || len(remote.ExcludeTools) != 1 {
+		t.Errorf("saved remote server = %+v", remote)
+	}
+	fs, _ := config.Server("fs")
+	if fs.Command != "npx" || strings.Join(fs.Args, " ") != "-y server-filesystem /tmp" || fs.Env["DEBUG"] != "1" {
+		t.Errorf("saved fs server = %+v", fs)
+	}
+
+	out, err := runMCPCommand(t, "list", "--config", configPath)
+	if err != nil {
+		t.Fatalf("mcp list: %v", err)
+	}
+	for _, want := range []string{"local", "remote", "sse", "trusted", "https://example.com/sse", "npx -y server-filesystem /tmp"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mcp list output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := runMCPCommand(t, "remove", "remote", "--config", configPath); err != nil {
+		t.Fatalf("mcp remove: %v", err)
+	}
+	if out, _ := runMCPCommand(t, "list", "--config", configPath); strings.Contains(out, "remote") {
+		t.Errorf("mcp list output contains the removed server:\n%s", out)
+	}
+}
+
+func TestMCPCommandTestAndTools(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	handler := func(context.Context, mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		return mcpgo.NewToolResultText("ok"), nil
+	}
+	srv.AddTool(mcpgo.NewTool("get_pods", mcpgo.WithDescription("Lists pods"), mcpgo.WithReadOnlyHintAnnotation(true),
+		mcpgo.WithString("namespace", mcpgo.Required(), mcpgo.Description("Namespace of the pods"))), handler)
+	srv.AddTool(mcpgo.NewTool("delete_pod"), handler)
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(srv))
+	defer func() {
+		ts.CloseClientConnections()
+		ts.Close()
+	}()
+
+	configPath := filepath.Join(t.TempDir(), "mcp.yaml")
+	config := &mcp.Config{Servers: []mcp.ServerConfig{
+		{Name: "cluster", URL: ts.URL + "/mcp", ExcludeTools: []string{"delete_*"}},
+		{Name: "down", Command: "kubectl-ai-no-such-mcp-server"},
+	}}
+	if err := config.Save(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runMCPCommand(t, "test", "cluster", "--config", configPath)
+	if err != nil {
+		t.Fatalf("mcp test cluster: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Connected in") || !strings.Contains(out, "Listed 2 tools") || !strings.Contains(out, "1 excluded") {
+		t.Errorf("unexpected mcp test output:\n%s", out)
+	}
+
+	out, err = runMCPCommand(t, "test", "down", "--config", configPath)
+	if err == nil || !strings.Contains(out, "Hint: the server command is not installed") {
+		t.Errorf("mcp test down = %v, want a failure with a hint:\n%s", err, out)
+	}
+
+	out, err = runMCPCommand(t, "tools", "cluster", "--config", configPath)
+	if err != nil {
+		t.Fatalf("mcp tools cluster: %v\n%s", err, out)
+	}
+	for _, want := range []string{"## get_pods (modifies resources: no)", `"description": "Lists pods"`, `"namespace"`, `"required": [`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mcp tools output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "delete_pod") {
+		t.Errorf("mcp tools output contains an excluded tool:\n%s", out)
+	}
+}
+
+func TestMCPErrorHint(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: errors.New(`exec: "npx": executable file not found in $PATH`), want: "not installed"},
+		{err: errors.New("dial tcp 127.0.0.1:1: connect: connection refused"), want: "is running"},
+		{err: errors.New("tls: failed to verify certificate: x509: certificate signed by unknown authority"), want: "tls.ca_file"},
+		{err: errors.New("request failed with status 401"), want: "credentials"},
+		{err: fmt.Errorf("waiting: %w", context.DeadlineExceeded), want: "--timeout"},
+		{err: errors.New(`authorization required, run "kubectl-ai mcp login x"`), want: ""},
+		{err: errors.New("something else"), want: ""},
+	}
+	for _, tt := range tests {
+		got := mcpErrorHint(context.Background(), tt.err)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("mcpErrorHint(%q) = %q, want it to contain %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"k8s.io/klog/v2"
+)
+
+// mcpPolicy decides which tool calls MCP clients may make.
+type mcpPolicy struct {
+	// readOnly refuses every call that may modify resources.
+	readOnly bool
+	// skipPermissions runs calls that may modify resources without asking
+	// the client's user to confirm them.
+	skipPermissions bool
+
+	// allowVerbs and denyVerbs restrict the kubectl subcommands that may be run.
+	allowVerbs []string
+	denyVerbs  []string
+	// allowNamespaces and denyNamespaces restrict the namespaces kubectl commands may target.
+	allowNamespaces []string
+	denyNamespaces  []string
+	// defaultNamespace is the namespace of kubectl commands that do not select one.
+	defaultNamespace string
+}
+
+// newMCPPolicy creates the MCP server policy from the command line options.
+func newMCPPolicy(opt Options) *mcpPolicy {
+	defaultNamespace := opt.Namespace
+	if defaultNamespace == "" {
+		defaultNamespace = "default"
+	}
+	return &mcpPolicy{
+		readOnly:         opt.MCPReadOnly,
+		skipPermissions:  opt.SkipPermissions,
+		allowVerbs:       opt.MCPAllowVerbs,
+		denyVerbs:        opt.MCPDenyVerbs,
+		allowNamespaces:  opt.MCPAllowNamespaces,
+		denyNamespaces:   opt.MCPDenyNamespaces,
+		defaultNamespace: defaultNamespace,
+	}
+}
+
+// policyDecision is the outcome of checking a tool call against the policy.
+type policyDecision struct {
+	allowed bool
+	// needsConfirmation is set for allowed calls that may modify resources.
+	needsConfirmation bool
+	// reason explains why a call is not allowed.
+	reason string
+}
+
+func deny(format string, args ...any) policyDecision {
+	return policyDecision{reason: fmt.Sprintf(format, args...)}
+}
+
+// check checks a call to a built-in or custom tool.
+// The context carries the same values (e.g. the working directory) as when the tool runs.
+func (p *mcpPolicy) check(ctx context.Context, tool tools.Tool, args map[string]any) policyDecision {
+	interactive, err := tool.IsInteractive(args)
+	if err != nil {
+		return deny("%v", err)
+	}
+	if interactive {
+		return deny("interactive commands are not supported over MCP")
+	}
+
+	modifies := tools.CheckModifiesResource(ctx, tool, args)
+	if p.readOnly && modifies != "no" {
+		return deny("the server is read-only, and this call may modify resources")
+	}
+
+	if command, ok := args["command"].(string); ok && p.restrictsKubectl() {
+		invocations, ok := tools.KubectlInvocations(command)
+		if !ok {
+			return deny("the kubectl calls of the command cannot be determined, e.g. because it runs a nested shell or eval, so it cannot be checked against the server policy")
+		}
+		for _, inv := range invocations {
+			if d := p.checkKubectl(inv); !d.allowed {
+				return d
+			}
+		}
+	}
+
+	return policyDecision{allowed: true, needsConfirmation: modifies != "no" && !p.skipPermissions}
+}
+
+// checkExternal checks a call to a tool of an external MCP server.
+// We cannot tell what such tools do, so unless the MCP configuration or the tool's
+// annotations say otherwise, they are treated as modifying resources.
+func (p *mcpPolicy) checkExternal(tool mcp.Tool) policyDecision {
+	modifies := tool.ModifiesResource != "no"
+	if p.readOnly && modifies {
+		return deny("the server is read-only, and external tool %q may modify resources", tool.Name)
+	}
+	return policyDecision{allowed: true, needsConfirmation: modifies && !p.skipPermissions}
+}
+
+func (p *mcpPolicy) restrictsKubectl() bool {
+	return len(p.allowVerbs)+len(p.denyVerbs)+len(p.allowNamespaces)+len(p.denyNamespaces) > 0
+}
+
+func (p *mcpPolicy) checkKubectl(inv tools.KubectlInvocation) policyDecision {
+	if slices.Contains(p.denyVerbs, inv.Verb) || (len(p.allowVerbs) > 0 && !slices.Contains(p.allowVerbs, inv.Verb)) {
+		return deny("kubectl %s is not allowed by the server policy", inv.Verb)
+	}
+	if inv.AllNamespaces {
+		if len(p.allowNamespaces)+len(p.denyNamespaces) > 0 {
+			return deny("kubectl %s across all namespaces is not allowed by the server policy", inv.Verb)
+		}
+		return policyDecision{allowed: true}
+	}
+	namespace := inv.Namespace
+	if namespace == "" {
+		namespace = p.defaultNamespace
+	}
+	if slices.Contains(p.denyNamespaces, namespace) || (len(p.allowNamespaces) > 0 && !slices.Contains(p.allowNamespaces, namespace)) {
+		return deny("namespace %q is not allowed by the server policy", namespace)
+	}
+	return policyDecision{allowed: true}
+}
+
+// Audit decisions.
+const (
+	auditAllowed   = "allowed"
+	auditConfirmed = "confirmed"
+	auditDenied    = "denied"
+	auditDeclined  = "declined"
+)
+
+// mcpCaller identifies the client making a tool call.
+type mcpCaller struct {
+	SessionID  string `json:"sessionID,omitempty"`
+	Client     string `json:"client,omitempty"`
+	Transport  string `json:"transport"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	UserAgent  string `json:"userAgent,omitempty"`
+}
+
+// mcpAuditEntry is the audit journal payload for a tool call.
+type mcpAuditEntry struct {
+	Caller mcpCaller  `json:"caller"`
+	Tenant *mcpTenant `json:"tenant,omitempty"`
+	// Via is the tool the call was made through, e.g. ask_kubectl_ai for the calls of the agent.
+	Via       string         `json:"via,omitempty"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Decision  string         `json:"decision"`
+	Reason    string         `json:"reason,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	// Duration is how long the call took. It is not known for the calls of the agent,
+	// which are recorded before they run.
+	Duration string `json:"duration,omitempty"`
+}
+
+type auditEntryKey struct{}
+
+type httpCallerKey struct{}
+
+// withHTTPCaller records the HTTP client's address and user agent, to identify it in the audit journal.
+func withHTTPCaller(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, httpCallerKey{}, mcpCaller{
+		Transport:  "http",
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	})
+}
+
+// mcpCallerFromContext identifies the client making the current request.
+func mcpCallerFromContext(ctx context.Context) mcpCaller {
+	caller, ok := ctx.Value(httpCallerKey{}).(mcpCaller)
+	if !ok {
+		caller = mcpCaller{Transport: "stdio"}
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		caller.SessionID = session.SessionID()
+		if s, ok := session.(server.SessionWithClientInfo); ok {
+			info := s.GetClientInfo()
+			caller.Client = strings.TrimSuffix(info.Name+"/"+info.Version, "/")
+		}
+	}
+	return caller
+}
+
+// auditToolCall is a tool handler middleware that records every tool call in the audit journal.
+func (s *kubectlMCPServer) auditToolCall(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		entry := &mcpAuditEntry{
+			Caller:    mcpCallerFromContext(ctx),
+			Tool:      request.Params.Name,
+			Arguments: auditArguments(request.GetArguments()),
+			Decision:  auditAllowed,
+		}
+		start := time.Now()
+		result, err := next(context.WithValue(ctx, auditEntryKey{}, entry), request)
+		entry.Duration = time.Since(start).Round(time.Millisecond).String()
+		if err != nil {
+			entry.Error = err.Error()
+		} else if result != nil && result.IsError && entry.Reason == "" {
+			entry.Error = truncateString(resultText(result), 500)
+		}
+
+		s.writeAudit(ctx, entry)
+		return result, err
+	}
+}
+
+// writeAudit records a tool call in the audit journal, if there is one.
+func (s *kubectlMCPServer) writeAudit(ctx context.Context, entry *mcpAuditEntry) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Write(ctx, &journal.Event{Action: journal.ActionMCPToolCall, Payload: entry}); err != nil {
+		klog.Warningf("failed to write MCP audit event: %v", err)
+	}
+}
+
+// auditArguments returns the call arguments to record, with long values (e.g. file contents) truncated.
+func auditArguments(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok {
+			v = truncateString(s, 1000)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "... (truncated)"
+}
+
+func resultText(result *mcpgo.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcpgo.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// authorize enforces a policy decision, asking the client's user to confirm the call
+// (described by description) if needed. It returns an error result if the call must not run.
+func (s *kubectlMCPServer) authorize(ctx context.Context, decision policyDecision, description string) *mcpgo.CallToolResult {
+	entry, _ := ctx.Value(auditEntryKey{}).(*mcpAuditEntry)
+	if entry == nil {
+		entry = &mcpAuditEntry{}
+	}
+
+	if !decision.allowed {
+		entry.Decision, entry.Reason = auditDenied, decision.reason
+		return mcpgo.NewToolResultError("Refused: " + decision.reason)
+	}
+	if !decision.needsConfirmation {
+		return nil
+	}
+
+	confirmed, err := confirmWithClient(ctx, "kubectl-ai was asked to run the following, which may modify resources:\n\n"+description+"\n\nDo you want to proceed?")
+	if err != nil {
+		entry.Decision, entry.Reason = auditDenied, err.Error()
+		return mcpgo.NewToolResultError("Refused: " + err.Error())
+	}
+	if !confirmed {
+		entry.Decision, entry.Reason = auditDeclined, "the user declined to run this call"
+		return mcpgo.NewToolResultError("The user declined to run this call.")
+	}
+	entry.Decision = auditConfirmed
+	return nil
+}
+
+// confirmWithClient asks the client's user for confirmation, using MCP elicitation.
+func confirmWithClient(ctx context.Context, message string) (bool, error) {
+	srv := server.ServerFromContext(ctx)
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if srv == nil || !ok || session.GetClientCapabilities().Elicitation == nil {
+		return false, fmt.Errorf("this call may modify resources and needs confirmation, but the client does not support elicitation " +
+			"(start the server with --skip-permissions to allow such calls without confirmation)")
+	}
+	result, err := srv.RequestElicitation(ctx, mcpgo.ElicitationRequest{
+		Params: mcpgo.ElicitationParams{
+			Message: message,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"confirm": map[string]any{
+						"type":        "boolean",
+						"title":       "Run it",
+						"description": "Confirm that kubectl-ai may run this call",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("asking the client for confirmation: %w", err)
+	}
+	if result.Action != mcpgo.ElicitationResponseActionAccept {
+		return false, nil
+	}
+	content, _ := result.Content.(map[string]any)
+	confirm, _ := content["confirm"].(bool)
+	return confirm, nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMCPPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy mcpPolicy
+		// tool runs the command, kubectl if nil.
+		tool              tools.Tool
+		command           string
+		allowed           bool
+		needsConfirmation bool
+		reason            string
+	}{
+		{
+			name:    "read-only call",
+			command: "kubectl get pods",
+			allowed: true,
+		},
+		{
+			name:              "mutation needs confirmation",
+			command:           "kubectl delete pod web-0",
+			allowed:           true,
+			needsConfirmation: true,
+		},
+		{
+			name:    "mutation with skip permissions",
+			policy:  mcpPolicy{skipPermissions: true},
+			command: "kubectl delete pod web-0",
+			allowed: true,
+		},
+		{
+			name:    "read-only mode",
+			policy:  mcpPolicy{readOnly: true, skipPermissions: true},
+			command: "kubectl delete pod web-0",
+			reason:  "read-only",
+		},
+		{
+			name:    "read-only mode allows reads",
+			policy:  mcpPolicy{readOnly: true},
+			command: "kubectl get pods -A",
+			allowed: true,
+		},
+		{
+			name:    "allowed verbs",
+			policy:  mcpPolicy{allowVerbs: []string{"get", "describe"}},
+			command: "kubectl get pods && kubectl logs web-0",
+			reason:  "kubectl logs is not allowed",
+		},
+		{
+			name:              "denied verbs",
+			policy:            mcpPolicy{denyVerbs: []string{"delete"}},
+			command:           "kubectl scale deploy web --replicas=2",
+			allowed:           true,
+			needsConfirmation: true,
+		},
+		{
+			name:    "denied verb in a pipeline",
+			policy:  mcpPolicy{denyVerbs: []string{"delete"}},
+			command: "kubectl get pods -o name | xargs kubectl delete",
+			reason:  "kubectl delete is not allowed",
+		},
+		{
+			name:    "denied namespace",
+			policy:  mcpPolicy{denyNamespaces: []string{"kube-system"}, defaultNamespace: "default"},
+			command: "kubectl -n kube-system get pods",
+			reason:  `namespace "kube-system" is not allowed`,
+		},
+		{
+			name:    "default namespace is checked",
+			policy:  mcpPolicy{allowNamespaces: []string{"web"}, defaultNamespace: "default"},
+			command: "kubectl get pods",
+			reason:  `namespace "default" is not allowed`,
+		},
+		{
+			name:    "all namespaces with a namespace policy",
+			policy:  mcpPolicy{allowNamespaces: []string{"web"}, defaultNamespace: "default"},
+			command: "kubectl get pods --all-namespaces",
+			reason:  "across all namespaces",
+		},
+		{
+			name:    "allowed namespace",
+			policy:  mcpPolicy{allowNamespaces: []string{"web"}, defaultNamespace: "default"},
+			command: "kubectl get pods --namespace=web",
+			allowed: true,
+		},
+		{
+			name:    "interactive command",
+			command: "kubectl exec -it web-0 -- sh",
+			reason:  "interactive",
+		},
+		{
+			name:    "denied verb in a nested shell",
+			policy:  mcpPolicy{denyVerbs: []string{"delete"}, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "bash -c 'kubectl delete ns prod'",
+			reason:  "cannot be determined",
+		},
+		{
+			name:    "denied verb in eval",
+			policy:  mcpPolicy{denyVerbs: []string{"delete"}, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: `eval "kubectl delete ns prod"`,
+			reason:  "cannot be determined",
+		},
+		{
+			name:    "denied verb through a variable",
+			policy:  mcpPolicy{denyVerbs: []string{"delete"}, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "k=kubectl; $k delete ns prod",
+			reason:  "cannot be determined",
+		},
+		{
+			name:    "allowed namespace in a nested shell",
+			policy:  mcpPolicy{allowNamespaces: []string{"web"}, defaultNamespace: "web", skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "sh -c 'kubectl get secrets -n kube-system'",
+			reason:  "cannot be determined",
+		},
+		{
+			name:    "allowed verb run by awk",
+			policy:  mcpPolicy{allowVerbs: []string{"get"}, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: `kubectl get ns -o name | awk '{system("kubectl delete " $1)}'`,
+			reason:  "cannot be determined",
+		},
+		{
+			name:    "denied verb with a pipeline filter",
+			policy:  mcpPolicy{denyVerbs: []string{"delete"}, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "kubectl get pods | grep web",
+			allowed: true,
+		},
+		{
+			name:    "read-only mode in a nested shell",
+			policy:  mcpPolicy{readOnly: true, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "bash -c 'kubectl delete ns prod'",
+			reason:  "read-only",
+		},
+		{
+			name:    "read-only mode with eval",
+			policy:  mcpPolicy{readOnly: true, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: `eval "kubectl delete ns prod"`,
+			reason:  "read-only",
+		},
+		{
+			name:    "read-only mode through a variable",
+			policy:  mcpPolicy{readOnly: true, skipPermissions: true},
+			tool:    &tools.BashTool{},
+			command: "k=kubectl; $k delete ns prod",
+			reason:  "read-only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := tt.tool
+			if tool == nil {
+				tool = &tools.Kubectl{}
+			}
+			got := tt.policy.check(context.Background(), tool, map[string]any{"command": tt.command})
+			if got.allowed != tt.allowed || got.needsConfirmation != tt.needsConfirmation || !strings.Contains(got.reason, tt.reason) {
+				t.Errorf("check(%q) = %+v, want allowed=%v needsConfirmation=%v reason containing %q",
+					tt.command, got, tt.allowed, tt.needsConfirmation, tt.reason)
+			}
+		})
+	}
+}
+
+func TestMCPPolicyCheckExternal(t *testing.T) {
+	tests := []struct {
+		name              string
+		policy            mcpPolicy
+		modifies          string
+		allowed           bool
+		needsConfirmation bool
+	}{
+		{name: "unknown tool needs confirmation", modifies: "unknown", allowed: true, needsConfirmation: true},
+		{name: "read-only tool", modifies: "no", allowed: true},
+		{name: "read-only mode allows read-only tools", policy: mcpPolicy{readOnly: true}, modifies: "no", allowed: true},
+		{name: "read-only mode", policy: mcpPolicy{readOnly: true}, modifies: "unknown"},
+		{name: "skip permissions", policy: mcpPolicy{skipPermissions: true}, modifies: "yes", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.checkExternal(mcp.Tool{Name: "tool", Server: "server", ModifiesResource: tt.modifies})
+			if got.allowed != tt.allowed || got.needsConfirmation != tt.needsConfirmation {
+				t.Errorf("checkExternal(%q) = %+v, want allowed=%v needsConfirmation=%v",
+					tt.modifies, got, tt.allowed, tt.needsConfirmation)
+			}
+		})
+	}
+}
+
+func TestMCPServerAuditsToolCalls(t *testing.T) {
+	ctx := context.Background()
+
+	toolset := tools.Tools{}
+	toolset.Init()
+	toolset.RegisterTool(&tools.Kubectl{})
+
+	s, err := newKubectlMCPServer(ctx, "", toolset, t.TempDir(), false, "stdio", 0)
+	if err != nil {
+		t.Fatalf("failed to create MCP server: %v", err)
+	}
+	recorder := &journal.MemoryRecorder{}
+	s.audit = recorder
+	s.policy = &mcpPolicy{denyVerbs: []string{"drain"}, defaultNamespace: "default"}
+	handler := s.auditToolCall(s.handleToolCall)
+
+	call := func(command string) *mcpgo.CallToolResult {
+		request := mcpgo.CallToolRequest{}
+		request.Params.Name = "kubectl"
+		request.Params.Arguments = map[string]any{"command": command}
+		result, err := handler(ctx, request)
+		if err != nil {
+			t.Fatalf("tool call failed: %v", err)
+		}
+		return result
+	}
+
+	// Without a client session there is nobody to confirm the mutation, so it is refused.
+	if result := call("kubectl delete pod web-0"); !result.IsError || !strings.Contains(resultText(result), "does not support elicitation") {
+		t.Errorf("expected the mutation to be refused, got %+v", result.Content)
+	}
+	if result := call("kubectl drain node-1"); !result.IsError || !strings.Contains(resultText(result), "kubectl drain is not allowed") {
+		t.Errorf("expected kubectl drain to be refused, got %+v", result.Content)
+	}
+
+	if len(recorder.Events()) != 2 {
+		t.Fatalf("expected 2 audit events, got %d", len(recorder.Events()))
+	}
+	for _, event := range recorder.Events() {
+		entry, ok := event.Payload.(*mcpAuditEntry)
+		if event.Action != journal.ActionMCPToolCall || !ok {
+			t.Fatalf("unexpected audit event %+v", event)
+		}
+		if entry.Decision != auditDenied || entry.Reason == "" || entry.Caller.Transport != "stdio" || entry.Tool != "kubectl" {
+			t.Errorf("unexpected audit entry %+v", entry)
+		}
+	}
+}
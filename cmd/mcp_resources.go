@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"k8s.io/klog/v2"
+)
+
+// kubeResourceURITemplate is the URI template of the cluster resources published by the server.
+// "_" selects the current context, or a cluster-scoped resource in place of the namespace.
+const kubeResourceURITemplate = "k8s://{context}/{namespace}/{kind}/{name}"
+
+// kubeResourceURIDefault is the placeholder for the current context and for cluster-scoped resources.
+const kubeResourceURIDefault = "_"
+
+// kubeNamePattern matches the URI segments we pass to kubectl: resource names, kinds (e.g. deployments.apps) and context names.
+var kubeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:@-]*$`)
+
+// addResourcesAndPrompts publishes cluster resources, read with the kubectl tool, and the curated prompts.
+func (s *kubectlMCPServer) addResourcesAndPrompts() {
+	if s.tools.Lookup("kubectl") != nil {
+		s.server.AddResourceTemplate(mcpgo.NewResourceTemplate(kubeResourceURITemplate, "Kubernetes resource",
+			mcpgo.WithTemplateDescription("A Kubernetes resource, as YAML. Use _ for the current context, and as the namespace of cluster-scoped resources."),
+			mcpgo.WithTemplateMIMEType("application/yaml"),
+		), s.handleReadKubeResource)
+	}
+
+	for _, p := range mcpPrompts {
+		s.server.AddPrompt(p.prompt, p.handler)
+	}
+}
+
+// kubeResourceCommand returns the kubectl command reading the resource with the given URI template arguments.
+func kubeResourceCommand(args map[string]any) (string, error) {
+	values := map[string]string{}
+	for _, key := range []string{"context", "namespace", "kind", "name"} {
+		var value string
+		switch v := args[key].(type) {
+		case string:
+			value = v
+		case []string:
+			value = strings.Join(v, ",")
+		}
+		if !kubeNamePattern.MatchString(value) && value != kubeResourceURIDefault {
+			return "", fmt.Errorf("invalid %s %q in resource URI", key, value)
+		}
+		values[key] = value
+	}
+	if values["kind"] == kubeResourceURIDefault || values["name"] == kubeResourceURIDefault {
+		return "", fmt.Errorf("the resource URI must name a kind and a resource")
+	}
+
+	command := fmt.Sprintf("kubectl get %s %s -o yaml", values["kind"], values["name"])
+	if ns := values["namespace"]; ns != kubeResourceURIDefault {
+		command += " -n " + ns
+	}
+	if kubeContext := values["context"]; kubeContext != kubeResourceURIDefault {
+		command += " --context " + kubeContext
+	}
+	return command, nil
+}
+
+// handleReadKubeResource reads a cluster resource with the kubectl tool, subject to the server policy.
+func (s *kubectlMCPServer) handleReadKubeResource(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+	command, err := kubeResourceCommand(request.Params.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done, err := s.toolEnv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", request.Params.URI, err)
+	}
+	defer done()
+
+	kubectl := s.tools.Lookup("kubectl")
+	args := map[string]any{"command": command}
+	if decision := s.checkBuiltin(ctx, kubectl, args); !decision.allowed || decision.needsConfirmation {
+		return nil, fmt.Errorf("reading %s is not allowed: %s", request.Params.URI, decision.reason)
+	}
+
+	klog.V(2).Infof("Reading MCP resource %s with %q", request.Params.URI, command)
+	output, err := kubectl.Run(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", request.Params.URI, err)
+	}
+	result, ok := output.(*tools.ExecResult)
+	if !ok {
+		return nil, fmt.Errorf("reading %s: unexpected result %T", request.Params.URI, output)
+	}
+	if result.Error != "" || result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading %s: %s", request.Params.URI, strings.TrimSpace(result.Stderr+" "+result.Error))
+	}
+
+	return []mcpgo.ResourceContents{
+		mcpgo.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/yaml",
+			Text:     result.Stdout,
+		},
+	}, nil
+}
+
+// mcpPrompt is a curated prompt published by the server.
+type mcpPrompt struct {
+	prompt  mcpgo.Prompt
+	handler func(ctx context.Context, request mcpgo.GetPromptRequest) (*mcpgo.GetPromptResult, error)
+}
+
+var mcpPrompts = []mcpPrompt{
+	{
+		prompt: mcpgo.NewPrompt("diagnose-crashloop",
+			mcpgo.WithPromptDescription("Find out why a pod is crash-looping, and how to fix it"),
+			mcpgo.WithArgument("pod", mcpgo.RequiredArgument(), mcpgo.ArgumentDescription("Name of the crash-looping pod")),
+			mcpgo.WithArgument("namespace", mcpgo.ArgumentDescription("Namespace of the pod. Defaults to the current namespace.")),
+		),
+		handler: func(ctx context.Context, request mcpgo.GetPromptRequest) (*mcpgo.GetPromptResult, error) {
+			pod := request.Params.Arguments["pod"]
+			if pod == "" {
+				return nil, fmt.Errorf("the pod argument is required")
+			}
+			return promptResult("Diagnose a crash-looping pod", fmt.Sprintf(`The pod %q%s is in CrashLoopBackOff. Find out why, and how to fix it:
+
+1. Describe the pod, and check its status, restart count, exit codes and last termination reason (e.g. OOMKilled, Error).
+2. Read the logs of the previous run of each failing container (kubectl logs --previous).
+3. Check the recent events for the pod and its node.
+4. Check the liveness and readiness probes, resource limits, image, command, and the ConfigMaps, Secrets and volumes it depends on.
+
+Explain the root cause, citing the evidence, and propose a fix. Do not change anything without asking first.`,
+				pod, inNamespace(request.Params.Arguments["namespace"]))), nil
+		},
+	},
+	{
+		prompt: mcpgo.NewPrompt("review-rbac",
+			mcpgo.WithPromptDescription("Review RBAC permissions for risky grants"),
+			mcpgo.WithArgument("namespace", mcpgo.ArgumentDescription("Namespace to review. Defaults to the whole cluster.")),
+			mcpgo.WithArgument("subject", mcpgo.ArgumentDescription("Only review the permissions of this user, group or service account")),
+		),
+		handler: func(ctx context.Context, request mcpgo.GetPromptRequest) (*mcpgo.GetPromptResult, error) {
+			scope := "across the cluster"
+			if ns := request.Params.Arguments["namespace"]; ns != "" {
+				scope = fmt.Sprintf("in namespace %q, including the cluster roles bound there", ns)
+			}
+			if subject := request.Params.Arguments["subject"]; subject != "" {
+				scope += fmt.Sprintf(", for the subject %q", subject)
+			}
+			return promptResult("Review RBAC permissions", fmt.Sprintf(`Review the RBAC roles and bindings %s. Flag:
+
+- bindings to cluster-admin or other roles granting wildcard verbs or resources
+- access to Secrets, and to pods/exec, pods/attach or nodes/proxy
+- the escalate, bind and impersonate verbs, and permission to create pods or workloads in privileged namespaces
+- permissions granted to default service accounts, to system:authenticated or to system:unauthenticated
+
+For each finding, give the binding, the subject, why it is risky and a least-privilege alternative. Only read resources; do not change anything.`,
+				scope)), nil
+		},
+	},
+}
+
+func inNamespace(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in namespace %q", namespace)
+}
+
+func promptResult(description, text string) *mcpgo.GetPromptResult {
+	return mcpgo.NewGetPromptResult(description, []mcpgo.PromptMessage{
+		mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewTextContent(text)),
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestKubeResourceCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "namespaced resource in the current context",
+			args: map[string]any{"context": "_", "namespace": "web", "kind": "deployments.apps", "name": "frontend"},
+			want: "kubectl get deployments.apps frontend -o yaml -n web",
+		},
+		{
+			name: "cluster-scoped resource in another context",
+			args: map[string]any{"context": []string{"prod"}, "namespace": "_", "kind": "nodes", "name": "node-1"},
+			want: "kubectl get nodes node-1 -o yaml --context prod",
+		},
+		{
+			name:    "shell metacharacters",
+			args:    map[string]any{"context": "_", "namespace": "web", "kind": "pods", "name": "x;rm"},
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			args:    map[string]any{"context": "_", "namespace": "web", "kind": "pods", "name": "_"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kubeResourceCommand(tt.args)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("kubeResourceCommand() = %q, %v; want %q (error: %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeKubectl echoes the commands it is asked to run.
+type fakeKubectl struct {
+	tools.Kubectl
+}
+
+func (fakeKubectl) Run(_ context.Context, args map[string]any) (any, error) {
+	return &tools.ExecResult{Command: args["command"].(string), Stdout: "# " + args["command"].(string)}, nil
+}
+
+func TestMCPResourcesAndPrompts(t *testing.T) {
+	ctx := context.Background()
+
+	toolset := tools.Tools{}
+	toolset.Init()
+	toolset.RegisterTool(&fakeKubectl{})
+
+	s, err := newKubectlMCPServer(ctx, "", toolset, t.TempDir(), false, "stdio", 0)
+	if err != nil {
+		t.Fatalf("failed to create MCP server: %v", err)
+	}
+	s.policy = &mcpPolicy{denyNamespaces: []string{"kube-system"}, defaultNamespace: "default"}
+
+	client, err := mcpclient.NewInProcessClient(s.server)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	if _, err := client.Initialize(ctx, mcpgo.InitializeRequest{}); err != nil {
+		t.Fatalf("failed to initialize client: %v", err)
+	}
+
+	templates, err := client.ListResourceTemplates(ctx, mcpgo.ListResourceTemplatesRequest{})
+	if err != nil || len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate.Raw() != kubeResourceURITemplate {
+		t.Fatalf("unexpected resource templates %+v (%v)", templates, err)
+	}
+
+	read := mcpgo.ReadResourceRequest{}
+	read.Params.URI = "k8s://_/web/pods/web-0"
+	result, err := client.ReadResource(ctx, read)
+	if err != nil {
+		t.Fatalf("failed to read resource: %v", err)
+	}
+	if text, ok := result.Contents[0].(mcpgo.TextResourceContents); !ok || text.Text != "# kubectl get pods web-0 -o yaml -n web" {
+		t.Errorf("unexpected resource contents %+v", result.Contents)
+	}
+
+	read.Params.URI = "k8s://_/kube-system/secrets/token"
+	if _, err := client.ReadResource(ctx, read); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected reading from a denied namespace to fail, got %v", err)
+	}
+
+	prompts, err := client.ListPrompts(ctx, mcpgo.ListPromptsRequest{})
+	if err != nil || len(prompts.Prompts) != len(mcpPrompts) {
+		t.Fatalf("unexpected prompts %+v (%v)", prompts, err)
+	}
+	get := mcpgo.GetPromptRequest{}
+	get.Params.Name = "diagnose-crashloop"
+	get.Params.Arguments = map[string]string{"pod": "web-0", "namespace": "web"}
+	prompt, err := client.GetPrompt(ctx, get)
+	if err != nil {
+		t.Fatalf("failed to get prompt: %v", err)
+	}
+	if text, ok := prompt.Messages[0].Content.(mcpgo.TextContent); !ok || !strings.Contains(text.Text, `"web-0" in namespace "web"`) {
+		t.Errorf("unexpected prompt %+v", prompt.Messages)
+	}
+}
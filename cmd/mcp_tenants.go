@@ -0,0 +1,657 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// HTTP headers with which MCP clients select the cluster and context of their requests.
+const (
+	tenantClusterHeader = "X-Kubectl-AI-Cluster"
+	tenantContextHeader = "X-Kubectl-AI-Context"
+)
+
+// Impersonation modes of a multi-tenant MCP server.
+const (
+	// impersonationDisabled runs every request with the identity of the server's kubeconfigs.
+	impersonationDisabled = "disabled"
+	// impersonationOptional impersonates the callers that send a bearer token.
+	impersonationOptional = "optional"
+	// impersonationRequired refuses the requests that do not send a bearer token.
+	impersonationRequired = "required"
+)
+
+// tenantKubectlVerbs are the kubectl subcommands tenants may run, which only reach the selected
+// cluster with the tenant's identity. The others are refused: `config` prints and changes the
+// kubeconfig, and so the server's credentials; cp, proxy, port-forward, kustomize and
+// cluster-info dump reach local files, ports or URLs; and any other verb may be a plugin,
+// which runs a program from the server's PATH.
+var tenantKubectlVerbs = map[string]bool{
+	"annotate": true, "api-resources": true, "api-versions": true, "apply": true, "attach": true,
+	"auth": true, "autoscale": true, "certificate": true, "cordon": true, "create": true,
+	"debug": true, "delete": true, "describe": true, "diff": true, "drain": true,
+	"events": true, "exec": true, "explain": true, "expose": true, "get": true,
+	"label": true, "logs": true, "patch": true, "replace": true, "rollout": true,
+	"run": true, "scale": true, "set": true, "taint": true, "top": true,
+	"uncordon": true, "version": true, "wait": true,
+}
+
+// tokenReviewCacheTTL is how long the identity behind a bearer token is remembered.
+const tokenReviewCacheTTL = time.Minute
+
+// mcpTenancyConfig is the configuration of a multi-tenant MCP server (--mcp-tenants-config).
+type mcpTenancyConfig struct {
+	// Clusters are the kubeconfigs requests may select.
+	Clusters []mcpTenantCluster `json:"clusters"`
+	// DefaultCluster is used by requests that do not select a cluster. If empty, requests must select one.
+	DefaultCluster string `json:"defaultCluster,omitempty"`
+	// Impersonation is "disabled" (default), "optional" or "required".
+	Impersonation string `json:"impersonation,omitempty"`
+	// RateLimit limits the tool calls of each tenant.
+	RateLimit mcpRateLimit `json:"rateLimit,omitempty"`
+}
+
+// mcpTenantCluster is a kubeconfig requests may select.
+type mcpTenantCluster struct {
+	Name       string `json:"name"`
+	Kubeconfig string `json:"kubeconfig"`
+	// Contexts are the contexts of the kubeconfig requests may select.
+	// If empty, requests use the kubeconfig's current context.
+	Contexts []string `json:"contexts,omitempty"`
+}
+
+// mcpRateLimit is a token bucket: tenants make up to Burst calls at once, and RequestsPerMinute on average.
+type mcpRateLimit struct {
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	Burst             int `json:"burst,omitempty"`
+}
+
+// loadMCPTenancyConfig reads and validates the multi-tenancy configuration file.
+func loadMCPTenancyConfig(path string) (*mcpTenancyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading MCP tenants config: %w", err)
+	}
+	var config mcpTenancyConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing MCP tenants config %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid MCP tenants config %s: %w", path, err)
+	}
+	// Relative kubeconfig paths are relative to the configuration file.
+	for i, cluster := range config.Clusters {
+		if !filepath.IsAbs(cluster.Kubeconfig) {
+			config.Clusters[i].Kubeconfig = filepath.Join(filepath.Dir(path), cluster.Kubeconfig)
+		}
+	}
+	return &config, nil
+}
+
+func (c *mcpTenancyConfig) validate() error {
+	if len(c.Clusters) == 0 {
+		return fmt.Errorf("no clusters configured")
+	}
+	names := map[string]bool{}
+	for i, cluster := range c.Clusters {
+		if cluster.Name == "" || cluster.Kubeconfig == "" {
+			return fmt.Errorf("cluster %d: name and kubeconfig are required", i)
+		}
+		if names[cluster.Name] {
+			return fmt.Errorf("duplicate cluster name: %s", cluster.Name)
+		}
+		names[cluster.Name] = true
+	}
+	if c.DefaultCluster != "" && !names[c.DefaultCluster] {
+		return fmt.Errorf("defaultCluster %q is not one of the clusters", c.DefaultCluster)
+	}
+	switch c.Impersonation {
+	case "", impersonationDisabled, impersonationOptional, impersonationRequired:
+	default:
+		return fmt.Errorf("invalid impersonation %q: must be %s, %s or %s", c.Impersonation, impersonationDisabled, impersonationOptional, impersonationRequired)
+	}
+	if c.RateLimit.RequestsPerMinute < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("rateLimit values cannot be negative")
+	}
+	return nil
+}
+
+func (c *mcpTenancyConfig) cluster(name string) (mcpTenantCluster, bool) {
+	for _, cluster := range c.Clusters {
+		if cluster.Name == name {
+			return cluster, true
+		}
+	}
+	return mcpTenantCluster{}, false
+}
+
+// tenantRequest is what an HTTP request says about its tenant.
+type tenantRequest struct {
+	cluster     string
+	kubeContext string
+	bearerToken string
+}
+
+type tenantRequestKey struct{}
+
+// withHTTPRequest records the caller of an HTTP request, and the tenant it selects.
+func withHTTPRequest(ctx context.Context, r *http.Request) context.Context {
+	return withTenantRequest(withHTTPCaller(ctx, r), r)
+}
+
+// withTenantRequest records the cluster, context and bearer token of an HTTP request.
+func withTenantRequest(ctx context.Context, r *http.Request) context.Context {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return context.WithValue(ctx, tenantRequestKey{}, tenantRequest{
+		cluster:     r.Header.Get(tenantClusterHeader),
+		kubeContext: r.Header.Get(tenantContextHeader),
+		bearerToken: strings.TrimSpace(token),
+	})
+}
+
+// mcpTenant is the tenant a tool call runs for.
+type mcpTenant struct {
+	// Cluster and Context are the selected kubeconfig and context.
+	Cluster string `json:"cluster"`
+	Context string `json:"context,omitempty"`
+	// User and Groups are the impersonated identity, if any.
+	User   string   `json:"user,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// reviewedUser is the identity behind a bearer token.
+type reviewedUser struct {
+	username string
+	groups   []string
+	expires  time.Time
+}
+
+// mcpTenancy runs the tool calls of a multi-tenant MCP server with the kubeconfig, context and
+// identity each request selects, in a work directory of its own, within per-tenant rate limits.
+type mcpTenancy struct {
+	config  *mcpTenancyConfig
+	workDir string
+	limiter *tenantRateLimiter
+
+	// reviewToken returns the identity behind a bearer token, using the kubeconfig of a cluster.
+	// It is a field so tests can replace it.
+	reviewToken func(ctx context.Context, cluster mcpTenantCluster, token string) (username string, groups []string, err error)
+
+	mu      sync.Mutex
+	reviews map[string]reviewedUser
+}
+
+func newMCPTenancy(config *mcpTenancyConfig, workDir string) *mcpTenancy {
+	return &mcpTenancy{
+		config:      config,
+		workDir:     workDir,
+		limiter:     newTenantRateLimiter(config.RateLimit),
+		reviewToken: kubectlTokenReview,
+		reviews:     map[string]reviewedUser{},
+	}
+}
+
+// tenantEnv is the environment tools run in for a tenant: its kubeconfig and work directory.
+type tenantEnv struct {
+	tenant     mcpTenant
+	kubeconfig string
+	workDir    string
+	cleanup    func()
+}
+
+// resolve decides the tenant of a request.
+func (t *mcpTenancy) resolve(ctx context.Context) (mcpTenant, mcpTenantCluster, error) {
+	req, _ := ctx.Value(tenantRequestKey{}).(tenantRequest)
+
+	name := req.cluster
+	if name == "" {
+		name = t.config.DefaultCluster
+	}
+	if name == "" {
+		return mcpTenant{}, mcpTenantCluster{}, fmt.Errorf("select a cluster with the %s header (one of: %s)", tenantClusterHeader, strings.Join(t.clusterNames(), ", "))
+	}
+	cluster, ok := t.config.cluster(name)
+	if !ok {
+		return mcpTenant{}, mcpTenantCluster{}, fmt.Errorf("cluster %q is not allowed (allowed clusters: %s)", name, strings.Join(t.clusterNames(), ", "))
+	}
+	if req.kubeContext != "" && !slices.Contains(cluster.Contexts, req.kubeContext) {
+		return mcpTenant{}, mcpTenantCluster{}, fmt.Errorf("context %q of cluster %q is not allowed", req.kubeContext, name)
+	}
+	tenant := mcpTenant{Cluster: name, Context: req.kubeContext}
+
+	switch {
+	case t.config.Impersonation == impersonationDisabled || t.config.Impersonation == "":
+	case req.bearerToken != "":
+		user, err := t.review(ctx, cluster, req.bearerToken)
+		if err != nil {
+			return mcpTenant{}, mcpTenantCluster{}, err
+		}
+		tenant.User, tenant.Groups = user.username, user.groups
+	case t.config.Impersonation == impersonationRequired:
+		return mcpTenant{}, mcpTenantCluster{}, fmt.Errorf("this server impersonates its callers: send a Kubernetes bearer token in the Authorization header")
+	}
+	return tenant, cluster, nil
+}
+
+func (t *mcpTenancy) clusterNames() []string {
+	var names []string
+	for _, cluster := range t.config.Clusters {
+		names = append(names, cluster.Name)
+	}
+	return names
+}
+
+// review returns the identity behind a bearer token, from the cache if it was reviewed recently.
+func (t *mcpTenancy) review(ctx context.Context, cluster mcpTenantCluster, token string) (reviewedUser, error) {
+	sum := sha256.Sum256([]byte(cluster.Name + "\x00" + token))
+	key := hex.EncodeToString(sum[:])
+
+	t.mu.Lock()
+	user, ok := t.reviews[key]
+	t.mu.Unlock()
+	if ok && time.Now().Before(user.expires) {
+		return user, nil
+	}
+
+	username, groups, err := t.reviewToken(ctx, cluster, token)
+	if err != nil {
+		return reviewedUser{}, fmt.Errorf("authenticating the bearer token with cluster %q: %w", cluster.Name, err)
+	}
+	user = reviewedUser{username: username, groups: groups, expires: time.Now().Add(tokenReviewCacheTTL)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, u := range t.reviews {
+		if time.Now().After(u.expires) {
+			delete(t.reviews, k)
+		}
+	}
+	t.reviews[key] = user
+	return user, nil
+}
+
+// rateLimitKey identifies the tenant whose calls are rate limited: the impersonated user,
+// or the client's address.
+func rateLimitKey(ctx context.Context, tenant mcpTenant) string {
+	if tenant.User != "" {
+		return tenant.Cluster + "/user:" + tenant.User
+	}
+	addr := mcpCallerFromContext(ctx).RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return tenant.Cluster + "/addr:" + addr
+}
+
+// prepare resolves the tenant of a request, checks its rate limit, and creates its work directory
+// and kubeconfig. The caller must call cleanup when the request is done.
+func (t *mcpTenancy) prepare(ctx context.Context) (*tenantEnv, error) {
+	tenant, cluster, err := t.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ok, retryAfter := t.limiter.allow(rateLimitKey(ctx, tenant)); !ok {
+		return nil, fmt.Errorf("rate limit exceeded, retry in %s", retryAfter.Round(time.Second))
+	}
+
+	dir, err := os.MkdirTemp(t.workDir, "request-")
+	if err != nil {
+		return nil, fmt.Errorf("creating request directory: %w", err)
+	}
+	env := &tenantEnv{
+		tenant:     tenant,
+		kubeconfig: filepath.Join(dir, "kubeconfig"),
+		workDir:    filepath.Join(dir, "work"),
+		cleanup: func() {
+			if err := os.RemoveAll(dir); err != nil {
+				klog.Warningf("failed to remove request directory %s: %v", dir, err)
+			}
+		},
+	}
+	// The kubeconfig and the credentials it refers to are outside the work directory,
+	// so that the file tools cannot read them.
+	if err := os.Mkdir(env.workDir, 0o700); err != nil {
+		env.cleanup()
+		return nil, fmt.Errorf("creating request work directory: %w", err)
+	}
+	if err := writeTenantKubeconfig(cluster.Kubeconfig, env.kubeconfig, tenant); err != nil {
+		env.cleanup()
+		return nil, err
+	}
+	return env, nil
+}
+
+// writeTenantKubeconfig writes a kubeconfig with only the tenant's context, its cluster and its user,
+// impersonating the tenant's user if any. Relative file paths are made absolute, as the kubeconfig moves.
+// The credentials of the user are written to files next to it, which the kubeconfig refers to.
+func writeTenantKubeconfig(src, dst string, tenant mcpTenant) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig of cluster %q: %w", tenant.Cluster, err)
+	}
+	var kubeconfig map[string]any
+	if err := yaml.Unmarshal(data, &kubeconfig); err != nil {
+		return fmt.Errorf("parsing kubeconfig of cluster %q: %w", tenant.Cluster, err)
+	}
+
+	contextName := tenant.Context
+	if contextName == "" {
+		contextName, _ = kubeconfig["current-context"].(string)
+	}
+	kubeContext, ok := namedEntry(kubeconfig, "contexts", contextName)
+	if !ok {
+		return fmt.Errorf("context %q not found in the kubeconfig of cluster %q", contextName, tenant.Cluster)
+	}
+	contextSpec, _ := kubeContext["context"].(map[string]any)
+	clusterName, _ := contextSpec["cluster"].(string)
+	userName, _ := contextSpec["user"].(string)
+	cluster, ok := namedEntry(kubeconfig, "clusters", clusterName)
+	if !ok {
+		return fmt.Errorf("cluster %q of context %q not found in the kubeconfig of cluster %q", clusterName, contextName, tenant.Cluster)
+	}
+	user, _ := namedEntry(kubeconfig, "users", userName)
+	if user == nil {
+		user = map[string]any{"name": userName, "user": map[string]any{}}
+	}
+
+	baseDir := filepath.Dir(src)
+	absolutePaths(cluster, "cluster", baseDir, "certificate-authority")
+	absolutePaths(user, "user", baseDir, "client-certificate", "client-key", "tokenFile")
+	userSpec, _ := user["user"].(map[string]any)
+	if userSpec == nil {
+		userSpec = map[string]any{}
+		user["user"] = userSpec
+	}
+	if err := writeCredentialFiles(userSpec, filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("user %q of cluster %q: %w", userName, tenant.Cluster, err)
+	}
+	if tenant.User != "" {
+		userSpec["as"] = tenant.User
+		if len(tenant.Groups) > 0 {
+			userSpec["as-groups"] = tenant.Groups
+		} else {
+			delete(userSpec, "as-groups")
+		}
+		delete(userSpec, "as-uid")
+		delete(userSpec, "as-user-extra")
+	}
+
+	out, err := yaml.Marshal(map[string]any{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": contextName,
+		"contexts":        []any{kubeContext},
+		"clusters":        []any{cluster},
+		"users":           []any{user},
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling tenant kubeconfig: %w", err)
+	}
+	if err := os.WriteFile(dst, out, 0o600); err != nil {
+		return fmt.Errorf("writing tenant kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// credentialFiles are the credentials of a kubeconfig user that can be kept in a file instead:
+// the key holding the credential, the key holding the path of the file, and the name of the file.
+var credentialFiles = []struct {
+	dataKey, fileKey, name string
+	base64                 bool
+}{
+	{dataKey: "token", fileKey: "tokenFile", name: "token"},
+	{dataKey: "client-certificate-data", fileKey: "client-certificate", name: "client.crt", base64: true},
+	{dataKey: "client-key-data", fileKey: "client-key", name: "client.key", base64: true},
+}
+
+// writeCredentialFiles moves the credentials of a kubeconfig user to files in dir, so that the
+// kubeconfig only refers to them: kubectl uses them, but cannot print them. Credentials that
+// cannot be kept in a file (passwords, and auth providers which cache tokens in the kubeconfig)
+// are refused.
+func writeCredentialFiles(userSpec map[string]any, dir string) error {
+	for _, key := range []string{"password", "auth-provider"} {
+		if _, ok := userSpec[key]; ok {
+			return fmt.Errorf("%s credentials cannot be used on multi-tenant servers: use a token, a client certificate or an exec plugin", key)
+		}
+	}
+	for _, f := range credentialFiles {
+		value, ok := userSpec[f.dataKey].(string)
+		if !ok {
+			continue
+		}
+		data := []byte(value)
+		if f.base64 {
+			var err error
+			if data, err = base64.StdEncoding.DecodeString(value); err != nil {
+				return fmt.Errorf("decoding %s: %w", f.dataKey, err)
+			}
+		}
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", f.dataKey, err)
+		}
+		delete(userSpec, f.dataKey)
+		userSpec[f.fileKey] = path
+	}
+	return nil
+}
+
+// namedEntry returns the entry with the given name in a kubeconfig list (contexts, clusters or users).
+func namedEntry(kubeconfig map[string]any, list, name string) (map[string]any, bool) {
+	entries, _ := kubeconfig[list].([]any)
+	for _, e := range entries {
+		entry, ok := e.(map[string]any)
+		if ok && entry["name"] == name {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// absolutePaths makes the given file paths of a kubeconfig entry absolute.
+func absolutePaths(entry map[string]any, specKey, baseDir string, keys ...string) {
+	spec, _ := entry[specKey].(map[string]any)
+	for _, key := range keys {
+		if p, ok := spec[key].(string); ok && p != "" && !filepath.IsAbs(p) {
+			spec[key] = filepath.Join(baseDir, p)
+		}
+	}
+}
+
+// kubectlTokenReview authenticates a bearer token with a TokenReview, using the server's
+// kubeconfig for the cluster. The server's identity needs permission to create tokenreviews.
+func kubectlTokenReview(ctx context.Context, cluster mcpTenantCluster, token string) (string, []string, error) {
+	review, err := json.Marshal(map[string]any{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenReview",
+		"spec":       map[string]any{"token": token},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", "create", "--raw", "/apis/authentication.k8s.io/v1/tokenreviews", "-f", "-")
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+cluster.Kubeconfig)
+	cmd.Stdin = bytes.NewReader(review)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("creating TokenReview: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result struct {
+		Status struct {
+			Authenticated bool   `json:"authenticated"`
+			Error         string `json:"error"`
+			User          struct {
+				Username string   `json:"username"`
+				Groups   []string `json:"groups"`
+			} `json:"user"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return "", nil, fmt.Errorf("parsing TokenReview: %w", err)
+	}
+	if !result.Status.Authenticated {
+		if result.Status.Error != "" {
+			return "", nil, fmt.Errorf("the token is not valid: %s", result.Status.Error)
+		}
+		return "", nil, fmt.Errorf("the token is not valid")
+	}
+	return result.Status.User.Username, result.Status.User.Groups, nil
+}
+
+// tenantRateLimiter is a token bucket per tenant.
+type tenantRateLimiter struct {
+	// perSecond is the refill rate; zero disables rate limiting.
+	perSecond float64
+	burst     float64
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxRateLimitBuckets bounds the memory used by the rate limiter; full buckets are dropped beyond it.
+const maxRateLimitBuckets = 10000
+
+func newTenantRateLimiter(limit mcpRateLimit) *tenantRateLimiter {
+	burst := limit.Burst
+	if burst == 0 {
+		burst = max(1, limit.RequestsPerMinute/6)
+	}
+	return &tenantRateLimiter{
+		perSecond: float64(limit.RequestsPerMinute) / 60,
+		burst:     float64(burst),
+		now:       time.Now,
+		buckets:   map[string]*tokenBucket{},
+	}
+}
+
+// allow takes a token from the tenant's bucket. If there is none, it returns how long until there is.
+func (l *tenantRateLimiter) allow(key string) (bool, time.Duration) {
+	if l.perSecond == 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= maxRateLimitBuckets {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// toolEnv returns the context tools run in for a request, and a function to call when the request is done.
+// On multi-tenant servers, the tenant's kubeconfig and work directory are used; otherwise, the server's.
+func (s *kubectlMCPServer) toolEnv(ctx context.Context) (context.Context, func(), error) {
+	if s.tenancy == nil {
+		ctx = context.WithValue(ctx, tools.KubeconfigKey, s.kubectlConfig)
+		ctx = context.WithValue(ctx, tools.WorkDirKey, s.workDir)
+		return ctx, func() {}, nil
+	}
+	env, err := s.tenancy.prepare(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if entry, ok := ctx.Value(auditEntryKey{}).(*mcpAuditEntry); ok {
+		entry.Tenant = &env.tenant
+	}
+	ctx = context.WithValue(ctx, tools.KubeconfigKey, env.kubeconfig)
+	ctx = context.WithValue(ctx, tools.WorkDirKey, env.workDir)
+	return ctx, env.cleanup, nil
+}
+
+// checkBuiltin checks a call to a built-in or custom tool against the multi-tenancy restrictions and the policy.
+func (s *kubectlMCPServer) checkBuiltin(ctx context.Context, tool tools.Tool, args map[string]any) policyDecision {
+	if s.tenancy != nil {
+		if d := checkTenant(tool, args); !d.allowed {
+			return d
+		}
+	}
+	return s.policy.check(ctx, tool, args)
+}
+
+// checkTenant checks a call to a built-in or custom tool on a multi-tenant server: a tenant must not
+// be able to reach the clusters or files of other tenants.
+func checkTenant(tool tools.Tool, args map[string]any) policyDecision {
+	if tool.Name() == "bash" {
+		return deny("the bash tool is not available on multi-tenant servers")
+	}
+	command, ok := args["command"].(string)
+	if !ok || tool.Name() != "kubectl" {
+		return policyDecision{allowed: true}
+	}
+	if !tools.OnlyRunsKubectl(command) {
+		return deny("on multi-tenant servers, the kubectl tool can only run kubectl, without pipes to other commands, redirections or variables")
+	}
+	invocations, _ := tools.KubectlInvocations(command)
+	for _, inv := range invocations {
+		if len(inv.CredentialFlags) > 0 {
+			return deny("%s cannot be used on multi-tenant servers", strings.Join(inv.CredentialFlags, ", "))
+		}
+		if !tenantKubectlVerbs[inv.Verb] {
+			return deny("kubectl %s is not available on multi-tenant servers", inv.Verb)
+		}
+		for _, f := range inv.Files {
+			if f != "-" && !strings.HasPrefix(f, "https://") && (filepath.IsAbs(f) || !filepath.IsLocal(f)) {
+				return deny("file %q is outside the work directory", f)
+			}
+		}
+	}
+	return policyDecision{allowed: true}
+}
@@ -18,12 +18,19 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/mock/gomock"
 )
 
 func TestKubectlMCPServerHTTPClientIntegration(t *testing.T) {
@@ -97,6 +104,15 @@ func TestKubectlMCPServerHTTPClientIntegration(t *testing.T) {
 		t.Fatalf("expected to find stub tool, got %v", availableTools)
 	}
 
+	prompts, err := client.ListPrompts(toolsCtx)
+	if err != nil || len(prompts) != len(mcpPrompts) {
+		t.Fatalf("unexpected prompts %v (%v)", prompts, err)
+	}
+	prompt, err := client.GetPrompt(toolsCtx, "review-rbac", map[string]string{"namespace": "web"})
+	if err != nil || !strings.Contains(prompt.Text(), `in namespace "web"`) {
+		t.Fatalf("unexpected prompt %+v (%v)", prompt, err)
+	}
+
 	cancel()
 
 	select {
@@ -105,6 +121,196 @@ func TestKubectlMCPServerHTTPClientIntegration(t *testing.T) {
 	}
 }
 
+func TestAskAgentTool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mutatingTool := mocks.NewMockTool(ctrl)
+	mutatingTool.EXPECT().Name().Return("scale").AnyTimes()
+	mutatingTool.EXPECT().Description().Return("scale a deployment").AnyTimes()
+	mutatingTool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "scale"}).AnyTimes()
+	mutatingTool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+	mutatingTool.EXPECT().CheckModifiesResource(gomock.Any()).Return("yes").AnyTimes()
+	// Run is never expected: without --skip-permissions the call must be declined.
+
+	toolset := tools.Tools{}
+	toolset.Init()
+	toolset.RegisterTool(&stubTool{})
+	toolset.RegisterTool(mutatingTool)
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	client.EXPECT().Close().Return(nil)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+	gomock.InOrder(
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(textPart{calls: []gollm.FunctionCall{{ID: "1", Name: "stub", Arguments: map[string]any{}}}}), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(textPart{calls: []gollm.FunctionCall{{ID: "2", Name: "scale", Arguments: map[string]any{"replicas": 3}}}}), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(textPart{text: "The stub is healthy."}), nil),
+	)
+
+	s := &kubectlMCPServer{
+		server: server.NewMCPServer("kubectl-ai", "0.0.1", server.WithToolCapabilities(true)),
+		tools:  toolset,
+		policy: &mcpPolicy{},
+	}
+	s.addAgentTool(&mcpAgentConfig{
+		newLLMClient:  func(context.Context) (gollm.Client, error) { return client, nil },
+		model:         "test-model",
+		maxIterations: 5,
+	})
+
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = askAgentToolName
+	request.Params.Arguments = map[string]any{"query": "is the stub healthy?", "context": "test"}
+	result, err := s.handleAgentToolCall(ctx, request)
+	if err != nil {
+		t.Fatalf("handleAgentToolCall() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected a successful result, got %+v", result.Content)
+	}
+	text := result.Content[0].(mcpgo.TextContent).Text
+	for _, want := range []string{"The stub is healthy.", "$ stub()\nok", "Declined, as approval is required", "scale(replicas=3)", `context "test"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("result does not contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestAskAgentToolAppliesPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	toolset := tools.Tools{}
+	toolset.Init()
+	toolset.RegisterTool(&tools.Kubectl{})
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	client.EXPECT().Close().Return(nil)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+	kubectlCall := func(id, command string) gollm.Part {
+		return textPart{calls: []gollm.FunctionCall{{ID: id, Name: "kubectl", Arguments: map[string]any{"command": command}}}}
+	}
+	// Neither command may run: kubectl is not expected to be installed.
+	gomock.InOrder(
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(kubectlCall("1", "kubectl drain node-1")), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(kubectlCall("2", "kubectl get pods")), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(textPart{text: "I am not allowed to look."}), nil),
+	)
+
+	recorder := &journal.MemoryRecorder{}
+	s := &kubectlMCPServer{
+		server: server.NewMCPServer("kubectl-ai", "0.0.1", server.WithToolCapabilities(true)),
+		tools:  toolset,
+		audit:  recorder,
+		policy: &mcpPolicy{
+			skipPermissions:  true,
+			denyVerbs:        []string{"drain"},
+			allowNamespaces:  []string{"web"},
+			defaultNamespace: "web",
+		},
+	}
+	s.addAgentTool(&mcpAgentConfig{
+		newLLMClient:    func(context.Context) (gollm.Client, error) { return client, nil },
+		model:           "test-model",
+		maxIterations:   5,
+		skipPermissions: true,
+	})
+
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = askAgentToolName
+	request.Params.Arguments = map[string]any{"query": "what runs in kube-system?", "namespace": "kube-system"}
+	result, err := s.handleAgentToolCall(ctx, request)
+	if err != nil {
+		t.Fatalf("handleAgentToolCall() error = %v", err)
+	}
+	text := resultText(result)
+	for _, want := range []string{"Refused: kubectl drain is not allowed", `Refused: namespace "kube-system" is not allowed`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("result does not contain %q:\n%s", want, text)
+		}
+	}
+
+	if len(recorder.Events()) != 2 {
+		t.Fatalf("expected 2 audit events, got %d", len(recorder.Events()))
+	}
+	for _, event := range recorder.Events() {
+		entry, ok := event.Payload.(*mcpAuditEntry)
+		if !ok || entry.Via != askAgentToolName || entry.Tool != "kubectl" || entry.Decision != auditDenied {
+			t.Errorf("unexpected audit event %+v", event.Payload)
+		}
+	}
+}
+
+func TestTranscriptOutputTruncation(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "short",
+			output: "pod/web-0 deleted\n",
+			want:   "pod/web-0 deleted",
+		},
+		{
+			name:   "ascii",
+			output: strings.Repeat("a", maxTranscriptOutput+10),
+			want:   strings.Repeat("a", maxTranscriptOutput) + "\n... (output truncated)",
+		},
+		{
+			// The limit falls in the middle of the last "é".
+			name:   "multibyte",
+			output: strings.Repeat("a", maxTranscriptOutput-1) + "éé",
+			want:   strings.Repeat("a", maxTranscriptOutput-1) + "\n... (output truncated)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := transcriptOutput(map[string]any{"stdout": tt.output})
+			if got != tt.want {
+				t.Errorf("transcriptOutput() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("transcriptOutput() = %q, not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+type textPart struct {
+	text  string
+	calls []gollm.FunctionCall
+}
+
+func (p textPart) AsText() (string, bool) { return p.text, p.text != "" }
+
+func (p textPart) AsFunctionCalls() ([]gollm.FunctionCall, bool) { return p.calls, p.calls != nil }
+
+type partsCandidate []gollm.Part
+
+func (c partsCandidate) String() string      { return "" }
+func (c partsCandidate) Parts() []gollm.Part { return c }
+
+type candidateResponse struct{ candidate gollm.Candidate }
+
+func (r candidateResponse) UsageMetadata() any            { return nil }
+func (r candidateResponse) Candidates() []gollm.Candidate { return []gollm.Candidate{r.candidate} }
+
+// llmResponse returns a streamed LLM response made of the given parts.
+func llmResponse(parts ...gollm.Part) gollm.ChatResponseIterator {
+	return func(yield func(gollm.ChatResponse, error) bool) {
+		yield(candidateResponse{candidate: partsCandidate(parts)}, nil)
+	}
+}
+
 func waitForHTTPServer(t *testing.T, port int) {
 	t.Helper()
 
@@ -176,3 +382,25 @@ func (stubTool) IsInteractive(map[string]any) (bool, error) {
 func (stubTool) CheckModifiesResource(map[string]any) string {
 	return "no"
 }
+
+func TestBuiltinToolResult(t *testing.T) {
+	result, err := builtinToolResult(&tools.ExecResult{Command: "kubectl get pods", Stdout: "web-0", ExitCode: 1, Error: "exit status 1"})
+	if err != nil {
+		t.Fatalf("builtinToolResult() error = %v", err)
+	}
+	if !result.IsError {
+		t.Errorf("expected a failed command to be an error result")
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["stdout"] != "web-0" || structured["command"] != "kubectl get pods" {
+		t.Errorf("unexpected structured content %+v", result.StructuredContent)
+	}
+	if text := resultText(result); !strings.Contains(text, `"stdout":"web-0"`) {
+		t.Errorf("expected JSON text content, got %q", text)
+	}
+
+	result, err = builtinToolResult("plain output")
+	if err != nil || result.IsError || result.StructuredContent != nil || resultText(result) != "plain output" {
+		t.Errorf("unexpected result for text output: %+v (%v)", result, err)
+	}
+}
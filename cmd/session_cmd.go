@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/spf13/cobra"
+)
+
+// newSessionCommand creates the session command, which works with the saved sessions.
+func newSessionCommand() *cobra.Command {
+	sessionCmd := &cobra.Command{
+		Use:   "session",
+		Short: "Work with the saved sessions (--new-session, --resume-session)",
+		// The arguments are valid by the time this runs: errors from here on are not usage errors.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+		},
+	}
+
+	var (
+		format            string
+		outputPath        string
+		maxOutputLines    int
+		redactionPatterns []string
+	)
+	exportCmd := &cobra.Command{
+		Use:   "export <session-id>",
+		Short: "Export a session as a report, e.g. of an incident, with secrets redacted",
+		Long: `Export a session as a report of what happened: the questions, the commands run and their output,
+who approved them and when, the errors and the answers. Use "latest" for the most recent session.
+Secrets are always redacted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportFormat, err := sessions.ParseExportFormat(format)
+			if err != nil {
+				return err
+			}
+			redactor, err := redact.New(redactionPatterns)
+			if err != nil {
+				return fmt.Errorf("creating redactor: %w", err)
+			}
+			opts := sessions.ExportOptions{Format: exportFormat, MaxOutputLines: maxOutputLines, Redactor: redactor}
+			if outputPath == "" || outputPath == "-" {
+				return runSessionExport(cmd.OutOrStdout(), args[0], opts)
+			}
+			f, err := os.Create(outputPath)
+			if err != nil {
+				return fmt.Errorf("creating report file: %w", err)
+			}
+			if err := runSessionExport(f, args[0], opts); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	exportCmd.Flags().StringVarP(&format, "format", "f", string(sessions.ExportFormatMarkdown), "format of the report: md, html or json")
+	exportCmd.Flags().StringVarP(&outputPath, "output", "o", "", "file to write the report to (default: standard output)")
+	exportCmd.Flags().IntVar(&maxOutputLines, "max-output-lines", 50, "truncate the output of tool calls to this many lines (0 keeps all of it)")
+	exportCmd.Flags().StringArrayVar(&redactionPatterns, "redaction-pattern", nil, "additional regular expression to mask in the report (a group named 'value' masks only that part of the match)")
+	sessionCmd.AddCommand(exportCmd)
+
+	return sessionCmd
+}
+
+// runSessionExport writes the report of a saved session, or of the latest one if id is "latest".
+func runSessionExport(w io.Writer, id string, opts sessions.ExportOptions) error {
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		return fmt.Errorf("creating session manager: %w", err)
+	}
+	var session *sessions.Session
+	if id == "latest" {
+		session, err = manager.GetLatestSession()
+		if err == nil && session == nil {
+			err = fmt.Errorf("no sessions found")
+		}
+	} else {
+		session, err = manager.FindSessionByID(id)
+	}
+	if err != nil {
+		return fmt.Errorf("finding session %q: %w", id, err)
+	}
+	if err := session.Export(w, opts); err != nil {
+		return fmt.Errorf("exporting session %q: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+)
+
+func TestSessionExportCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := manager.NewSession(sessions.Metadata{ProviderID: "gemini", ModelID: "gemini-2.5-pro", LastAccessed: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := &api.ToolCall{ID: "call-1", Name: "kubectl", Arguments: map[string]any{"command": "kubectl get secret db -o yaml"}}
+	if err := session.SetChatMessages([]*api.Message{
+		{ID: "1", Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "what is the db password?", Timestamp: time.Now()},
+		{ID: "2", Source: api.MessageSourceModel, Type: api.MessageTypeToolCallRequest, Payload: "Running: kubectl get secret db -o yaml", ToolCall: call, Timestamp: time.Now()},
+		{ID: "3", Source: api.MessageSourceAgent, Type: api.MessageTypeToolCallResponse, ToolCall: call, Timestamp: time.Now(), Payload: map[string]any{
+			"stdout": "apiVersion: v1\nkind: Secret\ndata:\n  password: aHVudGVyMjI=\n",
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := newSessionCommand()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(args)
+		err := cmd.ExecuteContext(context.Background())
+		return out.String(), err
+	}
+
+	out, err := run("export", session.ID)
+	if err != nil {
+		t.Fatalf("session export: %v", err)
+	}
+	for _, want := range []string{"`gemini-2.5-pro`", "> what is the db password?", "kubectl get secret db -o yaml"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the report to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "aHVudGVyMjI=") {
+		t.Errorf("expected the Secret data to be redacted:\n%s", out)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if _, err := run("export", "latest", "--format", "html", "--output", path); err != nil {
+		t.Fatalf("session export latest: %v", err)
+	}
+	html, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "<pre>kubectl get secret db -o yaml</pre>") {
+		t.Errorf("expected an HTML report of the session:\n%s", html)
+	}
+
+	if _, err := run("export", "nope"); err == nil {
+		t.Errorf("exporting an unknown session succeeded, want an error")
+	}
+	if _, err := run("export", session.ID, "--format", "pdf"); err == nil {
+		t.Errorf("exporting as pdf succeeded, want an error")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui/html"
+)
+
+// The ways users of the web UI can authenticate (--ui-auth).
+const (
+	uiAuthNone  = "none"
+	uiAuthToken = "token"
+	uiAuthBasic = "basic"
+	uiAuthOIDC  = "oidc"
+	uiAuthProxy = "proxy"
+)
+
+// uiOIDCClientSecretEnv is the environment variable with the OIDC client secret of the web UI,
+// which is kept out of flags so it does not show in process listings.
+const uiOIDCClientSecretEnv = "KUBECTL_AI_UI_OIDC_CLIENT_SECRET"
+
+// validateWebUIOptions checks that the web UI authentication flags are consistent.
+func validateWebUIOptions(opt Options) error {
+	if opt.UIType != ui.UITypeWeb {
+		return nil
+	}
+	switch opt.UIAuth {
+	case uiAuthNone:
+		if len(opt.UIAllowedUsers) > 0 || len(opt.UIAllowedGroups) > 0 || len(opt.UIAdminGroups) > 0 {
+			return fmt.Errorf("--ui-allowed-users, --ui-allowed-groups and --ui-admin-groups require --ui-auth")
+		}
+	case uiAuthToken:
+		if opt.UIAuthTokenFile == "" {
+			return fmt.Errorf("--ui-auth=token requires --ui-auth-token-file")
+		}
+	case uiAuthBasic:
+		if opt.UIAuthHtpasswdFile == "" {
+			return fmt.Errorf("--ui-auth=basic requires --ui-auth-htpasswd-file")
+		}
+	case uiAuthOIDC:
+		if opt.UIOIDCIssuer == "" || opt.UIOIDCClientID == "" {
+			return fmt.Errorf("--ui-auth=oidc requires --ui-oidc-issuer and --ui-oidc-client-id")
+		}
+	case uiAuthProxy:
+		if len(opt.UIAuthProxyTrustedCIDRs) == 0 {
+			return fmt.Errorf("--ui-auth=proxy requires --ui-auth-proxy-trusted-cidrs")
+		}
+	default:
+		return fmt.Errorf("invalid --ui-auth %q: must be none, token, basic, oidc or proxy", opt.UIAuth)
+	}
+	if (opt.UITLSCertFile == "") != (opt.UITLSKeyFile == "") {
+		return fmt.Errorf("--ui-tls-cert-file and --ui-tls-key-file must be set together")
+	}
+	return nil
+}
+
+// newWebUIOptions creates the authenticator of the web UI, and the options to serve it with.
+func newWebUIOptions(ctx context.Context, opt Options) (html.Options, error) {
+	options := html.Options{
+		ListenAddress: opt.UIListenAddress,
+		Authorization: html.Authorization{
+			AllowedUsers:  opt.UIAllowedUsers,
+			AllowedGroups: opt.UIAllowedGroups,
+			AdminGroups:   opt.UIAdminGroups,
+		},
+		TLSCertFile: opt.UITLSCertFile,
+		TLSKeyFile:  opt.UITLSKeyFile,
+	}
+
+	var err error
+	switch opt.UIAuth {
+	case uiAuthNone:
+		if !isLoopbackAddress(opt.UIListenAddress) {
+			fmt.Fprintf(os.Stderr, "warning: the web UI listens on %s without authentication; anyone who can reach it can run commands in your cluster (see --ui-auth)\n", opt.UIListenAddress)
+		}
+	case uiAuthToken:
+		options.Auth, err = html.NewTokenAuthenticator(opt.UIAuthTokenFile)
+	case uiAuthBasic:
+		options.Auth, err = html.NewBasicAuthenticator(opt.UIAuthHtpasswdFile)
+	case uiAuthOIDC:
+		options.Auth, err = html.NewOIDCAuthenticator(ctx, html.OIDCConfig{
+			IssuerURL:    opt.UIOIDCIssuer,
+			ClientID:     opt.UIOIDCClientID,
+			ClientSecret: os.Getenv(uiOIDCClientSecretEnv),
+			RedirectURL:  opt.UIOIDCRedirectURL,
+			Scopes:       opt.UIOIDCScopes,
+		})
+	case uiAuthProxy:
+		options.Auth, err = html.NewProxyAuthenticator(opt.UIAuthProxyUserHeader, opt.UIAuthProxyGroupsHeader, opt.UIAuthProxyTrustedCIDRs)
+	}
+	if err != nil {
+		return html.Options{}, fmt.Errorf("setting up %s authentication for the web UI: %w", opt.UIAuth, err)
+	}
+	return options, nil
+}
+
+// isLoopbackAddress reports whether a listen address only accepts connections from this machine.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+func TestValidateWebUIOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(o *Options) {}},
+		{name: "token", modify: func(o *Options) { o.UIAuth = uiAuthToken; o.UIAuthTokenFile = "tokens.csv" }},
+		{name: "token without file", modify: func(o *Options) { o.UIAuth = uiAuthToken }, wantErr: true},
+		{name: "basic without file", modify: func(o *Options) { o.UIAuth = uiAuthBasic }, wantErr: true},
+		{name: "oidc without client", modify: func(o *Options) { o.UIAuth = uiAuthOIDC; o.UIOIDCIssuer = "https://accounts.example.com" }, wantErr: true},
+		{name: "proxy without trusted proxies", modify: func(o *Options) { o.UIAuth = uiAuthProxy }, wantErr: true},
+		{name: "unknown", modify: func(o *Options) { o.UIAuth = "ldap" }, wantErr: true},
+		{name: "allowed users without auth", modify: func(o *Options) { o.UIAllowedUsers = []string{"alice"} }, wantErr: true},
+		{name: "certificate without key", modify: func(o *Options) { o.UITLSCertFile = "tls.crt" }, wantErr: true},
+		{name: "terminal UI", modify: func(o *Options) { o.UIType = ui.UITypeTerminal; o.UIAuth = "ldap" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opt Options
+			opt.InitDefaults()
+			opt.UIType = ui.UITypeWeb
+			tt.modify(&opt)
+			if err := validateWebUIOptions(opt); (err != nil) != tt.wantErr {
+				t.Errorf("validateWebUIOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	tests := map[string]bool{
+		"localhost:8888": true,
+		"127.0.0.1:8888": true,
+		"[::1]:8888":     true,
+		"0.0.0.0:8888":   false,
+		":8888":          false,
+		"10.0.0.1:8888":  false,
+	}
+	for address, want := range tests {
+		if got := isLoopbackAddress(address); got != want {
+			t.Errorf("isLoopbackAddress(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
@@ -240,6 +240,11 @@ func (c *AzureOpenAIChat) Send(ctx context.Context, contents ...any) (ChatRespon
 				Content: azopenai.NewChatRequestUserMessageContent(v),
 			}
 			c.history = append(c.history, &message)
+		case ImageContent:
+			message := azopenai.ChatRequestUserMessage{
+				Content: azopenai.NewChatRequestUserMessageContent(v.placeholder()),
+			}
+			c.history = append(c.history, &message)
 		case FunctionCallResult:
 			message := azopenai.ChatRequestUserMessage{
 				Content: azopenai.NewChatRequestUserMessageContent(fmt.Sprintf("Function call result: %s", v.Result)),
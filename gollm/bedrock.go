@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -431,6 +432,19 @@ func (c *bedrockChat) addContentsToHistory(contents []any) error {
 		case string:
 			// Add text content block
 			contentBlocks = append(contentBlocks, &types.ContentBlockMemberText{Value: c})
+		case ImageContent:
+			// The Converse API only accepts a few image formats
+			format := types.ImageFormat(strings.TrimPrefix(c.MIMEType, "image/"))
+			if !slices.Contains(format.Values(), format) {
+				contentBlocks = append(contentBlocks, &types.ContentBlockMemberText{Value: c.placeholder()})
+				continue
+			}
+			contentBlocks = append(contentBlocks, &types.ContentBlockMemberImage{
+				Value: types.ImageBlock{
+					Format: format,
+					Source: &types.ImageSourceMemberBytes{Value: c.Data},
+				},
+			})
 		case FunctionCallResult:
 			// Determine status based on Result content
 			status := types.ToolResultStatusSuccess
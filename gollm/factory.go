@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -68,6 +69,13 @@ func WithSkipVerifySSL() Option {
 
 type FactoryFunc func(ctx context.Context, opts ClientOptions) (Client, error)
 
+// ListProviders returns the IDs of the registered providers, sorted.
+func ListProviders() []string {
+	providers := globalRegistry.listProviders()
+	sort.Strings(providers)
+	return providers
+}
+
 func RegisterProvider(id string, factoryFunc FactoryFunc) error {
 	return globalRegistry.RegisterProvider(id, factoryFunc)
 }
@@ -93,15 +101,14 @@ func (r *registry) NewClient(ctx context.Context, providerID string, opts ...Opt
 		providerID = providerID + "://"
 	}
 
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
 	u, err := url.Parse(providerID)
 	if err != nil {
 		return nil, fmt.Errorf("parsing provider id %q: %w", providerID, err)
 	}
 
+	r.mutex.Lock()
 	factoryFunc := r.providers[u.Scheme]
+	r.mutex.Unlock()
 	if factoryFunc == nil {
 		return nil, fmt.Errorf("provider %q not registered. Available providers: %v", u.Scheme, r.listProviders())
 	}
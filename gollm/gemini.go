@@ -383,6 +383,8 @@ func (c *GeminiChat) partsToGemini(contents ...any) ([]*genai.Part, error) {
 		switch v := content.(type) {
 		case string:
 			parts = append(parts, genai.NewPartFromText(v))
+		case ImageContent:
+			parts = append(parts, genai.NewPartFromBytes(v.Data, v.MIMEType))
 		case FunctionCallResult:
 			parts = append(parts, &genai.Part{
 				FunctionResponse: &genai.FunctionResponse{
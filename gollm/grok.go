@@ -222,6 +222,11 @@ func (cs *grokChatSession) Send(ctx context.Context, contents ...any) (ChatRespo
 		case string:
 			klog.V(2).Infof("Adding user message to history: %s", c)
 			cs.history = append(cs.history, openai.UserMessage(c))
+		case ImageContent:
+			klog.V(2).Infof("Adding %s image to history", c.MIMEType)
+			cs.history = append(cs.history, openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: c.dataURL()}),
+			}))
 		case FunctionCallResult:
 			klog.V(2).Infof("Adding tool call result to history: Name=%s, ID=%s", c.Name, c.ID)
 			// Marshal the result map into a JSON string for the message content
@@ -287,6 +292,11 @@ func (cs *grokChatSession) SendStreaming(ctx context.Context, contents ...any) (
 		case string:
 			klog.V(2).Infof("Adding user message to history: %s", c)
 			cs.history = append(cs.history, openai.UserMessage(c))
+		case ImageContent:
+			klog.V(2).Infof("Adding %s image to history", c.MIMEType)
+			cs.history = append(cs.history, openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: c.dataURL()}),
+			}))
 		case FunctionCallResult:
 			klog.V(2).Infof("Adding tool call result to history: Name=%s, ID=%s", c.Name, c.ID)
 			resultJSON, err := json.Marshal(c.Result)
@@ -16,6 +16,7 @@ package gollm
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -136,6 +137,23 @@ type FunctionCallResult struct {
 	Result map[string]any `json:"result,omitempty"`
 }
 
+// ImageContent is an image to send to the LLM, e.g. one returned by a tool.
+// Providers that do not support images send a short text placeholder instead.
+type ImageContent struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+// dataURL returns the image as a base64 data URL.
+func (i ImageContent) dataURL() string {
+	return "data:" + i.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// placeholder describes the image, for providers that cannot send it.
+func (i ImageContent) placeholder() string {
+	return fmt.Sprintf("[%s image of %d bytes omitted: images are not supported with this provider]", i.MIMEType, len(i.Data))
+}
+
 // ChatResponse is a generic chat response from the LLM.
 type ChatResponse interface {
 	UsageMetadata() any
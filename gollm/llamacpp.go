@@ -197,6 +197,12 @@ func (c *LlamaCppChat) Send(ctx context.Context, contents ...any) (ChatResponse,
 				Content: ptrTo(v),
 			}
 			c.history = append(c.history, message)
+		case ImageContent:
+			message := llamacppChatMessage{
+				Role:    "user",
+				Content: ptrTo(v.placeholder()),
+			}
+			c.history = append(c.history, message)
 		case FunctionCallResult:
 			resultJSON, err := json.Marshal(v.Result)
 			if err != nil {
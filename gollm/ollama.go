@@ -146,6 +146,12 @@ func (c *OllamaChat) Send(ctx context.Context, contents ...any) (ChatResponse, e
 				Content: v,
 			}
 			c.history = append(c.history, message)
+		case ImageContent:
+			message := api.Message{
+				Role:   "user",
+				Images: []api.ImageData{v.Data},
+			}
+			c.history = append(c.history, message)
 		case FunctionCallResult:
 			message := api.Message{
 				Role:    "user",
@@ -464,8 +464,27 @@ func (cs *openAIChatSession) IsRetryableError(err error) bool {
 	return DefaultIsRetryableError(err)
 }
 
+// Initialize replaces the history of the chat with the text of the given messages, keeping the system prompt.
+// Tool calls and their results are not replayed.
 func (cs *openAIChatSession) Initialize(messages []*api.Message) error {
-	klog.Warning("chat history persistence is not supported for provider 'openai', using in-memory chat history")
+	history := []openai.ChatCompletionMessageParamUnion{}
+	if len(cs.history) > 0 && cs.history[0].OfSystem != nil {
+		history = append(history, cs.history[0])
+	}
+	for _, msg := range messages {
+		text, ok := msg.Payload.(string)
+		if msg.Type != api.MessageTypeText || !ok || text == "" {
+			continue
+		}
+		switch msg.Source {
+		case api.MessageSourceUser:
+			history = append(history, openai.UserMessage(text))
+		case api.MessageSourceModel:
+			history = append(history, openai.AssistantMessage(text))
+		}
+		// Messages of the agent are for the user, not the model.
+	}
+	cs.history = history
 	return nil
 }
 
@@ -814,6 +833,11 @@ func (cs *openAIChatSession) addContentsToHistory(contents []any) error {
 		case string:
 			klog.V(2).Infof("Adding user message to history: %s", c)
 			cs.history = append(cs.history, openai.UserMessage(c))
+		case ImageContent:
+			klog.V(2).Infof("Adding %s image to history", c.MIMEType)
+			cs.history = append(cs.history, openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: c.dataURL()}),
+			}))
 		case FunctionCallResult:
 			klog.V(2).Infof("Adding tool call result to history: Name=%s, ID=%s", c.Name, c.ID)
 			// Marshal the result map into a JSON string for the message content
@@ -296,6 +296,15 @@ func (cs *openAIResponseChatSession) addContentsToHistory(contents []any) error
 					Role: responses.EasyInputMessageRoleUser,
 				},
 			})
+		case ImageContent:
+			cs.history = append(cs.history, responses.ResponseInputItemUnionParam{
+				OfMessage: &responses.EasyInputMessageParam{
+					Content: responses.EasyInputMessageContentUnionParam{
+						OfString: openai.String(c.placeholder()),
+					},
+					Role: responses.EasyInputMessageRoleUser,
+				},
+			})
 		case FunctionCallResult:
 			klog.V(2).Infof("Adding tool call result to history: Name=%s, ID=%s", c.Name, c.ID)
 			// Marshal the result map into a JSON string for the message content
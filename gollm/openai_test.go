@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
 	"github.com/openai/openai-go"
 )
 
@@ -604,3 +605,28 @@ func TestConvertToolCallsToFunctionCalls(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAIChatSessionInitialize(t *testing.T) {
+	cs := &openAIChatSession{
+		history: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("system prompt"),
+			openai.UserMessage("forgotten"),
+		},
+	}
+	err := cs.Initialize([]*api.Message{
+		{Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "why is web-0 pending?"},
+		{Source: api.MessageSourceAgent, Type: api.MessageTypeText, Payload: "Switched to context `prod`."},
+		{Source: api.MessageSourceModel, Type: api.MessageTypeToolCallRequest, Payload: "kubectl describe pod web-0"},
+		{Source: api.MessageSourceModel, Type: api.MessageTypeText, Payload: "The node pool is full."},
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	if len(cs.history) != 3 || cs.history[0].OfSystem == nil || cs.history[1].OfUser == nil || cs.history[2].OfAssistant == nil {
+		t.Fatalf("expected the system prompt, the query and the answer in the history, got %+v", cs.history)
+	}
+	if got := cs.history[1].OfUser.Content.OfString.Value; got != "why is web-0 pending?" {
+		t.Errorf("user message = %q, want the replayed query", got)
+	}
+}
@@ -16,12 +16,16 @@ package agent
 
 import (
 	"context"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	"go.uber.org/mock/gomock"
@@ -188,6 +192,7 @@ func TestAgentEndToEndToolExecution(t *testing.T) {
 
 	// Expect tool invocation messages and final response.
 	sawToolReq, sawToolResp, sawFinal := false, false, false
+	var toolProgress *api.Progress
 	for !(sawToolReq && sawToolResp && sawFinal) {
 		select {
 		case v := <-a.Output:
@@ -201,6 +206,10 @@ func TestAgentEndToEndToolExecution(t *testing.T) {
 				sawToolReq = true
 			case api.MessageTypeToolCallResponse:
 				sawToolResp = true
+			case api.MessageTypeProgress:
+				if progress := m.Payload.(*api.Progress); progress.Tool != "" {
+					toolProgress = progress
+				}
 			case api.MessageTypeText:
 				if m.Source == api.MessageSourceModel {
 					sawFinal = true
@@ -210,6 +219,10 @@ func TestAgentEndToEndToolExecution(t *testing.T) {
 			t.Fatalf("timeout before complete tool execution flow: req=%v resp=%v final=%v", sawToolReq, sawToolResp, sawFinal)
 		}
 	}
+	// The agent tells the UI which tool it runs, once approved.
+	if toolProgress == nil || toolProgress.State != api.AgentStateRunning || toolProgress.Iteration != 1 {
+		t.Errorf("expected progress of the approved tool call in iteration 1 while running, got %+v", toolProgress)
+	}
 
 	// After final model text, the agent may either prompt for more input (UI loop)
 	// or declare Done depending on configuration. Accept either behavior.
@@ -227,6 +240,175 @@ func TestAgentEndToEndToolExecution(t *testing.T) {
 	}
 }
 
+func TestAgentEndToEndRecordsApprovingUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+	gomock.InOrder(
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+			yield(chatWith(fCalls("mocktool", map[string]any{"command": "do"})), nil)
+		}), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+			yield(chatWith(fText("all done")), nil)
+		}), nil),
+	)
+
+	tool := mocks.NewMockTool(ctrl)
+	tool.EXPECT().Name().Return("mocktool").AnyTimes()
+	tool.EXPECT().Description().Return("mock tool").AnyTimes()
+	tool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "mocktool"}).AnyTimes()
+	tool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+	tool.EXPECT().CheckModifiesResource(gomock.Any()).Return("yes").AnyTimes()
+	tool.EXPECT().Run(gomock.Any(), gomock.Any()).Return(map[string]any{"result": "ok"}, nil)
+
+	var toolset tools.Tools
+	toolset.Init()
+	toolset.RegisterTool(tool)
+
+	recorder := &journal.MemoryRecorder{}
+	a := &Agent{
+		ChatMessageStore: sessions.NewInMemoryChatStore(),
+		LLM:              client,
+		Model:            "test-model",
+		Tools:            toolset,
+		MaxIterations:    4,
+		Recorder:         recorder,
+	}
+	if err := a.Init(ctx); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := a.Run(ctx, "test"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	recvUntil(t, ctx, a.Output, func(m *api.Message) bool { return m.Type == api.MessageTypeUserChoiceRequest })
+	// The UI tells the agent which authenticated user made the choice.
+	a.Input <- &api.UserChoiceResponse{Choice: 1, User: "alice"}
+	recvUntil(t, ctx, a.Output, func(m *api.Message) bool {
+		return m.Type == api.MessageTypeText && m.Source == api.MessageSourceModel
+	})
+
+	choices := recorder.Events(journal.ActionUserChoice)
+	if len(choices) != 1 {
+		t.Fatalf("expected 1 user choice event, got %d", len(choices))
+	}
+	if user, _ := choices[0].GetString("user"); user != "alice" {
+		t.Errorf("user choice recorded for %q, want alice", user)
+	}
+	if decision, _ := choices[0].GetString("decision"); decision != "approved" {
+		t.Errorf("user choice recorded as %q, want approved", decision)
+	}
+}
+
+func TestAgentEndToEndEditToolCall(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+
+	firstIter := gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+		yield(chatWith(fCalls("mocktool", map[string]any{"command": "scale --replicas=30"})), nil)
+	})
+	secondIter := gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+		yield(chatWith(fText("all done")), nil)
+	})
+	var results []any
+	gomock.InOrder(
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(firstIter, nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+				results = contents
+				return secondIter, nil
+			}),
+	)
+
+	tool := mocks.NewMockTool(ctrl)
+	tool.EXPECT().Name().Return("mocktool").AnyTimes()
+	tool.EXPECT().Description().Return("mock tool").AnyTimes()
+	tool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "mocktool"}).AnyTimes()
+	tool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+	// Dry runs do not modify resources.
+	tool.EXPECT().CheckModifiesResource(gomock.Any()).DoAndReturn(func(args map[string]any) string {
+		if strings.Contains(args["command"].(string), "--dry-run") {
+			return "no"
+		}
+		return "yes"
+	}).AnyTimes()
+	var ranCommand any
+	tool.EXPECT().Run(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, args map[string]any) (any, error) {
+		ranCommand = args["command"]
+		return map[string]any{"result": "ok"}, nil
+	})
+
+	var toolset tools.Tools
+	toolset.Init()
+	toolset.RegisterTool(tool)
+
+	a := &Agent{
+		ChatMessageStore: sessions.NewInMemoryChatStore(),
+		LLM:              client,
+		Model:            "test-model",
+		Tools:            toolset,
+		MaxIterations:    4,
+	}
+	if err := a.Init(ctx); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := a.Run(ctx, ""); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	recvUntil(t, ctx, a.Output, func(m *api.Message) bool { return m.Type == api.MessageTypeUserInputRequest })
+	a.Input <- &api.UserInputResponse{Query: "scale web to 3"}
+
+	isChoiceRequest := func(m *api.Message) bool { return m.Type == api.MessageTypeUserChoiceRequest }
+	choiceRequest := recvUntil(t, ctx, a.Output, isChoiceRequest).Payload.(*api.UserChoiceRequest)
+	if len(choiceRequest.Options) != 4 || choiceRequest.Options[ChoiceEdit-1].Value != api.UserChoiceEdit {
+		t.Fatalf("expected an edit option, got %+v", choiceRequest.Options)
+	}
+	if len(choiceRequest.ToolCalls) != 1 || choiceRequest.ToolCalls[0].EditText() != "scale --replicas=30" {
+		t.Fatalf("expected the tool call to edit in the choice request, got %+v", choiceRequest.ToolCalls)
+	}
+
+	// Edits that do not match the tool calls are rejected, and the user is asked again.
+	a.Input <- &api.UserChoiceResponse{Choice: ChoiceEdit}
+	recvUntil(t, ctx, a.Output, isChoiceRequest)
+
+	a.Input <- &api.UserChoiceResponse{Choice: ChoiceEdit, Edits: []string{"scale --replicas=3 --dry-run"}}
+	recvUntil(t, ctx, a.Output, func(m *api.Message) bool {
+		return m.Type == api.MessageTypeText && m.Source == api.MessageSourceModel
+	})
+
+	if ranCommand != "scale --replicas=3 --dry-run" {
+		t.Errorf("tool ran %q, want the edited command", ranCommand)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one function call result sent to the LLM, got %d", len(results))
+	}
+	result, ok := results[0].(gollm.FunctionCallResult)
+	if !ok {
+		t.Fatalf("expected a function call result, got %T", results[0])
+	}
+	if note, _ := result.Result["modified_by_user"].(string); !strings.Contains(note, "scale --replicas=3 --dry-run") {
+		t.Errorf("expected the LLM to be told about the edited command, got %v", result.Result)
+	}
+}
+
 func TestAgentEndToEndMetaClear(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -278,7 +460,7 @@ func TestAgentEndToEndMetaClear(t *testing.T) {
 	}
 
 	// UI sends the meta command
-	a.Input <- &api.UserInputResponse{Query: "clear"}
+	a.Input <- &api.UserInputResponse{Query: "/clear"}
 
 	sawClear, sawPrompt := false, false
 	for !(sawClear && sawPrompt) {
@@ -311,3 +493,162 @@ func TestAgentEndToEndMetaClear(t *testing.T) {
 		t.Fatalf("second message type = %v, want user input request", msgs[1].Type)
 	}
 }
+
+func TestAgentStreamsTextDeltas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+	chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+		if !yield(chatWith(fText("The pods ")), nil) {
+			return
+		}
+		yield(chatWith(fText("are healthy.")), nil)
+	}), nil)
+
+	var toolset tools.Tools
+	toolset.Init()
+	a := &Agent{
+		ChatMessageStore: sessions.NewInMemoryChatStore(),
+		LLM:              client,
+		Model:            "test-model",
+		Tools:            toolset,
+		MaxIterations:    4,
+	}
+	if err := a.Init(ctx); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := a.Run(ctx, ""); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	recvUntil(t, ctx, a.Output, func(m *api.Message) bool { return m.Type == api.MessageTypeUserInputRequest })
+
+	a.Input <- &api.UserInputResponse{Query: "are my pods healthy?"}
+
+	// The text is sent piece by piece as it streams, then as a whole in a message with the same ID.
+	var deltas []string
+	var messageID string
+	final := recvUntil(t, ctx, a.Output, func(m *api.Message) bool {
+		if m.Type == api.MessageTypeTextDelta {
+			if messageID != "" && m.ID != messageID {
+				t.Errorf("delta of message %q, want %q", m.ID, messageID)
+			}
+			messageID = m.ID
+			deltas = append(deltas, m.Payload.(string))
+		}
+		return m.Type == api.MessageTypeText && m.Source == api.MessageSourceModel
+	})
+	if want := []string{"The pods ", "are healthy."}; !slices.Equal(deltas, want) {
+		t.Errorf("deltas = %q, want %q", deltas, want)
+	}
+	if final.ID != messageID || final.Payload != "The pods are healthy." {
+		t.Errorf("final message %q = %q, want message %q with the whole text", final.ID, final.Payload, messageID)
+	}
+	for _, m := range a.Session().AllMessages() {
+		if m.Type == api.MessageTypeTextDelta {
+			t.Errorf("delta %q was added to the session", m.Payload)
+		}
+	}
+}
+
+func TestAgentRunOnceStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxIterations int
+		wantStats     RunStats
+	}{
+		{
+			name:          "failed command",
+			maxIterations: 4,
+			wantStats:     RunStats{Iterations: 2, ToolCalls: 1, ToolErrors: 1},
+		},
+		{
+			name:          "max iterations",
+			maxIterations: 1,
+			wantStats:     RunStats{Iterations: 1, ToolCalls: 1, ToolErrors: 1, MaxIterationsReached: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			client := mocks.NewMockClient(ctrl)
+			chat := mocks.NewMockChat(ctrl)
+			client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+			chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+			chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+			chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+				yield(chatWith(fCalls("mocktool", map[string]any{"command": "kubectl get pods"})), nil)
+			}), nil)
+			chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(gollm.ChatResponseIterator(func(yield func(gollm.ChatResponse, error) bool) {
+				yield(chatWith(fText("kubectl is not installed.")), nil)
+			}), nil).MaxTimes(1)
+
+			tool := mocks.NewMockTool(ctrl)
+			tool.EXPECT().Name().Return("mocktool").AnyTimes()
+			tool.EXPECT().Description().Return("mock tool").AnyTimes()
+			tool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "mocktool"}).AnyTimes()
+			tool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+			tool.EXPECT().CheckModifiesResource(gomock.Any()).Return("no").AnyTimes()
+			tool.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&tools.ExecResult{Command: "kubectl get pods", Stderr: "kubectl: not found", ExitCode: 127}, nil)
+
+			var toolset tools.Tools
+			toolset.Init()
+			toolset.RegisterTool(tool)
+
+			query := "list the pods"
+			a := &Agent{
+				ChatMessageStore: sessions.NewInMemoryChatStore(),
+				LLM:              client,
+				Model:            "test-model",
+				Tools:            toolset,
+				MaxIterations:    tt.maxIterations,
+				RunOnce:          true,
+				InitialQuery:     query,
+			}
+			if err := a.Init(ctx); err != nil {
+				t.Fatalf("init: %v", err)
+			}
+			if err := a.Run(ctx, query); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			// The messages about the tool call carry the call, for UIs to show its arguments.
+			var toolCallMessages int
+			for done := false; !done; {
+				select {
+				case v := <-a.Output:
+					m := v.(*api.Message)
+					if m.Type != api.MessageTypeToolCallRequest && m.Type != api.MessageTypeToolCallResponse {
+						continue
+					}
+					toolCallMessages++
+					if m.ToolCall == nil || m.ToolCall.Name != "mocktool" || m.ToolCall.Arguments["command"] != "kubectl get pods" {
+						t.Errorf("%s message has tool call %+v, want the call of mocktool", m.Type, m.ToolCall)
+					}
+				case <-a.Done():
+					done = len(a.Output) == 0
+				case <-ctx.Done():
+					t.Fatalf("timed out waiting for the agent to exit: %v", ctx.Err())
+				}
+			}
+			if toolCallMessages != 2 {
+				t.Errorf("got %d tool call messages, want 2", toolCallMessages)
+			}
+			if got := a.Stats(); !reflect.DeepEqual(got, tt.wantStats) {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+}
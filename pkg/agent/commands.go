@@ -0,0 +1,427 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// CommandPrefix starts the queries that are commands for the agent (e.g. /clear) rather than questions for the LLM.
+const CommandPrefix = "/"
+
+// Command is a command users can type instead of a query, such as /clear or /session resume <id>.
+type Command struct {
+	// Name is typed after the slash, e.g. clear for /clear.
+	Name string
+	// Aliases are other names of the command, e.g. quit for /exit.
+	Aliases []string
+	// Usage describes the arguments of the command, e.g. "<session-id>".
+	Usage string
+	// Description is a one-line description, shown by /help.
+	Description string
+	// Hidden commands are not listed by /help nor completed, such as the old spellings of commands.
+	Hidden bool
+
+	// MinArgs and MaxArgs bound the number of arguments of the command. MaxArgs < 0 means no limit.
+	MinArgs int
+	MaxArgs int
+	// Flags defines the flags of the command, if it has any.
+	Flags func(flags *pflag.FlagSet)
+
+	// Subcommands are selected by the first argument, e.g. resume in /session resume <id>.
+	Subcommands []*Command
+
+	// Run runs the command, and returns the answer to show to the user.
+	// It may be nil for commands that only have subcommands.
+	Run func(ctx context.Context, inv *Invocation) (string, error)
+
+	// Complete returns the candidates for the argument following args, which the user already typed.
+	// Candidates are filtered by what the user typed of the argument, so Complete may return them all.
+	Complete func(ctx context.Context, a *Agent, args []string) []string
+}
+
+// Invocation is a command typed by the user.
+type Invocation struct {
+	Agent *Agent
+	// Command is the command that runs, which is a subcommand for e.g. /session resume <id>.
+	Command *Command
+	// Args are the arguments of the command, once the subcommands and flags are removed.
+	Args []string
+	// Flags holds the flags of the command.
+	Flags *pflag.FlagSet
+}
+
+// CommandRegistry holds the commands of an agent.
+type CommandRegistry struct {
+	mu sync.RWMutex
+	// commands holds the commands by name and alias.
+	commands map[string]*Command
+}
+
+// NewCommandRegistry creates an empty registry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{commands: make(map[string]*Command)}
+}
+
+// Register adds a command. It fails if the name or an alias of the command is already taken.
+func (r *CommandRegistry) Register(cmd *Command) error {
+	if cmd.Name == "" {
+		return fmt.Errorf("command has no name")
+	}
+	if cmd.Run == nil && len(cmd.Subcommands) == 0 {
+		return fmt.Errorf("command %q has nothing to run", cmd.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if strings.ContainsFunc(name, unicode.IsSpace) || strings.Contains(name, CommandPrefix) {
+			return fmt.Errorf("invalid command name %q", name)
+		}
+		if _, ok := r.commands[name]; ok {
+			return fmt.Errorf("command %q is already registered", name)
+		}
+	}
+	for _, name := range names {
+		r.commands[name] = cmd
+	}
+	return nil
+}
+
+// Lookup returns the command with the given name or alias, or nil.
+func (r *CommandRegistry) Lookup(name string) *Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.commands[name]
+}
+
+// Commands returns the commands that are not hidden, sorted by name.
+func (r *CommandRegistry) Commands() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var commands []*Command
+	for name, cmd := range r.commands {
+		if name == cmd.Name && !cmd.Hidden {
+			commands = append(commands, cmd)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Commands returns the registry of the commands of the agent, which starts with the built-in commands.
+func (c *Agent) Commands() *CommandRegistry {
+	c.commandsOnce.Do(func() {
+		c.commands = NewCommandRegistry()
+		for _, cmd := range builtinCommands() {
+			if err := c.commands.Register(cmd); err != nil {
+				panic(fmt.Sprintf("registering built-in command: %v", err))
+			}
+		}
+	})
+	return c.commands
+}
+
+// RegisterCommand adds a command to the agent, for UIs and plugins to extend the built-in commands.
+func (c *Agent) RegisterCommand(cmd *Command) error {
+	return c.Commands().Register(cmd)
+}
+
+// handleMetaQuery runs the command typed as a query, if it is one.
+// Queries starting with a slash are commands, unless they start with a path, such as /var/log/messages.
+// With LegacyCommands, queries starting with the name of a command (e.g. clear) are commands too,
+// as long as they are valid invocations of it.
+func (c *Agent) handleMetaQuery(ctx context.Context, query string) (answer string, handled bool, err error) {
+	query = strings.TrimSpace(query)
+	args, err := splitCommandLine(query)
+	if err != nil || len(args) == 0 {
+		if rest, ok := strings.CutPrefix(query, CommandPrefix); ok && err != nil && !strings.Contains(firstWord(rest), CommandPrefix) {
+			return fmt.Sprintf("Invalid command: %v.", err), true, nil
+		}
+		return "", false, nil
+	}
+
+	if name, ok := strings.CutPrefix(args[0], CommandPrefix); ok {
+		if name == "" || strings.Contains(name, CommandPrefix) {
+			return "", false, nil
+		}
+		cmd := c.Commands().Lookup(name)
+		if cmd == nil {
+			return fmt.Sprintf("Unknown command `/%s`. Type `/help` to list the commands.", name), true, nil
+		}
+		inv, usageErr := c.newInvocation(cmd, args[1:])
+		if usageErr != nil {
+			return usageErr.Error(), true, nil
+		}
+		answer, err := inv.Command.Run(ctx, inv)
+		return answer, true, err
+	}
+
+	if !c.LegacyCommands {
+		return "", false, nil
+	}
+	cmd := c.Commands().Lookup(args[0])
+	if cmd == nil {
+		return "", false, nil
+	}
+	inv, usageErr := c.newInvocation(cmd, args[1:])
+	if usageErr != nil {
+		// e.g. "context is missing from the pod", which is a question for the LLM.
+		return "", false, nil
+	}
+	answer, err = inv.Command.Run(ctx, inv)
+	return answer, true, err
+}
+
+// usageError is an invalid invocation of a command, whose message tells the user how to use it.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// newInvocation resolves the subcommand and parses the flags and arguments of an invocation of cmd.
+func (c *Agent) newInvocation(cmd *Command, args []string) (*Invocation, error) {
+	path := []string{cmd.Name}
+	for len(cmd.Subcommands) > 0 && len(args) > 0 {
+		sub := findSubcommand(cmd, args[0])
+		if sub == nil {
+			break
+		}
+		cmd = sub
+		path = append(path, sub.Name)
+		args = args[1:]
+	}
+	fullName := CommandPrefix + strings.Join(path, " ")
+	if cmd.Run == nil {
+		return nil, &usageError{message: commandHelp(fullName, cmd)}
+	}
+
+	flags := newCommandFlagSet(fullName, cmd)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil, &usageError{message: commandHelp(fullName, cmd)}
+		}
+		return nil, &usageError{message: fmt.Sprintf("Invalid command: %v.\n\n%s", err, commandHelp(fullName, cmd))}
+	}
+	args = flags.Args()
+	if len(args) < cmd.MinArgs || (cmd.MaxArgs >= 0 && len(args) > cmd.MaxArgs) {
+		return nil, &usageError{message: "Usage: " + commandSynopsis(fullName, cmd)}
+	}
+	return &Invocation{Agent: c, Command: cmd, Args: args, Flags: flags}, nil
+}
+
+// findSubcommand returns the subcommand of cmd with the given name or alias, or nil.
+func findSubcommand(cmd *Command, name string) *Command {
+	for _, sub := range cmd.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+func newCommandFlagSet(name string, cmd *Command) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if cmd.Flags != nil {
+		cmd.Flags(flags)
+	}
+	return flags
+}
+
+// commandSynopsis returns how to type a command, e.g. "/session resume <session-id>".
+func commandSynopsis(fullName string, cmd *Command) string {
+	synopsis := "`" + fullName
+	if len(cmd.Subcommands) > 0 {
+		var names []string
+		for _, sub := range cmd.Subcommands {
+			if !sub.Hidden {
+				names = append(names, sub.Name)
+			}
+		}
+		if cmd.Run != nil {
+			synopsis += " [" + strings.Join(names, "|") + "]"
+		} else {
+			synopsis += " " + strings.Join(names, "|")
+		}
+	}
+	if cmd.Usage != "" {
+		synopsis += " " + cmd.Usage
+	}
+	return synopsis + "`"
+}
+
+// commandHelp describes a command, its subcommands and its flags.
+func commandHelp(fullName string, cmd *Command) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Usage: %s\n\n%s\n", commandSynopsis(fullName, cmd), cmd.Description)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&sb, "\nAliases: %s\n", strings.Join(cmd.Aliases, ", "))
+	}
+	var subs []string
+	for _, sub := range cmd.Subcommands {
+		if !sub.Hidden {
+			subs = append(subs, fmt.Sprintf("  - %s: %s", commandSynopsis(fullName+" "+sub.Name, sub), sub.Description))
+		}
+	}
+	if len(subs) > 0 {
+		fmt.Fprintf(&sb, "\nSubcommands:\n\n%s\n", strings.Join(subs, "\n"))
+	}
+	if flags := newCommandFlagSet(fullName, cmd); flags.HasFlags() {
+		fmt.Fprintf(&sb, "\nFlags:\n\n```text\n%s```\n", flags.FlagUsages())
+	}
+	return sb.String()
+}
+
+// CompleteCommand returns the completions of the last word of a command being typed, such as
+// /con or /context sta. The completions replace the last word; line ends with a space when
+// the user started a new word. Only queries starting with a slash are completed.
+func (c *Agent) CompleteCommand(ctx context.Context, line string) []string {
+	if !strings.HasPrefix(line, CommandPrefix) {
+		return nil
+	}
+	words, err := splitCommandLine(line)
+	if err != nil {
+		return nil
+	}
+	if strings.HasSuffix(line, " ") || len(words) == 0 {
+		words = append(words, "")
+	}
+	partial := words[len(words)-1]
+
+	var candidates []string
+	if len(words) == 1 {
+		for _, cmd := range c.Commands().Commands() {
+			candidates = append(candidates, CommandPrefix+cmd.Name)
+		}
+		return filterCompletions(candidates, partial)
+	}
+
+	cmd := c.Commands().Lookup(strings.TrimPrefix(words[0], CommandPrefix))
+	if cmd == nil {
+		return nil
+	}
+	args := words[1 : len(words)-1]
+	for len(cmd.Subcommands) > 0 && len(args) > 0 {
+		sub := findSubcommand(cmd, args[0])
+		if sub == nil {
+			break
+		}
+		cmd, args = sub, args[1:]
+	}
+
+	if strings.HasPrefix(partial, "-") {
+		newCommandFlagSet(cmd.Name, cmd).VisitAll(func(flag *pflag.Flag) {
+			candidates = append(candidates, "--"+flag.Name)
+		})
+		return filterCompletions(candidates, partial)
+	}
+	if len(args) == 0 {
+		for _, sub := range cmd.Subcommands {
+			if !sub.Hidden {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+	}
+	if cmd.Complete != nil {
+		candidates = append(candidates, cmd.Complete(ctx, c, args)...)
+	}
+	return filterCompletions(candidates, partial)
+}
+
+// filterCompletions returns the sorted, distinct candidates that start with prefix.
+func filterCompletions(candidates []string, prefix string) []string {
+	seen := make(map[string]bool)
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) && !seen[candidate] {
+			seen[candidate] = true
+			completions = append(completions, candidate)
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// splitCommandLine splits a command line into words, which are separated by spaces
+// unless they are quoted with single or double quotes, or escaped with a backslash.
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// firstWord returns the first space-separated word of s.
+func firstWord(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
@@ -0,0 +1,454 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/spf13/pflag"
+)
+
+// builtinCommands returns the commands every agent has.
+func builtinCommands() []*Command {
+	sessionShow := &Command{
+		Name:        "show",
+		Description: "Show the current session.",
+		Run:         runSessionShow,
+	}
+	sessionList := &Command{
+		Name:        "list",
+		Description: "List the saved sessions.",
+		Run:         runSessionList,
+	}
+	sessionSave := &Command{
+		Name:        "save",
+		Description: "Save the conversation as a new session, if it is not saved already.",
+		Run:         runSessionSave,
+	}
+	sessionResume := &Command{
+		Name:        "resume",
+		Usage:       "<session-id>",
+		Description: "Resume a saved session.",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Run:         runSessionResume,
+		Complete:    completeSessionIDs,
+	}
+
+	return []*Command{
+		{
+			Name:        "help",
+			Usage:       "[command]",
+			Description: "List the commands, or describe one of them.",
+			MaxArgs:     1,
+			Run:         runHelp,
+			Complete: func(ctx context.Context, a *Agent, args []string) []string {
+				if len(args) > 0 {
+					return nil
+				}
+				var names []string
+				for _, cmd := range a.Commands().Commands() {
+					names = append(names, cmd.Name)
+				}
+				return names
+			},
+		},
+		{
+			Name:        "clear",
+			Aliases:     []string{"reset"},
+			Description: "Clear the conversation.",
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				if err := inv.Agent.clearConversation(); err != nil {
+					return "", fmt.Errorf("clearing the conversation: %w", err)
+				}
+				return "Cleared the conversation.", nil
+			},
+		},
+		{
+			Name:        "exit",
+			Aliases:     []string{"quit"},
+			Description: "Exit kubectl-ai.",
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				inv.Agent.setAgentState(api.AgentStateExited)
+				return "It has been a pleasure assisting you. Have a great day!", nil
+			},
+		},
+		{
+			Name:        "model",
+			Usage:       "[name]",
+			Description: "Show the current model, or continue the conversation with another model.",
+			MaxArgs:     1,
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				if len(inv.Args) == 0 {
+					return "Current model is " + inv.Agent.describeLLMTarget(), nil
+				}
+				return inv.Agent.switchModel(ctx, inv.Args[0])
+			},
+			Complete: completeModels,
+		},
+		{
+			Name:        "provider",
+			Usage:       "[id] [model]",
+			Description: "Show the current LLM provider, or continue the conversation with a model of another provider.",
+			MaxArgs:     2,
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				if len(inv.Args) == 0 {
+					return "Current model is " + inv.Agent.describeLLMTarget() + "\n\n" + formatProviders(), nil
+				}
+				model := ""
+				if len(inv.Args) > 1 {
+					model = inv.Args[1]
+				}
+				return inv.Agent.switchProvider(ctx, inv.Args[0], model)
+			},
+			Complete: completeProviders,
+		},
+		{
+			Name:        "models",
+			Description: "List the models of the LLM provider.",
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				models, err := inv.Agent.listModels(ctx)
+				if err != nil {
+					return "", fmt.Errorf("listing models: %w", err)
+				}
+				return "Available models:\n\n  - " + strings.Join(models, "\n  - ") + "\n\n", nil
+			},
+		},
+		{
+			Name:        "tools",
+			Description: "List the tools the agent can use.",
+			Flags: func(flags *pflag.FlagSet) {
+				flags.BoolP("verbose", "v", false, "also show the description of the tools")
+			},
+			Run: runTools,
+		},
+		{
+			Name:        "session",
+			Description: "Show, list, save or resume sessions.",
+			Subcommands: []*Command{sessionShow, sessionList, sessionSave, sessionResume},
+			Run:         runSessionShow,
+		},
+		{
+			Name:        "export",
+			Description: "Export the conversation as a report, e.g. of an incident, with secrets redacted.",
+			Flags: func(flags *pflag.FlagSet) {
+				flags.StringP("format", "f", string(sessions.ExportFormatMarkdown), "format of the report: md, html or json")
+				flags.StringP("output", "o", "", "file to write the report to (default kubectl-ai-session-<id>.<format>)")
+				flags.Int("max-output-lines", defaultExportMaxOutputLines, "truncate the output of tool calls to this many lines (0 keeps all of it)")
+			},
+			Run: runExport,
+		},
+		// The old spellings of the session commands, kept for LegacyCommands.
+		{Name: "sessions", Hidden: true, Description: sessionList.Description, Run: runSessionList},
+		{Name: "save-session", Hidden: true, Description: sessionSave.Description, Run: runSessionSave},
+		{
+			Name:        "resume-session",
+			Hidden:      true,
+			Usage:       sessionResume.Usage,
+			Description: sessionResume.Description,
+			MinArgs:     1,
+			MaxArgs:     1,
+			Run:         runSessionResume,
+		},
+		{
+			Name:        "context",
+			Usage:       "[name]",
+			Description: "Show the Kubernetes context and list the others, or switch to another one.",
+			MaxArgs:     1,
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				name := ""
+				if len(inv.Args) > 0 {
+					name = inv.Args[0]
+				}
+				return inv.Agent.switchKubeContext(ctx, name)
+			},
+			Complete: completeKubeContexts,
+		},
+		{
+			Name:        "namespace",
+			Aliases:     []string{"ns"},
+			Usage:       "[name]",
+			Description: "Show the Kubernetes namespace, or switch to another one.",
+			MaxArgs:     1,
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				name := ""
+				if len(inv.Args) > 0 {
+					name = inv.Args[0]
+				}
+				return inv.Agent.switchNamespace(name), nil
+			},
+		},
+		{
+			Name:        "mcp",
+			Description: "Show the state of the MCP servers, or reconnect or disable one of them.",
+			Subcommands: []*Command{
+				{
+					Name:        "status",
+					Description: "Show the state of the MCP servers.",
+					Run:         runMCPStatus,
+				},
+				{
+					Name:        "reconnect",
+					Usage:       "<server>",
+					Description: "Reconnect to an MCP server, enabling it if it was disabled.",
+					MinArgs:     1,
+					MaxArgs:     1,
+					Run:         runMCPReconnect,
+					Complete:    completeMCPServers,
+				},
+				{
+					Name:        "disable",
+					Usage:       "<server>",
+					Description: "Disconnect from an MCP server, and remove its tools.",
+					MinArgs:     1,
+					MaxArgs:     1,
+					Run:         runMCPDisable,
+					Complete:    completeMCPServers,
+				},
+			},
+			Run: runMCPStatus,
+		},
+		{
+			Name:        "resources",
+			Description: "List the resources published by the MCP servers.",
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				if inv.Agent.mcpManager == nil {
+					return noMCPServers, nil
+				}
+				return formatMCPResources(inv.Agent.mcpManager.ListResources(ctx)), nil
+			},
+		},
+		{
+			Name:        "prompts",
+			Description: "List the prompts published by the MCP servers.",
+			Run: func(ctx context.Context, inv *Invocation) (string, error) {
+				if inv.Agent.mcpManager == nil {
+					return noMCPServers, nil
+				}
+				return formatMCPPrompts(inv.Agent.mcpManager.ListPrompts(ctx)), nil
+			},
+		},
+	}
+}
+
+func runHelp(ctx context.Context, inv *Invocation) (string, error) {
+	registry := inv.Agent.Commands()
+	if len(inv.Args) == 1 {
+		name := strings.TrimPrefix(inv.Args[0], CommandPrefix)
+		cmd := registry.Lookup(name)
+		if cmd == nil {
+			return fmt.Sprintf("Unknown command `/%s`. Type `/help` to list the commands.", name), nil
+		}
+		return commandHelp(CommandPrefix+cmd.Name, cmd), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Available commands:\n\n")
+	for _, cmd := range registry.Commands() {
+		fmt.Fprintf(&sb, "  - %s: %s\n", commandSynopsis(CommandPrefix+cmd.Name, cmd), cmd.Description)
+	}
+	sb.WriteString("\nType `/help <command>` for the details of a command.\n")
+	return sb.String(), nil
+}
+
+func runTools(ctx context.Context, inv *Invocation) (string, error) {
+	verbose, _ := inv.Flags.GetBool("verbose")
+	if !verbose {
+		return "Available tools:\n\n  - " + strings.Join(inv.Agent.Tools.Names(), "\n  - ") + "\n\n", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Available tools:\n\n")
+	for _, name := range inv.Agent.Tools.Names() {
+		// Only the first line, as some descriptions go on with instructions for the LLM.
+		description, _, _ := strings.Cut(strings.TrimSpace(inv.Agent.Tools.Lookup(name).Description()), "\n")
+		fmt.Fprintf(&sb, "  - %s: %s\n", name, description)
+	}
+	return sb.String(), nil
+}
+
+func runSessionShow(ctx context.Context, inv *Invocation) (string, error) {
+	s, ok := inv.Agent.ChatMessageStore.(*sessions.Session)
+	if !ok {
+		return "Session not found (session persistence not enabled)", nil
+	}
+	out, err := s.String()
+	if err != nil {
+		return "", fmt.Errorf("failed to get session string: %w", err)
+	}
+	return out, nil
+}
+
+func runSessionList(ctx context.Context, inv *Invocation) (string, error) {
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session manager: %w", err)
+	}
+
+	sessionList, err := manager.ListSessions()
+	if err != nil {
+		return "", fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(sessionList) == 0 {
+		return "No sessions found.", nil
+	}
+
+	// Add ```text so markdown doesn't wreck the format
+	availableSessions := "```text"
+	availableSessions += "Available sessions:\n\n"
+	availableSessions += "ID\t\t\tCreated\t\t\tLast Accessed\t\tModel\t\tProvider\n"
+	availableSessions += "--\t\t\t-------\t\t\t-------------\t\t-----\t\t--------\n"
+
+	for _, session := range sessionList {
+		metadata, err := session.LoadMetadata()
+		if err != nil {
+			availableSessions += fmt.Sprintf("%s\t\t<error loading metadata>\n", session.ID)
+			continue
+		}
+
+		availableSessions += fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n",
+			session.ID,
+			metadata.CreatedAt.Format("2006-01-02 15:04"),
+			metadata.LastAccessed.Format("2006-01-02 15:04"),
+			metadata.ModelID,
+			metadata.ProviderID)
+	}
+	// close the ```text box
+	availableSessions += "```"
+	return availableSessions, nil
+}
+
+func runSessionSave(ctx context.Context, inv *Invocation) (string, error) {
+	savedSessionID, err := inv.Agent.SaveSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to save session: %w", err)
+	}
+	return "Saved session as " + savedSessionID, nil
+}
+
+func runSessionResume(ctx context.Context, inv *Invocation) (string, error) {
+	sessionID := inv.Args[0]
+	if err := inv.Agent.loadSession(sessionID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Resumed session %s.", sessionID), nil
+}
+
+// defaultExportMaxOutputLines is how much of the output of tool calls reports keep by default.
+const defaultExportMaxOutputLines = 50
+
+func runExport(ctx context.Context, inv *Invocation) (string, error) {
+	formatName, _ := inv.Flags.GetString("format")
+	format, err := sessions.ParseExportFormat(formatName)
+	if err != nil {
+		return "", err
+	}
+	maxOutputLines, _ := inv.Flags.GetInt("max-output-lines")
+	report, err := inv.Agent.sessionReport(maxOutputLines)
+	if err != nil {
+		return "", err
+	}
+
+	path, _ := inv.Flags.GetString("output")
+	if path == "" {
+		path = fmt.Sprintf("kubectl-ai-session.%s", format)
+		if report.ID != "" {
+			path = fmt.Sprintf("kubectl-ai-session-%s.%s", report.ID, format)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("creating report file: %w", err)
+	}
+	if err := sessions.Export(f, report, format); err != nil {
+		f.Close()
+		return "", fmt.Errorf("writing report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("writing report: %w", err)
+	}
+	return fmt.Sprintf("Exported the session to `%s`.", path), nil
+}
+
+// sessionReport builds the report of the current session. Secrets are always redacted, as reports
+// are meant to be shared, even if tool output is sent to the model as is.
+func (c *Agent) sessionReport(maxOutputLines int) (*sessions.Report, error) {
+	redactor := c.Redactor
+	if redactor == nil {
+		var err error
+		if redactor, err = redact.New(nil); err != nil {
+			return nil, fmt.Errorf("creating redactor: %w", err)
+		}
+	}
+
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	metadata := &sessions.Metadata{
+		ProviderID: c.Provider,
+		ModelID:    c.Model,
+		CreatedAt:  c.session.CreatedAt,
+	}
+	if s, ok := c.ChatMessageStore.(*sessions.Session); ok {
+		if saved, err := s.LoadMetadata(); err == nil {
+			metadata.Name = saved.Name
+			metadata.LastAccessed = saved.LastAccessed
+		}
+	}
+	var messages []*api.Message
+	if c.session.ChatMessageStore != nil {
+		messages = c.session.ChatMessageStore.ChatMessages()
+	}
+	return sessions.NewReport(c.session.ID, metadata, messages, sessions.ExportOptions{
+		MaxOutputLines: maxOutputLines,
+		Redactor:       redactor,
+	}), nil
+}
+
+// completeSessionIDs completes the IDs of the saved sessions.
+func completeSessionIDs(ctx context.Context, a *Agent, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		return nil
+	}
+	sessionList, err := manager.ListSessions()
+	if err != nil {
+		return nil
+	}
+	ids := []string{"latest"}
+	for _, session := range sessionList {
+		ids = append(ids, session.ID)
+	}
+	return ids
+}
+
+// completeKubeContexts completes the names of the contexts in the kubeconfig.
+func completeKubeContexts(ctx context.Context, a *Agent, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	view, err := loadKubeConfigView(ctx, a.Kubeconfig)
+	if err != nil {
+		return nil
+	}
+	return view.contextNames()
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/spf13/pflag"
+)
+
+func TestHandleMetaQueryCommands(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		query       string
+		legacy      bool
+		wantHandled bool
+		wantAnswer  string
+	}{
+		{name: "command", query: "/model", wantHandled: true, wantAnswer: "Current model is `test-model`"},
+		{name: "spaces around", query: "  /model ", wantHandled: true, wantAnswer: "Current model is `test-model`"},
+		{name: "alias", query: "/ns", wantHandled: true, wantAnswer: "Current target is context `prod`"},
+		{name: "subcommand", query: "/mcp status", wantHandled: true, wantAnswer: "No MCP servers are connected"},
+		{name: "bare word is a question by default", query: "model"},
+		{name: "question", query: "why is my pod pending?"},
+		{name: "path", query: "/var/log/messages is filling up the disk, why?"},
+		{name: "unknown command", query: "/nope", wantHandled: true, wantAnswer: "Unknown command `/nope`"},
+		{name: "too many arguments", query: "/model a b", wantHandled: true, wantAnswer: "Usage: `/model [name]`"},
+		{name: "missing argument", query: "/session resume", wantHandled: true, wantAnswer: "Usage: `/session resume <session-id>`"},
+		{name: "unknown flag", query: "/model --fast", wantHandled: true, wantAnswer: "Invalid command: unknown flag: --fast"},
+		{name: "unterminated quote", query: `/namespace "kube-system`, wantHandled: true, wantAnswer: "Invalid command: unterminated \" quote"},
+		{name: "help of a command", query: "/help session", wantHandled: true, wantAnswer: "`/session resume <session-id>`: Resume a saved session."},
+		{name: "help flag", query: "/tools --help", wantHandled: true, wantAnswer: "--verbose"},
+		{name: "legacy bare word", query: "model", legacy: true, wantHandled: true, wantAnswer: "Current model is `test-model`"},
+		{name: "legacy alias", query: "ns", legacy: true, wantHandled: true, wantAnswer: "Current target is"},
+		{name: "legacy old spelling", query: "resume-session", legacy: true},
+		{name: "legacy sentence", query: "model which fits best?", legacy: true},
+		{name: "legacy slash command", query: "/model", legacy: true, wantHandled: true, wantAnswer: "Current model is `test-model`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Agent{Model: "test-model", LegacyCommands: tt.legacy}
+			a.session = &api.Session{KubeContext: "prod", Namespace: "web"}
+
+			answer, handled, err := a.handleMetaQuery(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("handleMetaQuery(%q) returned error: %v", tt.query, err)
+			}
+			if handled != tt.wantHandled {
+				t.Fatalf("handleMetaQuery(%q) handled = %v, want %v (answer %q)", tt.query, handled, tt.wantHandled, answer)
+			}
+			if !strings.Contains(answer, tt.wantAnswer) {
+				t.Errorf("handleMetaQuery(%q) = %q, want it to contain %q", tt.query, answer, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	ctx := context.Background()
+	a := &Agent{}
+	a.session = &api.Session{}
+
+	echo := &Command{
+		Name:        "echo",
+		Usage:       "<words...>",
+		Description: "Repeat the words.",
+		MinArgs:     1,
+		MaxArgs:     -1,
+		Flags: func(flags *pflag.FlagSet) {
+			flags.Bool("upper", false, "in upper case")
+		},
+		Run: func(ctx context.Context, inv *Invocation) (string, error) {
+			answer := strings.Join(inv.Args, " ")
+			if upper, _ := inv.Flags.GetBool("upper"); upper {
+				answer = strings.ToUpper(answer)
+			}
+			return answer, nil
+		},
+	}
+	if err := a.RegisterCommand(echo); err != nil {
+		t.Fatalf("RegisterCommand() error = %v", err)
+	}
+	if err := a.RegisterCommand(&Command{Name: "clear", Run: echo.Run}); err == nil {
+		t.Errorf("expected registering a second clear command to fail")
+	}
+	if err := a.RegisterCommand(&Command{Name: "two words", Run: echo.Run}); err == nil {
+		t.Errorf("expected a command name with a space to be rejected")
+	}
+
+	answer, handled, err := a.handleMetaQuery(ctx, `/echo --upper "hello  world" again`)
+	if err != nil || !handled {
+		t.Fatalf("handleMetaQuery() = %q, %v, %v", answer, handled, err)
+	}
+	if answer != "HELLO  WORLD AGAIN" {
+		t.Errorf("answer = %q, want %q", answer, "HELLO  WORLD AGAIN")
+	}
+
+	help, _, _ := a.handleMetaQuery(ctx, "/help")
+	if !strings.Contains(help, "`/echo <words...>`: Repeat the words.") {
+		t.Errorf("expected /help to list the registered command, got %q", help)
+	}
+	if strings.Contains(help, "save-session") {
+		t.Errorf("expected /help not to list hidden commands, got %q", help)
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	ctx := context.Background()
+	stubKubeConfigView(t)
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "/mo", want: []string{"/model", "/models"}},
+		{line: "/sess", want: []string{"/session"}},
+		{line: "/session ", want: []string{"list", "resume", "save", "show"}},
+		{line: "/session re", want: []string{"resume"}},
+		{line: "/context ", want: []string{"prod", "staging"}},
+		{line: "/context st", want: []string{"staging"}},
+		{line: "/context staging ", want: nil},
+		{line: "/tools --v", want: []string{"--verbose"}},
+		{line: "/help mc", want: []string{"mcp"}},
+		{line: "/nope ", want: nil},
+		{line: "model", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			a := &Agent{}
+			a.session = &api.Session{}
+			if got := a.CompleteCommand(ctx, tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompleteCommand(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "/context  staging ", want: []string{"/context", "staging"}},
+		{line: `/echo "a b" 'c "d"' e\ f`, want: []string{"/echo", "a b", `c "d"`, "e f"}},
+		{line: `/echo ""`, want: []string{"/echo", ""}},
+		{line: `/echo "a`, wantErr: true},
+		{line: `/echo a\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := splitCommandLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommandLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	ctx := context.Background()
+	store := sessions.NewInMemoryChatStore()
+	if err := store.SetChatMessages([]*api.Message{
+		{ID: "u1", Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "which token does the CI use?"},
+		{ID: "m1", Source: api.MessageSourceModel, Type: api.MessageTypeText, Payload: "It uses Bearer abcdefghijklmnop."},
+	}); err != nil {
+		t.Fatalf("SetChatMessages() error = %v", err)
+	}
+	a := &Agent{Provider: "gemini", Model: "test-model"}
+	a.session = &api.Session{ID: "s1", ChatMessageStore: store}
+
+	path := filepath.Join(t.TempDir(), "report.md")
+	answer, handled, err := a.handleMetaQuery(ctx, "/export --output "+path)
+	if err != nil || !handled {
+		t.Fatalf("/export = %q, %v, %v", answer, handled, err)
+	}
+	if !strings.Contains(answer, path) {
+		t.Errorf("expected the answer to name the report file, got %q", answer)
+	}
+	report, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the report: %v", err)
+	}
+	if !strings.Contains(string(report), "> which token does the CI use?") || !strings.Contains(string(report), "`test-model`") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+	// Reports are redacted even when the agent does not redact tool output.
+	if strings.Contains(string(report), "abcdefghijklmnop") {
+		t.Errorf("expected the token to be redacted:\n%s", report)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	"github.com/google/uuid"
@@ -70,6 +71,15 @@ type Agent struct {
 
 	LLM gollm.Client
 
+	// NewLLMClient creates a client for another LLM provider, when the user switches providers.
+	// If nil, gollm.NewClient is used.
+	NewLLMClient func(ctx context.Context, providerID string) (gollm.Client, error)
+	// ownedLLM is the client the agent created when the user switched providers, which it closes.
+	ownedLLM gollm.Client
+
+	// systemPrompt is the system prompt of the chat, which a new chat starts with when the user switches models.
+	systemPrompt string
+
 	// PromptTemplateFile allows specifying a custom template file
 	PromptTemplateFile string
 	// ExtraPromptPaths allows specifying additional prompt templates
@@ -85,8 +95,33 @@ type Agent struct {
 	// Kubeconfig is the path to the kubeconfig file.
 	Kubeconfig string
 
+	// KubeContext is the kubeconfig context that kubectl commands run against.
+	// If empty, the kubeconfig's current context is used.
+	KubeContext string
+
+	// Namespace is the namespace that kubectl commands run against.
+	// If empty, the context's default namespace is used.
+	Namespace string
+
+	// kubeTargetChanged is set when the user switches context or namespace,
+	// so the LLM can be told with the next query.
+	kubeTargetChanged bool
+
+	// AllowedDirs are user-approved directories, in addition to the work directory,
+	// that the file tools may read and write.
+	AllowedDirs []string
+
 	SkipPermissions bool
 
+	// AuthorizeToolCall, if set, is called before each tool call runs, with the context the tool
+	// runs with (e.g. its kubeconfig, namespace and working directory). Calls it returns an error
+	// for do not run, and the LLM is told why instead.
+	AuthorizeToolCall func(ctx context.Context, tool tools.Tool, args map[string]any) error
+
+	// Redactor masks secrets in tool output before it is sent to the LLM,
+	// recorded in the trace or saved in the session. If nil, output is not redacted.
+	Redactor *redact.Redactor
+
 	Tools tools.Tools
 
 	EnableToolUseShim bool
@@ -114,11 +149,31 @@ type Agent struct {
 	// mcpManager manages MCP client connections
 	mcpManager *mcp.Manager
 
+	// mcpToolsChanged holds the MCP servers whose tools changed since they were registered,
+	// protected by mcpToolsMu.
+	mcpToolsChanged map[string]bool
+	mcpToolsMu      sync.Mutex
+
 	// ChatMessageStore is the underlying session persistence layer.
 	ChatMessageStore api.ChatMessageStore
 
 	// lastErr is the most recent error run into, for use across the stack
 	lastErr error
+
+	// stats describes what the agent did, protected by statsMu.
+	stats   RunStats
+	statsMu sync.Mutex
+
+	// done is closed when the agent loop returns.
+	done chan struct{}
+
+	// LegacyCommands makes queries that start with the name of a command, such as "clear" or
+	// "context staging", run the command as if they started with a slash.
+	LegacyCommands bool
+
+	// commands holds the commands users can type, created on first use.
+	commands     *CommandRegistry
+	commandsOnce sync.Once
 }
 
 // Assert Session implements ChatMessageStore
@@ -141,15 +196,35 @@ func (s *Agent) Session() *api.Session {
 
 // addMessage creates a new message, adds it to the session, and sends it to the output channel
 func (c *Agent) addMessage(source api.MessageSource, messageType api.MessageType, payload any) *api.Message {
+	return c.appendMessage(&api.Message{
+		ID:      uuid.New().String(),
+		Source:  source,
+		Type:    messageType,
+		Payload: payload,
+	})
+}
+
+// addToolCallMessage is addMessage for a message about a tool call.
+func (c *Agent) addToolCallMessage(source api.MessageSource, messageType api.MessageType, payload any, call gollm.FunctionCall) *api.Message {
+	return c.appendMessage(&api.Message{
+		ID:      uuid.New().String(),
+		Source:  source,
+		Type:    messageType,
+		Payload: payload,
+		ToolCall: &api.ToolCall{
+			ID:        call.ID,
+			Name:      call.Name,
+			Arguments: call.Arguments,
+		},
+	})
+}
+
+// appendMessage timestamps a message, adds it to the session, and sends it to the output channel.
+// The message's ID may have been chosen beforehand, such as for a streamed message whose deltas were already sent.
+func (c *Agent) appendMessage(message *api.Message) *api.Message {
 	c.sessionMu.Lock()
 	defer c.sessionMu.Unlock()
-	message := &api.Message{
-		ID:        uuid.New().String(),
-		Source:    source,
-		Type:      messageType,
-		Payload:   payload,
-		Timestamp: time.Now(),
-	}
+	message.Timestamp = time.Now()
 	if c.session.ChatMessageStore != nil {
 		c.session.ChatMessageStore.AddChatMessage(message)
 	}
@@ -159,6 +234,35 @@ func (c *Agent) addMessage(source api.MessageSource, messageType api.MessageType
 	return message
 }
 
+// sendTextDelta sends a piece of streamed model text to the output channel, for the UI to show it
+// as it arrives. It is not added to the session: the whole text is, once the stream ends.
+func (c *Agent) sendTextDelta(messageID string, text string) {
+	c.Output <- &api.Message{
+		ID:        messageID,
+		Source:    api.MessageSourceModel,
+		Type:      api.MessageTypeTextDelta,
+		Payload:   text,
+		Timestamp: time.Now(),
+	}
+}
+
+// sendProgress sends what the agent is doing to the output channel, for UIs to show it while the agent works:
+// running a tool call, described by tool, or waiting for the LLM if tool is empty.
+func (c *Agent) sendProgress(tool string) {
+	c.Output <- &api.Message{
+		ID:     uuid.New().String(),
+		Source: api.MessageSourceAgent,
+		Type:   api.MessageTypeProgress,
+		Payload: &api.Progress{
+			State:         c.AgentState(),
+			Iteration:     c.currIteration + 1,
+			MaxIterations: c.MaxIterations,
+			Tool:          tool,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
 // setAgentState updates the agent state and ensures LastModified is updated
 func (c *Agent) setAgentState(newState api.AgentState) {
 	c.sessionMu.Lock()
@@ -188,6 +292,7 @@ func (s *Agent) Init(ctx context.Context) error {
 
 	s.Input = make(chan any, 10)
 	s.Output = make(chan any, 10)
+	s.done = make(chan struct{})
 	s.currIteration = 0
 	// when we support session, we will need to initialize this with the
 	// current history of the conversation.
@@ -211,6 +316,8 @@ func (s *Agent) Init(ctx context.Context) error {
 		s.session.LastModified = time.Now()
 	}
 
+	s.initKubeTarget(ctx)
+
 	// Create a temporary working directory
 	workDir, err := os.MkdirTemp("", "agent-workdir-*")
 	if err != nil {
@@ -220,8 +327,11 @@ func (s *Agent) Init(ctx context.Context) error {
 
 	log.Info("Created temporary working directory", "workDir", workDir)
 
+	kubeContext, namespace := s.kubeTarget()
 	systemPrompt, err := s.generatePrompt(ctx, defaultSystemPromptTemplate, PromptData{
 		Tools:             s.Tools,
+		KubeContext:       kubeContext,
+		Namespace:         namespace,
 		EnableToolUseShim: s.EnableToolUseShim,
 		// RunOnce is a good proxy to indicate the agentic session is non-interactive mode.
 		SessionIsInteractive: !s.RunOnce,
@@ -231,19 +341,10 @@ func (s *Agent) Init(ctx context.Context) error {
 	}
 
 	// Start a new chat session
-	s.llmChat = gollm.NewRetryChat(
-		s.LLM.StartChat(systemPrompt, s.Model),
-		gollm.RetryConfig{
-			MaxAttempts:    3,
-			InitialBackoff: 10 * time.Second,
-			MaxBackoff:     60 * time.Second,
-			BackoffFactor:  2,
-			Jitter:         true,
-		},
-	)
-	err = s.llmChat.Initialize(s.session.ChatMessageStore.ChatMessages())
+	s.systemPrompt = systemPrompt
+	s.llmChat, err = s.startChat(s.LLM, s.Model, false)
 	if err != nil {
-		return fmt.Errorf("initializing chat session: %w", err)
+		return err
 	}
 
 	if s.MCPClientEnabled {
@@ -259,16 +360,8 @@ func (s *Agent) Init(ctx context.Context) error {
 	}
 
 	if !s.EnableToolUseShim {
-		var functionDefinitions []*gollm.FunctionDefinition
-		for _, tool := range s.Tools.AllTools() {
-			functionDefinitions = append(functionDefinitions, tool.FunctionDefinition())
-		}
-		// Sort function definitions to help KV cache reuse
-		sort.Slice(functionDefinitions, func(i, j int) bool {
-			return functionDefinitions[i].Name < functionDefinitions[j].Name
-		})
-		if err := s.llmChat.SetFunctionDefinitions(functionDefinitions); err != nil {
-			return fmt.Errorf("setting function definitions: %w", err)
+		if err := s.setFunctionDefinitions(); err != nil {
+			return err
 		}
 	}
 	s.workDir = workDir
@@ -276,6 +369,52 @@ func (s *Agent) Init(ctx context.Context) error {
 	return nil
 }
 
+// startChat starts a chat with the model of the LLM, with the system prompt of the agent and the history
+// of the session. The function definitions of the tools are set if withTools is set; Init sets them
+// once the MCP tools are registered.
+func (s *Agent) startChat(llm gollm.Client, model string, withTools bool) (gollm.Chat, error) {
+	chat := gollm.NewRetryChat(
+		llm.StartChat(s.systemPrompt, model),
+		gollm.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: 10 * time.Second,
+			MaxBackoff:     60 * time.Second,
+			BackoffFactor:  2,
+			Jitter:         true,
+		},
+	)
+	if err := chat.Initialize(s.session.ChatMessageStore.ChatMessages()); err != nil {
+		return nil, fmt.Errorf("initializing chat session: %w", err)
+	}
+	if withTools && !s.EnableToolUseShim {
+		if err := chat.SetFunctionDefinitions(s.functionDefinitions()); err != nil {
+			return nil, fmt.Errorf("setting function definitions: %w", err)
+		}
+	}
+	return chat, nil
+}
+
+// setFunctionDefinitions tells the LLM about the registered tools.
+func (s *Agent) setFunctionDefinitions() error {
+	if err := s.llmChat.SetFunctionDefinitions(s.functionDefinitions()); err != nil {
+		return fmt.Errorf("setting function definitions: %w", err)
+	}
+	return nil
+}
+
+// functionDefinitions returns the definitions of the registered tools, sorted by name.
+func (s *Agent) functionDefinitions() []*gollm.FunctionDefinition {
+	var functionDefinitions []*gollm.FunctionDefinition
+	for _, tool := range s.Tools.AllTools() {
+		functionDefinitions = append(functionDefinitions, tool.FunctionDefinition())
+	}
+	// Sort function definitions to help KV cache reuse
+	sort.Slice(functionDefinitions, func(i, j int) bool {
+		return functionDefinitions[i].Name < functionDefinitions[j].Name
+	})
+	return functionDefinitions
+}
+
 func (c *Agent) Close() error {
 	if c.workDir != "" {
 		if c.RemoveWorkDir {
@@ -288,6 +427,11 @@ func (c *Agent) Close() error {
 	if err := c.CloseMCPClient(); err != nil {
 		klog.Warningf("error closing MCP client: %v", err)
 	}
+	if c.ownedLLM != nil {
+		if err := c.ownedLLM.Close(); err != nil {
+			klog.Warningf("error closing LLM client: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -295,6 +439,12 @@ func (c *Agent) LastErr() error {
 	return c.lastErr
 }
 
+// Done returns a channel that is closed when the agent loop returns, such as when
+// the agent exits in RunOnce mode. All the messages of the agent were sent on Output by then.
+func (c *Agent) Done() <-chan struct{} {
+	return c.done
+}
+
 func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 	log := klog.FromContext(ctx)
 
@@ -305,6 +455,7 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 	// Save unexpected error and return it in for RunOnce mode
 	log.Info("Starting agent loop", "initialQuery", initialQuery, "runOnce", c.RunOnce)
 	go func() {
+		defer close(c.done)
 		if initialQuery != "" {
 			c.addMessage(api.MessageSourceUser, api.MessageTypeText, initialQuery)
 			answer, handled, err := c.handleMetaQuery(ctx, initialQuery)
@@ -324,11 +475,15 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				c.setAgentState(api.AgentStateDone)
 				c.pendingFunctionCalls = []ToolCallAnalysis{}
 				c.addMessage(api.MessageSourceAgent, api.MessageTypeText, answer)
+			} else if content, err := c.newQueryContent(ctx, initialQuery); err != nil {
+				c.setAgentState(api.AgentStateDone)
+				c.pendingFunctionCalls = []ToolCallAnalysis{}
+				c.addMessage(api.MessageSourceAgent, api.MessageTypeError, "Error: "+err.Error())
 			} else {
 				// Start the agentic loop with the initial query
 				c.setAgentState(api.AgentStateRunning)
 				c.currIteration = 0
-				c.currChatContent = []any{initialQuery}
+				c.currChatContent = content
 				c.pendingFunctionCalls = []ToolCallAnalysis{}
 			}
 		} else {
@@ -402,9 +557,17 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						continue
 					}
 
+					content, err := c.newQueryContent(ctx, query.Query)
+					if err != nil {
+						c.setAgentState(api.AgentStateDone)
+						c.pendingFunctionCalls = []ToolCallAnalysis{}
+						c.addMessage(api.MessageSourceAgent, api.MessageTypeError, "Error: "+err.Error())
+						continue
+					}
+
 					c.setAgentState(api.AgentStateRunning)
 					c.currIteration = 0
-					c.currChatContent = []any{query.Query}
+					c.currChatContent = content
 					c.pendingFunctionCalls = []ToolCallAnalysis{}
 					log.Info("Set agent state to running, will process agentic loop", "currIteration", c.currIteration, "currChatContent", len(c.currChatContent))
 				}
@@ -412,6 +575,7 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				// In RunOnce mode, if we need user choice, exit with error
 				if c.RunOnce {
 					log.Error(nil, "RunOnce mode cannot handle user choice requests")
+					c.updateStats(func(stats *RunStats) { stats.PermissionRequired = true })
 					c.setAgentState(api.AgentStateExited)
 					c.addMessage(api.MessageSourceAgent, api.MessageTypeError, "Error: RunOnce mode cannot handle user choice requests")
 					return
@@ -434,6 +598,8 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 					}
 					dispatchToolCalls := c.handleChoice(ctx, choiceResponse)
 					if dispatchToolCalls {
+						// The agent runs the tool calls it waited for.
+						c.setAgentState(api.AgentStateRunning)
 						if err := c.DispatchToolCalls(ctx); err != nil {
 							log.Error(err, "error dispatching tool calls")
 							c.setAgentState(api.AgentStateDone)
@@ -450,8 +616,10 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						}
 						// Clear pending function calls after execution
 						c.pendingFunctionCalls = []ToolCallAnalysis{}
-						c.setAgentState(api.AgentStateRunning)
 						c.currIteration = c.currIteration + 1
+					} else if len(c.pendingFunctionCalls) > 0 {
+						// The user edited the tool calls, and is asked to approve them again.
+						continue
 					} else {
 						// if user has declined, we are done with this iteration
 						c.currIteration = c.currIteration + 1
@@ -472,6 +640,7 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				log.Info("Processing agentic loop", "currIteration", c.currIteration, "maxIterations", c.MaxIterations, "currChatContentLen", len(c.currChatContent))
 
 				if c.currIteration >= c.MaxIterations {
+					c.updateStats(func(stats *RunStats) { stats.MaxIterationsReached = true })
 					c.setAgentState(api.AgentStateDone)
 					c.pendingFunctionCalls = []ToolCallAnalysis{}
 					c.addMessage(api.MessageSourceAgent, api.MessageTypeText, "Maximum number of iterations reached.")
@@ -479,6 +648,11 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				}
 
 				// we run the agentic loop for one iteration
+				if err := c.applyMCPToolChanges(ctx); err != nil {
+					log.Error(err, "error updating MCP tools")
+				}
+
+				c.sendProgress("")
 				stream, err := c.llmChat.SendStreaming(ctx, c.currChatContent...)
 				if err != nil {
 					log.Error(err, "error sending streaming LLM response")
@@ -510,9 +684,12 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				// Process each part of the response
 				var functionCalls []gollm.FunctionCall
 
-				// accumulator for streamed text
+				// accumulator for streamed text, and the ID of the message it becomes
 				var streamedText string
+				streamedMessageID := uuid.New().String()
 				var llmError error
+				// the token usage last reported in the stream
+				var usage any
 
 				for response, err := range stream {
 					if err != nil {
@@ -528,6 +705,9 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						break
 					}
 					// klog.Infof("response: %+v", response)
+					if u := response.UsageMetadata(); !isNil(u) {
+						usage = u
+					}
 
 					if len(response.Candidates()) == 0 {
 						llmError = fmt.Errorf("no candidates in response")
@@ -544,6 +724,9 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						if text, ok := part.AsText(); ok {
 							log.Info("text response", "text", text)
 							streamedText += text
+							if text != "" {
+								c.sendTextDelta(streamedMessageID, text)
+							}
 						}
 
 						// Check if it's a function call
@@ -553,6 +736,7 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						}
 					}
 				}
+				c.recordLLMRequest(usage)
 				if llmError != nil {
 					log.Error(llmError, "error streaming LLM response")
 					c.setAgentState(api.AgentStateDone)
@@ -564,7 +748,12 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 				log.Info("streamedText", "streamedText", streamedText)
 
 				if streamedText != "" {
-					c.addMessage(api.MessageSourceModel, api.MessageTypeText, streamedText)
+					c.appendMessage(&api.Message{
+						ID:      streamedMessageID,
+						Source:  api.MessageSourceModel,
+						Type:    api.MessageTypeText,
+						Payload: streamedText,
+					})
 				}
 				// If no function calls to be made, we're done
 				if len(functionCalls) == 0 {
@@ -645,29 +834,14 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 						errorMessage += "\nUse --skip-permissions flag to bypass permission checks in RunOnce mode."
 
 						log.Error(nil, "RunOnce mode cannot handle permission requests", "commands", commandDescriptions)
+						c.updateStats(func(stats *RunStats) { stats.PermissionRequired = true })
 						c.setAgentState(api.AgentStateExited)
 						c.addMessage(api.MessageSourceAgent, api.MessageTypeError, errorMessage)
 						c.lastErr = fmt.Errorf("%s", errorMessage)
 						return
 					}
 
-					var commandDescriptions []string
-					for _, call := range c.pendingFunctionCalls {
-						commandDescriptions = append(commandDescriptions, call.ParsedToolCall.Description())
-					}
-					confirmationPrompt := "The following commands require your approval to run:\n* " + strings.Join(commandDescriptions, "\n* ")
-					confirmationPrompt += "\n\nDo you want to proceed ?"
-
-					choiceRequest := &api.UserChoiceRequest{
-						Prompt: confirmationPrompt,
-						Options: []api.UserChoiceOption{
-							{Value: "yes", Label: "Yes"},
-							{Value: "yes_and_dont_ask_me_again", Label: "Yes, and don't ask me again"},
-							{Value: "no", Label: "No"},
-						},
-					}
-					c.setAgentState(api.AgentStateWaitingForInput)
-					c.addMessage(api.MessageSourceAgent, api.MessageTypeUserChoiceRequest, choiceRequest)
+					c.askForApproval()
 					// Request input from the user by sending a message on the output channel.
 					// Remaining part of the loop will be now resumed when we receive a choice input
 					// from the user.
@@ -694,99 +868,16 @@ func (c *Agent) Run(ctx context.Context, initialQuery string) error {
 	return nil
 }
 
-func (c *Agent) handleMetaQuery(ctx context.Context, query string) (answer string, handled bool, err error) {
-	switch query {
-	case "clear", "reset":
-		c.sessionMu.Lock()
-		// TODO: Remove this check when session persistence is default
-		if err := c.session.ChatMessageStore.ClearChatMessages(); err != nil {
-			return "Failed to clear the conversation", false, err
-		}
-		c.llmChat.Initialize(c.session.ChatMessageStore.ChatMessages())
-		c.sessionMu.Unlock()
-		return "Cleared the conversation.", true, nil
-	case "exit", "quit":
-		c.setAgentState(api.AgentStateExited)
-		return "It has been a pleasure assisting you. Have a great day!", true, nil
-	case "model":
-		return "Current model is `" + c.Model + "`", true, nil
-	case "models":
-		models, err := c.listModels(ctx)
-		if err != nil {
-			return "", false, fmt.Errorf("listing models: %w", err)
-		}
-		return "Available models:\n\n  - " + strings.Join(models, "\n  - ") + "\n\n", true, nil
-	case "tools":
-		return "Available tools:\n\n  - " + strings.Join(c.Tools.Names(), "\n  - ") + "\n\n", true, nil
-	case "session":
-		if s, ok := c.ChatMessageStore.(*sessions.Session); ok {
-			out, err := s.String()
-			if err != nil {
-				return "", false, fmt.Errorf("failed to get session string: %w", err)
-			}
-			return out, true, nil
-		}
-		return "Session not found (session persistence not enabled)", true, nil
-
-	case "save-session":
-		savedSessionID, err := c.SaveSession()
-		if err != nil {
-			return "", false, fmt.Errorf("failed to save session: %w", err)
-		}
-		return "Saved session as " + savedSessionID, true, nil
-
-	case "sessions":
-		manager, err := sessions.NewSessionManager()
-		if err != nil {
-			return "", false, fmt.Errorf("failed to create session manager: %w", err)
-		}
-
-		sessionList, err := manager.ListSessions()
-		if err != nil {
-			return "", false, fmt.Errorf("failed to list sessions: %w", err)
-		}
-		if len(sessionList) == 0 {
-			return "No sessions found.", true, nil
-		}
-
-		// Add ```text so markdown doesn't wreck the format
-		availableSessions := "```text"
-		availableSessions += "Available sessions:\n\n"
-		availableSessions += "ID\t\t\tCreated\t\t\tLast Accessed\t\tModel\t\tProvider\n"
-		availableSessions += "--\t\t\t-------\t\t\t-------------\t\t-----\t\t--------\n"
-
-		for _, session := range sessionList {
-			metadata, err := session.LoadMetadata()
-			if err != nil {
-				availableSessions += fmt.Sprintf("%s\t\t<error loading metadata>\n", session.ID)
-				continue
-			}
-
-			availableSessions += fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n",
-				session.ID,
-				metadata.CreatedAt.Format("2006-01-02 15:04"),
-				metadata.LastAccessed.Format("2006-01-02 15:04"),
-				metadata.ModelID,
-				metadata.ProviderID)
-		}
-		// close the ```text box
-		availableSessions += "```"
-		return availableSessions, true, nil
-	}
-
-	if strings.HasPrefix(query, "resume-session") {
-		parts := strings.Split(query, " ")
-		if len(parts) != 2 {
-			return "Invalid command. Usage: resume-session <session_id>", true, nil
-		}
-		sessionID := parts[1]
-		if err := c.loadSession(sessionID); err != nil {
-			return "", false, err
-		}
-		return fmt.Sprintf("Resumed session %s.", sessionID), true, nil
+// clearConversation removes the messages of the session, and starts a new chat with the LLM.
+func (c *Agent) clearConversation() error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	// TODO: Remove this check when session persistence is default
+	if err := c.session.ChatMessageStore.ClearChatMessages(); err != nil {
+		return err
 	}
-
-	return "", false, nil
+	c.llmChat.Initialize(c.session.ChatMessageStore.ChatMessages())
+	return nil
 }
 
 func (c *Agent) SaveSession() (string, error) {
@@ -891,23 +982,39 @@ func (c *Agent) listModels(ctx context.Context) ([]string, error) {
 
 func (c *Agent) DispatchToolCalls(ctx context.Context) error {
 	log := klog.FromContext(ctx)
+	// Images returned by tools are sent after all the function results,
+	// as most providers only accept them in user content.
+	var images []any
 	// execute all pending function calls
 	for _, call := range c.pendingFunctionCalls {
 		// Only show "Running" message and proceed with execution for non-interactive commands
 		toolDescription := call.ParsedToolCall.Description()
 
-		c.addMessage(api.MessageSourceModel, api.MessageTypeToolCallRequest, toolDescription)
+		c.addToolCallMessage(api.MessageSourceModel, api.MessageTypeToolCallRequest, toolDescription, call.FunctionCall)
 
+		c.sendProgress(toolDescription)
 		output, err := call.ParsedToolCall.InvokeTool(ctx, tools.InvokeToolOptions{
-			Kubeconfig: c.Kubeconfig,
-			WorkDir:    c.workDir,
+			Kubeconfig:  c.Kubeconfig,
+			KubeContext: c.KubeContext,
+			Namespace:   c.Namespace,
+			WorkDir:     c.workDir,
+			AllowedDirs: c.AllowedDirs,
+			Redactor:    c.Redactor,
+			Authorize:   c.AuthorizeToolCall,
 		})
 
 		if err != nil {
 			log.Error(err, "error executing action", "output", output)
-			c.addMessage(api.MessageSourceAgent, api.MessageTypeToolCallResponse, err.Error())
+			c.recordToolCall(true)
+			c.addToolCallMessage(api.MessageSourceAgent, api.MessageTypeToolCallResponse, err.Error(), call.FunctionCall)
 			return err
 		}
+		c.recordToolCall(execFailed(output))
+
+		if redactions := call.ParsedToolCall.Redactions(); redactions.Total() > 0 {
+			c.addMessage(api.MessageSourceAgent, api.MessageTypeText,
+				fmt.Sprintf("Redacted %s from the tool output before sharing it with the model.", redactions))
+		}
 
 		// Handle timeout message using UI blocks
 		if execResult, ok := output.(*tools.ExecResult); ok && execResult != nil && execResult.StreamType == "timeout" {
@@ -920,6 +1027,9 @@ func (c *Agent) DispatchToolCalls(ctx context.Context) error {
 			observation := fmt.Sprintf("Result of running %q:\n%v",
 				call.FunctionCall.Name,
 				output)
+			if call.EditedByUser {
+				observation = editedByUserNote(call.FunctionCall) + "\n" + observation
+			}
 			c.currChatContent = append(c.currChatContent, observation)
 			payload = observation
 		} else {
@@ -929,18 +1039,37 @@ func (c *Agent) DispatchToolCalls(ctx context.Context) error {
 				log.Error(err, "error converting tool result to map", "output", output)
 				return err
 			}
+			if call.EditedByUser {
+				if result == nil {
+					result = make(map[string]any)
+				}
+				result["modified_by_user"] = editedByUserNote(call.FunctionCall)
+			}
 			payload = result
 			c.currChatContent = append(c.currChatContent, gollm.FunctionCallResult{
 				ID:     call.FunctionCall.ID,
 				Name:   call.FunctionCall.Name,
 				Result: result,
 			})
+			if imageResult, ok := output.(tools.ImageResult); ok && len(imageResult.Images()) > 0 {
+				images = append(images, fmt.Sprintf("Images returned by %q:", call.FunctionCall.Name))
+				for _, image := range imageResult.Images() {
+					images = append(images, image)
+				}
+			}
 		}
-		c.addMessage(api.MessageSourceAgent, api.MessageTypeToolCallResponse, payload)
+		c.addToolCallMessage(api.MessageSourceAgent, api.MessageTypeToolCallResponse, payload, call.FunctionCall)
 	}
+	c.currChatContent = append(c.currChatContent, images...)
 	return nil
 }
 
+// editedByUserNote tells the LLM that the user modified a tool call before it ran, and how it ran.
+func editedByUserNote(call gollm.FunctionCall) string {
+	edited := api.ToolCall{Name: call.Name, Arguments: call.Arguments}
+	return fmt.Sprintf("The user modified this %s call before approving it. It ran as: %s", call.Name, edited.EditText())
+}
+
 // The key idea is to treat all tool calls to be executed atomically or not
 // If all tool calls are readonly call, it is straight forward
 // if some of the tool calls are not readonly, then the interesting question is should the permission
@@ -953,6 +1082,12 @@ type ToolCallAnalysis struct {
 	IsInteractive       bool
 	IsInteractiveError  error
 	ModifiesResourceStr string
+	// RiskExplanation describes why the call was flagged, if the tool can tell.
+	RiskExplanation string
+	// ChangePreview shows what the call will change (e.g. a diff), if the tool supports it.
+	ChangePreview string
+	// EditedByUser is set if the user changed the call before approving it.
+	EditedByUser bool
 }
 
 func (c *Agent) analyzeToolCalls(ctx context.Context, toolCalls []gollm.FunctionCall) ([]ToolCallAnalysis, error) {
@@ -967,26 +1102,94 @@ func (c *Agent) analyzeToolCalls(ctx context.Context, toolCalls []gollm.Function
 		if err != nil {
 			toolCallAnalysis[i].IsInteractiveError = err
 		}
-		toolCallAnalysis[i].ModifiesResourceStr = toolCall.GetTool().CheckModifiesResource(call.Arguments)
+		// The checks see the same context values as the tool when it runs, e.g. its working directory.
+		checkCtx := context.WithValue(ctx, tools.KubeconfigKey, c.Kubeconfig)
+		checkCtx = context.WithValue(checkCtx, tools.WorkDirKey, c.workDir)
+		checkCtx = context.WithValue(checkCtx, tools.AllowedDirsKey, c.AllowedDirs)
+		toolCallAnalysis[i].ModifiesResourceStr = tools.CheckModifiesResource(checkCtx, toolCall.GetTool(), call.Arguments)
+		if toolCallAnalysis[i].ModifiesResourceStr != "no" {
+			toolCallAnalysis[i].RiskExplanation = tools.ExplainRisk(checkCtx, toolCall.GetTool(), call.Arguments)
+		}
+		if previewer, ok := toolCall.GetTool().(tools.ChangePreviewer); ok && toolCallAnalysis[i].ModifiesResourceStr != "no" {
+			preview, err := previewer.PreviewChange(checkCtx, call.Arguments)
+			if err != nil {
+				preview = fmt.Sprintf("(unable to preview change: %v)", err)
+			}
+			toolCallAnalysis[i].ChangePreview = preview
+		}
 		toolCallAnalysis[i].ParsedToolCall = toolCall
 	}
 	return toolCallAnalysis, nil
 }
 
+// Choices of the user when asked to approve tool calls, by number, as sent in api.UserChoiceResponse.
+const (
+	ChoiceYes = iota + 1
+	ChoiceYesAndDontAskAgain
+	ChoiceNo
+	ChoiceEdit
+)
+
+// askForApproval asks the user to approve the pending tool calls.
+func (c *Agent) askForApproval() {
+	var commandDescriptions []string
+	var toolCalls []api.ToolCall
+	for _, call := range c.pendingFunctionCalls {
+		description := call.ParsedToolCall.Description()
+		if call.RiskExplanation != "" {
+			description += "\n  " + strings.ReplaceAll(call.RiskExplanation, "\n", "\n  ")
+		}
+		if call.ChangePreview != "" {
+			description += "\n\n  ```diff\n  " + strings.ReplaceAll(strings.TrimSuffix(call.ChangePreview, "\n"), "\n", "\n  ") + "\n  ```\n"
+		}
+		commandDescriptions = append(commandDescriptions, description)
+		toolCalls = append(toolCalls, api.ToolCall{
+			ID:        call.FunctionCall.ID,
+			Name:      call.FunctionCall.Name,
+			Arguments: call.FunctionCall.Arguments,
+		})
+	}
+	confirmationPrompt := "The following commands require your approval to run against " + c.describeKubeTarget() + "\n* " + strings.Join(commandDescriptions, "\n* ")
+	confirmationPrompt += "\n\nDo you want to proceed ?"
+
+	editLabel := "Edit the command first"
+	if len(toolCalls) > 1 {
+		editLabel = "Edit the commands first"
+	}
+	choiceRequest := &api.UserChoiceRequest{
+		Prompt: confirmationPrompt,
+		Options: []api.UserChoiceOption{
+			{Value: "yes", Label: "Yes"},
+			{Value: "yes_and_dont_ask_me_again", Label: "Yes, and don't ask me again"},
+			{Value: "no", Label: "No"},
+			{Value: api.UserChoiceEdit, Label: editLabel},
+		},
+		ToolCalls: toolCalls,
+	}
+	c.setAgentState(api.AgentStateWaitingForInput)
+	c.addMessage(api.MessageSourceAgent, api.MessageTypeUserChoiceRequest, choiceRequest)
+}
+
 func (c *Agent) handleChoice(ctx context.Context, choice *api.UserChoiceResponse) (dispatchToolCalls bool) {
 	log := klog.FromContext(ctx)
 	// if user input is a choice and use has declined the operation,
 	// we need to abort all pending function calls.
 	// update the currChatContent with the choice and keep the agent loop running.
 
+	c.recordChoice(ctx, choice)
+	// The choice is kept in the session too, for reports of who approved what and when.
+	c.addMessage(api.MessageSourceUser, api.MessageTypeUserChoiceResponse, choice)
+
 	// Normalize the input
 	switch choice.Choice {
-	case 1:
+	case ChoiceYes:
 		dispatchToolCalls = true
-	case 2:
+	case ChoiceYesAndDontAskAgain:
 		c.SkipPermissions = true
 		dispatchToolCalls = true
-	case 3:
+	case ChoiceEdit:
+		dispatchToolCalls = c.editToolCalls(ctx, choice.Edits)
+	case ChoiceNo:
 		c.currChatContent = append(c.currChatContent, gollm.FunctionCallResult{
 			ID:   c.pendingFunctionCalls[0].FunctionCall.ID,
 			Name: c.pendingFunctionCalls[0].FunctionCall.Name,
@@ -1010,6 +1213,95 @@ func (c *Agent) handleChoice(ctx context.Context, choice *api.UserChoiceResponse
 	return dispatchToolCalls
 }
 
+// editToolCalls replaces the pending tool calls with the ones edited by the user, and analyzes them again.
+// It returns true if they can run, or asks the user to approve them if they still need it, and returns false.
+// If the edits cannot be used, it tells the user why and asks again about the calls as they were.
+func (c *Agent) editToolCalls(ctx context.Context, edits []string) bool {
+	edited, err := c.analyzeEdits(ctx, edits)
+	if err != nil {
+		c.addMessage(api.MessageSourceAgent, api.MessageTypeError, "Unable to use the edited commands: "+err.Error())
+		c.askForApproval()
+		return false
+	}
+	c.pendingFunctionCalls = edited
+	for _, call := range edited {
+		if !c.SkipPermissions && call.ModifiesResourceStr != "no" {
+			c.askForApproval()
+			return false
+		}
+	}
+	return true
+}
+
+// analyzeEdits returns the analysis of the pending tool calls as edited by the user.
+func (c *Agent) analyzeEdits(ctx context.Context, edits []string) ([]ToolCallAnalysis, error) {
+	if len(edits) != len(c.pendingFunctionCalls) {
+		return nil, fmt.Errorf("got %d edited commands for %d tool calls", len(edits), len(c.pendingFunctionCalls))
+	}
+	var calls []gollm.FunctionCall
+	var changed []bool
+	for i, pending := range c.pendingFunctionCalls {
+		original := api.ToolCall{
+			ID:        pending.FunctionCall.ID,
+			Name:      pending.FunctionCall.Name,
+			Arguments: pending.FunctionCall.Arguments,
+		}
+		call, err := original.Edit(edits[i])
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, gollm.FunctionCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+		changed = append(changed, call.EditText() != original.EditText())
+	}
+
+	analysis, err := c.analyzeToolCalls(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	for i := range analysis {
+		if analysis[i].IsInteractive {
+			return nil, analysis[i].IsInteractiveError
+		}
+		analysis[i].EditedByUser = changed[i]
+	}
+	return analysis, nil
+}
+
+// recordChoice records in the journal who approved or declined the pending tool calls.
+func (c *Agent) recordChoice(ctx context.Context, choice *api.UserChoiceResponse) {
+	decision := "invalid"
+	switch choice.Choice {
+	case ChoiceYes:
+		decision = "approved"
+	case ChoiceYesAndDontAskAgain:
+		decision = "approved-all"
+	case ChoiceNo:
+		decision = "declined"
+	case ChoiceEdit:
+		decision = "edited"
+	}
+	var toolCalls []map[string]any
+	for _, call := range c.pendingFunctionCalls {
+		toolCalls = append(toolCalls, map[string]any{
+			"name":      call.FunctionCall.Name,
+			"arguments": call.FunctionCall.Arguments,
+		})
+	}
+	payload := map[string]any{
+		"decision":  decision,
+		"toolCalls": toolCalls,
+	}
+	if choice.User != "" {
+		payload["user"] = choice.User
+	}
+	if len(choice.Edits) > 0 {
+		payload["edits"] = choice.Edits
+	}
+	if err := journal.RecorderFromContext(ctx).Write(ctx, &journal.Event{Action: journal.ActionUserChoice, Payload: payload}); err != nil {
+		klog.Warningf("failed to record the user's choice: %v", err)
+	}
+}
+
 // generateFromTemplate generates a prompt for LLM. It uses the prompt from the provides template file or default.
 func (a *Agent) generatePrompt(_ context.Context, defaultPromptTemplate string, data PromptData) (string, error) {
 	promptTemplate := defaultPromptTemplate
@@ -1047,6 +1339,10 @@ type PromptData struct {
 	Query string
 	Tools tools.Tools
 
+	// KubeContext and Namespace are the Kubernetes context and namespace commands run against.
+	KubeContext string
+	Namespace   string
+
 	EnableToolUseShim    bool
 	SessionIsInteractive bool
 }
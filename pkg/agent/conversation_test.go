@@ -16,6 +16,7 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -39,7 +40,7 @@ func TestHandleMetaQuery(t *testing.T) {
 	}{
 		{
 			name:   "clear (shows store before/after with mocked model + tool outputs)",
-			query:  "clear",
+			query:  "/clear",
 			expect: "Cleared the conversation.",
 			expectations: func(t *testing.T) *Agent {
 				ctrl := gomock.NewController(t)
@@ -109,7 +110,7 @@ func TestHandleMetaQuery(t *testing.T) {
 		},
 		{
 			name:   "exit",
-			query:  "exit",
+			query:  "/exit",
 			expect: "It has been a pleasure assisting you. Have a great day!",
 			expectations: func(t *testing.T) *Agent {
 				a := &Agent{}
@@ -124,7 +125,7 @@ func TestHandleMetaQuery(t *testing.T) {
 		},
 		{
 			name:   "model",
-			query:  "model",
+			query:  "/model",
 			expect: "Current model is `test-model`",
 			expectations: func(t *testing.T) *Agent {
 				a := &Agent{Model: "test-model"}
@@ -134,7 +135,7 @@ func TestHandleMetaQuery(t *testing.T) {
 		},
 		{
 			name:   "models",
-			query:  "models",
+			query:  "/models",
 			expect: "Available models:\n\n  - a\n  - b\n\n",
 			expectations: func(t *testing.T) *Agent {
 				ctrl := gomock.NewController(t)
@@ -149,7 +150,7 @@ func TestHandleMetaQuery(t *testing.T) {
 		},
 		{
 			name:   "tools",
-			query:  "tools",
+			query:  "/tools",
 			expect: "Available tools:",
 			expectations: func(t *testing.T) *Agent {
 				ctrl := gomock.NewController(t)
@@ -177,7 +178,7 @@ func TestHandleMetaQuery(t *testing.T) {
 		},
 		{
 			name:   "session",
-			query:  "session",
+			query:  "/session",
 			expect: "Current session:",
 			expectations: func(t *testing.T) *Agent {
 				oldHome := os.Getenv("HOME")
@@ -204,8 +205,8 @@ func TestHandleMetaQuery(t *testing.T) {
 			},
 		},
 		{
-			name:   "sessions",
-			query:  "sessions",
+			name:   "session list",
+			query:  "/session list",
 			expect: "Available sessions:",
 			expectations: func(t *testing.T) *Agent {
 				oldHome := os.Getenv("HOME")
@@ -234,6 +235,76 @@ func TestHandleMetaQuery(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "context lists contexts",
+			query:  "/context",
+			expect: "Current target is context `prod`, namespace `web`.",
+			expectations: func(t *testing.T) *Agent {
+				stubKubeConfigView(t)
+				a := &Agent{}
+				a.session = &api.Session{KubeContext: "prod", Namespace: "web"}
+				return a
+			},
+			verify: func(t *testing.T, _ *Agent, answer string) {
+				if !strings.Contains(answer, "  - prod (current)\n  - staging") {
+					t.Fatalf("expected contexts to be listed, got %q", answer)
+				}
+			},
+		},
+		{
+			name:   "context switch",
+			query:  "/context staging",
+			expect: "Switched to context `staging`, namespace `team-a`.",
+			expectations: func(t *testing.T) *Agent {
+				stubKubeConfigView(t)
+				a := &Agent{KubeContext: "prod", Namespace: "web"}
+				a.session = &api.Session{KubeContext: "prod", Namespace: "web"}
+				return a
+			},
+			verify: func(t *testing.T, a *Agent, _ string) {
+				if a.KubeContext != "staging" || a.Namespace != "" {
+					t.Fatalf("expected context staging with its default namespace, got %q/%q", a.KubeContext, a.Namespace)
+				}
+				content := a.queryContent("list pods")
+				if len(content) != 1 || !strings.Contains(content[0].(string), "context `staging`") {
+					t.Fatalf("expected the next query to mention the switch, got %v", content)
+				}
+				if content := a.queryContent("list pods"); content[0] != "list pods" {
+					t.Fatalf("expected the switch to be mentioned only once, got %v", content)
+				}
+			},
+		},
+		{
+			name:   "unknown context",
+			query:  "/context nope",
+			expect: `Context "nope" not found`,
+			expectations: func(t *testing.T) *Agent {
+				stubKubeConfigView(t)
+				a := &Agent{KubeContext: "prod"}
+				a.session = &api.Session{KubeContext: "prod"}
+				return a
+			},
+			verify: func(t *testing.T, a *Agent, _ string) {
+				if a.KubeContext != "prod" {
+					t.Fatalf("expected context to be unchanged, got %q", a.KubeContext)
+				}
+			},
+		},
+		{
+			name:   "namespace switch",
+			query:  "/namespace kube-system",
+			expect: "Switched to context `prod`, namespace `kube-system`.",
+			expectations: func(t *testing.T) *Agent {
+				a := &Agent{KubeContext: "prod"}
+				a.session = &api.Session{KubeContext: "prod", Namespace: "default"}
+				return a
+			},
+			verify: func(t *testing.T, a *Agent, _ string) {
+				if a.Namespace != "kube-system" || a.Session().Namespace != "kube-system" {
+					t.Fatalf("expected namespace kube-system, got %q", a.Namespace)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -255,3 +326,20 @@ func TestHandleMetaQuery(t *testing.T) {
 		})
 	}
 }
+
+// stubKubeConfigView replaces the kubeconfig reader with one returning two contexts.
+func stubKubeConfigView(t *testing.T) {
+	orig := loadKubeConfigView
+	t.Cleanup(func() { loadKubeConfigView = orig })
+	loadKubeConfigView = func(context.Context, string) (*kubeConfigView, error) {
+		view := &kubeConfigView{}
+		err := json.Unmarshal([]byte(`{
+			"current-context": "prod",
+			"contexts": [
+				{"name": "prod", "context": {"namespace": "web"}},
+				{"name": "staging", "context": {"namespace": "team-a"}}
+			]
+		}`), view)
+		return view, err
+	}
+}
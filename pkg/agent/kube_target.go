@@ -0,0 +1,177 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// kubeConfigView is the subset of `kubectl config view -o json` we use.
+type kubeConfigView struct {
+	CurrentContext string `json:"current-context"`
+	Contexts       []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Namespace string `json:"namespace"`
+		} `json:"context"`
+	} `json:"contexts"`
+}
+
+// loadKubeConfigView reads the kubeconfig. It is a variable so tests can replace it.
+var loadKubeConfigView = func(ctx context.Context, kubeconfig string) (*kubeConfigView, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", "config", "view", "-o", "json")
+	cmd.Env = os.Environ()
+	if kubeconfig != "" {
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running kubectl config view: %w", err)
+	}
+	view := &kubeConfigView{}
+	if err := json.Unmarshal(out, view); err != nil {
+		return nil, fmt.Errorf("parsing kubectl config view output: %w", err)
+	}
+	return view, nil
+}
+
+// contextNames returns the names of the contexts in the kubeconfig.
+func (v *kubeConfigView) contextNames() []string {
+	var names []string
+	for _, c := range v.Contexts {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// defaultNamespace returns the namespace configured for the named context, or "default".
+func (v *kubeConfigView) defaultNamespace(contextName string) string {
+	for _, c := range v.Contexts {
+		if c.Name == contextName && c.Context.Namespace != "" {
+			return c.Context.Namespace
+		}
+	}
+	return "default"
+}
+
+// initKubeTarget resolves the kube context and namespace the agent starts with.
+// Failing to read the kubeconfig is not fatal; kubectl then uses its own defaults.
+func (c *Agent) initKubeTarget(ctx context.Context) {
+	view, err := loadKubeConfigView(ctx, c.Kubeconfig)
+	if err != nil {
+		klog.Warningf("unable to determine current kube context: %v", err)
+		c.setKubeTarget(c.KubeContext, c.Namespace, "")
+		return
+	}
+	kubeContext := c.KubeContext
+	if kubeContext == "" {
+		kubeContext = view.CurrentContext
+	}
+	c.setKubeTarget(kubeContext, c.Namespace, view.defaultNamespace(kubeContext))
+}
+
+// setKubeTarget updates the active kube context and namespace. namespace is the
+// namespace explicitly chosen by the user (if any); defaultNamespace is the context's own.
+func (c *Agent) setKubeTarget(kubeContext, namespace, defaultNamespace string) {
+	c.KubeContext = kubeContext
+	c.Namespace = namespace
+
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.session.KubeContext = kubeContext
+	c.session.Namespace = namespace
+	if c.session.Namespace == "" {
+		c.session.Namespace = defaultNamespace
+	}
+}
+
+// kubeTarget returns the active kube context and the effective namespace, for display.
+func (c *Agent) kubeTarget() (kubeContext, namespace string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	return c.session.KubeContext, c.session.Namespace
+}
+
+// switchKubeContext switches to the named context, or describes the current target and lists
+// the contexts if name is empty.
+func (c *Agent) switchKubeContext(ctx context.Context, name string) (string, error) {
+	view, err := loadKubeConfigView(ctx, c.Kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	if name == "" {
+		current, _ := c.kubeTarget()
+		var lines []string
+		for _, name := range view.contextNames() {
+			if name == current {
+				name += " (current)"
+			}
+			lines = append(lines, name)
+		}
+		return "Current target is " + c.describeKubeTarget() + "\n\nAvailable contexts:\n\n  - " + strings.Join(lines, "\n  - ") + "\n\n", nil
+	}
+	found := false
+	for _, n := range view.contextNames() {
+		found = found || n == name
+	}
+	if !found {
+		return fmt.Sprintf("Context %q not found in kubeconfig. Use `/context` to list the available contexts.", name), nil
+	}
+	// Switching context goes back to the new context's default namespace.
+	c.setKubeTarget(name, "", view.defaultNamespace(name))
+	c.kubeTargetChanged = true
+	return "Switched to " + c.describeKubeTarget(), nil
+}
+
+// switchNamespace switches to the named namespace, or describes the current target if name is empty.
+func (c *Agent) switchNamespace(name string) string {
+	if name == "" {
+		return "Current target is " + c.describeKubeTarget()
+	}
+	kubeContext, _ := c.kubeTarget()
+	c.setKubeTarget(kubeContext, name, "")
+	c.kubeTargetChanged = true
+	return "Switched to " + c.describeKubeTarget()
+}
+
+// describeKubeTarget describes the active kube context and namespace.
+func (c *Agent) describeKubeTarget() string {
+	kubeContext, namespace := c.kubeTarget()
+	if kubeContext == "" {
+		kubeContext = "(kubectl default)"
+	}
+	if namespace == "" {
+		namespace = "(kubectl default)"
+	}
+	return fmt.Sprintf("context `%s`, namespace `%s`.", kubeContext, namespace)
+}
+
+// queryContent returns the chat content for a new user query, telling the LLM
+// about any context or namespace switch since the system prompt was generated.
+func (c *Agent) queryContent(query string) []any {
+	if !c.kubeTargetChanged {
+		return []any{query}
+	}
+	c.kubeTargetChanged = false
+	note := "(The user switched the Kubernetes target; commands now run against " + c.describeKubeTarget() + ")"
+	return []any{note + "\n\n" + query}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"k8s.io/klog/v2"
+)
+
+// LLMTarget returns the LLM provider and model the agent talks to, which the user may switch while it runs.
+func (c *Agent) LLMTarget() (provider, model string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	return c.Provider, c.Model
+}
+
+// switchModel continues the conversation with another model of the LLM provider.
+func (c *Agent) switchModel(ctx context.Context, model string) (string, error) {
+	if model == c.Model {
+		return "Already using model `" + model + "`.", nil
+	}
+	if answer, ok := c.checkModel(ctx, c.LLM, c.Provider, model); !ok {
+		return answer, nil
+	}
+	if err := c.switchLLM(c.LLM, c.Provider, model); err != nil {
+		return "", err
+	}
+	return "Switched to model " + c.describeLLMTarget() + ". The conversation continues with it.", nil
+}
+
+// switchProvider continues the conversation with a model of another LLM provider,
+// or with the default model of the provider if model is empty.
+func (c *Agent) switchProvider(ctx context.Context, provider, model string) (string, error) {
+	newClient := c.NewLLMClient
+	if newClient == nil {
+		newClient = func(ctx context.Context, providerID string) (gollm.Client, error) {
+			return gollm.NewClient(ctx, providerID)
+		}
+	}
+	client, err := newClient(ctx, provider)
+	if err != nil {
+		return "", fmt.Errorf("creating client for provider %q: %w", provider, err)
+	}
+	if answer, ok := c.checkModel(ctx, client, provider, model); !ok {
+		client.Close()
+		return answer, nil
+	}
+	if err := c.switchLLM(client, provider, model); err != nil {
+		client.Close()
+		return "", err
+	}
+
+	// The client of the previous provider is closed by whoever created it, the agent or its caller.
+	if c.ownedLLM != nil {
+		if err := c.ownedLLM.Close(); err != nil {
+			klog.Warningf("error closing LLM client: %v", err)
+		}
+	}
+	c.ownedLLM = client
+	c.availableModels = nil
+	return "Switched to model " + c.describeLLMTarget() + ". The conversation continues with it.", nil
+}
+
+// checkModel checks that the provider offers the model, when it can list its models.
+// It returns false, and the answer to the user, if it does not.
+func (c *Agent) checkModel(ctx context.Context, client gollm.Client, provider, model string) (string, bool) {
+	if model == "" {
+		return "", true
+	}
+	models, err := client.ListModels(ctx)
+	if err != nil || len(models) == 0 {
+		// Some providers cannot list their models, the first query will tell if the model exists.
+		klog.Warningf("unable to list the models of provider %q to check model %q: %v", provider, model, err)
+		return "", true
+	}
+	if !slices.Contains(models, model) {
+		return fmt.Sprintf("Model %q is not offered by provider %q. Use `/models` to list the models of the current provider.", model, provider), false
+	}
+	return "", true
+}
+
+// switchLLM starts a new chat with the model, replaying the conversation, and records the switch in the session.
+func (c *Agent) switchLLM(client gollm.Client, provider, model string) error {
+	chat, err := c.startChat(client, model, true)
+	if err != nil {
+		return fmt.Errorf("starting chat with model %q: %w", model, err)
+	}
+
+	c.sessionMu.Lock()
+	c.llmChat = chat
+	c.LLM = client
+	c.Provider = provider
+	c.Model = model
+	c.sessionMu.Unlock()
+
+	if session, ok := c.ChatMessageStore.(*sessions.Session); ok {
+		metadata, err := session.LoadMetadata()
+		if err != nil {
+			return fmt.Errorf("loading session metadata: %w", err)
+		}
+		metadata.ProviderID = provider
+		metadata.ModelID = model
+		if err := session.SaveMetadata(metadata); err != nil {
+			return fmt.Errorf("updating session metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// describeLLMTarget describes the provider and model the agent talks to.
+func (c *Agent) describeLLMTarget() string {
+	provider, model := c.LLMTarget()
+	if model == "" {
+		model = "(default)"
+	}
+	if provider == "" {
+		return "`" + model + "`"
+	}
+	return fmt.Sprintf("`%s` of provider `%s`", model, provider)
+}
+
+// completeModels completes the names of the models of the current provider.
+func completeModels(ctx context.Context, a *Agent, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	models, err := a.listModels(ctx)
+	if err != nil {
+		return nil
+	}
+	return models
+}
+
+// completeProviders completes the IDs of the registered LLM providers.
+func completeProviders(ctx context.Context, a *Agent, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	return gollm.ListProviders()
+}
+
+// formatProviders lists the registered LLM providers.
+func formatProviders() string {
+	return "Available providers:\n\n  - " + strings.Join(gollm.ListProviders(), "\n  - ") + "\n\n"
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSwitchModel(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+
+	store := sessions.NewInMemoryChatStore()
+	history := []*api.Message{
+		{ID: "u1", Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "why is web-0 pending?"},
+		{ID: "m1", Source: api.MessageSourceModel, Type: api.MessageTypeText, Payload: "Let me check."},
+	}
+	if err := store.SetChatMessages(history); err != nil {
+		t.Fatalf("SetChatMessages() error = %v", err)
+	}
+
+	llm := mocks.NewMockClient(ctrl)
+	llm.EXPECT().ListModels(ctx).Return([]string{"fast", "strong"}, nil).AnyTimes()
+	newChat := mocks.NewMockChat(ctrl)
+	llm.EXPECT().StartChat("system prompt", "strong").Return(newChat)
+	newChat.EXPECT().Initialize(history).Return(nil)
+	newChat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+
+	a := &Agent{LLM: llm, Provider: "gemini", Model: "fast", systemPrompt: "system prompt", llmChat: mocks.NewMockChat(ctrl)}
+	a.session = &api.Session{ChatMessageStore: store}
+
+	answer, handled, err := a.handleMetaQuery(ctx, "/model unknown")
+	if err != nil || !handled || !strings.Contains(answer, `Model "unknown" is not offered`) {
+		t.Fatalf("/model unknown = %q, %v, %v", answer, handled, err)
+	}
+	if a.Model != "fast" {
+		t.Fatalf("expected the model to be unchanged, got %q", a.Model)
+	}
+
+	answer, handled, err = a.handleMetaQuery(ctx, "/model strong")
+	if err != nil || !handled {
+		t.Fatalf("/model strong = %q, %v, %v", answer, handled, err)
+	}
+	if !strings.Contains(answer, "Switched to model `strong` of provider `gemini`") {
+		t.Errorf("unexpected answer %q", answer)
+	}
+	if provider, model := a.LLMTarget(); provider != "gemini" || model != "strong" {
+		t.Errorf("LLMTarget() = %q, %q, want gemini, strong", provider, model)
+	}
+}
+
+func TestSwitchProvider(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+
+	t.Setenv("HOME", t.TempDir())
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		t.Fatalf("creating session manager: %v", err)
+	}
+	session, err := manager.NewSession(sessions.Metadata{ProviderID: "gemini", ModelID: "fast"})
+	if err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	client := mocks.NewMockClient(ctrl)
+	client.EXPECT().ListModels(ctx).Return(nil, nil)
+	newChat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat("system prompt", "gpt-4.1").Return(newChat)
+	newChat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	newChat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+
+	var providerID string
+	a := &Agent{
+		LLM:              mocks.NewMockClient(ctrl),
+		Provider:         "gemini",
+		Model:            "fast",
+		systemPrompt:     "system prompt",
+		ChatMessageStore: session,
+		NewLLMClient: func(ctx context.Context, id string) (gollm.Client, error) {
+			providerID = id
+			return client, nil
+		},
+	}
+	a.session = &api.Session{ChatMessageStore: session}
+
+	answer, handled, err := a.handleMetaQuery(ctx, "/provider openai gpt-4.1")
+	if err != nil || !handled {
+		t.Fatalf("/provider openai gpt-4.1 = %q, %v, %v", answer, handled, err)
+	}
+	if providerID != "openai" {
+		t.Errorf("expected a client for provider openai, got %q", providerID)
+	}
+	if a.LLM != client || a.Provider != "openai" || a.Model != "gpt-4.1" {
+		t.Errorf("expected the agent to use gpt-4.1 of openai, got %q of %q", a.Model, a.Provider)
+	}
+
+	metadata, err := session.LoadMetadata()
+	if err != nil {
+		t.Fatalf("LoadMetadata() error = %v", err)
+	}
+	if metadata.ProviderID != "openai" || metadata.ModelID != "gpt-4.1" {
+		t.Errorf("session metadata = %q/%q, want openai/gpt-4.1", metadata.ProviderID, metadata.ModelID)
+	}
+
+	// The agent closes the client it created.
+	client.EXPECT().Close().Return(nil)
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
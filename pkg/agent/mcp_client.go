@@ -27,6 +27,8 @@ import (
 
 // InitializeMCPClient initializes MCP client functionality for the agent.
 // It connects to servers and registers discovered tools with the kubectl-ai tool system.
+// The tools are kept up to date while the agent runs: servers are monitored and reconnected,
+// and their tools are re-registered when they change.
 func (a *Agent) InitializeMCPClient(ctx context.Context) error {
 	// Initialize the MCP manager
 	manager, err := mcp.InitializeManager()
@@ -36,22 +38,7 @@ func (a *Agent) InitializeMCPClient(ctx context.Context) error {
 
 	// Connect to servers and register tools
 	err = manager.RegisterWithToolSystem(ctx, func(serverName string, toolInfo mcp.Tool) error {
-		// Create schema for the tool
-		schema, err := tools.ConvertToolToGollm(&toolInfo)
-		if err != nil {
-			return err
-		}
-
-		// Create an MCPTool wrapper first to get the unique name
-		mcpTool := tools.NewMCPTool(serverName, toolInfo.Name, toolInfo.Description, schema, manager)
-
-		// Update schema with unique name and better description to avoid conflicts
-		schema.Name = mcpTool.UniqueToolName()
-		schema.Description = fmt.Sprintf("%s (from %s)", toolInfo.Description, serverName)
-
-		// Create and register MCP tool wrapper
-		tools.RegisterTool(mcpTool)
-		return nil
+		return a.registerMCPTool(manager, serverName, toolInfo)
 	})
 
 	if err != nil {
@@ -61,9 +48,164 @@ func (a *Agent) InitializeMCPClient(ctx context.Context) error {
 	// Store the manager for later use
 	a.mcpManager = manager
 
+	manager.OnToolsChanged(a.markMCPToolsChanged)
+	manager.StartHealthMonitor(ctx, mcp.DefaultHealthCheckInterval)
+
+	return nil
+}
+
+// registerMCPTool registers a tool of an MCP server with the kubectl-ai tool system.
+func (a *Agent) registerMCPTool(manager *mcp.Manager, serverName string, toolInfo mcp.Tool) error {
+	// Create schema for the tool
+	schema, err := tools.ConvertToolToGollm(&toolInfo)
+	if err != nil {
+		return err
+	}
+
+	// Create an MCPTool wrapper first to get the unique name
+	mcpTool := tools.NewMCPTool(serverName, toolInfo, schema, manager)
+
+	// Update schema with unique name and better description to avoid conflicts
+	schema.Name = mcpTool.UniqueToolName()
+	schema.Description = fmt.Sprintf("%s (from %s)", toolInfo.Description, serverName)
+
+	// Create and register MCP tool wrapper
+	a.Tools.RegisterTool(mcpTool)
 	return nil
 }
 
+// markMCPToolsChanged records that the tools of an MCP server may have changed.
+// It is called by the MCP manager from its own goroutines, so the tools are only
+// updated by the agent loop, in applyMCPToolChanges, before the next LLM call.
+func (a *Agent) markMCPToolsChanged(server string) {
+	a.mcpToolsMu.Lock()
+	defer a.mcpToolsMu.Unlock()
+	if a.mcpToolsChanged == nil {
+		a.mcpToolsChanged = make(map[string]bool)
+	}
+	a.mcpToolsChanged[server] = true
+}
+
+// applyMCPToolChanges re-registers the tools of the MCP servers whose tools changed,
+// and sends the updated function definitions to the LLM.
+func (a *Agent) applyMCPToolChanges(ctx context.Context) error {
+	a.mcpToolsMu.Lock()
+	changed := a.mcpToolsChanged
+	a.mcpToolsChanged = nil
+	a.mcpToolsMu.Unlock()
+	if len(changed) == 0 || a.mcpManager == nil {
+		return nil
+	}
+
+	for server := range changed {
+		for _, tool := range a.Tools.AllTools() {
+			if mcpTool, ok := tool.(*tools.MCPTool); ok && mcpTool.ServerName() == server {
+				a.Tools.UnregisterTool(mcpTool.UniqueToolName())
+			}
+		}
+
+		if _, ok := a.mcpManager.GetClient(server); !ok {
+			klog.InfoS("Removed the tools of disconnected MCP server", "server", server)
+			continue
+		}
+		serverTools, err := a.mcpManager.ListServerTools(ctx, server)
+		if err != nil {
+			klog.Warningf("Failed to list tools from MCP server %q: %v", server, err)
+			continue
+		}
+		for _, toolInfo := range serverTools {
+			if err := a.registerMCPTool(a.mcpManager, server, toolInfo); err != nil {
+				klog.Warningf("Failed to register tool %s from server %s: %v", toolInfo.Name, server, err)
+			}
+		}
+		klog.InfoS("Updated the tools of MCP server", "server", server, "tools", len(serverTools))
+	}
+
+	if err := a.UpdateMCPStatus(ctx, true); err != nil {
+		klog.Warningf("Failed to update MCP status: %v", err)
+	}
+	// With the tool-use shim, tools are described in the system prompt, which is not regenerated;
+	// changed tools can still be called, but the LLM is not told about them.
+	if !a.EnableToolUseShim {
+		return a.setFunctionDefinitions()
+	}
+	return nil
+}
+
+// runMCPStatus runs /mcp, which shows the state of the MCP servers.
+func runMCPStatus(ctx context.Context, inv *Invocation) (string, error) {
+	if inv.Agent.mcpManager == nil {
+		return noMCPServers, nil
+	}
+	return inv.Agent.formatMCPServerStates(), nil
+}
+
+// runMCPReconnect runs /mcp reconnect <server>.
+func runMCPReconnect(ctx context.Context, inv *Invocation) (string, error) {
+	if inv.Agent.mcpManager == nil {
+		return noMCPServers, nil
+	}
+	server := inv.Args[0]
+	if err := inv.Agent.mcpManager.Reconnect(ctx, server); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Reconnected to MCP server %q.", server), nil
+}
+
+// runMCPDisable runs /mcp disable <server>.
+func runMCPDisable(ctx context.Context, inv *Invocation) (string, error) {
+	if inv.Agent.mcpManager == nil {
+		return noMCPServers, nil
+	}
+	server := inv.Args[0]
+	if err := inv.Agent.mcpManager.Disable(server); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Disabled MCP server %q; use `/mcp reconnect %s` to enable it again.", server, server), nil
+}
+
+// completeMCPServers completes the names of the configured MCP servers.
+func completeMCPServers(ctx context.Context, a *Agent, args []string) []string {
+	if a.mcpManager == nil || len(args) > 0 {
+		return nil
+	}
+	var names []string
+	for _, state := range a.mcpManager.ServerStates() {
+		names = append(names, state.Name)
+	}
+	return names
+}
+
+// formatMCPServerStates describes the state of each configured MCP server, with its registered tools.
+func (a *Agent) formatMCPServerStates() string {
+	toolCounts := make(map[string]int)
+	for _, tool := range a.Tools.AllTools() {
+		if mcpTool, ok := tool.(*tools.MCPTool); ok {
+			toolCounts[mcpTool.ServerName()]++
+		}
+	}
+
+	states := a.mcpManager.ServerStates()
+	if len(states) == 0 {
+		return "No MCP servers are configured."
+	}
+	var sb strings.Builder
+	sb.WriteString("MCP servers:\n\n")
+	for _, state := range states {
+		switch {
+		case state.Disabled:
+			fmt.Fprintf(&sb, "  - %s: disabled\n", state.Name)
+		case state.Connected:
+			fmt.Fprintf(&sb, "  - %s: connected, %d tools\n", state.Name, toolCounts[state.Name])
+		case state.LastError != "":
+			fmt.Fprintf(&sb, "  - %s: disconnected (%s)\n", state.Name, state.LastError)
+		default:
+			fmt.Fprintf(&sb, "  - %s: disconnected\n", state.Name)
+		}
+	}
+	return sb.String()
+}
+
 // UpdateMCPStatus updates the MCP status in the agent's session
 func (a *Agent) UpdateMCPStatus(ctx context.Context, mcpClientEnabled bool) error {
 	if a.mcpManager == nil && !mcpClientEnabled {
@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/mock/gomock"
+)
+
+func TestApplyMCPToolChanges(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	handler := func(context.Context, mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		return mcpgo.NewToolResultText("ok"), nil
+	}
+	srv.AddTool(mcpgo.NewTool("first"), handler)
+	ts := server.NewTestStreamableHTTPServer(srv)
+	defer ts.Close()
+
+	manager := mcp.NewManager(&mcp.Config{Servers: []mcp.ServerConfig{{Name: "test", URL: ts.URL, UseStreaming: true, Timeout: 5}}})
+	defer manager.Close()
+	if err := manager.ConnectAll(ctx); err != nil {
+		t.Fatalf("ConnectAll() error = %v", err)
+	}
+
+	var definitions []string
+	chat := mocks.NewMockChat(ctrl)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).DoAndReturn(func(defs []*gollm.FunctionDefinition) error {
+		definitions = nil
+		for _, def := range defs {
+			definitions = append(definitions, def.Name)
+		}
+		return nil
+	}).Times(2)
+
+	a := &Agent{llmChat: chat, mcpManager: manager, session: &api.Session{}}
+	a.Tools.Init()
+	a.markMCPToolsChanged("test")
+	srv.AddTool(mcpgo.NewTool("second"), handler)
+	if err := a.applyMCPToolChanges(ctx); err != nil {
+		t.Fatalf("applyMCPToolChanges() error = %v", err)
+	}
+	if got := strings.Join(definitions, ","); got != "test_first,test_second" {
+		t.Errorf("function definitions = %q, want test_first,test_second", got)
+	}
+	if _, ok := a.Tools.Lookup("test_second").(*tools.MCPTool); !ok {
+		t.Errorf("expected test_second to be registered")
+	}
+
+	// Nothing changed, so the LLM is not told again.
+	if err := a.applyMCPToolChanges(ctx); err != nil {
+		t.Fatalf("applyMCPToolChanges() error = %v", err)
+	}
+
+	answer, handled, err := a.handleMetaQuery(ctx, "/mcp disable test")
+	if err != nil || !handled || !strings.Contains(answer, "Disabled") {
+		t.Fatalf("mcp disable = %q, %v, %v", answer, handled, err)
+	}
+	a.markMCPToolsChanged("test")
+	if err := a.applyMCPToolChanges(ctx); err != nil {
+		t.Fatalf("applyMCPToolChanges() error = %v", err)
+	}
+	if len(definitions) != 0 || len(a.Tools.AllTools()) != 0 {
+		t.Errorf("expected the tools of the disabled server to be removed, got %v", definitions)
+	}
+	if answer, _, _ := a.handleMetaQuery(ctx, "/mcp"); !strings.Contains(answer, "test: disabled") {
+		t.Errorf("unexpected mcp status %q", answer)
+	}
+}
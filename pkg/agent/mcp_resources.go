@@ -0,0 +1,199 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+)
+
+// mcpPromptMentionPrefix starts an @mention of an MCP prompt: @prompt:<server>/<name> [arg=value ...].
+const mcpPromptMentionPrefix = "prompt:"
+
+// mcpResourceSource provides the resources and prompts of MCP servers. It is implemented by *mcp.Manager.
+type mcpResourceSource interface {
+	ListResources(ctx context.Context) map[string][]mcp.ServerResource
+	ReadResource(ctx context.Context, uri string) (string, []mcp.Resource, error)
+	ListPrompts(ctx context.Context) map[string][]mcp.Prompt
+	GetPrompt(ctx context.Context, server, name string, arguments map[string]string) (*mcp.PromptResult, error)
+}
+
+// mcpMention is an @mention of an MCP resource or prompt in a query.
+type mcpMention struct {
+	// uri is set for resources.
+	uri string
+	// server, prompt and arguments are set for prompts.
+	server    string
+	prompt    string
+	arguments map[string]string
+}
+
+// parseMCPMentions finds the MCP resources (@<uri>) and prompts (@prompt:<server>/<name>) mentioned in a query.
+// The arg=value words following a prompt mention are its arguments.
+// Other words starting with @ (e.g. @alice) are not mentions.
+func parseMCPMentions(query string) []mcpMention {
+	var mentions []mcpMention
+	words := strings.Fields(query)
+	for i := 0; i < len(words); i++ {
+		word, ok := strings.CutPrefix(words[i], "@")
+		if !ok {
+			continue
+		}
+		if ref, ok := strings.CutPrefix(word, mcpPromptMentionPrefix); ok {
+			server, name, ok := strings.Cut(ref, "/")
+			if !ok || server == "" || name == "" {
+				continue
+			}
+			mention := mcpMention{server: server, prompt: name, arguments: map[string]string{}}
+			for i+1 < len(words) {
+				key, value, ok := strings.Cut(words[i+1], "=")
+				if !ok || key == "" || strings.HasPrefix(key, "@") {
+					break
+				}
+				mention.arguments[key] = value
+				i++
+			}
+			mentions = append(mentions, mention)
+			continue
+		}
+		// Trailing punctuation is part of the sentence, not of the URI.
+		uri := strings.TrimRight(word, ".,;:!?)")
+		if strings.Contains(uri, "://") {
+			mentions = append(mentions, mcpMention{uri: uri})
+		}
+	}
+	return mentions
+}
+
+// newQueryContent returns the chat content for a new user query, with the MCP resources
+// and prompts it mentions attached.
+func (c *Agent) newQueryContent(ctx context.Context, query string) ([]any, error) {
+	content := c.queryContent(query)
+	mentions := parseMCPMentions(query)
+	if len(mentions) == 0 {
+		return content, nil
+	}
+	if c.mcpManager == nil {
+		return nil, fmt.Errorf("the query mentions MCP resources or prompts, but no MCP servers are connected (use --mcp-client)")
+	}
+	attachments, err := c.mcpAttachments(ctx, c.mcpManager, mentions)
+	if err != nil {
+		return nil, err
+	}
+	return append(content, attachments...), nil
+}
+
+// mcpAttachments reads the mentioned resources and prompts, to be sent along with the query.
+func (c *Agent) mcpAttachments(ctx context.Context, source mcpResourceSource, mentions []mcpMention) ([]any, error) {
+	var attachments []any
+	for _, m := range mentions {
+		if m.uri == "" {
+			prompt, err := source.GetPrompt(ctx, m.server, m.prompt, m.arguments)
+			if err != nil {
+				return nil, fmt.Errorf("getting MCP prompt %s/%s: %w", m.server, m.prompt, err)
+			}
+			attachments = append(attachments, fmt.Sprintf("Prompt %q from MCP server %q:\n\n%s", m.prompt, m.server, prompt.Text()))
+			continue
+		}
+
+		server, contents, err := source.ReadResource(ctx, m.uri)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range contents {
+			uri := resource.URI
+			if uri == "" {
+				uri = m.uri
+			}
+			switch {
+			case resource.Blob == nil:
+				text := resource.Text
+				if c.Redactor != nil {
+					text, _ = c.Redactor.RedactText(text)
+				}
+				attachments = append(attachments, fmt.Sprintf("Contents of resource %s from MCP server %q:\n\n%s", uri, server, text))
+			case strings.HasPrefix(resource.MIMEType, "image/"):
+				attachments = append(attachments, fmt.Sprintf("Image resource %s from MCP server %q:", uri, server),
+					gollm.ImageContent{MIMEType: resource.MIMEType, Data: resource.Blob})
+			default:
+				attachments = append(attachments, fmt.Sprintf("Resource %s from MCP server %q is %d bytes of %s, which cannot be attached.",
+					uri, server, len(resource.Blob), resource.MIMEType))
+			}
+		}
+	}
+	return attachments, nil
+}
+
+// noMCPServers answers the MCP commands when the MCP client is not enabled.
+const noMCPServers = "No MCP servers are connected (use --mcp-client)."
+
+func formatMCPResources(resources map[string][]mcp.ServerResource) string {
+	if len(resources) == 0 {
+		return "The connected MCP servers do not publish any resources."
+	}
+	var sb strings.Builder
+	sb.WriteString("Available MCP resources (mention them in a query as @<uri>):\n")
+	for _, server := range sortedKeys(resources) {
+		fmt.Fprintf(&sb, "\n%s:\n", server)
+		for _, r := range resources[server] {
+			fmt.Fprintf(&sb, "  - %s", r.URI)
+			if r.Description != "" {
+				fmt.Fprintf(&sb, ": %s", r.Description)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func formatMCPPrompts(prompts map[string][]mcp.Prompt) string {
+	if len(prompts) == 0 {
+		return "The connected MCP servers do not publish any prompts."
+	}
+	var sb strings.Builder
+	sb.WriteString("Available MCP prompts (use them in a query as @prompt:<server>/<name> arg=value ...):\n")
+	for _, server := range sortedKeys(prompts) {
+		fmt.Fprintf(&sb, "\n%s:\n", server)
+		for _, p := range prompts[server] {
+			fmt.Fprintf(&sb, "  - %s", p.Name)
+			for _, arg := range p.Arguments {
+				if arg.Required {
+					fmt.Fprintf(&sb, " %s=...", arg.Name)
+				} else {
+					fmt.Fprintf(&sb, " [%s=...]", arg.Name)
+				}
+			}
+			if p.Description != "" {
+				fmt.Fprintf(&sb, ": %s", p.Description)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+)
+
+func TestParseMCPMentions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []mcpMention
+	}{
+		{
+			name:  "no mentions",
+			query: "why is web-0 failing? ask @alice",
+		},
+		{
+			name:  "resource",
+			query: "what is wrong with @k8s://_/web/pods/web-0?",
+			want:  []mcpMention{{uri: "k8s://_/web/pods/web-0"}},
+		},
+		{
+			name:  "prompt with arguments",
+			query: "@prompt:kubectl-ai/diagnose-crashloop pod=web-0 namespace=web then summarize",
+			want: []mcpMention{{server: "kubectl-ai", prompt: "diagnose-crashloop",
+				arguments: map[string]string{"pod": "web-0", "namespace": "web"}}},
+		},
+		{
+			name:  "prompt and resource",
+			query: "@prompt:docs/runbook @file:///runbooks/web.md",
+			want: []mcpMention{
+				{server: "docs", prompt: "runbook", arguments: map[string]string{}},
+				{uri: "file:///runbooks/web.md"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMCPMentions(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMCPMentions(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeResourceSource struct{}
+
+func (fakeResourceSource) ListResources(context.Context) map[string][]mcp.ServerResource {
+	return nil
+}
+
+func (fakeResourceSource) ReadResource(_ context.Context, uri string) (string, []mcp.Resource, error) {
+	switch uri {
+	case "k8s://_/web/pods/web-0":
+		return "kubectl-ai", []mcp.Resource{{URI: uri, Text: "kind: Pod"}}, nil
+	case "img://graph":
+		return "grafana", []mcp.Resource{{URI: uri, MIMEType: "image/png", Blob: []byte("png")}}, nil
+	}
+	return "", nil, fmt.Errorf("no MCP server provides resource %q", uri)
+}
+
+func (fakeResourceSource) ListPrompts(context.Context) map[string][]mcp.Prompt {
+	return nil
+}
+
+func (fakeResourceSource) GetPrompt(_ context.Context, server, name string, arguments map[string]string) (*mcp.PromptResult, error) {
+	return &mcp.PromptResult{Messages: []mcp.PromptMessage{{Role: "user", Text: "Diagnose " + arguments["pod"]}}}, nil
+}
+
+func TestMCPAttachments(t *testing.T) {
+	a := &Agent{}
+	attachments, err := a.mcpAttachments(context.Background(), fakeResourceSource{},
+		parseMCPMentions("@prompt:kubectl-ai/diagnose-crashloop pod=web-0 compare with @k8s://_/web/pods/web-0 and @img://graph"))
+	if err != nil {
+		t.Fatalf("mcpAttachments() error = %v", err)
+	}
+	if len(attachments) != 4 {
+		t.Fatalf("expected 4 attachments, got %+v", attachments)
+	}
+	if text := attachments[0].(string); !strings.Contains(text, "Diagnose web-0") {
+		t.Errorf("unexpected prompt attachment %q", text)
+	}
+	if text := attachments[1].(string); !strings.Contains(text, "kind: Pod") || !strings.Contains(text, `"kubectl-ai"`) {
+		t.Errorf("unexpected resource attachment %q", text)
+	}
+	if image, ok := attachments[3].(gollm.ImageContent); !ok || image.MIMEType != "image/png" {
+		t.Errorf("expected an image attachment, got %+v", attachments[3])
+	}
+
+	if _, err := a.mcpAttachments(context.Background(), fakeResourceSource{}, parseMCPMentions("@k8s://_/web/pods/missing")); err == nil {
+		t.Errorf("expected an error for an unknown resource")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// RunStats describes what the agent did since it started, for UIs that report it when the agent exits.
+type RunStats struct {
+	// Iterations is the number of requests sent to the LLM.
+	Iterations int
+	// ToolCalls is the number of tool calls run, and ToolErrors the number of them that failed.
+	ToolCalls  int
+	ToolErrors int
+	// Usage holds the token usage reported by the LLM for each request, in the format of the provider.
+	Usage []any
+
+	// PermissionRequired is set if the agent stopped in RunOnce mode because tool calls needed the user's approval.
+	PermissionRequired bool
+	// MaxIterationsReached is set if the agent stopped a query because it reached MaxIterations.
+	MaxIterationsReached bool
+}
+
+// Stats returns what the agent did since it started.
+func (c *Agent) Stats() RunStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.stats
+	stats.Usage = append([]any(nil), c.stats.Usage...)
+	return stats
+}
+
+// updateStats updates the stats under their lock.
+func (c *Agent) updateStats(update func(stats *RunStats)) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	update(&c.stats)
+}
+
+// recordLLMRequest counts a request sent to the LLM, and the usage it reported, if any.
+func (c *Agent) recordLLMRequest(usage any) {
+	c.updateStats(func(stats *RunStats) {
+		stats.Iterations++
+		if usage != nil {
+			stats.Usage = append(stats.Usage, usage)
+		}
+	})
+}
+
+// recordToolCall counts a tool call that was run.
+func (c *Agent) recordToolCall(failed bool) {
+	c.updateStats(func(stats *RunStats) {
+		stats.ToolCalls++
+		if failed {
+			stats.ToolErrors++
+		}
+	})
+}
+
+// execFailed returns true if the output of a tool is of a command that failed.
+func execFailed(output any) bool {
+	result, ok := output.(*tools.ExecResult)
+	return ok && result != nil && (result.Error != "" || result.ExitCode != 0)
+}
+
+// isNil returns true if v is nil, or a nil pointer, as some providers report missing usage.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
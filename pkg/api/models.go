@@ -15,6 +15,9 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -24,6 +27,9 @@ type Session struct {
 	AgentState   AgentState
 	CreatedAt    time.Time
 	LastModified time.Time
+	// KubeContext and Namespace are the Kubernetes context and namespace the agent targets.
+	KubeContext string
+	Namespace   string
 	// MCP status information
 	MCPStatus *MCPStatus
 	// ChatMessageStore is an interface that allows the session to store and retrieve chat messages.
@@ -52,6 +58,14 @@ const (
 	MessageTypeUserInputResponse  MessageType = "user-input-response"
 	MessageTypeUserChoiceRequest  MessageType = "user-choice-request"
 	MessageTypeUserChoiceResponse MessageType = "user-choice-response"
+	// MessageTypeTextDelta is a piece of text the model is still streaming. Its ID is the ID of the
+	// text message the agent adds once the model is done, and its payload is the new text.
+	// Deltas are only sent on the agent's output channel, they are not part of the session.
+	MessageTypeTextDelta MessageType = "text-delta"
+	// MessageTypeProgress tells what the agent is doing while it works on a query. Its payload is a
+	// *Progress, and its timestamp is when the agent started doing it. Like deltas, progress messages
+	// are only sent on the agent's output channel.
+	MessageTypeProgress MessageType = "progress"
 )
 
 type Message struct {
@@ -60,6 +74,52 @@ type Message struct {
 	Type      MessageType
 	Payload   any
 	Timestamp time.Time
+	// ToolCall is the tool call a tool-call-request or tool-call-response message is about.
+	ToolCall *ToolCall `json:",omitempty"`
+}
+
+// ToolCall is a call of a tool requested by the model.
+type ToolCall struct {
+	// ID is the ID the model gave the call, if any.
+	ID        string `json:",omitempty"`
+	Name      string
+	Arguments map[string]any `json:",omitempty"`
+}
+
+// EditText returns the tool call as text for the user to edit: the command of tools that run commands,
+// or the arguments as JSON.
+func (t ToolCall) EditText() string {
+	if command, ok := t.Arguments["command"].(string); ok {
+		return command
+	}
+	b, err := json.MarshalIndent(t.Arguments, "", "  ")
+	if err != nil {
+		return fmt.Sprint(t.Arguments)
+	}
+	return string(b)
+}
+
+// Edit returns the tool call with the arguments of text edited by the user, in the form of EditText.
+func (t ToolCall) Edit(text string) (ToolCall, error) {
+	edited := t
+	if _, ok := t.Arguments["command"].(string); ok {
+		edited.Arguments = make(map[string]any, len(t.Arguments))
+		for name, value := range t.Arguments {
+			edited.Arguments[name] = value
+		}
+		edited.Arguments["command"] = strings.TrimSpace(text)
+		return edited, nil
+	}
+	var arguments map[string]any
+	if err := json.Unmarshal([]byte(text), &arguments); err != nil {
+		return t, fmt.Errorf("the arguments of %s are not a JSON object: %w", t.Name, err)
+	}
+	// null decodes to a nil map.
+	if arguments == nil {
+		return t, fmt.Errorf("the arguments of %s are not a JSON object", t.Name)
+	}
+	edited.Arguments = arguments
+	return edited, nil
 }
 
 type MessageSource string
@@ -73,8 +133,13 @@ const (
 type UserChoiceRequest struct {
 	Prompt  string
 	Options []UserChoiceOption
+	// ToolCalls are the tool calls awaiting approval, for UIs to let the user edit them.
+	ToolCalls []ToolCall `json:",omitempty"`
 }
 
+// UserChoiceEdit is the value of the option to edit the tool calls awaiting approval, see UserChoiceResponse.Edits.
+const UserChoiceEdit = "edit"
+
 type UserChoiceOption struct {
 	Label string `json:"label,omitempty"`
 	Value string `json:"value,omitempty"`
@@ -82,6 +147,21 @@ type UserChoiceOption struct {
 
 type UserChoiceResponse struct {
 	Choice int `json:"choice"`
+	// User is the authenticated user who made the choice, if the UI knows who they are.
+	User string `json:"user,omitempty"`
+	// Edits are the tool calls awaiting approval as edited by the user, if the choice is to edit them:
+	// one per call, in order, in the form of ToolCall.EditText.
+	Edits []string `json:"edits,omitempty"`
+}
+
+// Progress is the payload of progress messages.
+type Progress struct {
+	State AgentState `json:"state"`
+	// Iteration is the iteration of the agentic loop the agent is in, out of MaxIterations.
+	Iteration     int `json:"iteration"`
+	MaxIterations int `json:"maxIterations"`
+	// Tool describes the tool call the agent is running, if any. Otherwise it waits for the LLM.
+	Tool string `json:"tool,omitempty"`
 }
 
 type UserInputResponse struct {
@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolCallEdit(t *testing.T) {
+	command := ToolCall{Name: "kubectl", Arguments: map[string]any{"command": "kubectl scale --replicas=30", "modifies_resource": "yes"}}
+	other := ToolCall{Name: "get_pods", Arguments: map[string]any{"namespace": "default"}}
+
+	tests := []struct {
+		name    string
+		call    ToolCall
+		text    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "command",
+			call: command,
+			text: " kubectl scale --replicas=3\n",
+			want: map[string]any{"command": "kubectl scale --replicas=3", "modifies_resource": "yes"},
+		},
+		{
+			name: "json object",
+			call: other,
+			text: `{"namespace": "web", "all": true}`,
+			want: map[string]any{"namespace": "web", "all": true},
+		},
+		{
+			name: "empty json object",
+			call: other,
+			text: `{}`,
+			want: map[string]any{},
+		},
+		{name: "null", call: other, text: "null", wantErr: true},
+		{name: "array", call: other, text: `["web"]`, wantErr: true},
+		{name: "string", call: other, text: `"web"`, wantErr: true},
+		{name: "invalid json", call: other, text: `{"namespace":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := tt.call.Edit(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Edit(%q) = %v, want an error", tt.text, edited.Arguments)
+				}
+				if !reflect.DeepEqual(edited, tt.call) {
+					t.Errorf("Edit(%q) returned %+v on error, want the call unchanged", tt.text, edited)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Edit(%q) error = %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(edited.Arguments, tt.want) {
+				t.Errorf("Edit(%q) arguments = %v, want %v", tt.text, edited.Arguments, tt.want)
+			}
+			if edited.Name != tt.call.Name {
+				t.Errorf("Edit(%q) name = %q, want %q", tt.text, edited.Name, tt.call.Name)
+			}
+		})
+	}
+
+	// The arguments of the original call are not changed.
+	if command.Arguments["command"] != "kubectl scale --replicas=30" {
+		t.Errorf("Edit changed the arguments of the original call: %v", command.Arguments)
+	}
+}
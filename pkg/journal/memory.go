@@ -0,0 +1,51 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryRecorder keeps the events written to it in memory, e.g. to check them in tests.
+type MemoryRecorder struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (r *MemoryRecorder) Write(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *MemoryRecorder) Close() error {
+	return nil
+}
+
+// Events returns the events written so far. If actions are given, only the events with one of them are returned.
+func (r *MemoryRecorder) Events(actions ...string) []*Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*Event
+	for _, event := range r.events {
+		if len(actions) == 0 || slices.Contains(actions, event.Action) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemoryRecorderEvents(t *testing.T) {
+	ctx := context.Background()
+	r := &MemoryRecorder{}
+	if events := r.Events(); len(events) != 0 {
+		t.Fatalf("Events() of an empty recorder = %v", events)
+	}
+
+	written := []*Event{
+		{Action: "tool-request", Payload: "1"},
+		{Action: "tool-response", Payload: "2"},
+		{Action: "tool-request", Payload: "3"},
+		{Action: ActionMCPToolCall, Payload: "4"},
+	}
+	for _, event := range written {
+		if err := r.Write(ctx, event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		actions []string
+		want    []*Event
+	}{
+		{actions: nil, want: written},
+		{actions: []string{"tool-request"}, want: []*Event{written[0], written[2]}},
+		{actions: []string{"tool-response", ActionMCPToolCall}, want: []*Event{written[1], written[3]}},
+		{actions: []string{"unknown"}, want: nil},
+	}
+	for _, tt := range tests {
+		got := r.Events(tt.actions...)
+		if len(got) != len(tt.want) {
+			t.Errorf("Events(%v) returned %d events, want %d", tt.actions, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Events(%v)[%d] = %+v, want %+v", tt.actions, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestMemoryRecorderConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	r := &MemoryRecorder{}
+
+	const writers, perWriter = 8, 100
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				r.Write(ctx, &Event{Action: fmt.Sprintf("writer-%d", w), Payload: i})
+				// Reading while others write must be safe too.
+				r.Events()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(r.Events()); got != writers*perWriter {
+		t.Fatalf("recorded %d events, want %d", got, writers*perWriter)
+	}
+	for w := 0; w < writers; w++ {
+		events := r.Events(fmt.Sprintf("writer-%d", w))
+		if len(events) != perWriter {
+			t.Errorf("recorded %d events of writer %d, want %d", len(events), w, perWriter)
+			continue
+		}
+		// The events of each writer keep the order they were written in.
+		for i, event := range events {
+			if event.Payload != i {
+				t.Errorf("event %d of writer %d has payload %v", i, w, event.Payload)
+				break
+			}
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/yaml"
@@ -35,7 +36,8 @@ type Recorder interface {
 
 // FileRecorder writes a structured log of the agent's actions and observations to a file.
 type FileRecorder struct {
-	f *os.File
+	mu sync.Mutex
+	f  *os.File
 }
 
 // NewFileRecorder creates a new FileRecorder that writes to the given file.
@@ -49,6 +51,18 @@ func NewFileRecorder(path string) (*FileRecorder, error) {
 	}, nil
 }
 
+// NewAppendingFileRecorder creates a FileRecorder that appends to the given file,
+// keeping earlier events, e.g. for an audit log.
+func NewAppendingFileRecorder(path string) (*FileRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	return &FileRecorder{
+		f: file,
+	}, nil
+}
+
 // Close closes the file.
 func (r *FileRecorder) Close() error {
 	return r.f.Close()
@@ -66,6 +80,10 @@ func (r *FileRecorder) Write(ctx context.Context, event *Event) error {
 	var b bytes.Buffer
 	b.Write(yamlBytes)
 	b.Write([]byte("\n\n---\n\n"))
+
+	// Writes may come from concurrent requests, e.g. in MCP server mode.
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	_, err = r.f.Write(b.Bytes())
 	return err
 }
@@ -82,9 +100,15 @@ const (
 	ActionHTTPError    = "http.error"
 )
 
+// ActionMCPToolCall is for an event that records a tool call made by an MCP client
+const ActionMCPToolCall = "mcp.tool-call"
+
 // ActionUIRender is for an event that indicates we wrote output to the UI
 const ActionUIRender = "ui.render"
 
+// ActionUserChoice is for an event that records a user approving or declining tool calls
+const ActionUserChoice = "user.choice"
+
 // GetString is a helper to get a string value from the Payload
 func (e *Event) GetString(key string) (string, bool) {
 	if e.Payload == nil {
@@ -17,7 +17,6 @@ package mcp
 import (
 	"context"
 	"fmt"
-	"reflect"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	mcpclient "github.com/mark3labs/mcp-go/client"
@@ -47,6 +46,17 @@ type Tool struct {
 	Server      string `json:"server,omitempty"`
 
 	InputSchema *gollm.Schema `json:"inputSchema,omitempty"`
+
+	// ReadOnlyHint and DestructiveHint are the server's annotations of the tool, if any.
+	ReadOnlyHint    *bool `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool `json:"destructiveHint,omitempty"`
+
+	// ModifiesResource is whether calls to the tool modify resources: "yes", "no" or "unknown".
+	// It is set by the Manager, from the server configuration and the tool's annotations,
+	// along with the reason for it and the trust level of the server.
+	ModifiesResource       string `json:"modifiesResource,omitempty"`
+	ModifiesResourceReason string `json:"-"`
+	Trust                  string `json:"trust,omitempty"`
 }
 
 // NewClient creates a new MCP client with the given configuration.
@@ -146,16 +156,82 @@ func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
 // CallTool calls a tool on the MCP server and returns the result as a string.
 // The arguments should be a map of parameter names to values that will be passed to the tool.
 func (c *Client) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (string, error) {
+	result, err := c.CallToolResult(ctx, toolName, arguments)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// CallToolResult calls a tool on the MCP server and returns its result, with all of its content.
+func (c *Client) CallToolResult(ctx context.Context, toolName string, arguments map[string]interface{}) (*ToolResult, error) {
 	klog.V(2).InfoS("Calling MCP tool", "server", c.Name, "tool", toolName, "args", arguments)
 
 	if err := c.ensureConnected(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Delegate to implementation
 	return c.impl.CallTool(ctx, toolName, arguments)
 }
 
+// Ping checks that the MCP server is responding.
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+	if err := c.client.Ping(pingCtx); err != nil {
+		return fmt.Errorf("pinging MCP server: %w", err)
+	}
+	return nil
+}
+
+// OnToolsChanged registers a function that is called when the server reports that its tools changed.
+func (c *Client) OnToolsChanged(fn func()) {
+	if c.client == nil {
+		return
+	}
+	c.client.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationToolsListChanged {
+			fn()
+		}
+	})
+}
+
+// ListResources lists the resources and resource templates published by the MCP server.
+func (c *Client) ListResources(ctx context.Context) ([]ServerResource, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return c.impl.ListResources(ctx)
+}
+
+// ReadResource reads a resource from the MCP server.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]Resource, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return c.impl.ReadResource(ctx, uri)
+}
+
+// ListPrompts lists the prompts published by the MCP server.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return c.impl.ListPrompts(ctx)
+}
+
+// GetPrompt renders a prompt of the MCP server with the given arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*PromptResult, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return c.impl.GetPrompt(ctx, name, arguments)
+}
+
 // ===================================================================
 // Tool Factory Functions and Methods
 // ===================================================================
@@ -200,10 +276,11 @@ func convertMCPToolsToTools(mcpTools []mcp.Tool) ([]Tool, error) {
 	tools := make([]Tool, 0, len(mcpTools))
 	for _, mcpTool := range mcpTools {
 		tool := Tool{
-			Name:        mcpTool.Name,
-			Description: mcpTool.Description,
+			Name:            mcpTool.Name,
+			Description:     mcpTool.Description,
+			ReadOnlyHint:    mcpTool.Annotations.ReadOnlyHint,
+			DestructiveHint: mcpTool.Annotations.DestructiveHint,
 		}
-		// TODO: Annotations (give hints about e.g. read-only, destructive, idempotent, open-world)
 
 		if mcpTool.InputSchema.Type != "" {
 			schema, err := convertMCPInputSchema(&mcpTool.InputSchema)
@@ -344,6 +421,12 @@ func ensureClientConnected(client *mcpclient.Client) error {
 
 // initializeClientConnection initializes the MCP connection with proper handshake.
 func initializeClientConnection(ctx context.Context, client *mcpclient.Client) error {
+	// Start the client, so that it handles notifications from the server. The connection
+	// lives until the client is closed, not just for the duration of ctx.
+	if err := client.Start(context.Background()); err != nil {
+		return fmt.Errorf("starting MCP client: %w", err)
+	}
+
 	initCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
 	defer cancel()
 
@@ -383,61 +466,6 @@ func cleanupClient(client **mcpclient.Client) {
 	}
 }
 
-// processToolResponse processes a tool call response and extracts the text result.
-// This function works with any MCP response object that has the expected fields.
-func processToolResponse(result any) (string, error) {
-	// Use reflection to safely access fields
-	rv := reflect.ValueOf(result)
-
-	// Handle pointer to struct
-	if rv.Kind() == reflect.Ptr {
-		rv = rv.Elem()
-	}
-
-	if rv.Kind() != reflect.Struct {
-		return "", fmt.Errorf("unexpected response type: %T", result)
-	}
-
-	// Check for IsError field
-	isErrorField := rv.FieldByName("IsError")
-	if isErrorField.IsValid() && isErrorField.Kind() == reflect.Bool {
-		isError := isErrorField.Bool()
-
-		// Handle error response
-		if isError {
-			// Extract error message
-			errorMsg := fmt.Sprintf("%+v", result)
-
-			// Try to get message from Content field
-			contentField := rv.FieldByName("Content")
-			if contentField.IsValid() && contentField.Len() > 0 {
-				if content := contentField.Index(0).Interface(); content != nil {
-					if textContent, ok := mcp.AsTextContent(content); ok {
-						errorMsg = textContent.Text
-					}
-				}
-			}
-
-			// Return JSON error data instead of Go error
-			return fmt.Sprintf(`{"error": true, "message": %q, "status": "failed"}`, errorMsg), nil
-		}
-	}
-
-	// Check for Content field
-	contentField := rv.FieldByName("Content")
-	if contentField.IsValid() && contentField.Len() > 0 {
-		// Let's rely on the AsTextContent method from MCP package
-		// which handles the specific response format
-		content := contentField.Index(0).Interface()
-		if textContent, ok := mcp.AsTextContent(content); ok {
-			return textContent.Text, nil
-		}
-	}
-
-	// If we couldn't extract text content, return a generic message
-	return "Tool executed successfully, but no text content was returned", nil
-}
-
 // listClientTools implements the common ListTools functionality shared by both client types.
 func listClientTools(ctx context.Context, client *mcpclient.Client, serverName string) ([]Tool, error) {
 	if err := ensureClientConnected(client); err != nil {
@@ -17,8 +17,10 @@ package mcp
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -28,31 +30,146 @@ import (
 // Config represents the complete MCP client configuration file
 type Config struct {
 	// Servers is a list of MCP server configurations
-	Servers []ServerConfig `yaml:"servers,omitempty"`
+	Servers []ServerConfig `json:"servers,omitempty" yaml:"servers,omitempty"`
 }
 
 // ServerConfig represents the configuration for a single MCP server
 type ServerConfig struct {
 	// Name is a friendly name for this MCP server
-	Name string `yaml:"name"`
+	Name string `json:"name" yaml:"name"`
 	// Command is the command to execute for stdio-based MCP servers
-	Command string `yaml:"command"`
+	Command string `json:"command" yaml:"command"`
 	// Args are the arguments to pass to the command
-	Args []string `yaml:"args,omitempty"`
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
 	// Env are the environment variables to set for the command
-	Env map[string]string `yaml:"env,omitempty"`
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
 	// URL is the URL for HTTP-based MCP servers
-	URL string `yaml:"url,omitempty"`
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Transport is the transport of HTTP-based MCP servers: "streamable-http" (default) or "sse"
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	// Headers are added to the requests to HTTP-based MCP servers. ${VAR} in values is replaced by the environment variable
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// TLS configures client certificates (mTLS) and the CA for HTTPS connections
+	TLS *TLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
 	// Auth is the authentication configuration for HTTP-based MCP servers
-	Auth *AuthConfig `yaml:"auth,omitempty"`
+	Auth *AuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
 	// OAuthConfig is the OAuth configuration for HTTP-based MCP servers
-	OAuthConfig *OAuthConfig `yaml:"oauth,omitempty"`
+	OAuthConfig *OAuthConfig `json:"oauth,omitempty" yaml:"oauth,omitempty"`
 	// Timeout is the timeout in seconds for HTTP requests
-	Timeout int `yaml:"timeout,omitempty"`
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 	// UseStreaming enables streaming HTTP for better performance
-	UseStreaming bool `yaml:"use_streaming,omitempty"`
+	UseStreaming bool `json:"use_streaming,omitempty" yaml:"use_streaming,omitempty"`
 	// SkipVerify skips TLS certificate verification for HTTPS connections
-	SkipVerify bool `yaml:"skip_verify,omitempty"`
+	SkipVerify bool `json:"skip_verify,omitempty" yaml:"skip_verify,omitempty"`
+	// IncludeTools limits the tools used from this server to those matching one of these glob patterns
+	IncludeTools []string `json:"include_tools,omitempty" yaml:"include_tools,omitempty"`
+	// ExcludeTools are glob patterns of tools not to use from this server
+	ExcludeTools []string `json:"exclude_tools,omitempty" yaml:"exclude_tools,omitempty"`
+	// ModifiesResource overrides whether calls to a tool, by name, modify resources: "yes", "no" or "unknown"
+	ModifiesResource map[string]string `json:"modifies_resource,omitempty" yaml:"modifies_resource,omitempty"`
+	// Trust is the trust level of the server, which decides when calls to its tools need approval
+	Trust string `json:"trust,omitempty" yaml:"trust,omitempty"`
+}
+
+// Trust levels of MCP servers.
+const (
+	// TrustUntrusted ignores the server's tool annotations: calls to its tools always need approval,
+	// unless modifies_resource says otherwise.
+	TrustUntrusted = "untrusted"
+	// TrustStandard uses the server's tool annotations: calls to tools annotated as read-only
+	// run without approval. This is the default.
+	TrustStandard = "standard"
+	// TrustTrusted runs calls without approval, except to tools annotated as destructive.
+	TrustTrusted = "trusted"
+)
+
+// TrustLevel returns the trust level of the server, defaulting to TrustStandard.
+func (s ServerConfig) TrustLevel() string {
+	if s.Trust == "" {
+		return TrustStandard
+	}
+	return s.Trust
+}
+
+// expandedHeaders returns the headers, with environment variables expanded.
+func (s ServerConfig) expandedHeaders() map[string]string {
+	if len(s.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(s.Headers))
+	for key, value := range s.Headers {
+		headers[key] = os.ExpandEnv(value)
+	}
+	return headers
+}
+
+// expandedAuth returns the authentication configuration, with environment variables expanded in the credentials.
+func (s ServerConfig) expandedAuth() *AuthConfig {
+	if s.Auth == nil {
+		return nil
+	}
+	auth := *s.Auth
+	auth.Username = os.ExpandEnv(auth.Username)
+	auth.Password = os.ExpandEnv(auth.Password)
+	auth.Token = os.ExpandEnv(auth.Token)
+	auth.ApiKey = os.ExpandEnv(auth.ApiKey)
+	return &auth
+}
+
+// ToolAllowed reports whether the named tool of the server may be used,
+// according to include_tools and exclude_tools.
+func (s ServerConfig) ToolAllowed(name string) bool {
+	if len(s.IncludeTools) > 0 && !matchesAny(s.IncludeTools, name) {
+		return false
+	}
+	return !matchesAny(s.ExcludeTools, name)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyToolPolicy sets whether calls to a tool of the server modify resources, and why.
+// modifies_resource takes precedence, then the tool's annotations, unless the server is untrusted.
+func (s ServerConfig) applyToolPolicy(tool Tool) Tool {
+	tool.Trust = s.TrustLevel()
+	if modifies, ok := s.ModifiesResource[tool.Name]; ok {
+		tool.ModifiesResource = modifies
+		tool.ModifiesResourceReason = "set by modifies_resource in the MCP configuration"
+		return tool
+	}
+
+	readOnly := tool.ReadOnlyHint != nil && *tool.ReadOnlyHint
+	// The destructive hint is only meaningful for tools that are not read-only;
+	// mcp-go sets it by default on every tool.
+	destructive := !readOnly && tool.DestructiveHint != nil && *tool.DestructiveHint
+	annotated := tool.ReadOnlyHint != nil || tool.DestructiveHint != nil
+	switch {
+	case tool.Trust == TrustUntrusted:
+		tool.ModifiesResource = "unknown"
+		tool.ModifiesResourceReason = "the server is untrusted, so its tool annotations are ignored"
+	case tool.Trust == TrustTrusted && !destructive:
+		tool.ModifiesResource = "no"
+		tool.ModifiesResourceReason = "the server is trusted, and the tool is not annotated as destructive"
+	case destructive:
+		tool.ModifiesResource = "yes"
+		tool.ModifiesResourceReason = "the server annotates the tool as destructive"
+	case readOnly:
+		tool.ModifiesResource = "no"
+		tool.ModifiesResourceReason = "the server annotates the tool as read-only"
+	case annotated:
+		tool.ModifiesResource = "yes"
+		tool.ModifiesResourceReason = "the server annotates the tool as not read-only"
+	default:
+		tool.ModifiesResource = "unknown"
+		tool.ModifiesResourceReason = "the server does not annotate the tool as read-only"
+	}
+	return tool
 }
 
 // ===================================================================
@@ -113,6 +230,25 @@ func DefaultConfigPath() (string, error) {
 // If path is empty, the default config path is used
 // If the file doesn't exist, it creates a default configuration file
 func LoadConfig(path string) (*Config, error) {
+	config, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply environment variable overrides
+	applyEnvironmentVariables(config)
+
+	return config, nil
+}
+
+// LoadConfigForEdit loads the MCP configuration like LoadConfig, but without the environment
+// variable overrides, so that saving the configuration does not write them to the file.
+func LoadConfigForEdit(path string) (*Config, error) {
+	return loadConfigFile(path)
+}
+
+// loadConfigFile loads and validates the MCP configuration file, creating it if it doesn't exist.
+func loadConfigFile(path string) (*Config, error) {
 	if path == "" {
 		var err error
 		path, err = DefaultConfigPath()
@@ -140,9 +276,6 @@ func LoadConfig(path string) (*Config, error) {
 			return nil, fmt.Errorf("saving default config: %w", err)
 		}
 
-		// Apply environment variable overrides
-		applyEnvironmentVariables(defaultConfig)
-
 		return defaultConfig, nil
 	}
 
@@ -163,12 +296,45 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Apply environment variable overrides
-	applyEnvironmentVariables(&config)
-
 	return &config, nil
 }
 
+// Server returns the configuration of the named server.
+func (c *Config) Server(name string) (ServerConfig, bool) {
+	for _, server := range c.Servers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// AddServer validates a server configuration and adds it to the configuration.
+func (c *Config) AddServer(server ServerConfig) error {
+	if err := ValidateServerConfig(server); err != nil {
+		return err
+	}
+	if _, exists := c.Server(server.Name); exists {
+		return fmt.Errorf("MCP server %q already exists", server.Name)
+	}
+	c.Servers = append(c.Servers, server)
+	return nil
+}
+
+// RemoveServer removes the named server from the configuration.
+// The configuration must keep at least one server to be valid.
+func (c *Config) RemoveServer(name string) error {
+	i := slices.IndexFunc(c.Servers, func(server ServerConfig) bool { return server.Name == name })
+	if i < 0 {
+		return fmt.Errorf("MCP server %q is not configured", name)
+	}
+	if len(c.Servers) == 1 {
+		return fmt.Errorf("MCP server %q is the only configured server, and the configuration needs at least one", name)
+	}
+	c.Servers = slices.Delete(c.Servers, i, i+1)
+	return nil
+}
+
 // Save saves the configuration to the given path using atomic write
 func (c *Config) Save(path string) error {
 	if path == "" {
@@ -273,6 +439,34 @@ func ValidateServerConfig(config ServerConfig) error {
 		return fmt.Errorf("either URL or Command must be specified")
 	}
 
+	switch config.Transport {
+	case "", TransportStreamableHTTP, TransportSSE:
+	default:
+		return fmt.Errorf("invalid transport %q: must be %s or %s", config.Transport, TransportStreamableHTTP, TransportSSE)
+	}
+	if config.URL == "" && (config.Transport != "" || len(config.Headers) > 0 || config.TLS != nil) {
+		return fmt.Errorf("transport, headers and tls can only be used with a URL")
+	}
+	if config.TLS != nil && (config.TLS.CertFile == "") != (config.TLS.KeyFile == "") {
+		return fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+
+	for _, pattern := range append(slices.Clone(config.IncludeTools), config.ExcludeTools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+	for tool, modifies := range config.ModifiesResource {
+		if modifies != "yes" && modifies != "no" && modifies != "unknown" {
+			return fmt.Errorf("invalid modifies_resource %q for tool %q: must be yes, no or unknown", modifies, tool)
+		}
+	}
+	switch config.Trust {
+	case "", TrustUntrusted, TrustStandard, TrustTrusted:
+	default:
+		return fmt.Errorf("invalid trust %q: must be %s, %s or %s", config.Trust, TrustUntrusted, TrustStandard, TrustTrusted)
+	}
+
 	// Additional validation could be added here:
 	// - Check if command exists and is executable
 	// - Validate environment variable format
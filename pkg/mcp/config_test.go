@@ -0,0 +1,264 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReadsServerOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.yaml")
+	data := `servers:
+  - name: github
+    url: https://example.com/mcp
+    use_streaming: true
+    skip_verify: true
+    include_tools: ["get_*"]
+    exclude_tools: ["get_secret"]
+    modifies_resource:
+      get_file: "no"
+    trust: trusted
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	want := ServerConfig{
+		Name:             "github",
+		URL:              "https://example.com/mcp",
+		UseStreaming:     true,
+		SkipVerify:       true,
+		IncludeTools:     []string{"get_*"},
+		ExcludeTools:     []string{"get_secret"},
+		ModifiesResource: map[string]string{"get_file": "no"},
+		Trust:            TrustTrusted,
+	}
+	if len(config.Servers) != 1 || !reflect.DeepEqual(config.Servers[0], want) {
+		t.Errorf("LoadConfig() servers = %+v, want [%+v]", config.Servers, want)
+	}
+}
+
+func TestServerConfigToolAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		tool    string
+		want    bool
+	}{
+		{name: "no filters", tool: "create_issue", want: true},
+		{name: "included", include: []string{"get_*", "list_*"}, tool: "list_issues", want: true},
+		{name: "not included", include: []string{"get_*", "list_*"}, tool: "create_issue", want: false},
+		{name: "excluded", exclude: []string{"delete_*"}, tool: "delete_repo", want: false},
+		{name: "included and excluded", include: []string{"*_issue"}, exclude: []string{"delete_*"}, tool: "delete_issue", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ServerConfig{IncludeTools: tt.include, ExcludeTools: tt.exclude}
+			if got := cfg.ToolAllowed(tt.tool); got != tt.want {
+				t.Errorf("ToolAllowed(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerConfigApplyToolPolicy(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name             string
+		cfg              ServerConfig
+		tool             Tool
+		wantModifies     string
+		wantReasonSubstr string
+	}{
+		{
+			name:             "no annotations",
+			tool:             Tool{Name: "run"},
+			wantModifies:     "unknown",
+			wantReasonSubstr: "does not annotate",
+		},
+		{
+			name:             "read-only annotation",
+			tool:             Tool{Name: "get", ReadOnlyHint: &yes},
+			wantModifies:     "no",
+			wantReasonSubstr: "read-only",
+		},
+		{
+			name:             "not read-only annotation",
+			tool:             Tool{Name: "create", ReadOnlyHint: &no, DestructiveHint: &no},
+			wantModifies:     "yes",
+			wantReasonSubstr: "not read-only",
+		},
+		{
+			name:             "read-only annotation with the default destructive hint",
+			cfg:              ServerConfig{Trust: TrustTrusted},
+			tool:             Tool{Name: "get", ReadOnlyHint: &yes, DestructiveHint: &yes},
+			wantModifies:     "no",
+			wantReasonSubstr: "trusted",
+		},
+		{
+			name:             "read-only annotation wins over destructive",
+			tool:             Tool{Name: "get", ReadOnlyHint: &yes, DestructiveHint: &yes},
+			wantModifies:     "no",
+			wantReasonSubstr: "read-only",
+		},
+		{
+			name:             "destructive annotation",
+			tool:             Tool{Name: "delete", DestructiveHint: &yes},
+			wantModifies:     "yes",
+			wantReasonSubstr: "destructive",
+		},
+		{
+			name:             "untrusted server ignores annotations",
+			cfg:              ServerConfig{Trust: TrustUntrusted},
+			tool:             Tool{Name: "get", ReadOnlyHint: &yes},
+			wantModifies:     "unknown",
+			wantReasonSubstr: "untrusted",
+		},
+		{
+			name:             "trusted server",
+			cfg:              ServerConfig{Trust: TrustTrusted},
+			tool:             Tool{Name: "run"},
+			wantModifies:     "no",
+			wantReasonSubstr: "trusted",
+		},
+		{
+			name:             "trusted server with destructive tool",
+			cfg:              ServerConfig{Trust: TrustTrusted},
+			tool:             Tool{Name: "delete", ReadOnlyHint: &no, DestructiveHint: &yes},
+			wantModifies:     "yes",
+			wantReasonSubstr: "destructive",
+		},
+		{
+			name:             "configuration overrides annotations",
+			cfg:              ServerConfig{ModifiesResource: map[string]string{"get": "yes"}},
+			tool:             Tool{Name: "get", ReadOnlyHint: &yes},
+			wantModifies:     "yes",
+			wantReasonSubstr: "modifies_resource",
+		},
+		{
+			name:             "configuration overrides untrusted",
+			cfg:              ServerConfig{Trust: TrustUntrusted, ModifiesResource: map[string]string{"get": "no"}},
+			tool:             Tool{Name: "get"},
+			wantModifies:     "no",
+			wantReasonSubstr: "modifies_resource",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.applyToolPolicy(tt.tool)
+			if got.ModifiesResource != tt.wantModifies || !strings.Contains(got.ModifiesResourceReason, tt.wantReasonSubstr) {
+				t.Errorf("applyToolPolicy(%+v) = %q (%s), want %q with a reason containing %q",
+					tt.tool, got.ModifiesResource, got.ModifiesResourceReason, tt.wantModifies, tt.wantReasonSubstr)
+			}
+			if got.Trust != tt.cfg.TrustLevel() {
+				t.Errorf("applyToolPolicy(%+v) trust = %q, want %q", tt.tool, got.Trust, tt.cfg.TrustLevel())
+			}
+		})
+	}
+}
+
+func TestValidateServerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ServerConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: ServerConfig{Name: "github", URL: "https://example.com/mcp", Transport: TransportSSE, IncludeTools: []string{"get_*"},
+				ModifiesResource: map[string]string{"get_file": "no"}, Trust: TrustTrusted},
+		},
+		{name: "missing name", cfg: ServerConfig{Command: "server"}, wantErr: "name"},
+		{name: "invalid pattern", cfg: ServerConfig{Name: "s", Command: "server", ExcludeTools: []string{"[a-"}}, wantErr: "invalid tool pattern"},
+		{name: "invalid modifies_resource", cfg: ServerConfig{Name: "s", Command: "server", ModifiesResource: map[string]string{"t": "maybe"}}, wantErr: "modifies_resource"},
+		{name: "invalid transport", cfg: ServerConfig{Name: "s", URL: "https://example.com", Transport: "websocket"}, wantErr: "invalid transport"},
+		{name: "headers without URL", cfg: ServerConfig{Name: "s", Command: "server", Headers: map[string]string{"X-A": "b"}}, wantErr: "only be used with a URL"},
+		{name: "certificate without key", cfg: ServerConfig{Name: "s", URL: "https://example.com", TLS: &TLSConfig{CertFile: "client.pem"}}, wantErr: "cert_file and key_file"},
+		{name: "invalid trust", cfg: ServerConfig{Name: "s", Command: "server", Trust: "full"}, wantErr: "invalid trust"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateServerConfig(tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateServerConfig() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateServerConfig() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigAddAndRemoveServer(t *testing.T) {
+	config := &Config{Servers: []ServerConfig{{Name: "local", Command: "server"}}}
+
+	if err := config.AddServer(ServerConfig{Name: "remote", URL: "https://example.com/mcp"}); err != nil {
+		t.Fatalf("AddServer() = %v", err)
+	}
+	if err := config.AddServer(ServerConfig{Name: "remote", URL: "https://example.com/other"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("AddServer() of a duplicate = %v, want an error", err)
+	}
+	if err := config.AddServer(ServerConfig{Name: "invalid"}); err == nil {
+		t.Errorf("AddServer() of an invalid server succeeded, want an error")
+	}
+	if _, ok := config.Server("remote"); !ok {
+		t.Errorf("Server(%q) not found after adding it", "remote")
+	}
+
+	if err := config.RemoveServer("missing"); err == nil {
+		t.Errorf("RemoveServer() of a missing server succeeded, want an error")
+	}
+	if err := config.RemoveServer("local"); err != nil {
+		t.Fatalf("RemoveServer() = %v", err)
+	}
+	if err := config.RemoveServer("remote"); err == nil || !strings.Contains(err.Error(), "at least one") {
+		t.Errorf("RemoveServer() of the last server = %v, want an error", err)
+	}
+	if len(config.Servers) != 1 || config.Servers[0].Name != "remote" {
+		t.Errorf("servers = %+v, want only remote", config.Servers)
+	}
+}
+
+func TestLoadConfigForEditSkipsEnvironmentOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.yaml")
+	if err := (&Config{Servers: []ServerConfig{{Name: "remote", URL: "https://example.com/mcp"}}}).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvMCPServerPrefix+"REMOTE_URL", "https://override.example.com/mcp")
+
+	config, err := LoadConfig(path)
+	if err != nil || config.Servers[0].URL != "https://override.example.com/mcp" {
+		t.Fatalf("LoadConfig() = %+v, %v; want the URL from the environment", config, err)
+	}
+	config, err = LoadConfigForEdit(path)
+	if err != nil || config.Servers[0].URL != "https://example.com/mcp" {
+		t.Errorf("LoadConfigForEdit() = %+v, %v; want the URL from the file", config, err)
+	}
+}
@@ -29,6 +29,9 @@ const (
 
 	// DefaultStabilizationDelay is the delay to allow servers to stabilize after connection
 	DefaultStabilizationDelay = 2 * time.Second
+
+	// DefaultHealthCheckInterval is how often connected servers are pinged, and failed servers reconnected
+	DefaultHealthCheckInterval = 30 * time.Second
 )
 
 // Error message templates
@@ -17,9 +17,11 @@ package mcp
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	mcpclient "github.com/mark3labs/mcp-go/client"
@@ -36,11 +38,13 @@ import (
 type httpClient struct {
 	name         string
 	url          string
+	transport    string
 	auth         *AuthConfig
 	oauthConfig  *OAuthConfig
 	timeout      int
 	useStreaming bool
 	skipVerify   bool
+	tls          *TLSConfig
 	headers      map[string]string
 	client       *mcpclient.Client
 }
@@ -50,11 +54,13 @@ func NewHTTPClient(config ClientConfig) MCPClient {
 	return &httpClient{
 		name:         config.Name,
 		url:          config.URL,
+		transport:    config.Transport,
 		auth:         config.Auth,
 		oauthConfig:  config.OAuthConfig,
 		timeout:      config.Timeout,
 		useStreaming: config.UseStreaming,
 		skipVerify:   config.SkipVerify,
+		tls:          config.TLS,
 		headers:      config.Headers,
 	}
 }
@@ -87,6 +93,8 @@ func (c *httpClient) Connect(ctx context.Context) error {
 	// Create the appropriate client based on configuration
 	if c.oauthConfig != nil {
 		client, err = c.createOAuthClient(ctx)
+	} else if c.transport == TransportSSE {
+		client, err = c.createSSEClient()
 	} else if c.useStreaming {
 		client, err = c.createStreamingClient()
 	} else {
@@ -102,6 +110,9 @@ func (c *httpClient) Connect(ctx context.Context) error {
 	// Initialize the connection
 	if err := c.initializeConnection(ctx); err != nil {
 		c.cleanup()
+		if mcpclient.IsOAuthAuthorizationRequiredError(err) {
+			return fmt.Errorf("authorization required, run \"kubectl-ai mcp login %s\": %w", c.name, err)
+		}
 		return fmt.Errorf("initializing connection: %w", err)
 	}
 
@@ -120,35 +131,109 @@ func (c *httpClient) createStreamingClient() (*mcpclient.Client, error) {
 	// Set up options for the HTTP client
 	var options []transport.StreamableHTTPCOption
 
-	// Add timeout if specified (only when not using custom client)
-	if c.timeout > 0 {
+	// Listen for notifications from the server, such as changes to its tools
+	options = append(options, transport.WithContinuousListening(), transport.WithHTTPLogger(klogLogger{}))
+
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		// Add timeout to custom client if specified
+		if c.timeout > 0 {
+			httpClient.Timeout = time.Duration(c.timeout) * time.Second
+		}
+		options = append(options, transport.WithHTTPBasicClient(httpClient))
+	} else if c.timeout > 0 {
 		options = append(options, transport.WithHTTPTimeout(time.Duration(c.timeout)*time.Second))
 	}
 
-	klog.V(2).InfoS("WARNING: TLS certificate verification is disabled", "server", c.name)
-	// Handle TLS verification skip by creating custom HTTP client
+	// Add headers if any were set
+	if headers := c.requestHeaders(); len(headers) > 0 {
+		options = append(options, transport.WithHTTPHeaders(headers))
+	}
+
+	klog.V(4).InfoS("Creating streamable HTTP client", "server", c.name, "url", c.url)
+	client, err := mcpclient.NewStreamableHttpClient(c.url, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating streamable HTTP client: %w", err)
+	}
+
+	return client, nil
+}
+
+// createSSEClient creates a client for servers using the older HTTP+SSE transport
+func (c *httpClient) createSSEClient() (*mcpclient.Client, error) {
+	options := []transport.ClientOption{transport.WithSSELogger(klogLogger{})}
+
+	// The timeout is not applied, as the event stream stays open for as long as the client is connected
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		options = append(options, transport.WithHTTPClient(httpClient))
+	}
+
+	if headers := c.requestHeaders(); len(headers) > 0 {
+		options = append(options, transport.WithHeaders(headers))
+	}
+
+	klog.V(4).InfoS("Creating SSE client", "server", c.name, "url", c.url)
+	client, err := mcpclient.NewSSEMCPClient(c.url, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating SSE client: %w", err)
+	}
+
+	return client, nil
+}
+
+// newHTTPClient creates the HTTP client for TLS verification skip, client certificates or a custom CA,
+// or returns nil when the default client will do
+func (c *httpClient) newHTTPClient() (*http.Client, error) {
+	if !c.skipVerify && c.tls == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
 	if c.skipVerify {
 		klog.V(2).InfoS("WARNING: TLS certificate verification is disabled", "server", c.name)
+		tlsConfig.InsecureSkipVerify = true
+	}
 
-		// Create custom HTTP client with TLS verification disabled
-		customClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
+	if c.tls != nil {
+		if c.tls.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(os.ExpandEnv(c.tls.CertFile), os.ExpandEnv(c.tls.KeyFile))
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+			klog.V(3).InfoS("Using client certificate for HTTP client", "server", c.name)
 		}
-
-		// Add timeout to custom client if specified
-		if c.timeout > 0 {
-			customClient.Timeout = time.Duration(c.timeout) * time.Second
+		if c.tls.CAFile != "" {
+			caFile := os.ExpandEnv(c.tls.CAFile)
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA file %q", caFile)
+			}
+			tlsConfig.RootCAs = pool
 		}
-
-		// Use the custom HTTP client
-		options = append(options, transport.WithHTTPBasicClient(customClient))
 	}
 
-	// Prepare headers map for authentication and custom headers
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// requestHeaders returns the custom and authentication headers to add to requests
+func (c *httpClient) requestHeaders() map[string]string {
 	headers := make(map[string]string)
 
 	// Add custom headers from configuration first
@@ -177,18 +262,7 @@ func (c *httpClient) createStreamingClient() (*mcpclient.Client, error) {
 		}
 	}
 
-	// Add headers if any were set
-	if len(headers) > 0 {
-		options = append(options, transport.WithHTTPHeaders(headers))
-	}
-
-	klog.V(4).InfoS("Creating streamable HTTP client", "server", c.name, "url", c.url)
-	client, err := mcpclient.NewStreamableHttpClient(c.url, options...)
-	if err != nil {
-		return nil, fmt.Errorf("creating streamable HTTP client: %w", err)
-	}
-
-	return client, nil
+	return headers
 }
 
 // createStandardClient creates a standard HTTP client
@@ -206,27 +280,45 @@ func (c *httpClient) createOAuthClient(ctx context.Context) (*mcpclient.Client,
 
 	klog.V(3).InfoS("Creating OAuth HTTP client", "server", c.name, "client_id", c.oauthConfig.ClientID)
 
-	// Set up options for the HTTP client
-	var options []transport.StreamableHTTPCOption
+	// Tokens are read from the cache filled by "kubectl-ai mcp login", and refreshed when they expire
+	cache, err := DefaultTokenCache()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := cache.get(c.name)
+	if err != nil {
+		return nil, err
+	}
+	oauthCfg := oauthTransportConfig(c.oauthConfig, creds, c.oauthConfig.RedirectURL, cache.TokenStore(c.name))
 
-	// Create OAuth configuration for the transport
-	oauthCfg := transport.OAuthConfig{
-		ClientID:     c.oauthConfig.ClientID,
-		ClientSecret: c.oauthConfig.ClientSecret,
-		Scopes:       c.oauthConfig.Scopes,
-		RedirectURI:  c.oauthConfig.RedirectURL,
-		// Use the token URL as the auth server metadata URL if available
-		AuthServerMetadataURL: c.oauthConfig.TokenURL,
+	if c.transport == TransportSSE {
+		return c.createOAuthSSEClient(oauthCfg)
 	}
 
+	// Set up options for the HTTP client
+	var options []transport.StreamableHTTPCOption
+	options = append(options, transport.WithContinuousListening(), transport.WithHTTPLogger(klogLogger{}))
+
 	// Add OAuth configuration
 	options = append(options, transport.WithHTTPOAuth(oauthCfg))
 
-	// Add timeout if specified
-	if c.timeout > 0 {
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		if c.timeout > 0 {
+			httpClient.Timeout = time.Duration(c.timeout) * time.Second
+		}
+		options = append(options, transport.WithHTTPBasicClient(httpClient))
+	} else if c.timeout > 0 {
 		options = append(options, transport.WithHTTPTimeout(time.Duration(c.timeout)*time.Second))
 	}
 
+	if len(c.headers) > 0 {
+		options = append(options, transport.WithHTTPHeaders(c.headers))
+	}
+
 	klog.V(4).InfoS("Creating OAuth streamable HTTP client", "server", c.name, "url", c.url)
 	client, err := mcpclient.NewStreamableHttpClient(c.url, options...)
 	if err != nil {
@@ -236,6 +328,30 @@ func (c *httpClient) createOAuthClient(ctx context.Context) (*mcpclient.Client,
 	return client, nil
 }
 
+// createOAuthSSEClient creates an SSE client with OAuth authentication
+func (c *httpClient) createOAuthSSEClient(oauthCfg transport.OAuthConfig) (*mcpclient.Client, error) {
+	options := []transport.ClientOption{transport.WithSSELogger(klogLogger{}), transport.WithOAuth(oauthCfg)}
+
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		options = append(options, transport.WithHTTPClient(httpClient))
+	}
+	if len(c.headers) > 0 {
+		options = append(options, transport.WithHeaders(c.headers))
+	}
+
+	klog.V(4).InfoS("Creating OAuth SSE client", "server", c.name, "url", c.url)
+	client, err := mcpclient.NewSSEMCPClient(c.url, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating OAuth SSE client: %w", err)
+	}
+
+	return client, nil
+}
+
 // initializeConnection initializes the MCP connection with proper handshake
 func (c *httpClient) initializeConnection(ctx context.Context) error {
 	return initializeClientConnection(ctx, c.client)
@@ -279,12 +395,12 @@ func (c *httpClient) ListTools(ctx context.Context) ([]Tool, error) {
 	return tools, nil
 }
 
-// CallTool calls a tool on the MCP server and returns the result as a string
-func (c *httpClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (string, error) {
+// CallTool calls a tool on the MCP server and returns its result
+func (c *httpClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*ToolResult, error) {
 	klog.V(2).InfoS("Calling MCP tool via HTTP", "server", c.name, "tool", toolName)
 
 	if err := c.ensureConnected(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Create v0.31.0 compatible request
@@ -298,8 +414,30 @@ func (c *httpClient) CallTool(ctx context.Context, toolName string, arguments ma
 	// Call the tool on the MCP server
 	result, err := c.client.CallTool(ctx, request)
 	if err != nil {
-		return "", fmt.Errorf("error calling tool %s: %w", toolName, err)
+		return nil, fmt.Errorf("error calling tool %s: %w", toolName, err)
 	}
 
-	return processToolResponse(result)
+	return newToolResult(result)
+}
+
+// ListResources lists the resources and resource templates published by the MCP server
+func (c *httpClient) ListResources(ctx context.Context) ([]ServerResource, error) {
+	return listClientResources(ctx, c.client, c.name)
+}
+
+// ReadResource reads a resource from the MCP server
+func (c *httpClient) ReadResource(ctx context.Context, uri string) ([]Resource, error) {
+	klog.V(2).InfoS("Reading MCP resource via HTTP", "server", c.name, "uri", uri)
+	return readClientResource(ctx, c.client, uri)
+}
+
+// ListPrompts lists the prompts published by the MCP server
+func (c *httpClient) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	return listClientPrompts(ctx, c.client, c.name)
+}
+
+// GetPrompt renders a prompt of the MCP server with the given arguments
+func (c *httpClient) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*PromptResult, error) {
+	klog.V(2).InfoS("Getting MCP prompt via HTTP", "server", c.name, "prompt", name)
+	return getClientPrompt(ctx, c.client, name, arguments)
 }
@@ -0,0 +1,181 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newTestMCPServer() *server.MCPServer {
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool("echo"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	return srv
+}
+
+// connectAndCall connects to the server with the manager and calls its echo tool.
+func connectAndCall(t *testing.T, cfg ServerConfig) {
+	t.Helper()
+	ctx := context.Background()
+	m := NewManager(&Config{Servers: []ServerConfig{cfg}})
+	defer m.Close()
+	if err := m.ConnectAll(ctx); err != nil {
+		t.Fatalf("ConnectAll() error = %v", err)
+	}
+	client, ok := m.GetClient(cfg.Name)
+	if !ok {
+		t.Fatalf("server %q is not connected: %+v", cfg.Name, m.ServerStates())
+	}
+	result, err := client.CallTool(ctx, "echo", nil)
+	if err != nil || result != "ok" {
+		t.Errorf("CallTool() = %q, %v; want %q", result, err, "ok")
+	}
+}
+
+func TestHTTPClientSSETransportWithHeaders(t *testing.T) {
+	t.Setenv("TEST_MCP_GATEWAY_TOKEN", "secret")
+
+	var mu sync.Mutex
+	var gatewayTokens []string
+	sseServer := server.NewSSEServer(newTestMCPServer())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gatewayTokens = append(gatewayTokens, r.Header.Get("X-Gateway-Token"))
+		mu.Unlock()
+		sseServer.ServeHTTP(w, r)
+	}))
+	defer func() {
+		ts.CloseClientConnections()
+		ts.Close()
+	}()
+
+	connectAndCall(t, ServerConfig{
+		Name:      "legacy",
+		URL:       ts.URL + "/sse",
+		Transport: TransportSSE,
+		Headers:   map[string]string{"X-Gateway-Token": "${TEST_MCP_GATEWAY_TOKEN}"},
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gatewayTokens) < 2 {
+		t.Fatalf("got %d requests, want the event stream and messages", len(gatewayTokens))
+	}
+	for i, token := range gatewayTokens {
+		if token != "secret" {
+			t.Errorf("request %d has X-Gateway-Token %q, want %q", i, token, "secret")
+		}
+	}
+}
+
+func TestHTTPClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert := writeClientCertificate(t, dir)
+
+	ts := httptest.NewUnstartedServer(server.NewStreamableHTTPServer(newTestMCPServer()))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer func() {
+		ts.CloseClientConnections()
+		ts.Close()
+	}()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ts.Certificate().Raw)
+
+	connectAndCall(t, ServerConfig{
+		Name: "mtls",
+		URL:  ts.URL,
+		TLS: &TLSConfig{
+			CertFile: filepath.Join(dir, "client.pem"),
+			KeyFile:  filepath.Join(dir, "client-key.pem"),
+			CAFile:   caFile,
+		},
+	})
+
+	// Without the client certificate, the server refuses the connection.
+	m := NewManager(&Config{Servers: []ServerConfig{{Name: "mtls", URL: ts.URL, TLS: &TLSConfig{CAFile: caFile}}}})
+	defer m.Close()
+	if _, err := m.connectServer(context.Background(), m.config.Servers[0]); err == nil {
+		t.Errorf("connecting without a client certificate succeeded, want an error")
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key to dir.
+func writeClientCertificate(t *testing.T, dir string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubectl-ai"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "client.pem"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(dir, "client-key.pem"), "PRIVATE KEY", keyDER)
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
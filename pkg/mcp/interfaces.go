@@ -35,8 +35,20 @@ type MCPClient interface {
 	// ListTools lists all available tools from the MCP server
 	ListTools(ctx context.Context) ([]Tool, error)
 
-	// CallTool calls a tool on the MCP server and returns the result as a string
-	CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (string, error)
+	// CallTool calls a tool on the MCP server and returns its result
+	CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*ToolResult, error)
+
+	// ListResources lists the resources and resource templates published by the MCP server
+	ListResources(ctx context.Context) ([]ServerResource, error)
+
+	// ReadResource reads a resource from the MCP server
+	ReadResource(ctx context.Context, uri string) ([]Resource, error)
+
+	// ListPrompts lists the prompts published by the MCP server
+	ListPrompts(ctx context.Context) ([]Prompt, error)
+
+	// GetPrompt renders a prompt of the MCP server with the given arguments
+	GetPrompt(ctx context.Context, name string, arguments map[string]string) (*PromptResult, error)
 
 	// ensureConnected makes sure the client is connected
 	ensureConnected() error
@@ -57,34 +69,51 @@ type ClientConfig struct {
 
 	// For HTTP-based clients
 	URL          string
+	Transport    string // TransportStreamableHTTP (default) or TransportSSE
 	Auth         *AuthConfig
 	OAuthConfig  *OAuthConfig
 	Timeout      int
 	UseStreaming bool              // Whether to use streaming HTTP for better performance
 	SkipVerify   bool              // Whether to skip TLS certificate verification for HTTPS connections
+	TLS          *TLSConfig        // Client certificate and CA for HTTPS connections
 	Headers      map[string]string // Custom headers to include in HTTP requests
 
 	// No LLM configuration needed - MCP doesn't need to know about LLM models
 }
 
+// Transports of HTTP-based MCP servers
+const (
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+)
+
 // AuthConfig represents authentication options for HTTP MCP servers
 type AuthConfig struct {
-	Type       string // "none", "basic", "bearer", "api-key"
-	Username   string // For basic auth
-	Password   string // For basic auth
-	Token      string // For bearer auth
-	ApiKey     string // For API key auth
-	HeaderName string // Custom header name for API key
+	Type       string `json:"type,omitempty"`        // "none", "basic", "bearer", "api-key"
+	Username   string `json:"username,omitempty"`    // For basic auth
+	Password   string `json:"password,omitempty"`    // For basic auth
+	Token      string `json:"token,omitempty"`       // For bearer auth
+	ApiKey     string `json:"api_key,omitempty"`     // For API key auth
+	HeaderName string `json:"header_name,omitempty"` // Custom header name for API key
 }
 
-// OAuthConfig represents OAuth configuration for HTTP MCP servers
+// OAuthConfig represents OAuth options for HTTP MCP servers
 type OAuthConfig struct {
-	ClientID     string
-	ClientSecret string
-	TokenURL     string
-	AuthURL      string
-	Scopes       []string
-	RedirectURL  string
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	TokenURL     string   `json:"token_url,omitempty"`
+	AuthURL      string   `json:"auth_url,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	RedirectURL  string   `json:"redirect_url,omitempty"`
+}
+
+// TLSConfig represents TLS options for HTTPS MCP servers, e.g. for mutual TLS
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM-encoded client certificate and key
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// CAFile is the PEM-encoded CA bundle used to verify the server, instead of the system roots
+	CAFile string `json:"ca_file,omitempty"`
 }
 
 // NewMCPClient creates a new MCP client with the appropriate implementation based on the config
@@ -17,6 +17,7 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -55,16 +56,32 @@ type Manager struct {
 	config  *Config
 	clients map[string]*Client
 	mu      sync.RWMutex
+
+	// disabled servers are neither connected to nor reconnected.
+	disabled map[string]bool
+	// lastErrors holds the last connection error of servers that are not connected.
+	lastErrors map[string]error
+	// toolsChanged are called when the tools of a server may have changed.
+	toolsChanged []func(server string)
+	// reconnectMu serializes reconnections, so that a server is not connected to twice.
+	reconnectMu sync.Mutex
+	// stopHealthMonitor stops the health monitor, if it is running.
+	stopHealthMonitor context.CancelFunc
 }
 
 // NewManager creates a new MCP manager with the given configuration
 func NewManager(config *Config) *Manager {
 	return &Manager{
-		config:  config,
-		clients: make(map[string]*Client),
+		config:     config,
+		clients:    make(map[string]*Client),
+		disabled:   make(map[string]bool),
+		lastErrors: make(map[string]error),
 	}
 }
 
+// reconnectRetryConfig is the retry behavior when reconnecting to a server. It is a variable so tests can replace it.
+var reconnectRetryConfig = DefaultRetryConfig
+
 // InitializeManager creates and initializes the MCP manager
 // with configuration loaded from default paths
 func InitializeManager() (*Manager, error) {
@@ -95,35 +112,20 @@ func (m *Manager) ConnectAll(ctx context.Context) error {
 			klog.V(2).Info("MCP client already connected", "name", serverCfg.Name)
 			continue
 		}
-
-		// Convert environment map to slice
-		var envSlice []string
-		for k, v := range serverCfg.Env {
-			envSlice = append(envSlice, fmt.Sprintf("%s=%s", k, v))
-		}
-
-		// Create client config with environment map
-		config := ClientConfig{
-			Name:         serverCfg.Name,
-			Command:      serverCfg.Command,
-			Args:         serverCfg.Args,
-			Auth:         serverCfg.Auth,
-			OAuthConfig:  serverCfg.OAuthConfig,
-			Env:          envSlice,
-			URL:          serverCfg.URL,
-			Timeout:      serverCfg.Timeout,
-			UseStreaming: serverCfg.UseStreaming,
-			SkipVerify:   serverCfg.SkipVerify,
+		if m.disabled[serverCfg.Name] {
+			continue
 		}
 
-		client := NewClient(config)
-		if err := client.Connect(ctx); err != nil {
+		client, err := m.connectServer(ctx, serverCfg)
+		if err != nil {
 			err := fmt.Errorf(ErrServerConnectionFmt, serverCfg.Name, err)
+			m.lastErrors[serverCfg.Name] = err
 			errs = append(errs, err)
 			klog.Error(err)
 			continue
 		}
 
+		delete(m.lastErrors, serverCfg.Name)
 		m.clients[serverCfg.Name] = client
 		klog.V(2).Info("Connected to MCP server", "name", serverCfg.Name)
 	}
@@ -135,11 +137,229 @@ func (m *Manager) ConnectAll(ctx context.Context) error {
 	return nil
 }
 
+// connectServer connects to a configured server, and watches it for tool changes.
+func (m *Manager) connectServer(ctx context.Context, serverCfg ServerConfig) (*Client, error) {
+	// Convert environment map to slice
+	var envSlice []string
+	for k, v := range serverCfg.Env {
+		envSlice = append(envSlice, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Create client config with environment map
+	config := ClientConfig{
+		Name:         serverCfg.Name,
+		Command:      serverCfg.Command,
+		Args:         serverCfg.Args,
+		Auth:         serverCfg.expandedAuth(),
+		OAuthConfig:  serverCfg.OAuthConfig,
+		Env:          envSlice,
+		URL:          serverCfg.URL,
+		Transport:    serverCfg.Transport,
+		Timeout:      serverCfg.Timeout,
+		UseStreaming: serverCfg.UseStreaming,
+		SkipVerify:   serverCfg.SkipVerify,
+		TLS:          serverCfg.TLS,
+		Headers:      serverCfg.expandedHeaders(),
+	}
+
+	client := NewClient(config)
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	name := serverCfg.Name
+	client.OnToolsChanged(func() {
+		klog.V(1).Info("MCP server reported that its tools changed", "server", name)
+		m.notifyToolsChanged(name)
+	})
+	return client, nil
+}
+
+// OnToolsChanged registers a function that is called when the tools of a server may have changed:
+// when the server reports it, or when the server is reconnected, disconnected or disabled.
+// The function is called from a separate goroutine.
+func (m *Manager) OnToolsChanged(fn func(server string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolsChanged = append(m.toolsChanged, fn)
+}
+
+func (m *Manager) notifyToolsChanged(server string) {
+	m.mu.RLock()
+	handlers := slices.Clone(m.toolsChanged)
+	m.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(server)
+	}
+}
+
+// serverConfig returns the configuration of the named server.
+func (m *Manager) serverConfig(name string) (ServerConfig, bool) {
+	for _, serverCfg := range m.config.Servers {
+		if serverCfg.Name == name {
+			return serverCfg, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// Reconnect closes the connection to the named server, if any, and connects to it again, with retries.
+// It also re-enables the server if it was disabled.
+func (m *Manager) Reconnect(ctx context.Context, name string) error {
+	serverCfg, ok := m.serverConfig(name)
+	if !ok {
+		return fmt.Errorf("unknown MCP server %q", name)
+	}
+
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+
+	m.mu.Lock()
+	old := m.clients[name]
+	delete(m.clients, name)
+	delete(m.disabled, name)
+	m.mu.Unlock()
+	if old != nil {
+		if err := old.Close(); err != nil {
+			klog.V(2).Info("Failed to close MCP client before reconnecting", "server", name, "error", err)
+		}
+	}
+
+	var client *Client
+	err := RetryOperation(ctx, reconnectRetryConfig(fmt.Sprintf("reconnecting to MCP server %q", name)), func() error {
+		var err error
+		client, err = m.connectServer(ctx, serverCfg)
+		return err
+	})
+
+	m.mu.Lock()
+	if err != nil {
+		err = fmt.Errorf(ErrServerConnectionFmt, name, err)
+		m.lastErrors[name] = err
+	} else {
+		delete(m.lastErrors, name)
+		m.clients[name] = client
+		klog.InfoS("Reconnected to MCP server", "server", name)
+	}
+	m.mu.Unlock()
+
+	// The server's tools are gone if it failed, and may have changed if it restarted.
+	m.notifyToolsChanged(name)
+	return err
+}
+
+// Disable disconnects from the named server, and keeps it disconnected until it is reconnected with Reconnect.
+func (m *Manager) Disable(name string) error {
+	if _, ok := m.serverConfig(name); !ok {
+		return fmt.Errorf("unknown MCP server %q", name)
+	}
+
+	m.mu.Lock()
+	client := m.clients[name]
+	delete(m.clients, name)
+	delete(m.lastErrors, name)
+	m.disabled[name] = true
+	m.mu.Unlock()
+
+	var err error
+	if client != nil {
+		if cerr := client.Close(); cerr != nil {
+			err = fmt.Errorf(ErrServerCloseFmt, name, cerr)
+		}
+	}
+	m.notifyToolsChanged(name)
+	return err
+}
+
+// StartHealthMonitor checks the configured servers every interval, until ctx is done or the manager is closed.
+// Servers that do not respond to a ping, or are not connected, are reconnected.
+func (m *Manager) StartHealthMonitor(ctx context.Context, interval time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	if m.stopHealthMonitor != nil {
+		m.mu.Unlock()
+		cancel()
+		return
+	}
+	m.stopHealthMonitor = cancel
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+// checkHealth pings the connected servers, and reconnects to those that do not respond or are not connected.
+func (m *Manager) checkHealth(ctx context.Context) {
+	for _, serverCfg := range m.config.Servers {
+		name := serverCfg.Name
+		m.mu.RLock()
+		client, disabled := m.clients[name], m.disabled[name]
+		m.mu.RUnlock()
+		if disabled {
+			continue
+		}
+		if client != nil {
+			err := client.Ping(ctx)
+			if err == nil {
+				continue
+			}
+			klog.Warningf("MCP server %q is not responding, reconnecting: %v", name, err)
+		}
+		if err := m.Reconnect(ctx, name); err != nil {
+			klog.V(1).Info("Failed to reconnect to MCP server", "server", name, "error", err)
+		}
+	}
+}
+
+// ServerState is the connection state of a configured server.
+type ServerState struct {
+	Name      string
+	Connected bool
+	Disabled  bool
+	// LastError is the last connection error, for servers that are not connected.
+	LastError string
+}
+
+// ServerStates returns the connection state of every configured server.
+func (m *Manager) ServerStates() []ServerState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var states []ServerState
+	for _, serverCfg := range m.config.Servers {
+		state := ServerState{
+			Name:      serverCfg.Name,
+			Connected: m.clients[serverCfg.Name] != nil,
+			Disabled:  m.disabled[serverCfg.Name],
+		}
+		if err := m.lastErrors[serverCfg.Name]; err != nil {
+			state.LastError = err.Error()
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
 // Close closes all MCP client connections
 func (m *Manager) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.stopHealthMonitor != nil {
+		m.stopHealthMonitor()
+		m.stopHealthMonitor = nil
+	}
+
 	var errs []error
 
 	for name, client := range m.clients {
@@ -211,20 +431,117 @@ func (m *Manager) ListAvailableTools(ctx context.Context) (map[string][]Tool, er
 	tools := make(map[string][]Tool)
 
 	for name, client := range m.clients {
-		toolList, err := client.ListTools(ctx)
+		serverTools, err := m.serverTools(ctx, name, client)
+		if err != nil {
+			return nil, err
+		}
+		tools[name] = serverTools
+	}
+
+	return tools, nil
+}
+
+// ListServerTools returns the tools of the named server.
+func (m *Manager) ListServerTools(ctx context.Context, name string) ([]Tool, error) {
+	client, ok := m.GetClient(name)
+	if !ok {
+		return nil, fmt.Errorf("MCP server %q is not connected", name)
+	}
+	return m.serverTools(ctx, name, client)
+}
+
+// serverTools lists the tools of a server allowed by its configuration, and decides
+// whether calls to them modify resources.
+func (m *Manager) serverTools(ctx context.Context, name string, client *Client) ([]Tool, error) {
+	toolList, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tools from MCP server %q: %w", name, err)
+	}
+
+	serverCfg, _ := m.serverConfig(name)
+	var serverTools []Tool
+	for _, tool := range toolList {
+		if !serverCfg.ToolAllowed(tool.Name) {
+			klog.V(3).InfoS("Skipping MCP tool excluded by the configuration", "server", name, "tool", tool.Name)
+			continue
+		}
+		serverTools = append(serverTools, serverCfg.applyToolPolicy(tool.WithServer(name)))
+	}
+	if skipped := len(toolList) - len(serverTools); skipped > 0 {
+		klog.V(2).InfoS("Filtered MCP tools", "server", name, "kept", len(serverTools), "skipped", skipped)
+	}
+	return serverTools, nil
+}
+
+// ListResources returns the resources and resource templates of all connected servers.
+// Servers that fail to list their resources are skipped, as resources are optional.
+func (m *Manager) ListResources(ctx context.Context) map[string][]ServerResource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resources := make(map[string][]ServerResource)
+	for name, client := range m.clients {
+		serverResources, err := client.ListResources(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("listing tools from MCP server %q: %w", name, err)
+			klog.Warningf("Failed to list resources from MCP server %q: %v", name, err)
+			continue
+		}
+		if len(serverResources) > 0 {
+			resources[name] = serverResources
 		}
+	}
+	return resources
+}
 
-		var serverTools []Tool
-		for _, tool := range toolList {
-			serverTools = append(serverTools, tool.WithServer(name))
+// ReadResource reads a resource from the server that publishes it, or whose resource templates match it.
+// It returns the name of that server along with the resource contents.
+func (m *Manager) ReadResource(ctx context.Context, uri string) (string, []Resource, error) {
+	for server, resources := range m.ListResources(ctx) {
+		for _, resource := range resources {
+			if !resource.Matches(uri) {
+				continue
+			}
+			client, ok := m.GetClient(server)
+			if !ok {
+				break
+			}
+			contents, err := client.ReadResource(ctx, uri)
+			if err != nil {
+				return server, nil, fmt.Errorf("reading resource from MCP server %q: %w", server, err)
+			}
+			return server, contents, nil
 		}
+	}
+	return "", nil, fmt.Errorf("no MCP server provides resource %q", uri)
+}
 
-		tools[name] = serverTools
+// ListPrompts returns the prompts of all connected servers.
+// Servers that fail to list their prompts are skipped, as prompts are optional.
+func (m *Manager) ListPrompts(ctx context.Context) map[string][]Prompt {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prompts := make(map[string][]Prompt)
+	for name, client := range m.clients {
+		serverPrompts, err := client.ListPrompts(ctx)
+		if err != nil {
+			klog.Warningf("Failed to list prompts from MCP server %q: %v", name, err)
+			continue
+		}
+		if len(serverPrompts) > 0 {
+			prompts[name] = serverPrompts
+		}
 	}
+	return prompts
+}
 
-	return tools, nil
+// GetPrompt renders a prompt of the named server.
+func (m *Manager) GetPrompt(ctx context.Context, server, name string, arguments map[string]string) (*PromptResult, error) {
+	client, ok := m.GetClient(server)
+	if !ok {
+		return nil, fmt.Errorf("MCP server %q is not connected", server)
+	}
+	return client.GetPrompt(ctx, name, arguments)
 }
 
 // RefreshToolDiscovery discovers tools from all servers with retries
@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestManagerReconnectsAndReportsToolChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reconnectRetryConfig = func(description string) RetryConfig {
+		return RetryConfig{MaxRetries: 1, Description: description}
+	}
+	defer func() { reconnectRetryConfig = DefaultRetryConfig }()
+
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	handler := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	srv.AddTool(mcp.NewTool("first"), handler)
+	ts := server.NewTestStreamableHTTPServer(srv)
+	defer ts.Close()
+
+	m := NewManager(&Config{Servers: []ServerConfig{{Name: "test", URL: ts.URL, UseStreaming: true, Timeout: 5}}})
+	defer m.Close()
+	changed := make(chan string, 10)
+	m.OnToolsChanged(func(server string) { changed <- server })
+
+	if err := m.ConnectAll(ctx); err != nil {
+		t.Fatalf("ConnectAll() error = %v", err)
+	}
+	expectChange := func(what string) {
+		t.Helper()
+		select {
+		case server := <-changed:
+			if server != "test" {
+				t.Errorf("tools changed for server %q, want %q", server, "test")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no tool change reported after %s", what)
+		}
+	}
+
+	srv.AddTool(mcp.NewTool("second"), handler)
+	expectChange("adding a tool")
+
+	if err := m.Disable("test"); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	expectChange("disabling the server")
+	if states := m.ServerStates(); !states[0].Disabled || states[0].Connected {
+		t.Errorf("unexpected state after disabling: %+v", states)
+	}
+	m.checkHealth(ctx)
+	if _, ok := m.GetClient("test"); ok {
+		t.Errorf("disabled server was reconnected by the health check")
+	}
+
+	if err := m.Reconnect(ctx, "test"); err != nil {
+		t.Fatalf("Reconnect() error = %v", err)
+	}
+	expectChange("reconnecting")
+	client, ok := m.GetClient("test")
+	if !ok {
+		t.Fatalf("server is not connected after reconnecting")
+	}
+	if tools, err := client.ListTools(ctx); err != nil || len(tools) != 2 {
+		t.Errorf("ListTools() = %v, %v; want 2 tools", tools, err)
+	}
+
+	// Simulate the server going away.
+	ts.CloseClientConnections()
+	ts.Close()
+	m.checkHealth(ctx)
+	states := m.ServerStates()
+	if states[0].Connected || states[0].LastError == "" {
+		t.Errorf("expected the server to be disconnected with an error, got %+v", states)
+	}
+
+	if err := m.Reconnect(ctx, "unknown"); err == nil {
+		t.Errorf("expected an error reconnecting to an unknown server")
+	}
+}
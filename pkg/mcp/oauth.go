@@ -0,0 +1,391 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// tokenCacheFileName is the file, next to mcp.yaml, that OAuth tokens are cached in, encrypted.
+	tokenCacheFileName = "mcp-tokens.enc"
+	// tokenCacheKeyFileName is the file holding the key the token cache is encrypted with.
+	tokenCacheKeyFileName = "mcp-tokens.key"
+
+	// defaultRedirectPath is the path of the loopback redirect server, unless the redirect URL sets one.
+	defaultRedirectPath = "/callback"
+	// oauthClientName is the client name used for dynamic client registration.
+	oauthClientName = "kubectl-ai"
+)
+
+// cachedCredentials are the OAuth credentials of an MCP server.
+type cachedCredentials struct {
+	Token *transport.Token `json:"token,omitempty"`
+	// ClientID and ClientSecret are set when kubectl-ai registered itself with the
+	// authorization server, as no client ID was configured.
+	ClientID     string `json:"clientID,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// TokenCache stores the OAuth credentials of MCP servers, so that users do not have to log in
+// again on every run. The cache is encrypted with AES-GCM, but its key is kept in a file next to it,
+// with the same permissions: this only keeps the tokens from being read by accident (e.g. when
+// grepping or sharing the config directory). Anyone who can read the cache can read the key, so
+// the cache is protected by its file permissions, readable only by the user, like the kubeconfig.
+type TokenCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewTokenCache creates a token cache in the given directory.
+func NewTokenCache(dir string) *TokenCache {
+	return &TokenCache{dir: dir}
+}
+
+// DefaultTokenCache returns the token cache in the directory of the default MCP config file.
+func DefaultTokenCache() (*TokenCache, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewTokenCache(filepath.Dir(configPath)), nil
+}
+
+// TokenStore returns the store of the named server's OAuth token, for the MCP transport.
+func (c *TokenCache) TokenStore(server string) transport.TokenStore {
+	return &serverTokenStore{cache: c, server: server}
+}
+
+// Delete removes the credentials of the named server.
+// It reports whether there were any.
+func (c *TokenCache) Delete(server string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all, err := c.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := all[server]; !ok {
+		return false, nil
+	}
+	delete(all, server)
+	return true, c.save(all)
+}
+
+func (c *TokenCache) get(server string) (cachedCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all, err := c.load()
+	if err != nil {
+		return cachedCredentials{}, err
+	}
+	return all[server], nil
+}
+
+func (c *TokenCache) update(server string, fn func(*cachedCredentials)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all, err := c.load()
+	if err != nil {
+		return err
+	}
+	creds := all[server]
+	fn(&creds)
+	all[server] = creds
+	return c.save(all)
+}
+
+// load decrypts the cached credentials of all servers.
+func (c *TokenCache) load() (map[string]cachedCredentials, error) {
+	all := make(map[string]cachedCredentials)
+	data, err := os.ReadFile(filepath.Join(c.dir, tokenCacheFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading token cache: %w", err)
+	}
+
+	aead, err := c.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("token cache is corrupted")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting token cache (log in again after removing %s): %w", filepath.Join(c.dir, tokenCacheFileName), err)
+	}
+	if err := json.Unmarshal(plaintext, &all); err != nil {
+		return nil, fmt.Errorf("parsing token cache: %w", err)
+	}
+	return all, nil
+}
+
+// save encrypts and writes the cached credentials of all servers.
+func (c *TokenCache) save(all map[string]cachedCredentials) error {
+	plaintext, err := json.Marshal(all)
+	if err != nil {
+		return fmt.Errorf("marshaling token cache: %w", err)
+	}
+	aead, err := c.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	data := aead.Seal(nonce, nonce, plaintext, nil)
+
+	if err := os.MkdirAll(c.dir, ConfigDirPermissions); err != nil {
+		return fmt.Errorf("creating token cache directory: %w", err)
+	}
+	if err := atomicWriteFile(filepath.Join(c.dir, tokenCacheFileName), data, ConfigFilePermissions); err != nil {
+		return fmt.Errorf("writing token cache: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the cipher of the token cache, creating its key if needed and create is set.
+func (c *TokenCache) cipher(create bool) (cipher.AEAD, error) {
+	keyPath := filepath.Join(c.dir, tokenCacheKeyFileName)
+	key, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("generating token cache key: %w", err)
+		}
+		if err := os.MkdirAll(c.dir, ConfigDirPermissions); err != nil {
+			return nil, fmt.Errorf("creating token cache directory: %w", err)
+		}
+		if err := atomicWriteFile(keyPath, key, ConfigFilePermissions); err != nil {
+			return nil, fmt.Errorf("writing token cache key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("reading token cache key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token cache key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// serverTokenStore is the transport.TokenStore of a server, backed by the token cache.
+// The transport uses it to read the token, and to save it when it is refreshed.
+type serverTokenStore struct {
+	cache  *TokenCache
+	server string
+}
+
+func (s *serverTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	creds, err := s.cache.get(s.server)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token == nil {
+		return nil, transport.ErrNoToken
+	}
+	return creds.Token, nil
+}
+
+func (s *serverTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	klog.V(2).InfoS("Caching OAuth token for MCP server", "server", s.server, "expiresAt", token.ExpiresAt)
+	return s.cache.update(s.server, func(creds *cachedCredentials) {
+		creds.Token = token
+	})
+}
+
+// oauthTransportConfig returns the OAuth configuration of the MCP transport for the server.
+// The client ID registered at login is used when none is configured.
+func oauthTransportConfig(config *OAuthConfig, creds cachedCredentials, redirectURI string, store transport.TokenStore) transport.OAuthConfig {
+	clientID, clientSecret := config.ClientID, config.ClientSecret
+	if clientID == "" {
+		clientID, clientSecret = creds.ClientID, creds.ClientSecret
+	}
+	return transport.OAuthConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  redirectURI,
+		Scopes:       config.Scopes,
+		TokenStore:   store,
+		// Use the token URL as the auth server metadata URL if available
+		AuthServerMetadataURL: config.TokenURL,
+		PKCEEnabled:           true,
+	}
+}
+
+// Login runs the OAuth authorization-code flow, with PKCE, for the server: the authorization
+// page is opened with openURL (e.g. in a browser), the authorization code is received by a
+// loopback redirect server, and the tokens are stored in the cache.
+// If no client ID is configured, kubectl-ai registers itself with the authorization server.
+func Login(ctx context.Context, serverCfg ServerConfig, cache *TokenCache, openURL func(url string) error) error {
+	if serverCfg.URL == "" || serverCfg.OAuthConfig == nil {
+		return fmt.Errorf("MCP server %q does not use OAuth (set url and oauth in its configuration)", serverCfg.Name)
+	}
+	serverURL, err := url.Parse(serverCfg.URL)
+	if err != nil {
+		return fmt.Errorf("parsing server URL: %w", err)
+	}
+
+	listener, redirectURI, err := listenForRedirect(serverCfg.OAuthConfig.RedirectURL)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	creds, err := cache.get(serverCfg.Name)
+	if err != nil {
+		return err
+	}
+	store := cache.TokenStore(serverCfg.Name)
+	handler := transport.NewOAuthHandler(oauthTransportConfig(serverCfg.OAuthConfig, cachedCredentials{}, redirectURI, store))
+	handler.SetBaseURL(serverURL.Scheme + "://" + serverURL.Host)
+
+	if serverCfg.OAuthConfig.ClientID == "" {
+		// The registration is tied to the redirect URI, whose port may change between logins.
+		if err := handler.RegisterClient(ctx, oauthClientName); err != nil {
+			return fmt.Errorf("registering kubectl-ai with the authorization server (or set oauth.client_id): %w", err)
+		}
+		creds.ClientID, creds.ClientSecret = handler.GetClientID(), handler.GetClientSecret()
+		klog.V(2).InfoS("Registered OAuth client", "server", serverCfg.Name, "clientID", creds.ClientID)
+	}
+
+	verifier, err := transport.GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("generating code verifier: %w", err)
+	}
+	state, err := transport.GenerateState()
+	if err != nil {
+		return fmt.Errorf("generating state: %w", err)
+	}
+	authURL, err := handler.GetAuthorizationURL(ctx, state, transport.GenerateCodeChallenge(verifier))
+	if err != nil {
+		return fmt.Errorf("building authorization URL: %w", err)
+	}
+
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		return fmt.Errorf("parsing redirect URI: %w", err)
+	}
+	responses := make(chan url.Values, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if errCode := query.Get("error"); errCode != "" {
+			fmt.Fprintf(w, "<p>Authorization failed: %s</p>", html.EscapeString(errCode+" "+query.Get("error_description")))
+		} else {
+			fmt.Fprint(w, "<p>kubectl-ai is authorized. You can close this window.</p>")
+		}
+		select {
+		case responses <- query:
+		default:
+		}
+	})
+	redirectServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go redirectServer.Serve(listener)
+	defer redirectServer.Close()
+
+	if err := openURL(authURL); err != nil {
+		return fmt.Errorf("opening authorization page: %w", err)
+	}
+
+	var query url.Values
+	select {
+	case query = <-responses:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		return fmt.Errorf("authorization failed: %s %s", errCode, query.Get("error_description"))
+	}
+
+	// Clear the previous token first, so that a failed exchange does not leave a stale one behind.
+	creds.Token = nil
+	if err := cache.update(serverCfg.Name, func(c *cachedCredentials) { *c = creds }); err != nil {
+		return err
+	}
+	if err := handler.ProcessAuthorizationResponse(ctx, query.Get("code"), query.Get("state"), verifier); err != nil {
+		return fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	klog.V(1).InfoS("Logged in to MCP server", "server", serverCfg.Name)
+	return nil
+}
+
+// listenForRedirect starts listening for the OAuth redirect on the loopback interface.
+// The port and path of the configured redirect URL are used, if any; by default the port is chosen by the system.
+func listenForRedirect(redirectURL string) (net.Listener, string, error) {
+	host, port, path := "127.0.0.1", "0", defaultRedirectPath
+	if redirectURL != "" {
+		u, err := url.Parse(redirectURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing redirect URL: %w", err)
+		}
+		if u.Scheme != "http" || (u.Hostname() != "127.0.0.1" && u.Hostname() != "localhost" && u.Hostname() != "::1") {
+			return nil, "", fmt.Errorf("redirect URL %q must be a loopback http URL, such as http://127.0.0.1:8085/callback", redirectURL)
+		}
+		host = u.Hostname()
+		if u.Port() != "" {
+			port = u.Port()
+		}
+		if u.Path != "" {
+			path = u.Path
+		}
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, "", fmt.Errorf("listening for the OAuth redirect: %w", err)
+	}
+	_, actualPort, _ := net.SplitHostPort(listener.Addr().String())
+	return listener, fmt.Sprintf("http://%s%s", net.JoinHostPort(host, actualPort), path), nil
+}
+
+// Logout removes the cached OAuth credentials of the server.
+// It reports whether there were any.
+func Logout(cache *TokenCache, server string) (bool, error) {
+	return cache.Delete(server)
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeOAuthServer is a stand-in authorization server, protecting an MCP server.
+// It supports dynamic client registration, the authorization-code flow with PKCE, and refresh tokens.
+type fakeOAuthServer struct {
+	t  *testing.T
+	ts *httptest.Server
+
+	mu             sync.Mutex
+	clientID       string
+	redirectURI    string
+	codeChallenges map[string]string
+	validTokens    map[string]bool
+	refreshTokens  map[string]bool
+	tokensIssued   int
+	refreshes      int
+}
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	f := &fakeOAuthServer{
+		t:              t,
+		codeChallenges: map[string]string{},
+		validTokens:    map[string]bool{},
+		refreshTokens:  map[string]bool{},
+	}
+	mcpServer := server.NewStreamableHTTPServer(newTestMCPServer())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                   f.ts.URL,
+			"authorization_endpoint":   f.ts.URL + "/authorize",
+			"token_endpoint":           f.ts.URL + "/token",
+			"registration_endpoint":    f.ts.URL + "/register",
+			"response_types_supported": []string{"code"},
+		})
+	})
+	mux.HandleFunc("/register", f.register)
+	mux.HandleFunc("/authorize", f.authorize)
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		valid := f.validTokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		f.mu.Unlock()
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mcpServer.ServeHTTP(w, r)
+	})
+	f.ts = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		f.ts.CloseClientConnections()
+		f.ts.Close()
+	})
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeOAuthServer) register(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.RedirectURIs) != 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client_metadata"})
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clientID = "registered-client"
+	f.redirectURI = req.RedirectURIs[0]
+	writeJSON(w, http.StatusCreated, map[string]string{"client_id": f.clientID})
+}
+
+func (f *fakeOAuthServer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if q.Get("client_id") != f.clientID || q.Get("redirect_uri") != f.redirectURI || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	code := fmt.Sprintf("code-%d", len(f.codeChallenges))
+	f.codeChallenges[code] = q.Get("code_challenge")
+	http.Redirect(w, r, fmt.Sprintf("%s?code=%s&state=%s", f.redirectURI, code, url.QueryEscape(q.Get("state"))), http.StatusFound)
+}
+
+func (f *fakeOAuthServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		challenge, ok := f.codeChallenges[r.PostForm.Get("code")]
+		if !ok || transport.GenerateCodeChallenge(r.PostForm.Get("code_verifier")) != challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(f.codeChallenges, r.PostForm.Get("code"))
+	case "refresh_token":
+		if !f.refreshTokens[r.PostForm.Get("refresh_token")] {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		f.refreshes++
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	f.tokensIssued++
+	accessToken := fmt.Sprintf("access-%d", f.tokensIssued)
+	refreshToken := fmt.Sprintf("refresh-%d", f.tokensIssued)
+	f.validTokens = map[string]bool{accessToken: true}
+	f.refreshTokens[refreshToken] = true
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  accessToken,
+		"token_type":    "bearer",
+		"refresh_token": refreshToken,
+		"expires_in":    3600,
+	})
+}
+
+// browse follows the authorization URL and its redirect to the loopback server, as the user's browser would.
+func browse(authURL string) error {
+	resp, err := http.Get(authURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authorization returned %s", resp.Status)
+	}
+	return nil
+}
+
+func TestOAuthLoginRefreshAndLogout(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	cache, err := DefaultTokenCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeOAuthServer(t)
+	cfg := ServerConfig{Name: "protected", URL: f.ts.URL + "/mcp", OAuthConfig: &OAuthConfig{Scopes: []string{"mcp"}}}
+	connect := func() error {
+		m := NewManager(&Config{Servers: []ServerConfig{cfg}})
+		defer m.Close()
+		client, err := m.connectServer(context.Background(), cfg)
+		if err != nil {
+			return err
+		}
+		_, err = client.CallTool(context.Background(), "echo", nil)
+		return err
+	}
+
+	if err := connect(); err == nil || !strings.Contains(err.Error(), "kubectl-ai mcp login protected") {
+		t.Fatalf("connecting before logging in = %v, want an error telling the user to log in", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := Login(ctx, cfg, cache, browse); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "kubectl-ai", tokenCacheFileName))
+	if err != nil {
+		t.Fatalf("reading token cache: %v", err)
+	}
+	if bytes.Contains(data, []byte("access-1")) || bytes.Contains(data, []byte("refresh-1")) {
+		t.Errorf("token cache contains the tokens in plain text")
+	}
+
+	if err := connect(); err != nil {
+		t.Fatalf("connecting after logging in: %v", err)
+	}
+
+	// Expire the cached token: the client refreshes it, and caches the new one.
+	store := cache.TokenStore("protected")
+	token, err := store.GetToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := store.SaveToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if err := connect(); err != nil {
+		t.Fatalf("connecting with an expired token: %v", err)
+	}
+	if f.refreshes != 1 {
+		t.Errorf("token was refreshed %d times, want 1", f.refreshes)
+	}
+	if token, err := store.GetToken(ctx); err != nil || token.AccessToken != "access-2" {
+		t.Errorf("cached token after refresh = %+v, %v; want access-2", token, err)
+	}
+
+	if removed, err := Logout(cache, "protected"); err != nil || !removed {
+		t.Fatalf("Logout() = %v, %v; want true", removed, err)
+	}
+	if _, err := store.GetToken(ctx); err != transport.ErrNoToken {
+		t.Errorf("GetToken() after logout = %v, want ErrNoToken", err)
+	}
+	if removed, err := Logout(cache, "protected"); err != nil || removed {
+		t.Errorf("second Logout() = %v, %v; want false", removed, err)
+	}
+}
+
+func TestTokenCacheRejectsWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewTokenCache(dir)
+	ctx := context.Background()
+	if err := cache.TokenStore("s").SaveToken(ctx, &transport.Token{AccessToken: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, tokenCacheKeyFileName), bytes.Repeat([]byte{1}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.TokenStore("s").GetToken(ctx); err == nil || !strings.Contains(err.Error(), "decrypting") {
+		t.Errorf("GetToken() with the wrong key = %v, want a decryption error", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	mcp "github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerResource describes a resource, or a template of resources, published by an MCP server.
+type ServerResource struct {
+	// URI is the resource URI, or the URI template (RFC 6570) for templates.
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+	Server      string `json:"server,omitempty"`
+	IsTemplate  bool   `json:"isTemplate,omitempty"`
+}
+
+// Matches reports whether uri is this resource, or may be read with this template.
+// Templates are matched on their literal prefix, which is enough to route a URI to a server.
+func (r ServerResource) Matches(uri string) bool {
+	if !r.IsTemplate {
+		return r.URI == uri
+	}
+	prefix, _, _ := strings.Cut(r.URI, "{")
+	return prefix != "" && strings.HasPrefix(uri, prefix)
+}
+
+// Prompt describes a prompt published by an MCP server.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+	Server      string           `json:"server,omitempty"`
+}
+
+// PromptArgument is an argument of a prompt.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is a message of a prompt, with its content rendered as text.
+type PromptMessage struct {
+	Role string
+	Text string
+}
+
+// PromptResult is a prompt, rendered with its arguments.
+type PromptResult struct {
+	Description string
+	Messages    []PromptMessage
+}
+
+// Text joins the text of the prompt's messages.
+func (p *PromptResult) Text() string {
+	var parts []string
+	for _, m := range p.Messages {
+		parts = append(parts, m.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// listClientResources implements the common ListResources functionality shared by both client types.
+// Servers that do not support resources have none.
+func listClientResources(ctx context.Context, client *mcpclient.Client, serverName string) ([]ServerResource, error) {
+	if err := ensureClientConnected(client); err != nil {
+		return nil, err
+	}
+	if client.GetServerCapabilities().Resources == nil {
+		return nil, nil
+	}
+
+	var resources []ServerResource
+	result, err := client.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing resources: %w", err)
+	}
+	for _, r := range result.Resources {
+		resources = append(resources, ServerResource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Description: r.Description,
+			MIMEType:    r.MIMEType,
+			Server:      serverName,
+		})
+	}
+
+	templates, err := client.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing resource templates: %w", err)
+	}
+	for _, t := range templates.ResourceTemplates {
+		resources = append(resources, ServerResource{
+			URI:         t.URITemplate.Raw(),
+			Name:        t.Name,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+			Server:      serverName,
+			IsTemplate:  true,
+		})
+	}
+	return resources, nil
+}
+
+// readClientResource implements the common ReadResource functionality shared by both client types.
+func readClientResource(ctx context.Context, client *mcpclient.Client, uri string) ([]Resource, error) {
+	if err := ensureClientConnected(client); err != nil {
+		return nil, err
+	}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := client.ReadResource(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("reading resource %q: %w", uri, err)
+	}
+
+	var resources []Resource
+	for _, content := range result.Contents {
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			resources = append(resources, Resource{URI: c.URI, MIMEType: c.MIMEType, Text: c.Text})
+		case mcp.BlobResourceContents:
+			data, err := base64.StdEncoding.DecodeString(c.Blob)
+			if err != nil {
+				return nil, fmt.Errorf("decoding resource %q: %w", c.URI, err)
+			}
+			resources = append(resources, Resource{URI: c.URI, MIMEType: c.MIMEType, Blob: data})
+		}
+	}
+	return resources, nil
+}
+
+// listClientPrompts implements the common ListPrompts functionality shared by both client types.
+// Servers that do not support prompts have none.
+func listClientPrompts(ctx context.Context, client *mcpclient.Client, serverName string) ([]Prompt, error) {
+	if err := ensureClientConnected(client); err != nil {
+		return nil, err
+	}
+	if client.GetServerCapabilities().Prompts == nil {
+		return nil, nil
+	}
+
+	result, err := client.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing prompts: %w", err)
+	}
+
+	var prompts []Prompt
+	for _, p := range result.Prompts {
+		prompt := Prompt{Name: p.Name, Description: p.Description, Server: serverName}
+		for _, arg := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{Name: arg.Name, Description: arg.Description, Required: arg.Required})
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// getClientPrompt implements the common GetPrompt functionality shared by both client types.
+func getClientPrompt(ctx context.Context, client *mcpclient.Client, name string, arguments map[string]string) (*PromptResult, error) {
+	if err := ensureClientConnected(client); err != nil {
+		return nil, err
+	}
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	result, err := client.GetPrompt(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("getting prompt %q: %w", name, err)
+	}
+
+	prompt := &PromptResult{Description: result.Description}
+	for _, m := range result.Messages {
+		var text string
+		switch c := m.Content.(type) {
+		case mcp.TextContent:
+			text = c.Text
+		case mcp.EmbeddedResource:
+			if rc, ok := c.Resource.(mcp.TextResourceContents); ok {
+				text = fmt.Sprintf("[resource %s]\n%s", rc.URI, rc.Text)
+			}
+		default:
+			// Images and audio in prompts are not supported; prompts are sent as text.
+			continue
+		}
+		prompt.Messages = append(prompt.Messages, PromptMessage{Role: string(m.Role), Text: text})
+	}
+	return prompt, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolResult is the result of a tool call, with all of its content.
+type ToolResult struct {
+	// IsError is set if the tool reported an error.
+	IsError bool
+	// Text holds the text content items, in order.
+	Text []string
+	// Structured is the tool's structured content, if any.
+	Structured any
+	// Images are the image content items.
+	Images []Image
+	// Resources are the embedded resources, resource links and audio content items.
+	Resources []Resource
+
+	// Content is the content as returned by the server.
+	Content []mcp.Content
+}
+
+// Image is an image returned by a tool.
+type Image struct {
+	MIMEType string
+	Data     []byte
+}
+
+// Resource is a resource returned by a tool, either embedded (with Text or Blob set)
+// or as a link to be read separately.
+type Resource struct {
+	URI      string
+	Name     string
+	MIMEType string
+	Text     string
+	Blob     []byte
+}
+
+// IsLink reports whether the resource is a link, without any content.
+func (r *Resource) IsLink() bool {
+	return r.Text == "" && r.Blob == nil
+}
+
+// newToolResult converts a tool call result, decoding any binary content.
+func newToolResult(result *mcp.CallToolResult) (*ToolResult, error) {
+	if result == nil {
+		return nil, fmt.Errorf("empty tool call result")
+	}
+	r := &ToolResult{
+		IsError:    result.IsError,
+		Structured: result.StructuredContent,
+		Content:    result.Content,
+	}
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			r.Text = append(r.Text, c.Text)
+		case mcp.ImageContent:
+			data, err := base64.StdEncoding.DecodeString(c.Data)
+			if err != nil {
+				return nil, fmt.Errorf("decoding image content: %w", err)
+			}
+			r.Images = append(r.Images, Image{MIMEType: c.MIMEType, Data: data})
+		case mcp.AudioContent:
+			data, err := base64.StdEncoding.DecodeString(c.Data)
+			if err != nil {
+				return nil, fmt.Errorf("decoding audio content: %w", err)
+			}
+			r.Resources = append(r.Resources, Resource{Name: "audio", MIMEType: c.MIMEType, Blob: data})
+		case mcp.ResourceLink:
+			r.Resources = append(r.Resources, Resource{URI: c.URI, Name: c.Name, MIMEType: c.MIMEType})
+		case mcp.EmbeddedResource:
+			switch rc := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				r.Resources = append(r.Resources, Resource{URI: rc.URI, MIMEType: rc.MIMEType, Text: rc.Text})
+			case mcp.BlobResourceContents:
+				data, err := base64.StdEncoding.DecodeString(rc.Blob)
+				if err != nil {
+					return nil, fmt.Errorf("decoding resource %q: %w", rc.URI, err)
+				}
+				r.Resources = append(r.Resources, Resource{URI: rc.URI, MIMEType: rc.MIMEType, Blob: data})
+			}
+		}
+	}
+	return r, nil
+}
+
+// String renders the result as text: the text content, then the structured content
+// if there was no text, then a short description of any other content.
+// Errors are rendered as a JSON error object.
+func (r *ToolResult) String() string {
+	var parts []string
+	parts = append(parts, r.Text...)
+	if len(r.Text) == 0 && r.Structured != nil {
+		if b, err := json.Marshal(r.Structured); err == nil {
+			parts = append(parts, string(b))
+		}
+	}
+	for _, image := range r.Images {
+		parts = append(parts, fmt.Sprintf("[image: %s, %d bytes]", image.MIMEType, len(image.Data)))
+	}
+	for _, resource := range r.Resources {
+		switch {
+		case resource.IsLink():
+			parts = append(parts, fmt.Sprintf("[resource link: %s]", resource.URI))
+		case resource.Text != "":
+			parts = append(parts, fmt.Sprintf("[resource %s]\n%s", resource.URI, resource.Text))
+		default:
+			parts = append(parts, fmt.Sprintf("[resource %s: %s, %d bytes]", resource.URI, resource.MIMEType, len(resource.Blob)))
+		}
+	}
+
+	text := strings.Join(parts, "\n")
+	if r.IsError {
+		return fmt.Sprintf(`{"error": true, "message": %q, "status": "failed"}`, text)
+	}
+	if text == "" {
+		return "Tool executed successfully, but no content was returned"
+	}
+	return text
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp
+
+import (
+	"encoding/base64"
+	"testing"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestToolResult(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("png"))
+	tests := []struct {
+		name   string
+		result *mcp.CallToolResult
+		want   string
+	}{
+		{
+			name:   "text",
+			result: mcp.NewToolResultText("hello"),
+			want:   "hello",
+		},
+		{
+			name:   "structured without text",
+			result: &mcp.CallToolResult{StructuredContent: map[string]any{"replicas": 3}},
+			want:   `{"replicas":3}`,
+		},
+		{
+			name: "image and resources",
+			result: &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewTextContent("see attached"),
+				mcp.NewImageContent(png, "image/png"),
+				mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///logs.txt", Text: "line 1"}),
+				mcp.NewResourceLink("file:///big.tar", "big.tar", "", "application/x-tar"),
+			}},
+			want: "see attached\n[image: image/png, 3 bytes]\n[resource file:///logs.txt]\nline 1\n[resource link: file:///big.tar]",
+		},
+		{
+			name:   "error",
+			result: mcp.NewToolResultError("not found"),
+			want:   `{"error": true, "message": "not found", "status": "failed"}`,
+		},
+		{
+			name:   "empty",
+			result: &mcp.CallToolResult{},
+			want:   "Tool executed successfully, but no content was returned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newToolResult(tt.result)
+			if err != nil {
+				t.Fatalf("newToolResult() error = %v", err)
+			}
+			if got := result.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newToolResult(&mcp.CallToolResult{Content: []mcp.Content{mcp.NewImageContent("not base64!", "image/png")}}); err == nil {
+		t.Errorf("expected an error for invalid image data")
+	}
+}
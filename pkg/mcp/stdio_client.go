@@ -141,12 +141,12 @@ func (c *stdioClient) ListTools(ctx context.Context) ([]Tool, error) {
 	return tools, nil
 }
 
-// CallTool calls a tool on the MCP server and returns the result as a string
-func (c *stdioClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (string, error) {
+// CallTool calls a tool on the MCP server and returns its result
+func (c *stdioClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*ToolResult, error) {
 	klog.V(2).InfoS("Calling MCP tool via stdio", "server", c.name, "tool", toolName)
 
 	if err := c.ensureConnected(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Create v0.31.0 compatible request
@@ -160,8 +160,30 @@ func (c *stdioClient) CallTool(ctx context.Context, toolName string, arguments m
 	// Call the tool on the MCP server
 	result, err := c.client.CallTool(ctx, request)
 	if err != nil {
-		return "", fmt.Errorf("error calling tool %s: %w", toolName, err)
+		return nil, fmt.Errorf("error calling tool %s: %w", toolName, err)
 	}
 
-	return processToolResponse(result)
+	return newToolResult(result)
+}
+
+// ListResources lists the resources and resource templates published by the MCP server
+func (c *stdioClient) ListResources(ctx context.Context) ([]ServerResource, error) {
+	return listClientResources(ctx, c.client, c.name)
+}
+
+// ReadResource reads a resource from the MCP server
+func (c *stdioClient) ReadResource(ctx context.Context, uri string) ([]Resource, error) {
+	klog.V(2).InfoS("Reading MCP resource via stdio", "server", c.name, "uri", uri)
+	return readClientResource(ctx, c.client, uri)
+}
+
+// ListPrompts lists the prompts published by the MCP server
+func (c *stdioClient) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	return listClientPrompts(ctx, c.client, c.name)
+}
+
+// GetPrompt renders a prompt of the MCP server with the given arguments
+func (c *stdioClient) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*PromptResult, error) {
+	klog.V(2).InfoS("Getting MCP prompt via stdio", "server", c.name, "prompt", name)
+	return getClientPrompt(ctx, c.client, name, arguments)
 }
@@ -373,3 +373,16 @@ func IsBoolParam(name string) bool {
 
 	return false
 }
+
+// klogLogger sends the logs of the MCP library to klog, rather than to stderr.
+type klogLogger struct{}
+
+func (klogLogger) Infof(format string, v ...any) {
+	klog.V(3).Infof(format, v...)
+}
+
+// Errorf logs at a verbose level, as the library reports expected conditions (e.g. a server
+// going away while listening for notifications) as errors.
+func (klogLogger) Errorf(format string, v ...any) {
+	klog.V(2).Infof(format, v...)
+}
@@ -0,0 +1,267 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact masks credentials and other secrets in tool output before it
+// reaches the LLM, the trace file or the session history.
+package redact
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Counts records how many values were redacted, by rule name.
+type Counts map[string]int
+
+// Total returns the total number of redacted values.
+func (c Counts) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// Add merges other into c.
+func (c Counts) Add(other Counts) {
+	for name, n := range other {
+		c[name] += n
+	}
+}
+
+// String summarizes the counts, e.g. "3 values (secret-data: 2, bearer-token: 1)".
+func (c Counts) String() string {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %d", name, c[name]))
+	}
+	noun := "values"
+	if c.Total() == 1 {
+		noun = "value"
+	}
+	return fmt.Sprintf("%d %s (%s)", c.Total(), noun, strings.Join(parts, ", "))
+}
+
+// rule masks matches of a regular expression. If the expression has a group named
+// "value", only that group is masked, so keys such as `token:` stay readable.
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// defaultRules catch credentials in free-form text.
+var defaultRules = []rule{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"private-key", regexp.MustCompile(`"private_key"\s*:\s*"(?P<value>[^"]+)"`)},
+	{"kubeconfig-credential", regexp.MustCompile(`(?m)^\s*(?:- )?(?:token|client-key-data|password|id-token|refresh-token|access-token|client-secret|idp-certificate-authority-data)\s*:\s*["']?(?P<value>[^\s"']+)`)},
+	{"bearer-token", regexp.MustCompile(`(?i)\b(?:bearer|authorization:\s*basic)\s+(?P<value>[A-Za-z0-9\-._~+/]{8,}=*)`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-key", regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*["']?(?P<value>[A-Za-z0-9/+=]{40})`)},
+	{"gcp-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z\-_]{35}\b`)},
+	{"azure-storage-key", regexp.MustCompile(`(?i)AccountKey=(?P<value>[A-Za-z0-9+/=]{40,})`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"api-key", regexp.MustCompile(`\bsk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"env-secret", regexp.MustCompile(`(?i)name:\s*["']?[A-Za-z_]*(?:password|secret|token|api_?key)[A-Za-z_]*["']?\s*\n\s*value:\s*["']?(?P<value>[^\s"']+)`)},
+	{"env-secret", regexp.MustCompile(`(?i)"name":\s*"[A-Za-z_]*(?:password|secret|token|api_?key)[A-Za-z_]*",\s*"value":\s*"(?P<value>[^"]+)"`)},
+	{"password", regexp.MustCompile(`(?i)[A-Za-z_]*(?:password|passwd|secret_key|api_key|apikey|access_token|auth_token)[A-Za-z_]*\s*[=:]\s*["']?(?P<value>[^\s"'<>]{4,})`)},
+}
+
+// secretCommand matches commands that print values of Secrets in a way we cannot
+// parse back, e.g. with jsonpath or by piping them through base64.
+var (
+	secretCommand    = regexp.MustCompile(`\bkubectl\b.*\b(?:get|describe|view-secret)\s+(?:[^|;&]*\s)?(?:secrets?|secret/\S+)\b`)
+	secretExtraction = regexp.MustCompile(`(?:-o\s*=?\s*['"]?(?:jsonpath|go-template|template|custom-columns)|--template|base64|\bjq\b|\byq\b)`)
+)
+
+// Redactor masks secrets in tool output.
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a Redactor with the built-in rules plus the given extra regular expressions.
+// Extra expressions may use a group named "value" to mask only part of a match.
+func New(extraPatterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule{}, defaultRules...)}
+	for _, pattern := range extraPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling redaction pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, rule{name: "custom", re: re})
+	}
+	return r, nil
+}
+
+// RedactCommandOutput redacts the output of a shell command. Knowing the command lets it
+// mask output that reveals Secret values but has no recognizable structure.
+func (r *Redactor) RedactCommandOutput(command, output string) (string, Counts) {
+	if output != "" && secretCommand.MatchString(command) && secretExtraction.MatchString(command) {
+		return "[REDACTED: output of a command reading Secret values]", Counts{"secret-data": 1}
+	}
+	return r.RedactText(output)
+}
+
+// RedactText masks Secret data in YAML or JSON manifests, and any credentials
+// matched by the redaction rules.
+func (r *Redactor) RedactText(text string) (string, Counts) {
+	counts := Counts{}
+	if text == "" {
+		return text, counts
+	}
+	text = redactSecretManifests(text, counts)
+	for _, rule := range r.rules {
+		valueGroup := rule.re.SubexpIndex("value")
+		text = rule.re.ReplaceAllStringFunc(text, func(match string) string {
+			if valueGroup < 0 {
+				counts[rule.name]++
+				return placeholder(rule.name)
+			}
+			loc := rule.re.FindStringSubmatchIndex(match)
+			start, end := loc[2*valueGroup], loc[2*valueGroup+1]
+			if start < 0 || alreadyRedacted(match[start:end]) {
+				return match
+			}
+			counts[rule.name]++
+			return match[:start] + placeholder(rule.name) + match[end:]
+		})
+	}
+	for name, n := range counts {
+		if n == 0 {
+			delete(counts, name)
+		}
+	}
+	return text, counts
+}
+
+func placeholder(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// alreadyRedacted reports whether a value was masked already, by us or by kubectl.
+func alreadyRedacted(value string) bool {
+	return strings.HasPrefix(value, "[REDACTED") || value == "REDACTED" || value == "DATA+OMITTED"
+}
+
+// redactSecretManifests masks the data and stringData of Secrets in text that is a YAML
+// or JSON manifest (or list of manifests). Other text is returned unchanged.
+func redactSecretManifests(text string, counts Counts) string {
+	if !strings.Contains(text, "Secret") {
+		return text
+	}
+	trimmed := strings.TrimSpace(text)
+	isJSON := strings.HasPrefix(trimmed, "{")
+
+	docs := []string{trimmed}
+	if !isJSON {
+		docs = splitYAMLDocuments(trimmed)
+	}
+	changed := false
+	for i, doc := range docs {
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || obj == nil {
+			continue
+		}
+		if !redactObject(obj, counts) {
+			continue
+		}
+		changed = true
+		var out []byte
+		var err error
+		if isJSON {
+			out, err = json.MarshalIndent(obj, "", "    ")
+		} else {
+			out, err = yaml.Marshal(obj)
+		}
+		if err != nil {
+			continue
+		}
+		docs[i] = strings.TrimSuffix(string(out), "\n")
+	}
+	if !changed {
+		return text
+	}
+	return strings.Join(docs, "\n---\n") + "\n"
+}
+
+func splitYAMLDocuments(text string) []string {
+	var docs []string
+	for _, doc := range regexp.MustCompile(`(?m)^---\s*$`).Split(text, -1) {
+		if strings.TrimSpace(doc) != "" {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// redactObject masks Secret values in a decoded Kubernetes object, recursing into lists.
+// It reports whether anything was changed.
+func redactObject(obj map[string]any, counts Counts) bool {
+	changed := false
+	if items, ok := obj["items"].([]any); ok {
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok && redactObject(m, counts) {
+				changed = true
+			}
+		}
+	}
+	if obj["kind"] != "Secret" {
+		return changed
+	}
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := obj[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, value := range data {
+			s, _ := value.(string)
+			if alreadyRedacted(s) {
+				continue
+			}
+			size := len(s)
+			if field == "data" {
+				if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+					size = len(decoded)
+				}
+			}
+			data[key] = fmt.Sprintf("[REDACTED:secret-data, %d bytes]", size)
+			counts["secret-data"]++
+			changed = true
+		}
+	}
+	// The last applied configuration annotation holds a full copy of the Secret.
+	if metadata, ok := obj["metadata"].(map[string]any); ok {
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			const lastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+			if _, ok := annotations[lastApplied]; ok {
+				annotations[lastApplied] = placeholder("secret-data")
+				counts["secret-data"]++
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,382 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+)
+
+// ExportFormat is the format of an exported session.
+type ExportFormat string
+
+const (
+	ExportFormatMarkdown ExportFormat = "md"
+	ExportFormatHTML     ExportFormat = "html"
+	ExportFormatJSON     ExportFormat = "json"
+)
+
+// ParseExportFormat parses the name of an export format.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "md", "markdown":
+		return ExportFormatMarkdown, nil
+	case "html":
+		return ExportFormatHTML, nil
+	case "json":
+		return ExportFormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (use md, html or json)", s)
+}
+
+// ExportOptions configures the export of a session.
+type ExportOptions struct {
+	Format ExportFormat
+	// MaxOutputLines truncates the output of tool calls to their first lines. 0 keeps the whole output.
+	MaxOutputLines int
+	// Redactor masks secrets in the report. If nil, the report is not redacted.
+	Redactor *redact.Redactor
+}
+
+// Report is a session prepared for export, e.g. as an incident report.
+type Report struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	ProviderID   string    `json:"providerID,omitempty"`
+	ModelID      string    `json:"modelID,omitempty"`
+	CreatedAt    time.Time `json:"createdAt,omitzero"`
+	LastAccessed time.Time `json:"lastAccessed,omitzero"`
+	// Started and Ended are the times of the first and last events.
+	Started time.Time `json:"started,omitzero"`
+	Ended   time.Time `json:"ended,omitzero"`
+
+	Events []*ReportEvent `json:"events"`
+	// Redactions is the number of secrets masked in the report.
+	Redactions int `json:"redactions,omitempty"`
+}
+
+// ReportEventType is the type of an event of a report.
+type ReportEventType string
+
+const (
+	ReportEventQuestion ReportEventType = "question"
+	ReportEventAnswer   ReportEventType = "answer"
+	ReportEventToolCall ReportEventType = "tool-call"
+	ReportEventApproval ReportEventType = "approval"
+	ReportEventError    ReportEventType = "error"
+	ReportEventNote     ReportEventType = "note"
+)
+
+// ReportEvent is something that happened in a session.
+type ReportEvent struct {
+	Type ReportEventType `json:"type"`
+	Time time.Time       `json:"time,omitzero"`
+	// Text is the text of questions, answers, errors and notes, and the prompt of approvals.
+	Text string `json:"text,omitempty"`
+
+	// Tool, Command, Output and Completed describe tool calls. Command is the command run by the tool,
+	// or its arguments if it does not run commands.
+	Tool            string    `json:"tool,omitempty"`
+	Command         string    `json:"command,omitempty"`
+	Output          string    `json:"output,omitempty"`
+	OutputTruncated bool      `json:"outputTruncated,omitempty"`
+	Completed       time.Time `json:"completed,omitzero"`
+
+	// Decision, Option and User describe approvals: Decision is the value of the option chosen
+	// (e.g. yes or no), Option its label and User who chose it, if known.
+	Decision string `json:"decision,omitempty"`
+	Option   string `json:"option,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
+// Duration returns how long a tool call took, or 0 if it is not known.
+func (e *ReportEvent) Duration() time.Duration {
+	if e.Time.IsZero() || e.Completed.IsZero() {
+		return 0
+	}
+	return e.Completed.Sub(e.Time).Round(time.Millisecond)
+}
+
+// Duration returns how long the session lasted.
+func (r *Report) Duration() time.Duration {
+	if r.Started.IsZero() || r.Ended.IsZero() {
+		return 0
+	}
+	return r.Ended.Sub(r.Started).Round(time.Second)
+}
+
+// Export writes the session as a report.
+func (s *Session) Export(w io.Writer, opts ExportOptions) error {
+	metadata, err := s.LoadMetadata()
+	if err != nil {
+		return fmt.Errorf("loading session metadata: %w", err)
+	}
+	return Export(w, NewReport(s.ID, metadata, s.ChatMessages(), opts), opts.Format)
+}
+
+// Export writes a report in the given format.
+func Export(w io.Writer, report *Report, format ExportFormat) error {
+	switch format {
+	case ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case ExportFormatHTML:
+		return htmlReportTemplate.Execute(w, report)
+	case ExportFormatMarkdown, "":
+		return markdownReportTemplate.Execute(w, report)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// NewReport builds the report of the messages of a session.
+func NewReport(id string, metadata *Metadata, messages []*api.Message, opts ExportOptions) *Report {
+	report := &Report{ID: id}
+	if metadata != nil {
+		report.Name = metadata.Name
+		report.ProviderID = metadata.ProviderID
+		report.ModelID = metadata.ModelID
+		report.CreatedAt = metadata.CreatedAt
+		report.LastAccessed = metadata.LastAccessed
+	}
+	redactText := func(text string) string {
+		if opts.Redactor == nil {
+			return text
+		}
+		redacted, counts := opts.Redactor.RedactText(text)
+		report.Redactions += counts.Total()
+		return redacted
+	}
+
+	// Tool calls run one after the other, so a result is of the first call waiting for one.
+	// The call is matched by ID too when messages have it.
+	type pendingCall struct {
+		event *ReportEvent
+		id    string
+	}
+	var waiting []pendingCall
+	var choiceRequest *api.UserChoiceRequest
+	for _, message := range messages {
+		if !message.Timestamp.IsZero() {
+			if report.Started.IsZero() {
+				report.Started = message.Timestamp
+			}
+			report.Ended = message.Timestamp
+		}
+		event := &ReportEvent{Time: message.Timestamp}
+
+		switch message.Type {
+		case api.MessageTypeText:
+			text, _ := message.Payload.(string)
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			switch message.Source {
+			case api.MessageSourceUser:
+				event.Type = ReportEventQuestion
+			case api.MessageSourceModel:
+				event.Type = ReportEventAnswer
+			default:
+				event.Type = ReportEventNote
+			}
+			event.Text = redactText(text)
+
+		case api.MessageTypeError:
+			event.Type = ReportEventError
+			event.Text = redactText(strings.TrimSpace(fmt.Sprint(message.Payload)))
+
+		case api.MessageTypeToolCallRequest:
+			event.Type = ReportEventToolCall
+			if message.ToolCall != nil {
+				event.Tool = message.ToolCall.Name
+				event.Command = toolCallCommand(message.ToolCall.Arguments)
+			}
+			if event.Command == "" {
+				event.Command = fmt.Sprint(message.Payload)
+			}
+			event.Command = redactText(event.Command)
+			call := pendingCall{event: event}
+			if message.ToolCall != nil {
+				call.id = message.ToolCall.ID
+			}
+			waiting = append(waiting, call)
+
+		case api.MessageTypeToolCallResponse:
+			for i, pending := range waiting {
+				if message.ToolCall != nil && pending.id != message.ToolCall.ID {
+					continue
+				}
+				call := pending.event
+				output := toolOutput(message.Payload)
+				if opts.Redactor != nil {
+					redacted, counts := opts.Redactor.RedactCommandOutput(call.Command, output)
+					report.Redactions += counts.Total()
+					output = redacted
+				}
+				call.Output, call.OutputTruncated = truncateLines(output, opts.MaxOutputLines)
+				call.Completed = message.Timestamp
+				waiting = append(waiting[:i], waiting[i+1:]...)
+				break
+			}
+			continue
+
+		case api.MessageTypeUserChoiceRequest:
+			choiceRequest = &api.UserChoiceRequest{}
+			if err := decodePayload(message.Payload, choiceRequest); err != nil {
+				choiceRequest = nil
+			}
+			continue
+
+		case api.MessageTypeUserChoiceResponse:
+			var choice api.UserChoiceResponse
+			if err := decodePayload(message.Payload, &choice); err != nil {
+				continue
+			}
+			event.Type = ReportEventApproval
+			event.User = choice.User
+			event.Decision = fmt.Sprintf("option %d", choice.Choice)
+			if choiceRequest != nil {
+				event.Text = redactText(choiceRequest.Prompt)
+				if choice.Choice >= 1 && choice.Choice <= len(choiceRequest.Options) {
+					option := choiceRequest.Options[choice.Choice-1]
+					event.Decision, event.Option = option.Value, option.Label
+				}
+			}
+			choiceRequest = nil
+
+		default:
+			continue
+		}
+		report.Events = append(report.Events, event)
+	}
+	return report
+}
+
+// toolCallCommand returns the command run by a tool call, or its arguments as JSON.
+func toolCallCommand(arguments map[string]any) string {
+	if command, ok := arguments["command"].(string); ok {
+		return command
+	}
+	if len(arguments) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Sprint(arguments)
+	}
+	return string(b)
+}
+
+// toolOutput returns the output of a tool call as text: the stdout and stderr of commands,
+// or the result of other tools.
+func toolOutput(payload any) string {
+	var result map[string]any
+	if err := decodePayload(payload, &result); err != nil || result == nil {
+		return fmt.Sprint(payload)
+	}
+	var parts []string
+	for _, key := range []string{"stdout", "stderr", "error", "content"} {
+		if text, ok := result[key].(string); ok && text != "" {
+			parts = append(parts, strings.TrimRight(text, "\n"))
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "\n")
+	}
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Sprint(payload)
+	}
+	return string(b)
+}
+
+// decodePayload converts the payload of a message into v. Payloads are Go values in a running session,
+// and their JSON decoding once loaded from disk.
+func decodePayload(payload any, v any) error {
+	if s, ok := payload.(string); ok {
+		if sp, ok := v.(*map[string]any); ok {
+			*sp = map[string]any{"content": s}
+			return nil
+		}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// truncateLines keeps the first maxLines lines of text, if maxLines > 0.
+func truncateLines(text string, maxLines int) (string, bool) {
+	if maxLines <= 0 {
+		return text, false
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) <= maxLines {
+		return text, false
+	}
+	return strings.TrimRight(strings.Join(lines[:maxLines], ""), "\n"), true
+}
+
+// fence returns a Markdown code fence longer than any run of backticks in text.
+func fence(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// quote quotes text as a Markdown blockquote.
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
+
+var reportFuncs = map[string]any{
+	"fence": fence,
+	"quote": quote,
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"clock": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("15:04:05")
+	},
+}
+
+//go:embed export_report.md.tmpl
+var markdownReportSource string
+
+//go:embed export_report.html.tmpl
+var htmlReportSource string
+
+var (
+	markdownReportTemplate = template.Must(template.New("report").Funcs(reportFuncs).Parse(markdownReportSource))
+	htmlReportTemplate     = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(htmlReportSource))
+)
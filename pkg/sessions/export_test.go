@@ -0,0 +1,183 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+)
+
+// incidentMessages is a session in which the user approves a command and the model answers.
+func incidentMessages() []*api.Message {
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	call := &api.ToolCall{ID: "call-1", Name: "kubectl", Arguments: map[string]any{"command": "kubectl logs web-0"}}
+	return []*api.Message{
+		{ID: "1", Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "why is web-0 crashing?", Timestamp: at(0)},
+		{ID: "2", Source: api.MessageSourceModel, Type: api.MessageTypeToolCallRequest, Payload: "Running: kubectl logs web-0", ToolCall: call, Timestamp: at(2)},
+		{ID: "3", Source: api.MessageSourceAgent, Type: api.MessageTypeUserChoiceRequest, Timestamp: at(3), Payload: &api.UserChoiceRequest{
+			Prompt: "Do you want to proceed?",
+			Options: []api.UserChoiceOption{
+				{Value: "yes", Label: "Yes"},
+				{Value: "yes_and_dont_ask_me_again", Label: "Yes, and don't ask me again"},
+				{Value: "no", Label: "No"},
+			},
+		}},
+		{ID: "4", Source: api.MessageSourceUser, Type: api.MessageTypeUserChoiceResponse, Payload: &api.UserChoiceResponse{Choice: 1, User: "alice@example.com"}, Timestamp: at(10)},
+		{ID: "5", Source: api.MessageSourceAgent, Type: api.MessageTypeToolCallResponse, ToolCall: call, Timestamp: at(12), Payload: map[string]any{
+			"command": "kubectl logs web-0",
+			"stdout":  "connecting with password=hunter22\nline 2\nline 3\nline 4\n",
+		}},
+		{ID: "6", Source: api.MessageSourceAgent, Type: api.MessageTypeError, Payload: "Error: tool timed out\n", Timestamp: at(20)},
+		{ID: "7", Source: api.MessageSourceModel, Type: api.MessageTypeText, Payload: "The database password is wrong.", Timestamp: at(30)},
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := &Metadata{Name: "web outage", ProviderID: "gemini", ModelID: "gemini-2.5-pro"}
+	report := NewReport("20250601-1", metadata, incidentMessages(), ExportOptions{MaxOutputLines: 2, Redactor: redactor})
+
+	var types []ReportEventType
+	for _, event := range report.Events {
+		types = append(types, event.Type)
+	}
+	wantTypes := []ReportEventType{ReportEventQuestion, ReportEventToolCall, ReportEventApproval, ReportEventError, ReportEventAnswer}
+	if len(types) != len(wantTypes) {
+		t.Fatalf("event types = %v, want %v", types, wantTypes)
+	}
+	for i := range types {
+		if types[i] != wantTypes[i] {
+			t.Fatalf("event types = %v, want %v", types, wantTypes)
+		}
+	}
+
+	call := report.Events[1]
+	if call.Tool != "kubectl" || call.Command != "kubectl logs web-0" {
+		t.Errorf("tool call = %q %q, want kubectl %q", call.Tool, call.Command, "kubectl logs web-0")
+	}
+	if strings.Contains(call.Output, "hunter22") {
+		t.Errorf("expected the password to be redacted, got %q", call.Output)
+	}
+	if !call.OutputTruncated || strings.Contains(call.Output, "line 3") {
+		t.Errorf("expected the output to be truncated to 2 lines, got %q", call.Output)
+	}
+	if call.Duration() != 10*time.Second {
+		t.Errorf("tool call duration = %v, want 10s", call.Duration())
+	}
+
+	approval := report.Events[2]
+	if approval.Decision != "yes" || approval.Option != "Yes" || approval.User != "alice@example.com" {
+		t.Errorf("approval = %q %q by %q, want yes Yes by alice@example.com", approval.Decision, approval.Option, approval.User)
+	}
+	if report.Redactions != 1 {
+		t.Errorf("redactions = %d, want 1", report.Redactions)
+	}
+	if report.Duration() != 30*time.Second {
+		t.Errorf("report duration = %v, want 30s", report.Duration())
+	}
+}
+
+func TestNewReportFromSavedSession(t *testing.T) {
+	// Once saved and loaded again, payloads are the JSON decoding of the Go values.
+	b, err := json.Marshal(incidentMessages())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []*api.Message
+	if err := json.Unmarshal(b, &messages); err != nil {
+		t.Fatal(err)
+	}
+	report := NewReport("20250601-1", nil, messages, ExportOptions{})
+	if len(report.Events) != 5 {
+		t.Fatalf("got %d events, want 5", len(report.Events))
+	}
+	if approval := report.Events[2]; approval.Option != "Yes" || approval.User != "alice@example.com" {
+		t.Errorf("approval = %q by %q, want Yes by alice@example.com", approval.Option, approval.User)
+	}
+	if output := report.Events[1].Output; !strings.Contains(output, "line 4") {
+		t.Errorf("expected the whole output, got %q", output)
+	}
+}
+
+func TestExport(t *testing.T) {
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := &Metadata{ProviderID: "gemini", ModelID: "gemini-2.5-pro"}
+	report := NewReport("20250601-1", metadata, incidentMessages(), ExportOptions{Redactor: redactor})
+
+	tests := []struct {
+		format ExportFormat
+		want   []string
+	}{
+		{
+			format: ExportFormatMarkdown,
+			want: []string{
+				"# kubectl-ai session 20250601-1",
+				"| Model | `gemini-2.5-pro` |",
+				"> why is web-0 crashing?",
+				"Tool call: kubectl (10s)",
+				"```shell\nkubectl logs web-0\n```",
+				"**10:00:10 Approval: Yes** (by alice@example.com)",
+				"Error:** Error: tool timed out",
+				"The database password is wrong.",
+			},
+		},
+		{
+			format: ExportFormatHTML,
+			want: []string{
+				"<h1>kubectl-ai session 20250601-1</h1>",
+				"<pre>kubectl logs web-0</pre>",
+				"Approval: Yes (by alice@example.com)",
+				"why is web-0 crashing?",
+			},
+		},
+		{
+			format: ExportFormatJSON,
+			want: []string{
+				`"modelID": "gemini-2.5-pro"`,
+				`"type": "approval"`,
+				`"user": "alice@example.com"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Export(&out, report, tt.format); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if strings.Contains(out.String(), "hunter22") {
+				t.Errorf("expected the password to be redacted:\n%s", out.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected the report to contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -33,6 +33,10 @@ const (
 
 // Metadata contains metadata about a session
 type Metadata struct {
+	// Name is a name the user gave the session, if any.
+	Name string `json:"name,omitempty"`
+	// Owner is the user of the web UI who created or first opened the session, if the UI authenticates its users.
+	Owner        string    `json:"owner,omitempty"`
 	ProviderID   string    `json:"providerID"`
 	ModelID      string    `json:"modelID"`
 	CreatedAt    time.Time `json:"createdAt"`
@@ -88,6 +92,31 @@ func (s *Session) UpdateLastAccessed() error {
 	return s.SaveMetadata(m)
 }
 
+// Rename sets the name of the session in the metadata.
+func (s *Session) Rename(name string) error {
+	m, err := s.LoadMetadata()
+	if err != nil {
+		return err
+	}
+	m.Name = name
+	return s.SaveMetadata(m)
+}
+
+// Claim makes user the owner of the session if it has none, and returns its owner.
+func (s *Session) Claim(user string) (string, error) {
+	m, err := s.LoadMetadata()
+	if err != nil {
+		return "", err
+	}
+	if m.Owner == "" {
+		m.Owner = user
+		if err := s.SaveMetadata(m); err != nil {
+			return "", err
+		}
+	}
+	return m.Owner, nil
+}
+
 // AddChatMessage appends a new message to the history and persists it to the sessions's history file.
 func (s *Session) AddChatMessage(msg *api.Message) error {
 	s.mu.Lock()
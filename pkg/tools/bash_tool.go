@@ -30,10 +30,6 @@ import (
 	"k8s.io/klog/v2"
 )
 
-func init() {
-	RegisterTool(&BashTool{})
-}
-
 const (
 	defaultBashBin = "/bin/bash"
 )
@@ -110,6 +106,9 @@ func (t *BashTool) Run(ctx context.Context, args map[string]any) (any, error) {
 		return &ExecResult{Command: command, Error: "port-forwarding is not allowed because assistant is running in an unattended mode, please try some other alternative"}, nil
 	}
 
+	kubeContext, namespace := kubeTargetFromContext(ctx)
+	command = InjectKubeTarget(command, kubeContext, namespace)
+
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
 		cmd = exec.CommandContext(ctx, os.Getenv("COMSPEC"), "/c", command)
@@ -117,16 +116,34 @@ func (t *BashTool) Run(ctx context.Context, args map[string]any) (any, error) {
 		cmd = exec.CommandContext(ctx, lookupBashBin(), "-c", command)
 	}
 	cmd.Dir = workDir
-	cmd.Env = os.Environ()
+	env, remove, err := kubeconfigEnv(ctx, kubeconfig, kubeContext, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+	cmd.Env = env
+
+	return executeCommand(ctx, cmd)
+}
+
+// kubeconfigEnv returns the environment for a command, with KUBECONFIG pinning the kube target.
+// remove deletes the kubeconfig written for the target once the command is done.
+func kubeconfigEnv(ctx context.Context, kubeconfig, kubeContext, namespace string) (env []string, remove func(), err error) {
 	if kubeconfig != "" {
-		kubeconfig, err := expandShellVar(kubeconfig)
+		kubeconfig, err = expandShellVar(kubeconfig)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
 	}
-
-	return executeCommand(ctx, cmd)
+	kubeconfig, remove, err = pinKubeTarget(ctx, kubeconfig, kubeContext, namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("selecting kube context %q and namespace %q: %w", kubeContext, namespace, err)
+	}
+	env = os.Environ()
+	if kubeconfig != "" {
+		env = append(env, "KUBECONFIG="+kubeconfig)
+	}
+	return env, remove, nil
 }
 
 type ExecResult struct {
@@ -310,14 +327,36 @@ func (t *BashTool) IsInteractive(args map[string]any) (bool, error) {
 // This is used for permission checks before command execution
 // Returns "yes", "no", or "unknown"
 func (t *BashTool) CheckModifiesResource(args map[string]any) string {
+	return t.CheckModifiesResourceContext(context.Background(), args)
+}
+
+// CheckModifiesResourceContext is CheckModifiesResource for a command running in the
+// working directory of the context, against which relative paths are resolved.
+func (t *BashTool) CheckModifiesResourceContext(ctx context.Context, args map[string]any) string {
 	command, ok := args["command"].(string)
 	if !ok {
 		return "unknown"
 	}
 
+	workDir, _ := ctx.Value(WorkDirKey).(string)
 	if strings.Contains(command, "kubectl") {
-		return kubectlModifiesResource(command)
+		return kubectlModifiesResource(command, workDir)
 	}
 
-	return "unknown"
+	return AnalyzeShellCommand(command, workDir).ModifiesResource()
+}
+
+// ExplainRisk describes any risky patterns found in the command, for the approval prompt.
+func (t *BashTool) ExplainRisk(args map[string]any) string {
+	return t.ExplainRiskContext(context.Background(), args)
+}
+
+// ExplainRiskContext is ExplainRisk for a command running in the working directory of the context.
+func (t *BashTool) ExplainRiskContext(ctx context.Context, args map[string]any) string {
+	command, ok := args["command"].(string)
+	if !ok {
+		return ""
+	}
+	workDir, _ := ctx.Value(WorkDirKey).(string)
+	return AnalyzeShellCommand(command, workDir).Explanation()
 }
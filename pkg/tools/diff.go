@@ -0,0 +1,341 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change.
+	diffContextLines = 3
+	// maxDiffCells bounds the size of the LCS table; larger inputs are shown as a full replacement.
+	maxDiffCells = 4_000_000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits text into lines, without line terminators.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line-based edit script turning a into b.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n*m > maxDiffCells {
+		var ops []diffOp
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// UnifiedDiff returns a unified diff between oldText and newText for the file at path.
+// It returns an empty string if the texts are identical.
+func UnifiedDiff(path, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", path, path)
+
+	// Walk the edit script, emitting hunks for each run of changes plus context.
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// Extend the hunk until we see more than 2*context unchanged lines.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				break
+			}
+			end = run
+		}
+
+		before := min(diffContextLines, start)
+		after := 0
+		for after < diffContextLines && end+after < len(ops) && ops[end+after].kind == ' ' {
+			after++
+		}
+
+		hunk := ops[start-before : end+after]
+		oldCount, newCount := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		oldStart, newStart := oldLine-before, newLine-before
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range hunk {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+
+		for _, op := range ops[start : end+after] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		start = end + after
+	}
+	return sb.String()
+}
+
+type patchHunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+}
+
+// parseUnifiedDiff parses the hunks of a single-file unified diff.
+// Line counts in hunk headers are used to tell body lines from file headers,
+// but miscounted hunks (common in generated patches) are tolerated.
+func parseUnifiedDiff(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var current *patchHunk
+	oldRemaining, newRemaining := 0, 0
+	fileHeaders := 0
+
+	for _, line := range strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n") {
+		inBody := current != nil && (oldRemaining > 0 || newRemaining > 0)
+		if !inBody {
+			switch {
+			case strings.HasPrefix(line, "--- "):
+				fileHeaders++
+				if fileHeaders > 1 {
+					return nil, fmt.Errorf("patch modifies more than one file")
+				}
+				current = nil
+				continue
+			case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+				current = nil
+				continue
+			case strings.HasPrefix(line, "@@"):
+				oldStart, oldCount, newCount, err := parseHunkHeader(line)
+				if err != nil {
+					return nil, err
+				}
+				hunks = append(hunks, patchHunk{oldStart: oldStart})
+				current = &hunks[len(hunks)-1]
+				oldRemaining, newRemaining = oldCount, newCount
+				continue
+			case current == nil, line == "":
+				// Preamble, or trailing blank lines after a hunk.
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file"
+		case strings.HasPrefix(line, "-"):
+			current.oldLines = append(current.oldLines, line[1:])
+			oldRemaining--
+		case strings.HasPrefix(line, "+"):
+			current.newLines = append(current.newLines, line[1:])
+			newRemaining--
+		case strings.HasPrefix(line, " "), line == "":
+			// Some generators drop the leading space of empty context lines.
+			text := strings.TrimPrefix(line, " ")
+			current.oldLines = append(current.oldLines, text)
+			current.newLines = append(current.newLines, text)
+			oldRemaining--
+			newRemaining--
+		default:
+			return nil, fmt.Errorf("unexpected line in patch: %q", line)
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch does not contain any hunks")
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses a "@@ -a,b +c,d @@" header. Omitted counts default to 1.
+func parseHunkHeader(line string) (oldStart, oldCount, newCount int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	parseRange := func(r string) (int, int, error) {
+		startStr, countStr, hasCount := strings.Cut(r, ",")
+		start, err := strconv.Atoi(startStr)
+		if err != nil {
+			return 0, 0, err
+		}
+		count := 1
+		if hasCount {
+			if count, err = strconv.Atoi(countStr); err != nil {
+				return 0, 0, err
+			}
+		}
+		return start, count, nil
+	}
+	if oldStart, oldCount, err = parseRange(fields[1][1:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	if _, newCount, err = parseRange(fields[2][1:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	return oldStart, oldCount, newCount, nil
+}
+
+// ApplyUnifiedDiff applies a single-file unified diff to original and returns the result.
+// Hunks are located by their context, so line numbers in the patch may be slightly off.
+func ApplyUnifiedDiff(original, patch string) (string, error) {
+	hunks, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := splitLines(original)
+	offset := 0 // difference between line numbers in the patch and in lines
+	minPos := 0 // hunks must apply in order
+	for i, h := range hunks {
+		want := max(h.oldStart-1+offset, minPos)
+		if len(h.oldLines) == 0 && h.oldStart == 0 {
+			want = 0
+		}
+		pos := findLines(lines, h.oldLines, want, minPos)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d (at line %d) does not apply: context not found", i+1, h.oldStart)
+		}
+
+		updated := make([]string, 0, len(lines)-len(h.oldLines)+len(h.newLines))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, h.newLines...)
+		updated = append(updated, lines[pos+len(h.oldLines):]...)
+		lines = updated
+
+		offset += len(h.newLines) - len(h.oldLines)
+		minPos = pos + len(h.newLines)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	result := strings.Join(lines, "\n")
+	if original == "" || strings.HasSuffix(original, "\n") {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines finds needle in haystack at or after minPos, preferring the position closest to want.
+func findLines(haystack, needle []string, want, minPos int) int {
+	matches := func(pos int) bool {
+		if pos < minPos || pos+len(needle) > len(haystack) {
+			return false
+		}
+		for i, l := range needle {
+			if haystack[pos+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= len(haystack); d++ {
+		if matches(want + d) {
+			return want + d
+		}
+		if d > 0 && matches(want-d) {
+			return want - d
+		}
+	}
+	return -1
+}
@@ -0,0 +1,527 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+const (
+	// maxReadFileBytes bounds how much of a file read_file returns in one call.
+	maxReadFileBytes = 256 * 1024
+	// maxListFilesEntries bounds how many entries list_files returns.
+	maxListFilesEntries = 1000
+)
+
+// FileResult is the result of a file tool invocation.
+type FileResult struct {
+	Path         string      `json:"path,omitempty"`
+	Content      string      `json:"content,omitempty"`
+	Entries      []FileEntry `json:"entries,omitempty"`
+	Diff         string      `json:"diff,omitempty"`
+	TotalLines   int         `json:"total_lines,omitempty"`
+	BytesWritten int         `json:"bytes_written,omitempty"`
+	Created      bool        `json:"created,omitempty"`
+	Truncated    bool        `json:"truncated,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// FileEntry is a single entry returned by list_files.
+type FileEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// fileScope confines file tools to the work directory and any user-approved extra directories.
+type fileScope struct {
+	workDir string
+	roots   []string
+}
+
+func fileScopeFromContext(ctx context.Context) (*fileScope, error) {
+	workDir, _ := ctx.Value(WorkDirKey).(string)
+	if workDir == "" {
+		return nil, fmt.Errorf("file tools require a working directory")
+	}
+	allowedDirs, _ := ctx.Value(AllowedDirsKey).([]string)
+
+	scope := &fileScope{workDir: workDir}
+	for _, dir := range append([]string{workDir}, allowedDirs...) {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving directory %q: %w", dir, err)
+		}
+		scope.roots = append(scope.roots, resolveSymlinks(root))
+	}
+	return scope, nil
+}
+
+// resolve returns the real path for path (relative paths are relative to the work directory),
+// and fails if it is not inside one of the allowed directories. Symlinks are resolved
+// before the check, so they cannot be used to escape the allowed directories.
+func (s *fileScope) resolve(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if strings.HasPrefix(path, "~") {
+		return "", fmt.Errorf("path %q: home-relative paths are not supported", path)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+	real := resolveSymlinks(filepath.Clean(path))
+	for _, root := range s.roots {
+		if isWithin(root, real) {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("path %q is outside the working directory %s and the allowed directories", path, s.workDir)
+}
+
+// display returns path relative to the work directory when possible, for use in prompts.
+func (s *fileScope) display(path string) string {
+	if rel, err := filepath.Rel(resolveSymlinks(s.workDir), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// resolveSymlinks evaluates symlinks in the longest existing prefix of path.
+func resolveSymlinks(path string) string {
+	var rest []string
+	for current := path; ; current = filepath.Dir(current) {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+	}
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func stringArg(args map[string]any, key string) string {
+	s, _ := args[key].(string)
+	return s
+}
+
+func intArg(args map[string]any, key string) int {
+	switch v := args[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// readExisting returns the contents of path, or "" if it does not exist.
+func readExisting(path string) (content string, exists bool, err error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
+
+// writeFile writes content to path, creating parent directories and keeping the mode of an existing file.
+func writeFile(path, content string) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	return os.WriteFile(path, []byte(content), mode)
+}
+
+// ReadFileTool reads files from the work directory.
+type ReadFileTool struct{}
+
+func (t *ReadFileTool) Name() string {
+	return "read_file"
+}
+
+func (t *ReadFileTool) Description() string {
+	return "Reads a text file from the working directory (or an allowed directory). Relative paths are relative to the working directory."
+}
+
+func (t *ReadFileTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"path": {
+					Type:        gollm.TypeString,
+					Description: "Path of the file to read.",
+				},
+				"offset": {
+					Type:        gollm.TypeInteger,
+					Description: "Line number to start reading from (1-based). Optional.",
+				},
+				"limit": {
+					Type:        gollm.TypeInteger,
+					Description: "Maximum number of lines to read. Optional.",
+				},
+			},
+			Required: []string{"path"},
+		},
+	}
+}
+
+func (t *ReadFileTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	scope, err := fileScopeFromContext(ctx)
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	path, err := scope.resolve(stringArg(args, "path"))
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return &FileResult{Path: scope.display(path), Error: err.Error()}, nil
+	}
+
+	lines := splitLines(string(b))
+	result := &FileResult{Path: scope.display(path), TotalLines: len(lines)}
+	offset, limit := intArg(args, "offset"), intArg(args, "limit")
+	if offset > 1 {
+		lines = lines[min(offset-1, len(lines)):]
+	}
+	if limit > 0 && limit < len(lines) {
+		lines = lines[:limit]
+		result.Truncated = true
+	}
+
+	content := strings.Join(lines, "\n")
+	if len(content) > maxReadFileBytes {
+		content = content[:maxReadFileBytes]
+		result.Truncated = true
+	}
+	result.Content = content
+	return result, nil
+}
+
+func (t *ReadFileTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ReadFileTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
+
+func (t *ReadFileTool) describeCall(args map[string]any) string {
+	return fmt.Sprintf("read_file %s", stringArg(args, "path"))
+}
+
+// ListFilesTool lists files in the work directory.
+type ListFilesTool struct{}
+
+func (t *ListFilesTool) Name() string {
+	return "list_files"
+}
+
+func (t *ListFilesTool) Description() string {
+	return "Lists files in a directory inside the working directory (or an allowed directory)."
+}
+
+func (t *ListFilesTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"path": {
+					Type:        gollm.TypeString,
+					Description: "Directory to list. Defaults to the working directory.",
+				},
+				"recursive": {
+					Type:        gollm.TypeBoolean,
+					Description: "Whether to list subdirectories recursively.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ListFilesTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	scope, err := fileScopeFromContext(ctx)
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	dirArg := stringArg(args, "path")
+	if dirArg == "" {
+		dirArg = "."
+	}
+	dir, err := scope.resolve(dirArg)
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	recursive, _ := args["recursive"].(bool)
+
+	result := &FileResult{Path: scope.display(dir)}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if len(result.Entries) >= maxListFilesEntries {
+			result.Truncated = true
+			return filepath.SkipAll
+		}
+		rel, _ := filepath.Rel(dir, path)
+		entry := FileEntry{Name: filepath.ToSlash(rel), Type: "file"}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			entry.Type = "symlink"
+		case d.IsDir():
+			entry.Type = "dir"
+		default:
+			if info, err := d.Info(); err == nil {
+				entry.Size = info.Size()
+			}
+		}
+		result.Entries = append(result.Entries, entry)
+		if d.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+func (t *ListFilesTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ListFilesTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
+
+func (t *ListFilesTool) describeCall(args map[string]any) string {
+	path := stringArg(args, "path")
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("list_files %s", path)
+}
+
+// WriteFileTool creates or overwrites files in the work directory.
+type WriteFileTool struct{}
+
+func (t *WriteFileTool) Name() string {
+	return "write_file"
+}
+
+func (t *WriteFileTool) Description() string {
+	return `Creates or overwrites a file in the working directory (or an allowed directory), e.g. a Kubernetes manifest to apply later with kubectl apply -f. Prefer this over shell heredocs. Use patch_file for small changes to existing files.`
+}
+
+func (t *WriteFileTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"path": {
+					Type:        gollm.TypeString,
+					Description: "Path of the file to write.",
+				},
+				"content": {
+					Type:        gollm.TypeString,
+					Description: "The full content of the file.",
+				},
+			},
+			Required: []string{"path", "content"},
+		},
+	}
+}
+
+func (t *WriteFileTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	scope, err := fileScopeFromContext(ctx)
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	path, err := scope.resolve(stringArg(args, "path"))
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	content := stringArg(args, "content")
+
+	_, exists, err := readExisting(path)
+	if err != nil {
+		return &FileResult{Path: scope.display(path), Error: err.Error()}, nil
+	}
+	if err := writeFile(path, content); err != nil {
+		return &FileResult{Path: scope.display(path), Error: err.Error()}, nil
+	}
+	return &FileResult{Path: scope.display(path), BytesWritten: len(content), Created: !exists}, nil
+}
+
+// PreviewChange returns the diff between the current file and the content to be written.
+func (t *WriteFileTool) PreviewChange(ctx context.Context, args map[string]any) (string, error) {
+	scope, err := fileScopeFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	path, err := scope.resolve(stringArg(args, "path"))
+	if err != nil {
+		return "", err
+	}
+	old, _, err := readExisting(path)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(scope.display(path), old, stringArg(args, "content")), nil
+}
+
+func (t *WriteFileTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+// CheckModifiesResource returns "yes" so that file writes are reviewed before they happen.
+func (t *WriteFileTool) CheckModifiesResource(args map[string]any) string {
+	return "yes"
+}
+
+func (t *WriteFileTool) describeCall(args map[string]any) string {
+	return fmt.Sprintf("write_file %s (%d lines)", stringArg(args, "path"), len(splitLines(stringArg(args, "content"))))
+}
+
+// PatchFileTool applies a unified diff to a file in the work directory.
+type PatchFileTool struct{}
+
+func (t *PatchFileTool) Name() string {
+	return "patch_file"
+}
+
+func (t *PatchFileTool) Description() string {
+	return "Applies a unified diff (as produced by `diff -u`) to a single file in the working directory (or an allowed directory)."
+}
+
+func (t *PatchFileTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"path": {
+					Type:        gollm.TypeString,
+					Description: "Path of the file to patch.",
+				},
+				"patch": {
+					Type:        gollm.TypeString,
+					Description: "Unified diff with @@ hunk headers and enough context lines to locate each change.",
+				},
+			},
+			Required: []string{"path", "patch"},
+		},
+	}
+}
+
+// apply computes the patched content of the file, without writing it.
+func (t *PatchFileTool) apply(ctx context.Context, args map[string]any) (scope *fileScope, path, old, updated string, err error) {
+	scope, err = fileScopeFromContext(ctx)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	path, err = scope.resolve(stringArg(args, "path"))
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	old, _, err = readExisting(path)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	updated, err = ApplyUnifiedDiff(old, stringArg(args, "patch"))
+	if err != nil {
+		return nil, "", "", "", fmt.Errorf("applying patch to %s: %w", scope.display(path), err)
+	}
+	return scope, path, old, updated, nil
+}
+
+func (t *PatchFileTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	scope, path, old, updated, err := t.apply(ctx, args)
+	if err != nil {
+		return &FileResult{Error: err.Error()}, nil
+	}
+	if err := writeFile(path, updated); err != nil {
+		return &FileResult{Path: scope.display(path), Error: err.Error()}, nil
+	}
+	return &FileResult{
+		Path:         scope.display(path),
+		Diff:         UnifiedDiff(scope.display(path), old, updated),
+		BytesWritten: len(updated),
+	}, nil
+}
+
+// PreviewChange returns the diff the patch will produce, or an error if it does not apply.
+func (t *PatchFileTool) PreviewChange(ctx context.Context, args map[string]any) (string, error) {
+	scope, path, old, updated, err := t.apply(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(scope.display(path), old, updated), nil
+}
+
+func (t *PatchFileTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+// CheckModifiesResource returns "yes" so that file changes are reviewed before they happen.
+func (t *PatchFileTool) CheckModifiesResource(args map[string]any) string {
+	return "yes"
+}
+
+func (t *PatchFileTool) describeCall(args map[string]any) string {
+	return fmt.Sprintf("patch_file %s", stringArg(args, "path"))
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fileToolContext(workDir string, allowedDirs ...string) context.Context {
+	ctx := context.WithValue(context.Background(), WorkDirKey, workDir)
+	return context.WithValue(ctx, AllowedDirsKey, allowedDirs)
+}
+
+func TestFileScopeResolve(t *testing.T) {
+	workDir := t.TempDir()
+	extraDir := t.TempDir()
+	outsideDir := t.TempDir()
+	if err := os.Symlink(outsideDir, filepath.Join(workDir, "escape")); err != nil {
+		t.Fatalf("creating symlink: %v", err)
+	}
+
+	scope, err := fileScopeFromContext(fileToolContext(workDir, extraDir))
+	if err != nil {
+		t.Fatalf("fileScopeFromContext() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"relative", "deployment.yaml", false},
+		{"nested new dir", "manifests/app/deployment.yaml", false},
+		{"absolute inside", filepath.Join(workDir, "a.yaml"), false},
+		{"allowed extra dir", filepath.Join(extraDir, "b.yaml"), false},
+		{"traversal", "../../etc/passwd", true},
+		{"traversal in the middle", "manifests/../../x.yaml", true},
+		{"absolute outside", "/etc/passwd", true},
+		{"symlink escape", "escape/secret.txt", true},
+		{"home relative", "~/.kube/config", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := scope.resolve(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileTools(t *testing.T) {
+	workDir := t.TempDir()
+	ctx := fileToolContext(workDir)
+
+	content := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 1\n"
+	write := &WriteFileTool{}
+	args := map[string]any{"path": "manifests/deployment.yaml", "content": content}
+
+	preview, err := write.PreviewChange(ctx, args)
+	if err != nil {
+		t.Fatalf("PreviewChange() error = %v", err)
+	}
+	if !strings.Contains(preview, "+++ b/manifests/deployment.yaml") || !strings.Contains(preview, "+  replicas: 1") {
+		t.Errorf("unexpected preview:\n%s", preview)
+	}
+
+	res, err := write.Run(ctx, args)
+	if err != nil {
+		t.Fatalf("write_file Run() error = %v", err)
+	}
+	if r := res.(*FileResult); r.Error != "" || !r.Created {
+		t.Fatalf("write_file result = %+v", r)
+	}
+
+	patch := &PatchFileTool{}
+	patchArgs := map[string]any{
+		"path": "manifests/deployment.yaml",
+		"patch": `--- a/manifests/deployment.yaml
++++ b/manifests/deployment.yaml
+@@ -4,3 +4,3 @@
+   name: web
+ spec:
+-  replicas: 1
++  replicas: 3
+`,
+	}
+	preview, err = patch.PreviewChange(ctx, patchArgs)
+	if err != nil {
+		t.Fatalf("patch PreviewChange() error = %v", err)
+	}
+	if !strings.Contains(preview, "-  replicas: 1\n+  replicas: 3") {
+		t.Errorf("unexpected patch preview:\n%s", preview)
+	}
+	if res, _ := patch.Run(ctx, patchArgs); res.(*FileResult).Error != "" {
+		t.Fatalf("patch_file error: %s", res.(*FileResult).Error)
+	}
+
+	res, _ = (&ReadFileTool{}).Run(ctx, map[string]any{"path": "manifests/deployment.yaml", "offset": float64(6), "limit": float64(1)})
+	if r := res.(*FileResult); r.Content != "  replicas: 3" || r.TotalLines != 6 {
+		t.Errorf("read_file result = %+v", r)
+	}
+
+	res, _ = (&ListFilesTool{}).Run(ctx, map[string]any{"recursive": true})
+	var names []string
+	for _, e := range res.(*FileResult).Entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "manifests,manifests/deployment.yaml" {
+		t.Errorf("list_files entries = %q", got)
+	}
+
+	res, _ = write.Run(ctx, map[string]any{"path": "../outside.yaml", "content": "x"})
+	if r := res.(*FileResult); r.Error == "" {
+		t.Errorf("expected write outside the work directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(workDir), "outside.yaml")); err == nil {
+		t.Errorf("file was written outside the work directory")
+	}
+}
+
+func TestApplyUnifiedDiff(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "single hunk",
+			original: original,
+			patch:    "@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+			expected: "a\nb\nC\nd\ne\nf\ng\nh\n",
+		},
+		{
+			name:     "wrong line numbers are tolerated",
+			original: original,
+			patch:    "@@ -10,3 +10,4 @@\n f\n g\n+g2\n h\n",
+			expected: "a\nb\nc\nd\ne\nf\ng\ng2\nh\n",
+		},
+		{
+			name:     "multiple hunks",
+			original: original,
+			patch:    "--- a/x\n+++ b/x\n@@ -1,2 +1,1 @@\n-a\n b\n@@ -7,2 +6,2 @@\n g\n-h\n+H\n",
+			expected: "b\nc\nd\ne\nf\ng\nH\n",
+		},
+		{
+			name:     "new file",
+			original: "",
+			patch:    "--- /dev/null\n+++ b/x\n@@ -0,0 +1,2 @@\n+hello\n+world\n",
+			expected: "hello\nworld\n",
+		},
+		{
+			name:     "removed line that looks like a header",
+			original: "-- x\ny\n",
+			patch:    "@@ -1,2 +1,1 @@\n--- x\n y\n",
+			expected: "y\n",
+		},
+		{
+			name:     "context mismatch",
+			original: original,
+			patch:    "@@ -2,3 +2,3 @@\n b\n-x\n+X\n d\n",
+			wantErr:  true,
+		},
+		{
+			name:     "no hunks",
+			original: original,
+			patch:    "just some text",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyUnifiedDiff(tt.original, tt.patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyUnifiedDiff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ApplyUnifiedDiff() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffRoundTrip(t *testing.T) {
+	oldText := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\nthirteen\n"
+	newText := "zero\none\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+
+	diff := UnifiedDiff("numbers.txt", oldText, newText)
+	if strings.Count(diff, "@@ -") != 2 {
+		t.Errorf("expected two hunks, got:\n%s", diff)
+	}
+	got, err := ApplyUnifiedDiff(oldText, diff)
+	if err != nil {
+		t.Fatalf("ApplyUnifiedDiff() error = %v\n%s", err, diff)
+	}
+	if got != newText {
+		t.Errorf("round trip = %q, want %q", got, newText)
+	}
+	if UnifiedDiff("numbers.txt", oldText, oldText) != "" {
+		t.Errorf("expected empty diff for identical input")
+	}
+}
@@ -46,3 +46,58 @@ type Tool interface {
 	// Returns "yes", "no", or "unknown"
 	CheckModifiesResource(args map[string]any) string
 }
+
+// RiskExplainer is optionally implemented by tools that can describe why a call
+// needs approval, e.g. a destructive command hidden in a pipeline.
+type RiskExplainer interface {
+	// ExplainRisk returns a human-readable description of the risks of the call,
+	// or an empty string if none were found.
+	ExplainRisk(args map[string]any) string
+}
+
+// ContextChecker is optionally implemented by tools whose checks depend on where the call runs,
+// e.g. whether a shell command writes outside the working directory. Callers that know where
+// the call runs use it, through the CheckModifiesResource and ExplainRisk functions.
+type ContextChecker interface {
+	// CheckModifiesResourceContext is CheckModifiesResource for a call running with the context.
+	// The context carries the same values (e.g. WorkDirKey) as when the tool runs.
+	CheckModifiesResourceContext(ctx context.Context, args map[string]any) string
+
+	// ExplainRiskContext is ExplainRisk for a call running with the context.
+	ExplainRiskContext(ctx context.Context, args map[string]any) string
+}
+
+// CheckModifiesResource determines if a call of the tool modifies resources, for a call running
+// with the context. It returns "yes", "no", or "unknown".
+func CheckModifiesResource(ctx context.Context, tool Tool, args map[string]any) string {
+	if checker, ok := tool.(ContextChecker); ok {
+		return checker.CheckModifiesResourceContext(ctx, args)
+	}
+	return tool.CheckModifiesResource(args)
+}
+
+// ExplainRisk describes the risks of a call of the tool running with the context,
+// or returns an empty string if the tool does not explain its risks.
+func ExplainRisk(ctx context.Context, tool Tool, args map[string]any) string {
+	if checker, ok := tool.(ContextChecker); ok {
+		return checker.ExplainRiskContext(ctx, args)
+	}
+	if explainer, ok := tool.(RiskExplainer); ok {
+		return explainer.ExplainRisk(args)
+	}
+	return ""
+}
+
+// ChangePreviewer is optionally implemented by tools that can show what a call
+// will change before it runs, e.g. a diff of a file write in the approval prompt.
+type ChangePreviewer interface {
+	// PreviewChange returns a preview of the change, typically a unified diff.
+	// The context carries the same values (e.g. WorkDirKey) as when the tool runs.
+	PreviewChange(ctx context.Context, args map[string]any) (string, error)
+}
+
+// callDescriber is implemented by tools that format their own call descriptions,
+// e.g. to avoid printing the full content of a file being written.
+type callDescriber interface {
+	describeCall(args map[string]any) string
+}
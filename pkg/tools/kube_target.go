@@ -0,0 +1,485 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// kubectlTargetedVerbs are the kubectl subcommands that talk to a cluster,
+// and so accept the --context and --namespace flags.
+// Other subcommands (config, completion, plugins, ...) are left alone.
+var kubectlTargetedVerbs = map[string]bool{
+	"annotate": true, "api-resources": true, "api-versions": true, "apply": true,
+	"attach": true, "auth": true, "autoscale": true, "certificate": true,
+	"cluster-info": true, "cordon": true, "cp": true, "create": true,
+	"debug": true, "delete": true, "describe": true, "diff": true,
+	"drain": true, "edit": true, "events": true, "exec": true,
+	"explain": true, "expose": true, "get": true, "label": true,
+	"logs": true, "patch": true, "port-forward": true, "proxy": true,
+	"replace": true, "rollout": true, "run": true, "scale": true,
+	"set": true, "taint": true, "top": true, "uncordon": true,
+	"version": true, "wait": true,
+}
+
+// kubeTargetFromContext returns the kube context and namespace tools should target.
+func kubeTargetFromContext(ctx context.Context) (kubeContext, namespace string) {
+	kubeContext, _ = ctx.Value(KubeContextKey).(string)
+	namespace, _ = ctx.Value(NamespaceKey).(string)
+	return kubeContext, namespace
+}
+
+// kubeConfigContexts is the subset of `kubectl config view -o json` needed to pin the kube target.
+type kubeConfigContexts struct {
+	CurrentContext string `json:"current-context"`
+	Contexts       []struct {
+		Name    string         `json:"name"`
+		Context map[string]any `json:"context"`
+	} `json:"contexts"`
+}
+
+// loadKubeConfigContexts reads the contexts of the kubeconfig. It is a variable so tests can replace it.
+var loadKubeConfigContexts = func(ctx context.Context, kubeconfig string) (*kubeConfigContexts, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", "config", "view", "-o", "json")
+	cmd.Env = os.Environ()
+	if kubeconfig != "" {
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running kubectl config view: %w", err)
+	}
+	contexts := &kubeConfigContexts{}
+	if err := json.Unmarshal(out, contexts); err != nil {
+		return nil, fmt.Errorf("parsing kubectl config view output: %w", err)
+	}
+	return contexts, nil
+}
+
+// pinKubeTarget writes a kubeconfig selecting kubeContext and namespace, and returns the
+// KUBECONFIG listing it before kubeconfig. kubectl takes the current context, and the
+// first definition of a context, from the first file that sets them, so every kubectl the
+// command runs targets them, not only the calls InjectKubeTarget rewrites (e.g. in
+// `bash -c '...'` or `eval`). The cluster and credentials still come from kubeconfig.
+// remove deletes the written file. Without a target, kubeconfig is returned unchanged.
+func pinKubeTarget(ctx context.Context, kubeconfig, kubeContext, namespace string) (pinned string, remove func(), err error) {
+	remove = func() {}
+	if kubeContext == "" && namespace == "" {
+		return kubeconfig, remove, nil
+	}
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	if kubeconfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", remove, fmt.Errorf("finding the default kubeconfig: %w", err)
+		}
+		kubeconfig = filepath.Join(home, ".kube", "config")
+	}
+
+	contexts, err := loadKubeConfigContexts(ctx, kubeconfig)
+	if err != nil {
+		return "", remove, err
+	}
+	if kubeContext == "" {
+		kubeContext = contexts.CurrentContext
+	}
+	if kubeContext == "" {
+		// Without a context, kubectl does not read a namespace from the kubeconfig.
+		return kubeconfig, remove, nil
+	}
+	var target map[string]any
+	found := false
+	for _, c := range contexts.Contexts {
+		if c.Name == kubeContext {
+			target, found = c.Context, true
+			break
+		}
+	}
+	if !found {
+		return "", remove, fmt.Errorf("kube context %q not found in the kubeconfig", kubeContext)
+	}
+	if target == nil {
+		target = map[string]any{}
+	}
+	if namespace != "" {
+		target["namespace"] = namespace
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": kubeContext,
+		"contexts":        []map[string]any{{"name": kubeContext, "context": target}},
+	})
+	if err != nil {
+		return "", remove, fmt.Errorf("encoding kubeconfig: %w", err)
+	}
+	f, err := os.CreateTemp("", "kubectl-ai-target-*.kubeconfig")
+	if err != nil {
+		return "", remove, fmt.Errorf("creating kubeconfig: %w", err)
+	}
+	remove = func() { os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return "", func() {}, fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	return f.Name() + string(filepath.ListSeparator) + kubeconfig, remove, nil
+}
+
+// InjectKubeTarget adds --context and --namespace flags to every kubectl invocation in
+// the shell command that does not already select them. Empty values are not injected,
+// and commands that cannot be parsed are returned unchanged.
+func InjectKubeTarget(command, kubeContext, namespace string) string {
+	if kubeContext == "" && namespace == "" {
+		return command
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		klog.V(2).Infof("not injecting kube target into unparseable command %q: %v", command, err)
+		return command
+	}
+
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	walkKubectlCalls(file, func(call *syntax.CallExpr, args []string, start int) {
+		kubectlArgs := args[start+1:]
+		if !kubectlTargetedVerbs[kubectlVerb(kubectlArgs)] {
+			return
+		}
+
+		// Flags go after the last kubectl argument, or before `--` (e.g. `kubectl exec pod -- ls`).
+		last := len(args) - 1
+		for i, arg := range kubectlArgs {
+			if arg == "--" {
+				last = start + i
+				break
+			}
+		}
+
+		var flags []string
+		if kubeContext != "" && !hasKubectlFlag(kubectlArgs, "--context") {
+			flags = append(flags, "--context="+shellQuote(kubeContext))
+		}
+		if namespace != "" && !hasKubectlFlag(kubectlArgs, "-n", "--namespace", "-A", "--all-namespaces") {
+			flags = append(flags, "--namespace="+shellQuote(namespace))
+		}
+		if len(flags) > 0 {
+			insertions = append(insertions, insertion{
+				offset: int(call.Args[last].End().Offset()),
+				text:   " " + strings.Join(flags, " "),
+			})
+		}
+	})
+
+	// Insert from the end, so earlier offsets stay valid.
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		command = command[:ins.offset] + ins.text + command[ins.offset:]
+	}
+	return command
+}
+
+// KubectlInvocation describes a kubectl call within a shell command.
+type KubectlInvocation struct {
+	// Verb is the kubectl subcommand, e.g. "get" or "delete".
+	Verb string
+	// Namespace is the namespace selected with -n or --namespace, if any.
+	Namespace string
+	// AllNamespaces is set if the call selects all namespaces with -A or --all-namespaces.
+	AllNamespaces bool
+	// CredentialFlags are the flags selecting another kubeconfig, cluster or identity
+	// than the configured one, e.g. --kubeconfig or --as.
+	CredentialFlags []string
+	// Files are the local files the call reads: manifests and kustomizations (-f, -k),
+	// --from-file and --from-env-file sources, and templates (--template, -o jsonpath-file=...).
+	Files []string
+}
+
+// kubectlCredentialFlags select the kubeconfig, cluster or identity kubectl uses.
+var kubectlCredentialFlags = []string{
+	"--kubeconfig", "--cluster", "--user", "-s", "--server", "--token",
+	"--as", "--as-group", "--as-uid", "--username", "--password",
+	"--client-certificate", "--client-key", "--certificate-authority", "--insecure-skip-tls-verify",
+}
+
+// KubectlInvocations returns the kubectl calls in a shell command.
+// ok is false if the command cannot be parsed, or may run kubectl calls that cannot be
+// enumerated, e.g. through `bash -c`, eval or a command name held in a variable.
+func KubectlInvocations(command string) (invocations []KubectlInvocation, ok bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil || runsHiddenCommands(file) {
+		return nil, false
+	}
+	walkKubectlCalls(file, func(_ *syntax.CallExpr, args []string, start int) {
+		kubectlArgs := args[start+1:]
+		invocations = append(invocations, KubectlInvocation{
+			Verb:            kubectlVerb(kubectlArgs),
+			Namespace:       kubectlFlagValue(kubectlArgs, "-n", "--namespace"),
+			AllNamespaces:   hasKubectlFlag(kubectlArgs, "-A", "--all-namespaces"),
+			CredentialFlags: kubectlFlagsSet(kubectlArgs, kubectlCredentialFlags...),
+			Files:           kubectlFiles(kubectlArgs),
+		})
+	})
+	return invocations, true
+}
+
+// OnlyRunsKubectl reports whether a shell command runs nothing but kubectl, without
+// redirections, expansions or substitutions, so that it cannot reach local files or
+// the environment other than through kubectl.
+func OnlyRunsKubectl(command string) bool {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil || len(file.Stmts) == 0 {
+		return false
+	}
+	ok := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			if len(n.Redirs) > 0 {
+				ok = false
+			}
+		case *syntax.CallExpr:
+			if len(n.Assigns) > 0 || len(n.Args) == 0 {
+				ok = false
+				break
+			}
+			name, isLiteral := wordLiteral(n.Args[0])
+			if !isLiteral || name != "kubectl" {
+				ok = false
+			}
+		case *syntax.ParamExp, *syntax.CmdSubst, *syntax.ProcSubst, *syntax.ArithmExp,
+			*syntax.FuncDecl, *syntax.DeclClause, *syntax.CoprocClause, *syntax.TestClause:
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+// runsHiddenCommands reports whether a parsed shell command runs commands that cannot be
+// seen in it: commands named by expansions, and commands given as text to shells, eval,
+// interpreters, find -exec or awk, or run with elevated privileges or through aliases.
+func runsHiddenCommands(file *syntax.File) bool {
+	hidden := false
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return !hidden
+		}
+		args := make([]string, len(call.Args))
+		literal := make([]bool, len(call.Args))
+		for i, word := range call.Args {
+			args[i], literal[i] = wordLiteral(word)
+		}
+
+		start := len(args) - len(unwrapCommand(args))
+		if start >= len(args) {
+			return true
+		}
+		if !literal[start] {
+			hidden = true
+			return false
+		}
+		name := commandName(args[start])
+		switch {
+		case shellInterpreters[name], scriptInterpreters[name], privilegeCommands[name], name == "eval", name == "alias":
+			hidden = true
+		case name == "find":
+			hidden = slices.ContainsFunc(args[start+1:], func(arg string) bool {
+				return arg == "-exec" || arg == "-execdir" || arg == "-ok" || arg == "-okdir"
+			})
+		case strings.HasSuffix(name, "awk"):
+			// system() and pipes to commands in the program.
+			hidden = slices.ContainsFunc(args[start+1:], func(arg string) bool {
+				return strings.Contains(arg, "system") || strings.Contains(arg, "|")
+			})
+		}
+		return !hidden
+	})
+	return hidden
+}
+
+// walkKubectlCalls calls fn for every kubectl call in a parsed shell command, with the
+// words of the call and the index of the kubectl word (after wrappers such as sudo or xargs).
+func walkKubectlCalls(file *syntax.File, fn func(call *syntax.CallExpr, args []string, start int)) {
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		args := make([]string, len(call.Args))
+		for i, word := range call.Args {
+			lit, ok := wordLiteral(word)
+			if !ok {
+				lit = printWord(word)
+			}
+			args[i] = lit
+		}
+
+		start := len(args) - len(unwrapCommand(args))
+		if start < len(args) && isKubectlName(commandName(args[start])) {
+			fn(call, args, start)
+		}
+		return true
+	})
+}
+
+// kubectlGlobalValueFlags are the kubectl flags that may come before the subcommand
+// and take a separate value, e.g. `kubectl -n kube-system get pods`.
+var kubectlGlobalValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--kubeconfig": true,
+	"--cluster": true, "--user": true, "-s": true, "--server": true,
+	"--token": true, "--as": true, "--as-group": true, "--request-timeout": true,
+}
+
+// kubectlVerb returns the kubectl subcommand, skipping any leading flags.
+func kubectlVerb(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return ""
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+		if kubectlGlobalValueFlags[arg] {
+			i++
+		}
+	}
+	return ""
+}
+
+// hasKubectlFlag reports whether args (up to any `--`) set one of the given flags,
+// in any of the forms `--flag value`, `--flag=value` or `-nvalue`.
+func hasKubectlFlag(args []string, names ...string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		for _, name := range names {
+			if arg == name || strings.HasPrefix(arg, name+"=") {
+				return true
+			}
+			if len(name) == 2 && name != "-A" && strings.HasPrefix(arg, name) && !strings.HasPrefix(arg, "--") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// kubectlFlagValue returns the value of the first of the given flags set in args (up to any `--`).
+func kubectlFlagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			return ""
+		}
+		for _, name := range names {
+			switch {
+			case arg == name && i+1 < len(args):
+				return args[i+1]
+			case strings.HasPrefix(arg, name+"="):
+				return strings.TrimPrefix(arg, name+"=")
+			case len(name) == 2 && strings.HasPrefix(arg, name) && !strings.HasPrefix(arg, "--") && len(arg) > 2:
+				return arg[2:]
+			}
+		}
+	}
+	return ""
+}
+
+// kubectlFlagValues returns the values of every occurrence of the given flags in args.
+func kubectlFlagValues(args []string, names ...string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		for _, name := range names {
+			switch {
+			case arg == name && i+1 < len(args):
+				values = append(values, args[i+1])
+				i++
+			case strings.HasPrefix(arg, name+"="):
+				values = append(values, strings.TrimPrefix(arg, name+"="))
+			case len(name) == 2 && strings.HasPrefix(arg, name) && !strings.HasPrefix(arg, "--") && len(arg) > 2:
+				values = append(values, arg[2:])
+			default:
+				continue
+			}
+			break
+		}
+	}
+	return values
+}
+
+// kubectlFiles returns the local files read by a kubectl call.
+func kubectlFiles(args []string) []string {
+	files := kubectlFlagValues(args, "-f", "--filename", "-k", "--kustomize", "--from-env-file", "--template")
+	for _, source := range kubectlFlagValues(args, "--from-file") {
+		// The source is a path, or key=path.
+		_, path, ok := strings.Cut(source, "=")
+		if !ok {
+			path = source
+		}
+		files = append(files, path)
+	}
+	for _, output := range kubectlFlagValues(args, "-o", "--output") {
+		if _, path, ok := strings.Cut(output, "-file="); ok {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// kubectlFlagsSet returns which of the given flags are set in args.
+func kubectlFlagsSet(args []string, names ...string) []string {
+	var set []string
+	for _, name := range names {
+		if hasKubectlFlag(args, name) {
+			set = append(set, name)
+		}
+	}
+	return set
+}
+
+func shellQuote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangBash)
+	if err != nil {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return quoted
+}
@@ -0,0 +1,361 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInjectKubeTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		kubeContext string
+		namespace   string
+		expected    string
+	}{
+		{
+			name:     "no target",
+			command:  "kubectl get pods",
+			expected: "kubectl get pods",
+		},
+		{
+			name:        "context and namespace",
+			command:     "kubectl get pods",
+			kubeContext: "prod",
+			namespace:   "web",
+			expected:    "kubectl get pods --context=prod --namespace=web",
+		},
+		{
+			name:        "explicit namespace is kept",
+			command:     "kubectl get pods -n kube-system",
+			kubeContext: "prod",
+			namespace:   "web",
+			expected:    "kubectl get pods -n kube-system --context=prod",
+		},
+		{
+			name:      "all namespaces",
+			command:   "kubectl get pods -A",
+			namespace: "web",
+			expected:  "kubectl get pods -A",
+		},
+		{
+			name:        "explicit context is kept",
+			command:     "kubectl --context=staging get pods",
+			kubeContext: "prod",
+			expected:    "kubectl --context=staging get pods",
+		},
+		{
+			name:        "flags go before --",
+			command:     "kubectl exec web-0 -- ls -n /",
+			kubeContext: "prod",
+			namespace:   "web",
+			expected:    "kubectl exec web-0 --context=prod --namespace=web -- ls -n /",
+		},
+		{
+			name:        "pipelines and wrappers",
+			command:     "kubectl get pods -o name | xargs kubectl delete && echo done",
+			kubeContext: "prod",
+			expected:    "kubectl get pods -o name --context=prod | xargs kubectl delete --context=prod && echo done",
+		},
+		{
+			name:        "heredoc",
+			command:     "kubectl apply -f - <<EOF\napiVersion: v1\nkind: Namespace\nEOF",
+			kubeContext: "prod",
+			expected:    "kubectl apply -f - --context=prod <<EOF\napiVersion: v1\nkind: Namespace\nEOF",
+		},
+		{
+			name:        "config commands are left alone",
+			command:     "kubectl config get-contexts",
+			kubeContext: "prod",
+			expected:    "kubectl config get-contexts",
+		},
+		{
+			name:        "values are quoted",
+			command:     "kubectl get pods",
+			kubeContext: "arn:aws:eks:us-east-1:123:cluster/my cluster",
+			expected:    "kubectl get pods --context='arn:aws:eks:us-east-1:123:cluster/my cluster'",
+		},
+		{
+			name:        "flags before the verb",
+			command:     "kubectl -n kube-system get pods",
+			kubeContext: "prod",
+			expected:    "kubectl -n kube-system get pods --context=prod",
+		},
+		{
+			name:        "unparseable command",
+			command:     "kubectl get pods |",
+			kubeContext: "prod",
+			expected:    "kubectl get pods |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InjectKubeTarget(tt.command, tt.kubeContext, tt.namespace); got != tt.expected {
+				t.Errorf("InjectKubeTarget(%q) = %q, want %q", tt.command, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKubectlInvocations(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []KubectlInvocation
+		ok       bool
+	}{
+		{
+			name:     "single call",
+			command:  "kubectl get pods",
+			expected: []KubectlInvocation{{Verb: "get"}},
+			ok:       true,
+		},
+		{
+			name:    "namespaces",
+			command: "kubectl delete pod web-0 -n web && kubectl --namespace=db get pods; kubectl -nops logs x",
+			expected: []KubectlInvocation{
+				{Verb: "delete", Namespace: "web"},
+				{Verb: "get", Namespace: "db"},
+				{Verb: "logs", Namespace: "ops"},
+			},
+			ok: true,
+		},
+		{
+			name:    "global flags and wrappers",
+			command: "kubectl -n web get pods -o name | xargs kubectl delete -A",
+			expected: []KubectlInvocation{
+				{Verb: "get", Namespace: "web"},
+				{Verb: "delete", AllNamespaces: true},
+			},
+			ok: true,
+		},
+		{
+			name: "credentials and files",
+			command: "kubectl --kubeconfig /etc/other get pods --as=admin -o jsonpath-file=/etc/t && kubectl apply -f a.yaml --filename=b.yaml -k overlay && " +
+				"kubectl create secret generic s --from-file=key=/etc/passwd --from-file=c.txt",
+			expected: []KubectlInvocation{
+				{Verb: "get", CredentialFlags: []string{"--kubeconfig", "--as"}, Files: []string{"/etc/t"}},
+				{Verb: "apply", Files: []string{"a.yaml", "b.yaml", "overlay"}},
+				{Verb: "create", Files: []string{"/etc/passwd", "c.txt"}},
+			},
+			ok: true,
+		},
+		{
+			name:    "no kubectl",
+			command: "ls -la",
+			ok:      true,
+		},
+		{
+			name:    "unparseable command",
+			command: "kubectl get pods |",
+		},
+		{
+			name:    "nested shell",
+			command: "kubectl get pods && bash -c 'kubectl delete ns prod'",
+		},
+		{
+			name:    "eval",
+			command: `eval "kubectl delete ns prod"`,
+		},
+		{
+			name:    "command name in a variable",
+			command: "k=kubectl; $k delete ns prod",
+		},
+		{
+			name:    "wrapped command name in a variable",
+			command: "kubectl get pods -o name | xargs $k delete",
+		},
+		{
+			name:    "sudo",
+			command: "sudo kubectl delete ns prod",
+		},
+		{
+			name:    "find -exec",
+			command: "find . -name '*.yaml' -exec kubectl delete -f {} ;",
+		},
+		{
+			name:    "awk system",
+			command: `kubectl get ns -o name | awk '{system("kubectl delete " $1)}'`,
+		},
+		{
+			name:     "awk filter",
+			command:  "kubectl get pods | awk '{print $1}'",
+			expected: []KubectlInvocation{{Verb: "get"}},
+			ok:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := KubectlInvocations(tt.command)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("KubectlInvocations(%q) = %+v, %v; want %+v, %v", tt.command, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestOnlyRunsKubectl(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{command: "kubectl get pods", want: true},
+		{command: "kubectl get pods -n web && kubectl get svc; kubectl get nodes || true", want: false},
+		{command: "kubectl get pods -n web && kubectl get svc", want: true},
+		{command: "kubectl get pods -o name | kubectl delete -f -", want: true},
+		{command: "kubectl get pods | grep web", want: false},
+		{command: "kubectl get pods > /tmp/pods", want: false},
+		{command: "kubectl apply -f - < /etc/passwd", want: false},
+		{command: "kubectl get pods $(cat /etc/passwd)", want: false},
+		{command: "kubectl get secret $SECRET_NAME", want: false},
+		{command: "/tmp/evil/kubectl get pods", want: false},
+		{command: "KUBECONFIG=/etc/other kubectl get pods", want: false},
+		{command: "sudo kubectl get pods", want: false},
+		{command: "", want: false},
+		{command: "kubectl get pods |", want: false},
+	}
+	for _, tt := range tests {
+		if got := OnlyRunsKubectl(tt.command); got != tt.want {
+			t.Errorf("OnlyRunsKubectl(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+// stubKubeConfigContexts makes pinKubeTarget read a kubeconfig with the contexts prod and staging.
+func stubKubeConfigContexts(t *testing.T) {
+	t.Helper()
+	orig := loadKubeConfigContexts
+	t.Cleanup(func() { loadKubeConfigContexts = orig })
+	loadKubeConfigContexts = func(context.Context, string) (*kubeConfigContexts, error) {
+		contexts := &kubeConfigContexts{}
+		err := json.Unmarshal([]byte(`{
+			"current-context": "staging",
+			"contexts": [
+				{"name": "prod", "context": {"cluster": "prod-cluster", "user": "admin"}},
+				{"name": "staging", "context": {"cluster": "staging-cluster", "user": "dev", "namespace": "qa"}}
+			]
+		}`), contexts)
+		return contexts, err
+	}
+}
+
+func TestPinKubeTarget(t *testing.T) {
+	stubKubeConfigContexts(t)
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+
+	tests := []struct {
+		name        string
+		kubeContext string
+		namespace   string
+		// want is the kubeconfig written for the target, or "" if none is.
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no target",
+		},
+		{
+			name:        "context and namespace",
+			kubeContext: "prod",
+			namespace:   "web",
+			want:        `{"apiVersion":"v1","contexts":[{"context":{"cluster":"prod-cluster","namespace":"web","user":"admin"},"name":"prod"}],"current-context":"prod","kind":"Config"}`,
+		},
+		{
+			name:        "context only",
+			kubeContext: "prod",
+			want:        `{"apiVersion":"v1","contexts":[{"context":{"cluster":"prod-cluster","user":"admin"},"name":"prod"}],"current-context":"prod","kind":"Config"}`,
+		},
+		{
+			name:      "namespace of the current context",
+			namespace: "web",
+			want:      `{"apiVersion":"v1","contexts":[{"context":{"cluster":"staging-cluster","namespace":"web","user":"dev"},"name":"staging"}],"current-context":"staging","kind":"Config"}`,
+		},
+		{
+			name:        "unknown context",
+			kubeContext: "dev",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinned, remove, err := pinKubeTarget(context.Background(), kubeconfig, tt.kubeContext, tt.namespace)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("pinKubeTarget() = %q, want an error", pinned)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pinKubeTarget() error: %v", err)
+			}
+			defer remove()
+
+			if tt.want == "" {
+				if pinned != kubeconfig {
+					t.Errorf("pinKubeTarget() = %q, want the kubeconfig unchanged", pinned)
+				}
+				return
+			}
+			written, rest, _ := strings.Cut(pinned, string(filepath.ListSeparator))
+			if rest != kubeconfig {
+				t.Errorf("pinKubeTarget() = %q, want the written kubeconfig before %q", pinned, kubeconfig)
+			}
+			data, err := os.ReadFile(written)
+			if err != nil {
+				t.Fatalf("reading the written kubeconfig: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("written kubeconfig = %s, want %s", data, tt.want)
+			}
+			remove()
+			if _, err := os.Stat(written); !os.IsNotExist(err) {
+				t.Errorf("the written kubeconfig was not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestBashToolPinsKubeTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses bash")
+	}
+	stubKubeConfigContexts(t)
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, KubeconfigKey, filepath.Join(t.TempDir(), "config"))
+	ctx = context.WithValue(ctx, WorkDirKey, t.TempDir())
+	ctx = context.WithValue(ctx, KubeContextKey, "prod")
+	ctx = context.WithValue(ctx, NamespaceKey, "web")
+
+	// The nested shell cannot be rewritten, but still sees the target through KUBECONFIG.
+	result, err := (&BashTool{}).Run(ctx, map[string]any{"command": `bash -c 'cat "${KUBECONFIG%%:*}"'`})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	stdout := result.(*ExecResult).Stdout
+	if !strings.Contains(stdout, `"current-context":"prod"`) || !strings.Contains(stdout, `"namespace":"web"`) {
+		t.Errorf("the command sees the kubeconfig %q, want it to select prod/web", stdout)
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"strings"
 
 	"k8s.io/klog/v2"
-	"mvdan.cc/sh/v3/syntax"
 )
 
 // Package-level constants for kubectl operations
@@ -58,89 +57,78 @@ var (
 			"undo":    true,
 		},
 	}
+
+	// pipelineFilters are the commands that may run along with read-only kubectl commands
+	// without approval. They only filter or format text, and neither write files, run
+	// programs nor reach the network.
+	pipelineFilters = map[string]bool{
+		"grep": true, "egrep": true, "fgrep": true, "head": true, "tail": true,
+		"wc": true, "sort": true, "uniq": true, "cut": true, "tr": true, "jq": true,
+		"awk": true, "cat": true, "column": true, "echo": true, "printf": true,
+	}
 )
 
-// KubectlModifiesResource analyzes a kubectl command to determine if it modifies resources
-func kubectlModifiesResource(command string) string {
-	parser := syntax.NewParser()
-	file, err := parser.Parse(strings.NewReader(command), "")
-	if err != nil {
-		klog.Errorf("Failed to parse kubectl command: %v, command: %q", err, command)
+// KubectlModifiesResource analyzes a kubectl command to determine if it modifies resources.
+// Composite commands are analyzed as a whole: the result is "yes" if any part of the
+// command is risky, "no" if it only runs read-only kubectl commands and pipelineFilters
+// (grep, awk, ...) without flags that read files, and "unknown" otherwise.
+// Relative paths are resolved against workDir.
+func kubectlModifiesResource(command, workDir string) string {
+	analysis := AnalyzeShellCommand(command, workDir)
+	if analysis.ParseError != nil {
+		klog.Errorf("Failed to parse kubectl command: %v, command: %q", analysis.ParseError, command)
 		return "unknown"
 	}
 
-	hasReadCommand := false
-	foundWrite := false
-	numCmds := 0
-
-	// Single pass through all command calls
-	syntax.Walk(file, func(node syntax.Node) bool {
-		if call, ok := node.(*syntax.CallExpr); ok {
-			result := analyzeCall(call)
-
-			// If we find any write operation, mark it and stop
-			if result == "yes" {
-				foundWrite = true
-				return false // Stop walking
-			}
-
-			// Track if we found any read operations
-			if result == "no" {
-				hasReadCommand = true
-			}
-			numCmds++
-			if numCmds > 1 {
-				return false // Stop walking if more then one command is found
-			}
-		}
-		return true
-	})
+	if len(analysis.Risks) > 0 {
+		klog.Infof("KubectlModifiesResource result: yes (%d risks found) for command: %q", len(analysis.Risks), command)
+		return "yes"
+	}
 
-	if numCmds > 1 {
-		// if it's a composite bash command, we should err on the side of caution and return unknown
-		// to prevent exfilteration attacks https://simonwillison.net/2025/Jun/16/the-lethal-trifecta/
-		klog.Infof("KubectlModifiesResource result: unknown for command: %q, multiple commands (%d) found", command, numCmds)
+	if analysis.KubectlReads == 0 {
+		// Default to unknown if no recognized kubectl commands found
+		klog.Infof("KubectlModifiesResource result: unknown (no kubectl command recognized) for command: %q", command)
 		return "unknown"
 	}
 
-	// Return results based on what we found
-	if foundWrite {
-		klog.Infof("KubectlModifiesResource result: yes (write operation found) for command: %q", command)
-		return "yes"
+	if analysis.KubectlUnknown > 0 || len(analysis.Unknown) > 0 {
+		// Err on the side of caution if anything else in the command could not be classified,
+		// to prevent exfilteration attacks https://simonwillison.net/2025/Jun/16/the-lethal-trifecta/
+		klog.Infof("KubectlModifiesResource result: unknown for command: %q, unclassified commands: %v", command, analysis.Unknown)
+		return "unknown"
 	}
 
-	if hasReadCommand {
-		klog.Infof("KubectlModifiesResource result: no (read-only) for command: %q", command)
-		return "no"
+	for _, args := range analysis.Others {
+		if !isPipelineFilter(args) {
+			// Same as above: composite commands only run other commands from a minimal allowlist.
+			klog.Infof("KubectlModifiesResource result: unknown for command: %q, %q is not a pipeline filter", command, strings.Join(args, " "))
+			return "unknown"
+		}
 	}
 
-	// Default to unknown if no recognized kubectl commands found
-	klog.Infof("KubectlModifiesResource result: unknown for command: %q", command)
-	return "unknown"
+	klog.Infof("KubectlModifiesResource result: no (read-only) for command: %q", command)
+	return "no"
 }
 
-func analyzeCall(call *syntax.CallExpr) string {
-	if call == nil || len(call.Args) == 0 {
-		klog.Warning("analyzeCall: call is nil or has no args")
-		return "unknown"
+// isPipelineFilter reports whether a command is one of pipelineFilters, without flags
+// that read their program, patterns or input from files.
+func isPipelineFilter(args []string) bool {
+	if !pipelineFilters[commandName(args[0])] {
+		return false
 	}
-
-	// Extract command and arguments
-	var args []string
-	for _, arg := range call.Args {
-		lit := arg.Lit()
-		if lit == "" {
-			var sb strings.Builder
-			syntax.NewPrinter().Print(&sb, arg)
-			lit = strings.Trim(sb.String(), "'\"")
-		}
-		if lit != "" {
-			args = append(args, lit)
+	for _, arg := range args[1:] {
+		if arg == "-f" || strings.HasPrefix(arg, "--from-file") || strings.HasPrefix(arg, "--file") ||
+			strings.HasPrefix(arg, "--rawfile") || strings.HasPrefix(arg, "--slurpfile") || strings.HasPrefix(arg, "--files0-from") {
+			return false
 		}
 	}
+	return true
+}
 
+// analyzeKubectlArgs classifies a single kubectl invocation given as a list of arguments.
+func analyzeKubectlArgs(args []string) string {
 	if len(args) == 0 {
-		klog.Warning("analyzeCall: no arguments extracted from call")
+		klog.Warning("analyzeKubectlArgs: no arguments given")
 		return "unknown"
 	}
 
@@ -149,17 +137,17 @@ func analyzeCall(call *syntax.CallExpr) string {
 
 	// Reject quoted arguments (e.g., '"/path/kubectl"')
 	if (strings.HasPrefix(firstArg, "'") && strings.HasSuffix(firstArg, "'")) || (strings.HasPrefix(firstArg, "\"") && strings.HasSuffix(firstArg, "\"")) {
-		klog.V(2).Infof("analyzeCall: first arg is quoted: %q", firstArg)
+		klog.V(2).Infof("analyzeKubectlArgs: first arg is quoted: %q", firstArg)
 		return "unknown"
 	}
 
 	// Check if this is kubectl
 	if !strings.Contains(firstArg, "kubectl") {
-		klog.V(2).Infof("analyzeCall: first arg does not contain kubectl: %q", firstArg)
+		klog.V(2).Infof("analyzeKubectlArgs: first arg does not contain kubectl: %q", firstArg)
 		return "unknown"
 	}
 
-	klog.V(2).Infof("analyzeCall: found kubectl: %q", firstArg)
+	klog.V(2).Infof("analyzeKubectlArgs: found kubectl: %q", firstArg)
 
 	// Check for boolean or spaced key-value flags before the verb
 	for _, arg := range args[1:] {
@@ -168,7 +156,7 @@ func analyzeCall(call *syntax.CallExpr) string {
 		}
 		// If flag does not contain '=', it's boolean or spaced key-value
 		if !strings.Contains(arg, "=") {
-			klog.Warningf("analyzeCall: boolean or spaced key-value flag before verb: %q", arg)
+			klog.Warningf("analyzeKubectlArgs: boolean or spaced key-value flag before verb: %q", arg)
 			return "unknown"
 		}
 	}
@@ -176,23 +164,23 @@ func analyzeCall(call *syntax.CallExpr) string {
 	// Parse kubectl arguments to extract verb, subverb, and flags
 	verb, subVerb, hasDryRun := parseKubectlArgs(args[1:])
 	if verb == "" {
-		klog.Warningf("analyzeCall: no verb found after kubectl in args: %v", args)
+		klog.Warningf("analyzeKubectlArgs: no verb found after kubectl in args: %v", args)
 		return "unknown"
 	}
 
 	// Check standard operations - write operations first (prioritize immediate detection)
 	if (writeOps[verb] || writeSubOps[verb][subVerb]) && !hasDryRun {
-		klog.V(1).Infof("analyzeCall: write op for verb=%q subVerb=%q", verb, subVerb)
+		klog.V(1).Infof("analyzeKubectlArgs: write op for verb=%q subVerb=%q", verb, subVerb)
 		return "yes"
 	}
 
 	// Check read-only operations or dry-run write operations
 	if (readOnlyOps[verb] || readOnlySubOps[verb][subVerb]) || ((writeOps[verb] || writeSubOps[verb][subVerb]) && hasDryRun) {
-		klog.V(1).Infof("analyzeCall: read op for verb=%q subVerb=%q (dry-run=%v)", verb, subVerb, hasDryRun)
+		klog.V(1).Infof("analyzeKubectlArgs: read op for verb=%q subVerb=%q (dry-run=%v)", verb, subVerb, hasDryRun)
 		return "no"
 	}
 
-	klog.V(1).Infof("analyzeCall: unknown op for verb=%q subVerb=%q", verb, subVerb)
+	klog.V(1).Infof("analyzeKubectlArgs: unknown op for verb=%q subVerb=%q", verb, subVerb)
 	return "unknown"
 }
 
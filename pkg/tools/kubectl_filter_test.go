@@ -87,15 +87,15 @@ func TestKubectlModifiesResource(t *testing.T) {
 			{"Command with pipe", "kubectl get pods | grep nginx", "no"},
 			{"Command with backticks", "kubectl get pod `cat podname.txt`", "no"},
 			{"Complex path", "\"/path with spaces/kubectl\" get pods", "no"},
-			{"Command with env var", "KUBECONFIG=/path/to/config kubectl get pods", "no"},
+			{"Command with env var", "KUBECONFIG=/path/to/config kubectl get pods", "unknown"},
 
 			{"Not kubectl command", "ls -la", "unknown"},
 			{"Multiple spaces", "kubectl  get   pods", "no"},
 			{"Complex command with variables", "kubectl get pods -l app=$APP_NAME -n $NAMESPACE", "no"},
 			{"Command with quotes", "kubectl get pods -l \"app=my app\"", "no"},
 			{"Command with escaped quotes", "kubectl patch configmap my-config --patch \"{\\\"data\\\":{\\\"key\\\":\\\"new-value\\\"}}\"", "yes"},
-			{"Complex env vars", "KUBECONFIG=/path/to/config NS=default kubectl get pods -n $NS", "no"},
-			{"Command with multiple env vars", "KUBECONFIG=/config KUBECTL_EXTERNAL_DIFF=\"diff -u\" kubectl diff -f file.yaml", "no"},
+			{"Complex env vars", "KUBECONFIG=/path/to/config NS=default kubectl get pods -n $NS", "unknown"},
+			{"Command with multiple env vars", "KUBECONFIG=/config KUBECTL_EXTERNAL_DIFF=\"diff -u\" kubectl diff -f file.yaml", "unknown"},
 			{"Sequential commands with semicolon", "kubectl get ns; kubectl create ns test", "yes"},
 			{"Multiple safe commands", "kubectl get pods; kubectl get deployments", "no"},
 			{"Mix safe and unsafe with result", "kubectl get pods && kubectl delete pod bad-pod", "yes"},
@@ -127,6 +127,12 @@ func TestKubectlModifiesResource(t *testing.T) {
 			{"Jsonpath with quotes", "kubectl get pods -o jsonpath='{.items[0].metadata.name}'", "no"},
 			{"Command with grep", "kubectl get pods | grep -v Completed", "no"},
 			{"Command with awk", "kubectl get pods | awk '{print $1}'", "no"},
+			{"Pipeline through filters", "kubectl get pods -o json | jq '.items[].metadata.name' | sort | uniq -c | head -5", "no"},
+			{"Pipeline to a command that is not a filter", "kubectl get secret db -o yaml | base64", "unknown"},
+			{"Pipeline to grep with a pattern file", "kubectl get secret db -o yaml | grep -f patterns.txt", "unknown"},
+			{"Pipeline to sort with a compress program", "kubectl get secret db -o yaml | sort --compress-program=./evil", "unknown"},
+			{"Pipeline to jq with a program file", "kubectl get secret db -o json | jq --from-file prog.jq", "unknown"},
+			{"Sequence with find", "kubectl get pods; find / -name id_rsa", "unknown"},
 			{"Delete with force", "kubectl delete pod stuck-pod --force --grace-period=0", "yes"},
 			{"Custom resource get", "kubectl get virtualmachines", "no"},
 			{"Custom resource apply", "kubectl apply -f vm-instance.yaml", "yes"},
@@ -144,7 +150,7 @@ func TestKubectlModifiesResource(t *testing.T) {
 		t.Run(category, func(t *testing.T) {
 			for _, tt := range cases {
 				t.Run(tt.name, func(t *testing.T) {
-					result := kubectlModifiesResource(tt.command)
+					result := kubectlModifiesResource(tt.command, "")
 					if result != tt.expected {
 						t.Errorf("KubectlModifiesResource(%q) = %q, want %q",
 							tt.command, result, tt.expected)
@@ -201,7 +207,7 @@ func TestKubectlAnalyzerComponents(t *testing.T) {
 		}
 
 		for _, tt := range tests {
-			result := kubectlModifiesResource(tt.command)
+			result := kubectlModifiesResource(tt.command, "")
 			if result != tt.expectedRes {
 				t.Errorf("KubectlModifiesResource(%q) = %q, want %q",
 					tt.command, result, tt.expectedRes)
@@ -239,9 +245,10 @@ func TestKubectlCommandParsing(t *testing.T) {
 		{"kubectl prefix", "kubectl-proxy --port=8080", "unknown", "kubectl with additional suffix"},
 		{"different command", "kubectx production", "unknown", "different k8s tool"},
 
-		// Environment variables
-		{"env var prefix", "KUBECONFIG=/path/config kubectl get pods", "no", "environment variable prefix"},
-		{"multiple env vars", "KUBECONFIG=/config NS=default kubectl apply -f deploy.yaml --dry-run", "no", "multiple environment variables"},
+		// Environment variables, which can change the cluster or the programs kubectl runs
+		{"env var prefix", "KUBECONFIG=/path/config kubectl get pods", "unknown", "environment variable prefix"},
+		{"multiple env vars", "KUBECONFIG=/config NS=default kubectl apply -f deploy.yaml --dry-run", "unknown", "multiple environment variables"},
+		{"env wrapper", "env KUBECTL_EXTERNAL_DIFF=./evil kubectl diff -f file.yaml", "unknown", "environment variable set by env"},
 
 		// Complex scenarios
 		{"long path", "/very/long/path/to/kubectl get pods", "no", "very long path"},
@@ -271,7 +278,7 @@ func TestKubectlCommandParsing(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := kubectlModifiesResource(tt.command)
+			result := kubectlModifiesResource(tt.command, "")
 			if result != tt.expected {
 				t.Errorf("KubectlModifiesResource(%q) = %q, want %q\nDescription: %s",
 					tt.command, result, tt.expected, tt.desc)
@@ -320,7 +327,7 @@ func TestKubectlPathHandling(t *testing.T) {
 
 // TestKubectlDetectionLogic tests the core kubectl detection logic
 func TestKubectlDetectionLogic(t *testing.T) {
-	// Simulate the kubectl detection logic from analyzeCall
+	// Simulate the kubectl detection logic from analyzeKubectlArgs
 	testKubectlDetection := func(arg string) bool {
 		// Reject quoted arguments
 		if (strings.HasPrefix(arg, "'") && strings.HasSuffix(arg, "'")) || (strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"")) {
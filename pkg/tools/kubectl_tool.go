@@ -23,10 +23,6 @@ import (
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 )
 
-func init() {
-	RegisterTool(&Kubectl{})
-}
-
 type Kubectl struct{}
 
 func (t *Kubectl) Name() string {
@@ -109,10 +105,13 @@ func (t *Kubectl) Run(ctx context.Context, args map[string]any) (any, error) {
 		return &ExecResult{Error: "kubectl command must be a string"}, nil
 	}
 
-	return runKubectlCommand(ctx, command, workDir, kubeconfig)
+	kubeContext, namespace := kubeTargetFromContext(ctx)
+	command = InjectKubeTarget(command, kubeContext, namespace)
+
+	return runKubectlCommand(ctx, command, workDir, kubeconfig, kubeContext, namespace)
 }
 
-func runKubectlCommand(ctx context.Context, command, workDir, kubeconfig string) (*ExecResult, error) {
+func runKubectlCommand(ctx context.Context, command, workDir, kubeconfig, kubeContext, namespace string) (*ExecResult, error) {
 	// Check for interactive commands before proceeding
 	if isInteractive, err := IsInteractiveCommand(command); isInteractive {
 		return &ExecResult{Error: err.Error()}, nil
@@ -124,15 +123,13 @@ func runKubectlCommand(ctx context.Context, command, workDir, kubeconfig string)
 	} else {
 		cmd = exec.CommandContext(ctx, lookupBashBin(), "-c", command)
 	}
-	cmd.Env = os.Environ()
 	cmd.Dir = workDir
-	if kubeconfig != "" {
-		kubeconfig, err := expandShellVar(kubeconfig)
-		if err != nil {
-			return nil, err
-		}
-		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	env, remove, err := kubeconfigEnv(ctx, kubeconfig, kubeContext, namespace)
+	if err != nil {
+		return nil, err
 	}
+	defer remove()
+	cmd.Env = env
 
 	return executeCommand(ctx, cmd)
 }
@@ -155,10 +152,32 @@ func (t *Kubectl) IsInteractive(args map[string]any) (bool, error) {
 // This is used for permission checks before command execution
 // Returns "yes", "no", or "unknown"
 func (t *Kubectl) CheckModifiesResource(args map[string]any) string {
+	return t.CheckModifiesResourceContext(context.Background(), args)
+}
+
+// CheckModifiesResourceContext is CheckModifiesResource for a command running in the
+// working directory of the context, against which relative paths are resolved.
+func (t *Kubectl) CheckModifiesResourceContext(ctx context.Context, args map[string]any) string {
 	command, ok := args["command"].(string)
 	if !ok {
 		return "unknown"
 	}
 
-	return kubectlModifiesResource(command)
+	workDir, _ := ctx.Value(WorkDirKey).(string)
+	return kubectlModifiesResource(command, workDir)
+}
+
+// ExplainRisk describes any risky patterns found in the command, for the approval prompt.
+func (t *Kubectl) ExplainRisk(args map[string]any) string {
+	return t.ExplainRiskContext(context.Background(), args)
+}
+
+// ExplainRiskContext is ExplainRisk for a command running in the working directory of the context.
+func (t *Kubectl) ExplainRiskContext(ctx context.Context, args map[string]any) string {
+	command, ok := args["command"].(string)
+	if !ok {
+		return ""
+	}
+	workDir, _ := ctx.Value(WorkDirKey).(string)
+	return AnalyzeShellCommand(command, workDir).Explanation()
 }
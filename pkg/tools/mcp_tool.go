@@ -17,7 +17,12 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
@@ -50,16 +55,25 @@ type MCPTool struct {
 	description string
 	schema      *gollm.FunctionDefinition
 	manager     *mcp.Manager
+
+	// modifiesResource, modifiesResourceReason and trust are decided by the MCP manager,
+	// from the server configuration and the tool's annotations.
+	modifiesResource       string
+	modifiesResourceReason string
+	trust                  string
 }
 
 // NewMCPTool creates a new MCP tool wrapper.
-func NewMCPTool(serverName, toolName, description string, schema *gollm.FunctionDefinition, manager *mcp.Manager) *MCPTool {
+func NewMCPTool(serverName string, toolInfo mcp.Tool, schema *gollm.FunctionDefinition, manager *mcp.Manager) *MCPTool {
 	return &MCPTool{
-		serverName:  serverName,
-		toolName:    toolName,
-		description: description,
-		schema:      schema,
-		manager:     manager,
+		serverName:             serverName,
+		toolName:               toolInfo.Name,
+		description:            toolInfo.Description,
+		schema:                 schema,
+		manager:                manager,
+		modifiesResource:       toolInfo.ModifiesResource,
+		modifiesResourceReason: toolInfo.ModifiesResourceReason,
+		trust:                  toolInfo.Trust,
 	}
 }
 
@@ -94,13 +108,27 @@ func (t *MCPTool) IsInteractive(args map[string]any) (bool, error) {
 }
 
 // CheckModifiesResource determines if the command modifies kubernetes resources
-// For MCP tools, we'll conservatively assume they might modify resources
-// since we can't easily determine this for arbitrary external tools
+// We can't tell what arbitrary external tools do, so this is decided by the MCP configuration
+// and the tool's annotations, and is "unknown" when neither says.
 // Returns "yes", "no", or "unknown"
 func (t *MCPTool) CheckModifiesResource(args map[string]any) string {
-	// Since MCP tools can be arbitrary external tools and we don't have a way to know
-	// if they modify resources, we'll conservatively return "unknown"
-	return "unknown"
+	if t.modifiesResource == "" {
+		return "unknown"
+	}
+	return t.modifiesResource
+}
+
+// ExplainRisk tells the user which MCP server provides the tool, its trust level,
+// and why the call may modify resources.
+func (t *MCPTool) ExplainRisk(args map[string]any) string {
+	explanation := fmt.Sprintf("This tool is provided by the MCP server %q", t.serverName)
+	if t.trust != "" {
+		explanation += fmt.Sprintf(" (trust: %s)", t.trust)
+	}
+	if t.modifiesResourceReason != "" {
+		explanation += "; " + t.modifiesResourceReason
+	}
+	return explanation + "."
 }
 
 // Run executes the MCP tool by calling the appropriate MCP server.
@@ -117,12 +145,121 @@ func (t *MCPTool) Run(ctx context.Context, args map[string]any) (any, error) {
 	// args = mcp.ConvertArgs(args)
 
 	// Execute tool on MCP server
-	result, err := client.CallTool(ctx, t.toolName, args)
+	result, err := client.CallToolResult(ctx, t.toolName, args)
 	if err != nil {
 		log.Info("tool info", "name", t.toolName, "schema", t.schema)
 		log.Info("call info", "args", args)
 		return nil, fmt.Errorf("calling MCP tool %q on server %q: %w", t.toolName, t.serverName, err)
 	}
 
-	return result, nil
+	workDir, _ := ctx.Value(WorkDirKey).(string)
+	return newMCPResult(result, workDir), nil
+}
+
+// maxInlineResourceSize is the largest text resource included in the result content.
+// Larger resources are only saved to the work directory.
+const maxInlineResourceSize = 8 * 1024
+
+// MCPResult is the result of a call to an MCP tool.
+type MCPResult struct {
+	// Content is the text of the result, with any other content described.
+	Content string `json:"content"`
+	// StructuredContent is the tool's structured result, if any.
+	StructuredContent any `json:"structuredContent,omitempty"`
+	// SavedResources are the files in the work directory that resources returned by the tool were saved to.
+	SavedResources []string `json:"savedResources,omitempty"`
+
+	// images are not part of the JSON result; they are sent to the LLM separately.
+	images []gollm.ImageContent
+}
+
+// ImageResult is implemented by tool results that include images for the LLM.
+type ImageResult interface {
+	Images() []gollm.ImageContent
+}
+
+var _ ImageResult = &MCPResult{}
+
+// Images returns the images returned by the tool.
+func (r *MCPResult) Images() []gollm.ImageContent {
+	return r.images
+}
+
+// newMCPResult converts an MCP tool result, saving any embedded resources to workDir.
+func newMCPResult(result *mcp.ToolResult, workDir string) *MCPResult {
+	if result.IsError {
+		return &MCPResult{Content: result.String()}
+	}
+
+	r := &MCPResult{StructuredContent: result.Structured}
+	parts := append([]string{}, result.Text...)
+	if len(parts) == 0 && result.Structured != nil {
+		if b, err := json.Marshal(result.Structured); err == nil {
+			parts = append(parts, string(b))
+		}
+	}
+	for _, image := range result.Images {
+		r.images = append(r.images, gollm.ImageContent{MIMEType: image.MIMEType, Data: image.Data})
+		parts = append(parts, fmt.Sprintf("[%s image of %d bytes attached]", image.MIMEType, len(image.Data)))
+	}
+	for _, resource := range result.Resources {
+		if resource.IsLink() {
+			parts = append(parts, fmt.Sprintf("[resource link: %s]", resource.URI))
+			continue
+		}
+		description := fmt.Sprintf("[resource %s", resource.URI)
+		if workDir != "" {
+			path, err := saveMCPResource(workDir, resource)
+			if err != nil {
+				klog.Warningf("failed to save MCP resource %q: %v", resource.URI, err)
+			} else {
+				r.SavedResources = append(r.SavedResources, path)
+				description += " saved to " + path
+			}
+		}
+		switch {
+		case resource.Text != "" && len(resource.Text) <= maxInlineResourceSize:
+			description += "]\n" + resource.Text
+		case resource.Text != "":
+			description += fmt.Sprintf(", %d bytes of text]", len(resource.Text))
+		default:
+			description += fmt.Sprintf(", %s, %d bytes]", resource.MIMEType, len(resource.Blob))
+		}
+		parts = append(parts, description)
+	}
+
+	r.Content = strings.Join(parts, "\n")
+	if r.Content == "" {
+		r.Content = "Tool executed successfully, but no content was returned"
+	}
+	return r
+}
+
+// saveMCPResource writes an embedded resource to a new file in workDir, named after its URI.
+func saveMCPResource(workDir string, resource mcp.Resource) (string, error) {
+	name := resource.Name
+	if u, err := url.Parse(resource.URI); err == nil && path.Base(u.Path) != "." && path.Base(u.Path) != "/" {
+		name = path.Base(u.Path)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+
+	f, err := os.CreateTemp(workDir, "mcp-resource-*-"+name)
+	if err != nil {
+		return "", fmt.Errorf("creating file: %w", err)
+	}
+	defer f.Close()
+
+	data := resource.Blob
+	if data == nil {
+		data = []byte(resource.Text)
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+	return f.Name(), nil
 }
@@ -0,0 +1,66 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/mcp"
+)
+
+func TestNewMCPResult(t *testing.T) {
+	workDir := t.TempDir()
+	result := newMCPResult(&mcp.ToolResult{
+		Text:       []string{"dashboard rendered"},
+		Structured: map[string]any{"panels": 2},
+		Images:     []mcp.Image{{MIMEType: "image/png", Data: []byte("png")}},
+		Resources: []mcp.Resource{
+			{URI: "file:///reports/summary.txt", Text: "all good"},
+			{URI: "https://example.com/trace.bin", MIMEType: "application/octet-stream", Blob: []byte{0, 1, 2}},
+			{URI: "https://example.com/big.tar", Name: "big.tar"},
+		},
+	}, workDir)
+
+	if len(result.Images()) != 1 || result.Images()[0].MIMEType != "image/png" {
+		t.Errorf("unexpected images %+v", result.Images())
+	}
+	if result.StructuredContent == nil {
+		t.Errorf("expected structured content to be kept")
+	}
+	if len(result.SavedResources) != 2 {
+		t.Fatalf("expected 2 saved resources, got %v", result.SavedResources)
+	}
+	for i, want := range []string{"all good", "\x00\x01\x02"} {
+		saved := result.SavedResources[i]
+		if !strings.HasPrefix(saved, workDir) {
+			t.Errorf("resource saved outside the work directory: %s", saved)
+		}
+		data, err := os.ReadFile(saved)
+		if err != nil || string(data) != want {
+			t.Errorf("saved resource %s = %q (%v), want %q", saved, data, err, want)
+		}
+	}
+	for _, want := range []string{"dashboard rendered", "[image/png image of 3 bytes attached]", "all good", "application/octet-stream, 3 bytes", "[resource link: https://example.com/big.tar]"} {
+		if !strings.Contains(result.Content, want) {
+			t.Errorf("content does not contain %q:\n%s", want, result.Content)
+		}
+	}
+
+	if got := newMCPResult(&mcp.ToolResult{IsError: true, Text: []string{"boom"}}, workDir); !strings.Contains(got.Content, `"error": true`) {
+		t.Errorf("unexpected error result %q", got.Content)
+	}
+}
@@ -0,0 +1,1065 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// RiskKind categorizes a risk found by the shell analyzer.
+type RiskKind string
+
+const (
+	RiskClusterWrite        RiskKind = "cluster-write"
+	RiskDestructive         RiskKind = "destructive"
+	RiskRemoteCodeExecution RiskKind = "remote-code-execution"
+	RiskExfiltration        RiskKind = "exfiltration"
+	RiskFileWrite           RiskKind = "file-write"
+	RiskPrivilegeEscalation RiskKind = "privilege-escalation"
+)
+
+// ShellRisk is a single risky pattern found in a shell command.
+type ShellRisk struct {
+	Kind    RiskKind
+	Command string
+	Reason  string
+}
+
+// ShellAnalysis is the result of analyzing a (possibly composite) shell command.
+type ShellAnalysis struct {
+	Command string
+	// Risks lists every risky pattern found, in the order they appear.
+	Risks []ShellRisk
+	// KubectlReads is the number of read-only kubectl invocations.
+	KubectlReads int
+	// KubectlUnknown is the number of kubectl invocations we could not classify.
+	KubectlUnknown int
+	// Unknown lists commands which are neither known to be safe nor known to be risky.
+	Unknown []string
+	// Others lists the arguments of the commands run other than kubectl and wrappers such as xargs.
+	Others [][]string
+	// ParseError is set if the command could not be parsed.
+	ParseError error
+}
+
+// maxShellNesting bounds how deep we follow `sh -c`, `eval` and similar wrappers.
+const maxShellNesting = 8
+
+var (
+	// safeCommands only read their input (or files) and write to stdout.
+	// Commands that can also write files, run commands or change the system with some
+	// arguments (e.g. sed scripts with w or e, `date -s`, `hostname NAME`, `tree -o`,
+	// or the shell escapes of less and more) are not listed, and need approval.
+	safeCommands = map[string]bool{
+		"grep": true, "egrep": true, "fgrep": true, "cat": true, "head": true,
+		"tail": true, "jq": true, "uniq": true, "wc": true, "cut": true,
+		"tr": true, "echo": true, "printf": true, "ls": true, "column": true,
+		"base64": true, "true": true, "false": true, ":": true,
+		"test": true, "[": true, "basename": true, "dirname": true, "pwd": true,
+		"diff": true, "cmp": true, "which": true, "whoami": true,
+		"uname": true, "id": true, "sleep": true, "seq": true, "nl": true,
+		"rev": true, "fold": true, "paste": true, "join": true, "comm": true,
+		"stat": true, "file": true, "du": true, "df": true, "ps": true,
+		"realpath": true, "readlink": true, "md5sum": true, "sha256sum": true,
+		"sha1sum": true, "tac": true, "expr": true,
+		"yq": true, "awk": true, "gawk": true,
+		"mawk": true, "nawk": true, "sort": true, "find": true,
+	}
+
+	// wrapperCommands run the rest of their arguments as a command.
+	wrapperCommands = map[string]bool{
+		"env": true, "nohup": true, "time": true, "nice": true, "timeout": true,
+		"command": true, "builtin": true, "exec": true, "stdbuf": true,
+		"watch": true, "xargs": true,
+	}
+
+	privilegeCommands = map[string]bool{"sudo": true, "su": true, "doas": true}
+
+	destructiveCommands = map[string]string{
+		"rm":       "deletes files",
+		"rmdir":    "deletes directories",
+		"unlink":   "deletes files",
+		"shred":    "irrecoverably overwrites files",
+		"dd":       "writes raw data to files or devices",
+		"truncate": "truncates files",
+		"wipefs":   "wipes filesystem signatures",
+		"fdisk":    "modifies disk partitions",
+		"parted":   "modifies disk partitions",
+		"kill":     "terminates processes",
+		"killall":  "terminates processes",
+		"pkill":    "terminates processes",
+		"shutdown": "shuts down the machine",
+		"reboot":   "reboots the machine",
+		"halt":     "halts the machine",
+		"poweroff": "powers off the machine",
+	}
+
+	// fileWriteCommands write to (some of) the paths given as arguments.
+	fileWriteCommands = map[string]bool{
+		"cp": true, "ln": true, "install": true, "mv": true, "touch": true,
+		"mkdir": true, "tee": true, "chmod": true, "chown": true, "chgrp": true,
+	}
+
+	downloadCommands = map[string]bool{"curl": true, "wget": true}
+
+	rawNetworkCommands = map[string]string{
+		"nc":     "opens a raw network connection",
+		"ncat":   "opens a raw network connection",
+		"netcat": "opens a raw network connection",
+		"socat":  "opens a raw network connection",
+		"telnet": "opens a raw network connection",
+		"scp":    "copies files over the network",
+		"sftp":   "copies files over the network",
+		"rsync":  "copies files, possibly over the network",
+		"ftp":    "copies files over the network",
+		"ssh":    "runs commands on a remote host",
+	}
+
+	shellInterpreters = map[string]bool{
+		"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	}
+
+	scriptInterpreters = map[string]bool{
+		"python": true, "python3": true, "perl": true, "ruby": true, "node": true,
+		"php": true, "source": true, ".": true,
+	}
+
+	// uploadFlags make curl or wget send data to the remote endpoint.
+	uploadFlags = []string{
+		"-d", "--data", "-F", "--form", "-T", "--upload-file", "--json",
+		"--post-data", "--post-file", "--body-data", "--body-file",
+	}
+
+	// sensitivePathMarkers identify paths that hold cluster credentials.
+	sensitivePathMarkers = []string{".kube", "kubeconfig", "KUBECONFIG", "/var/run/secrets", "serviceaccount"}
+)
+
+// AnalyzeShellCommand parses a shell command and classifies every command it runs,
+// including those hidden in pipelines, subshells, command substitutions and
+// wrappers such as `xargs` or `sh -c`. Relative paths are resolved against workDir;
+// if workDir is empty, relative paths are assumed to stay inside the working directory.
+func AnalyzeShellCommand(command, workDir string) *ShellAnalysis {
+	s := &shellAnalyzer{
+		workDir:  workDir,
+		dir:      workDir,
+		analysis: &ShellAnalysis{Command: command},
+	}
+	s.parseAndAnalyze(command, nil)
+	if s.createsLinks && len(s.writes) > 0 {
+		// The links may point outside the working directory by the time the writes run.
+		for _, write := range s.writes {
+			s.addUnknown(write)
+		}
+	}
+	return s.analysis
+}
+
+// ModifiesResource summarizes the analysis as "yes", "no" or "unknown".
+// "no" is only returned if every command is known to be read-only.
+func (a *ShellAnalysis) ModifiesResource() string {
+	switch {
+	case a.ParseError != nil:
+		return "unknown"
+	case len(a.Risks) > 0:
+		return "yes"
+	case a.KubectlUnknown > 0 || len(a.Unknown) > 0:
+		return "unknown"
+	case a.KubectlReads == 0 && strings.TrimSpace(a.Command) == "":
+		return "unknown"
+	}
+	return "no"
+}
+
+// Explanation returns a human-readable description of the risks found,
+// or an empty string if none were found.
+func (a *ShellAnalysis) Explanation() string {
+	if len(a.Risks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Risks detected:")
+	for _, risk := range a.Risks {
+		fmt.Fprintf(&sb, "\n  - %s: %s (%s)", risk.Kind, risk.Reason, risk.Command)
+	}
+	return sb.String()
+}
+
+// pipeContext describes what upstream pipeline stages feed into a command's stdin.
+type pipeContext struct {
+	clusterData bool
+	download    bool
+}
+
+type shellAnalyzer struct {
+	workDir  string
+	analysis *ShellAnalysis
+	depth    int
+	pipe     *pipeContext
+	// dir is the directory relative paths are resolved against, as the command changes to
+	// directories inside workDir. leftWorkDir is set once it changes to a directory outside
+	// workDir, or one that cannot be determined, after which relative paths can no longer be trusted.
+	dir         string
+	leftWorkDir bool
+	// createsLinks is set if the command creates links, and writes lists the commands writing
+	// inside the working directory: the writes may go through the links.
+	createsLinks bool
+	writes       []string
+}
+
+func (s *shellAnalyzer) addRisk(kind RiskKind, command, reason string) {
+	for _, r := range s.analysis.Risks {
+		if r.Kind == kind && r.Command == command && r.Reason == reason {
+			return
+		}
+	}
+	s.analysis.Risks = append(s.analysis.Risks, ShellRisk{Kind: kind, Command: command, Reason: reason})
+}
+
+func (s *shellAnalyzer) addUnknown(command string) {
+	s.analysis.Unknown = append(s.analysis.Unknown, command)
+}
+
+func (s *shellAnalyzer) parseAndAnalyze(command string, pipe *pipeContext) {
+	if s.depth > maxShellNesting {
+		s.addUnknown(command)
+		return
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		klog.V(2).Infof("shell analyzer: failed to parse %q: %v", command, err)
+		if s.analysis.ParseError == nil {
+			s.analysis.ParseError = err
+		}
+		return
+	}
+
+	s.depth++
+	saved := s.pipe
+	s.pipe = pipe
+	syntax.Walk(file, s.visit)
+	s.pipe = saved
+	s.depth--
+}
+
+func (s *shellAnalyzer) visit(node syntax.Node) bool {
+	switch n := node.(type) {
+	case *syntax.Stmt:
+		s.checkRedirects(n.Redirs)
+		if call, ok := n.Cmd.(*syntax.CallExpr); ok {
+			s.call(call, n.Redirs)
+			return false
+		}
+	case *syntax.BinaryCmd:
+		if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+			s.pipeline(n)
+			return false
+		}
+	case *syntax.CallExpr:
+		s.call(n, nil)
+		return false
+	case *syntax.CmdSubst:
+		s.withoutPipe(func() {
+			for _, stmt := range n.Stmts {
+				syntax.Walk(stmt, s.visit)
+			}
+		})
+		return false
+	case *syntax.ProcSubst:
+		s.withoutPipe(func() {
+			for _, stmt := range n.Stmts {
+				syntax.Walk(stmt, s.visit)
+			}
+		})
+		return false
+	}
+	return true
+}
+
+func (s *shellAnalyzer) withoutPipe(fn func()) {
+	saved := s.pipe
+	s.pipe = nil
+	fn()
+	s.pipe = saved
+}
+
+// pipeline analyzes each stage of a pipeline, telling later stages what the earlier ones produce.
+func (s *shellAnalyzer) pipeline(cmd *syntax.BinaryCmd) {
+	var stages []*syntax.Stmt
+	var flatten func(stmt *syntax.Stmt)
+	flatten = func(stmt *syntax.Stmt) {
+		if bc, ok := stmt.Cmd.(*syntax.BinaryCmd); ok && (bc.Op == syntax.Pipe || bc.Op == syntax.PipeAll) && len(stmt.Redirs) == 0 {
+			flatten(bc.X)
+			flatten(bc.Y)
+			return
+		}
+		stages = append(stages, stmt)
+	}
+	flatten(cmd.X)
+	flatten(cmd.Y)
+
+	var upstream pipeContext
+	if s.pipe != nil {
+		upstream = *s.pipe
+	}
+	saved := s.pipe
+	for _, stage := range stages {
+		ctx := upstream
+		s.pipe = &ctx
+		syntax.Walk(stage, s.visit)
+
+		syntax.Walk(stage, func(node syntax.Node) bool {
+			if call, ok := node.(*syntax.CallExpr); ok {
+				args := unwrapCommand(callArgs(call))
+				if len(args) == 0 {
+					return true
+				}
+				name := commandName(args[0])
+				if isKubectlName(name) || name == "helm" || mentionsSensitivePath(args) {
+					upstream.clusterData = true
+				}
+				if downloadCommands[name] {
+					upstream.download = true
+				}
+			}
+			return true
+		})
+	}
+	s.pipe = saved
+}
+
+func (s *shellAnalyzer) call(call *syntax.CallExpr, redirs []*syntax.Redirect) {
+	// Command substitutions in assignments and arguments run as separate commands.
+	s.withoutPipe(func() {
+		for _, assign := range call.Assigns {
+			if assign.Value != nil {
+				syntax.Walk(assign.Value, s.visit)
+			}
+		}
+		for _, word := range call.Args {
+			syntax.Walk(word, s.visit)
+		}
+	})
+
+	args := callArgs(call)
+	if len(args) == 0 {
+		// Plain variable assignment
+		return
+	}
+	if len(call.Assigns) > 0 {
+		// Variables such as PATH, LD_PRELOAD or KUBECTL_EXTERNAL_DIFF change what the command runs.
+		s.addUnknown(strings.Join(assignNames(call.Assigns), " ") + " " + strings.Join(args, " "))
+	}
+	s.classify(args, call, redirs)
+}
+
+// classify analyzes a single command given as a list of arguments. call is the
+// originating syntax node and is used to look into command substitutions.
+func (s *shellAnalyzer) classify(args []string, call *syntax.CallExpr, redirs []*syntax.Redirect) {
+	if len(args) == 0 {
+		return
+	}
+	display := strings.Join(args, " ")
+	name := commandName(args[0])
+	if !isKubectlName(name) && !wrapperCommands[name] && !privilegeCommands[name] {
+		s.analysis.Others = append(s.analysis.Others, args)
+	}
+
+	switch {
+	case isKubectlName(name):
+		switch analyzeKubectlArgs(args) {
+		case "yes":
+			s.addRisk(RiskClusterWrite, display, "modifies cluster resources")
+		case "no":
+			s.analysis.KubectlReads++
+		default:
+			s.analysis.KubectlUnknown++
+		}
+
+	case privilegeCommands[name]:
+		s.addRisk(RiskPrivilegeEscalation, display, "runs with elevated privileges")
+		s.classify(skipFlags(args[1:], nil), call, redirs)
+
+	case wrapperCommands[name]:
+		if setsEnv(args) {
+			s.addUnknown(display)
+		}
+		inner := unwrapCommand(args)
+		if len(inner) == 0 {
+			// e.g. bare `env` or `xargs` (which defaults to echo)
+			return
+		}
+		s.classify(inner, call, redirs)
+
+	case shellInterpreters[name]:
+		s.shellInterpreter(args, call)
+
+	case scriptInterpreters[name] || name == "eval":
+		s.scriptInterpreter(name, args, call)
+
+	case destructiveCommands[name] != "":
+		reason := destructiveCommands[name]
+		if name == "rm" && hasAnyFlag(args[1:], "r", "R", "f") {
+			reason = "deletes files recursively or without confirmation"
+		}
+		s.addRisk(RiskDestructive, display, reason)
+
+	case strings.HasPrefix(name, "mkfs"):
+		s.addRisk(RiskDestructive, display, "formats a filesystem")
+
+	case fileWriteCommands[name]:
+		s.fileWrite(name, args, display)
+
+	case downloadCommands[name]:
+		s.download(name, args, call, redirs, display)
+
+	case rawNetworkCommands[name] != "":
+		reason := rawNetworkCommands[name]
+		if s.pipe != nil && s.pipe.clusterData {
+			reason = "sends cluster data over the network"
+		}
+		s.addRisk(RiskExfiltration, display, reason)
+
+	case name == "cd" || name == "pushd":
+		target := ""
+		if rest := skipFlags(args[1:], nil); len(rest) > 0 {
+			target = rest[0]
+		}
+		// `cd` alone goes to the home directory, and `cd -` to the previous one.
+		if target == "" || target == "-" || s.outsideWorkDir(target) {
+			s.leftWorkDir = true
+		} else {
+			s.dir = s.resolve(target)
+		}
+
+	case name == "popd":
+		// The directory stack is not followed.
+		s.leftWorkDir = true
+
+	case name == "find":
+		s.find(args, call, redirs)
+
+	case name == "sed":
+		// sed scripts can write files (w, s///w) and run commands (e, s///e): only in-place
+		// edits outside the working directory are flagged as risks, any other use is unknown.
+		if hasInPlaceFlag(args[1:]) {
+			s.checkPaths(lastOperand(args), display)
+		}
+		s.addUnknown(display)
+
+	case safeCommands[name]:
+		s.safeCommand(name, args, display)
+
+	default:
+		s.addUnknown(display)
+	}
+}
+
+func (s *shellAnalyzer) shellInterpreter(args []string, call *syntax.CallExpr) {
+	display := strings.Join(args, " ")
+	for i, arg := range args[1:] {
+		if arg == "-c" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c")) {
+			if i+2 < len(args) {
+				s.parseAndAnalyze(args[i+2], s.pipe)
+			}
+			if call != nil && wordsRun(call.Args, isDownloadCall) {
+				s.addRisk(RiskRemoteCodeExecution, display, "executes a script downloaded from the network")
+			}
+			return
+		}
+	}
+	s.scriptInterpreter(commandName(args[0]), args, call)
+}
+
+func (s *shellAnalyzer) scriptInterpreter(name string, args []string, call *syntax.CallExpr) {
+	display := strings.Join(args, " ")
+	if call != nil && wordsRun(call.Args, isDownloadCall) {
+		s.addRisk(RiskRemoteCodeExecution, display, "executes a script downloaded from the network")
+		return
+	}
+	if name == "eval" {
+		s.parseAndAnalyze(strings.Join(args[1:], " "), s.pipe)
+		return
+	}
+	readsStdin := len(skipFlags(args[1:], nil)) == 0 || args[len(args)-1] == "-"
+	if readsStdin && s.pipe != nil && s.pipe.download {
+		s.addRisk(RiskRemoteCodeExecution, display, "pipes a script downloaded from the network into an interpreter")
+		return
+	}
+	// Inline code (python -c, perl -e, ...) or a script file we cannot inspect.
+	s.addUnknown(display)
+}
+
+func (s *shellAnalyzer) download(name string, args []string, call *syntax.CallExpr, redirs []*syntax.Redirect, display string) {
+	switch {
+	case s.pipe != nil && s.pipe.clusterData:
+		s.addRisk(RiskExfiltration, display, "sends cluster data to a remote endpoint")
+		return
+	case call != nil && wordsRun(call.Args, isClusterDataCall):
+		s.addRisk(RiskExfiltration, display, "embeds cluster data in a network request")
+		return
+	case mentionsSensitivePath(args):
+		s.addRisk(RiskExfiltration, display, "sends cluster credentials to a remote endpoint")
+		return
+	case hasInputRedirect(redirs):
+		s.addRisk(RiskExfiltration, display, "sends local file contents to a remote endpoint")
+		return
+	}
+	for _, arg := range args[1:] {
+		for _, flag := range uploadFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") || (len(flag) == 2 && strings.HasPrefix(arg, flag) && !strings.HasPrefix(arg, "--")) {
+				s.addRisk(RiskExfiltration, display, "uploads data to a remote endpoint")
+				return
+			}
+		}
+	}
+
+	outputFlag := "-o"
+	if name == "wget" {
+		outputFlag = "-O"
+	}
+	for i, arg := range args[1:] {
+		var target string
+		switch {
+		case arg == outputFlag || arg == "--output" || arg == "--output-document":
+			if i+2 < len(args) {
+				target = args[i+2]
+			}
+		case strings.HasPrefix(arg, "--output="), strings.HasPrefix(arg, "--output-document="):
+			target = arg[strings.Index(arg, "=")+1:]
+		default:
+			continue
+		}
+		if target != "-" && s.writeOutsideWorkDir(target, display) {
+			s.addRisk(RiskFileWrite, display, fmt.Sprintf("downloads to %s, outside the working directory", target))
+			return
+		}
+	}
+
+	// A plain download is not destructive, but it does reach out to the network.
+	s.addUnknown(display)
+}
+
+func (s *shellAnalyzer) fileWrite(name string, args []string, display string) {
+	if name == "ln" || (name == "cp" && createsCopyLinks(args[1:])) {
+		s.createsLinks = true
+	}
+	s.checkPaths(fileWriteTargets(name, args[1:]), display)
+}
+
+// fileWriteValueFlags are the short flags of file writing commands that take a value.
+var fileWriteValueFlags = map[string]string{
+	"cp":      "St",
+	"mv":      "St",
+	"ln":      "St",
+	"install": "Stmog",
+}
+
+// fileWriteTargets returns the paths a file writing command writes to, given its arguments.
+func fileWriteTargets(name string, args []string) []string {
+	var operands []string
+	targetDir, hasTargetDir, dirs := "", false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case arg == "--target-directory":
+			hasTargetDir = true
+			if i+1 < len(args) {
+				targetDir = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--target-directory="):
+			hasTargetDir = true
+			targetDir = strings.TrimPrefix(arg, "--target-directory=")
+		case arg == "--suffix" || arg == "--mode" || arg == "--owner" || arg == "--group":
+			i++
+		case name == "install" && arg == "--directory":
+			dirs = true
+		case name == "chmod" && isSymbolicMode(arg):
+			// e.g. chmod -w file
+			operands = append(operands, arg)
+		case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && arg != "-":
+			// A group of short flags, the first one taking a value ends it: -vt DIR, -tDIR.
+			for j := 1; j < len(arg); j++ {
+				flag := arg[j]
+				if name == "install" && flag == 'd' {
+					dirs = true
+				}
+				if !strings.ContainsRune(fileWriteValueFlags[name], rune(flag)) {
+					continue
+				}
+				value := arg[j+1:]
+				if value == "" && i+1 < len(args) {
+					value = args[i+1]
+					i++
+				}
+				if flag == 't' {
+					hasTargetDir, targetDir = true, value
+				}
+				break
+			}
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			// Other long flags
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	switch name {
+	case "chmod", "chown", "chgrp":
+		// The first operand is the mode or owner, unless they are taken from a reference file.
+		if len(operands) > 0 && !hasFlagPrefix(args, "--reference") {
+			operands = operands[1:]
+		}
+	case "cp", "ln", "install":
+		switch {
+		case hasTargetDir:
+			operands = []string{targetDir}
+		case dirs:
+			// install -d creates every operand.
+		case len(operands) > 0:
+			operands = operands[len(operands)-1:]
+		}
+	case "mv":
+		// The sources are removed too.
+		if hasTargetDir {
+			operands = append(operands, targetDir)
+		}
+	}
+	return operands
+}
+
+// createsCopyLinks reports whether cp arguments make it create links instead of copies.
+func createsCopyLinks(args []string) bool {
+	for _, arg := range args {
+		if arg == "--symbolic-link" || arg == "--link" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "sl")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSymbolicMode reports whether a chmod argument starting with - is a mode rather than a flag.
+func isSymbolicMode(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && strings.Trim(arg[1:], "rwxXst") == ""
+}
+
+func (s *shellAnalyzer) checkPaths(paths []string, display string) {
+	for _, path := range paths {
+		if s.writeOutsideWorkDir(path, display) {
+			s.addRisk(RiskFileWrite, display, fmt.Sprintf("writes to %s, outside the working directory", path))
+			return
+		}
+	}
+}
+
+func (s *shellAnalyzer) find(args []string, call *syntax.CallExpr, redirs []*syntax.Redirect) {
+	display := strings.Join(args, " ")
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "-delete":
+			s.addRisk(RiskDestructive, display, "deletes the files it finds")
+		case "-fprint", "-fprint0", "-fprintf", "-fls":
+			if i+1 < len(args) && s.writeOutsideWorkDir(args[i+1], display) {
+				s.addRisk(RiskFileWrite, display, fmt.Sprintf("writes to %s, outside the working directory", args[i+1]))
+			}
+		case "-exec", "-execdir", "-ok", "-okdir":
+			var inner []string
+			for i++; i < len(args) && args[i] != ";" && args[i] != "+"; i++ {
+				inner = append(inner, args[i])
+			}
+			s.classify(inner, call, redirs)
+		}
+	}
+}
+
+func (s *shellAnalyzer) safeCommand(name string, args []string, display string) {
+	switch name {
+	case "yq":
+		if hasInPlaceFlag(args[1:]) {
+			s.checkPaths(lastOperand(args), display)
+		}
+	case "uniq":
+		// `uniq INPUT OUTPUT` writes to OUTPUT.
+		if len(skipFlags(args[1:], map[string]bool{"-f": true, "-s": true, "-w": true})) > 1 {
+			s.addUnknown(display)
+		}
+	case "sort":
+		// sort writes to its output file and temporary directory, and runs its compress program.
+		for _, arg := range args[1:] {
+			short := strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--")
+			if (short && strings.ContainsAny(arg, "oT")) || strings.HasPrefix(arg, "--output") ||
+				strings.HasPrefix(arg, "--temporary-directory") || strings.HasPrefix(arg, "--compress-program") {
+				s.addUnknown(display)
+				return
+			}
+		}
+	case "awk", "gawk", "mawk", "nawk":
+		// awk programs can run commands and write files themselves, and programs read from files
+		// or loaded as extensions cannot be inspected.
+		for _, arg := range args[1:] {
+			if strings.Contains(arg, "system") || strings.Contains(arg, ">") || strings.Contains(arg, "|") {
+				s.addUnknown(display)
+				return
+			}
+		}
+		if hasFlagPrefix(args[1:], "-f", "-E", "-i", "-l", "--file", "--exec", "--include", "--load") {
+			s.addUnknown(display)
+		}
+	}
+}
+
+// checkRedirects flags output redirections that write outside the working directory.
+func (s *shellAnalyzer) checkRedirects(redirs []*syntax.Redirect) {
+	for _, r := range redirs {
+		switch r.Op {
+		case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+		default:
+			continue
+		}
+		target, ok := wordLiteral(r.Word)
+		if !ok {
+			s.addRisk(RiskFileWrite, "> "+printWord(r.Word), "redirects output to a path that cannot be determined")
+			continue
+		}
+		if s.writeOutsideWorkDir(target, "> "+target) {
+			reason := fmt.Sprintf("overwrites %s, outside the working directory", target)
+			if r.Op == syntax.AppOut || r.Op == syntax.AppAll {
+				reason = fmt.Sprintf("appends to %s, outside the working directory", target)
+			}
+			s.addRisk(RiskFileWrite, "> "+target, reason)
+		}
+	}
+}
+
+// writeOutsideWorkDir reports whether path, written to by command, is (or may be) outside the
+// working directory. Writes inside it are recorded, in case the command creates links.
+func (s *shellAnalyzer) writeOutsideWorkDir(path, command string) bool {
+	if isStandardStream(path) {
+		return false
+	}
+	if s.outsideWorkDir(path) {
+		return true
+	}
+	s.writes = append(s.writes, command)
+	return false
+}
+
+func isStandardStream(path string) bool {
+	return path == "/dev/null" || path == "/dev/stdout" || path == "/dev/stderr" || strings.HasPrefix(path, "/dev/fd/")
+}
+
+// outsideWorkDir reports whether path is (or may be) outside the working directory.
+// Symbolic links in the part of the path that exists are followed.
+func (s *shellAnalyzer) outsideWorkDir(path string) bool {
+	switch {
+	case path == "":
+		return true
+	case isStandardStream(path):
+		return false
+	case strings.HasPrefix(path, "~"), strings.Contains(path, "$"), strings.ContainsAny(path, "*?["):
+		return true
+	}
+
+	if hasParentAfterName(path) {
+		// The parent of a link is not the directory the link is in.
+		return true
+	}
+	if !filepath.IsAbs(path) {
+		if s.leftWorkDir {
+			return true
+		}
+		if s.workDir == "" {
+			cleaned := filepath.Clean(filepath.Join(s.dir, path))
+			return cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+		}
+		path = filepath.Join(s.dir, path)
+	}
+	if s.workDir == "" {
+		return true
+	}
+	if !isWithin(filepath.Clean(s.workDir), filepath.Clean(path)) {
+		return true
+	}
+	return !isWithin(resolveSymlinks(filepath.Clean(s.workDir)), resolveSymlinks(filepath.Clean(path)))
+}
+
+// hasParentAfterName reports whether path goes to the parent of a directory it names, e.g. a/../b.
+func hasParentAfterName(path string) bool {
+	named := false
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			if named {
+				return true
+			}
+		default:
+			named = true
+		}
+	}
+	return false
+}
+
+// resolve returns the directory path inside the working directory that relative paths
+// are resolved against after changing to it.
+func (s *shellAnalyzer) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(s.dir, path)
+}
+
+// callArgs extracts the arguments of a call as strings. Quoted literals are unquoted;
+// arguments with expansions are printed as written, without surrounding quotes.
+func callArgs(call *syntax.CallExpr) []string {
+	var args []string
+	for _, arg := range call.Args {
+		lit, ok := wordLiteral(arg)
+		if !ok {
+			lit = strings.Trim(printWord(arg), "'\"")
+		}
+		if lit != "" {
+			args = append(args, lit)
+		}
+	}
+	return args
+}
+
+func printWord(word *syntax.Word) string {
+	if word == nil {
+		return ""
+	}
+	var sb strings.Builder
+	syntax.NewPrinter().Print(&sb, word)
+	return sb.String()
+}
+
+// wordLiteral returns the value of a word if it does not contain any expansions.
+func wordLiteral(word *syntax.Word) (string, bool) {
+	if word == nil {
+		return "", false
+	}
+	var sb strings.Builder
+	for _, part := range word.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			sb.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, inner := range p.Parts {
+				lit, ok := inner.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// wordsRun reports whether any command substitution inside words runs a command matching pred.
+func wordsRun(words []*syntax.Word, pred func(args []string) bool) bool {
+	found := false
+	for _, word := range words {
+		syntax.Walk(word, func(node syntax.Node) bool {
+			if call, ok := node.(*syntax.CallExpr); ok && pred(unwrapCommand(callArgs(call))) {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+func isDownloadCall(args []string) bool {
+	return len(args) > 0 && downloadCommands[commandName(args[0])]
+}
+
+func isClusterDataCall(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name := commandName(args[0])
+	return isKubectlName(name) || name == "helm" || mentionsSensitivePath(args)
+}
+
+// commandName returns the base name of a command, without any .exe suffix.
+func commandName(arg string) string {
+	return strings.TrimSuffix(filepath.Base(arg), ".exe")
+}
+
+func isKubectlName(name string) bool {
+	return strings.Contains(name, "kubectl")
+}
+
+// envValueFlags are the flags of env that take a value.
+var envValueFlags = map[string]bool{"-u": true, "-C": true}
+
+// unwrapCommand strips wrappers such as `env FOO=bar`, `timeout 10` or `xargs -n1`
+// and returns the command they run.
+func unwrapCommand(args []string) []string {
+	for len(args) > 0 && wrapperCommands[commandName(args[0])] {
+		args = unwrapOnce(args)
+	}
+	return args
+}
+
+// unwrapOnce strips the wrapper command args starts with.
+func unwrapOnce(args []string) []string {
+	name := commandName(args[0])
+	var valueFlags map[string]bool
+	switch name {
+	case "xargs":
+		valueFlags = map[string]bool{"-I": true, "-n": true, "-P": true, "-L": true, "-s": true, "-d": true, "-E": true, "-a": true}
+	case "nice":
+		valueFlags = map[string]bool{"-n": true}
+	case "watch":
+		valueFlags = map[string]bool{"-n": true}
+	case "timeout":
+		valueFlags = map[string]bool{"-s": true, "-k": true}
+	case "env":
+		valueFlags = envValueFlags
+	}
+	rest := skipFlags(args[1:], valueFlags)
+	switch name {
+	case "env":
+		for len(rest) > 0 && strings.Contains(rest[0], "=") {
+			rest = rest[1:]
+		}
+	case "timeout":
+		if len(rest) > 0 {
+			rest = rest[1:] // duration
+		}
+	}
+	return rest
+}
+
+// setsEnv reports whether a chain of wrapper commands sets environment variables with `env NAME=VALUE`.
+func setsEnv(args []string) bool {
+	for len(args) > 0 && wrapperCommands[commandName(args[0])] {
+		if commandName(args[0]) == "env" {
+			if rest := skipFlags(args[1:], envValueFlags); len(rest) > 0 && strings.Contains(rest[0], "=") {
+				return true
+			}
+		}
+		args = unwrapOnce(args)
+	}
+	return false
+}
+
+// assignNames returns the variable assignments of a call as written, e.g. PATH=.
+func assignNames(assigns []*syntax.Assign) []string {
+	var names []string
+	for _, assign := range assigns {
+		value := ""
+		if assign.Value != nil {
+			value = printWord(assign.Value)
+		}
+		names = append(names, assign.Name.Value+"="+value)
+	}
+	return names
+}
+
+// skipFlags drops leading flags (and the values of flags listed in valueFlags).
+func skipFlags(args []string, valueFlags map[string]bool) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		if args[0] == "--" {
+			return args[1:]
+		}
+		if valueFlags[args[0]] && len(args) > 1 {
+			args = args[2:]
+			continue
+		}
+		args = args[1:]
+	}
+	return args
+}
+
+// hasAnyFlag reports whether any short flag in args contains one of letters.
+func hasAnyFlag(args []string, letters ...string) bool {
+	for _, arg := range args {
+		if arg == "--recursive" || arg == "--force" {
+			return true
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
+			for _, l := range letters {
+				if strings.Contains(arg, l) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasFlagPrefix reports whether any of args starts with one of prefixes.
+func hasFlagPrefix(args []string, prefixes ...string) bool {
+	for _, arg := range args {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(arg, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasInPlaceFlag(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--in-place") || arg == "-i" || (strings.HasPrefix(arg, "-i") && !strings.HasPrefix(arg, "--")) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasInputRedirect(redirs []*syntax.Redirect) bool {
+	for _, r := range redirs {
+		if r.Op == syntax.RdrIn || r.Op == syntax.RdrInOut {
+			return true
+		}
+	}
+	return false
+}
+
+func lastOperand(args []string) []string {
+	if len(args) < 2 || strings.HasPrefix(args[len(args)-1], "-") {
+		return nil
+	}
+	return args[len(args)-1:]
+}
+
+func mentionsSensitivePath(args []string) bool {
+	for _, arg := range args[1:] {
+		for _, marker := range sensitivePathMarkers {
+			if strings.Contains(arg, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
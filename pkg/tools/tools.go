@@ -24,54 +24,109 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
 	"github.com/google/uuid"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
 )
 
 type ContextKey string
 
 const (
-	KubeconfigKey ContextKey = "kubeconfig"
-	WorkDirKey    ContextKey = "work_dir"
+	KubeconfigKey  ContextKey = "kubeconfig"
+	KubeContextKey ContextKey = "kube_context"
+	NamespaceKey   ContextKey = "namespace"
+	WorkDirKey     ContextKey = "work_dir"
+	AllowedDirsKey ContextKey = "allowed_dirs"
 )
 
-func Lookup(name string) Tool {
-	return allTools.Lookup(name)
+// builtinTools constructs the tools that ship with kubectl-ai, keyed by name.
+var builtinTools = map[string]func() Tool{
+	"kubectl":    func() Tool { return &Kubectl{} },
+	"bash":       func() Tool { return &BashTool{} },
+	"read_file":  func() Tool { return &ReadFileTool{} },
+	"write_file": func() Tool { return &WriteFileTool{} },
+	"list_files": func() Tool { return &ListFilesTool{} },
+	"patch_file": func() Tool { return &PatchFileTool{} },
 }
 
-var allTools Tools = Tools{
-	tools: make(map[string]Tool),
+// BuiltinToolNames returns the names of the tools that ship with kubectl-ai.
+func BuiltinToolNames() []string {
+	names := slices.Collect(maps.Keys(builtinTools))
+	sort.Strings(names)
+	return names
 }
 
-func Default() Tools {
-	return allTools
+// Tools is a set of tools available to an agent (or MCP server).
+// Each agent owns its own Tools, so several agents with different tool sets
+// can coexist in one process. Copies of a Tools share the same underlying set.
+type Tools struct {
+	mu       *sync.RWMutex
+	tools    map[string]Tool
+	disabled map[string]bool
 }
 
-// RegisterTool makes a tool available to the LLM.
-func RegisterTool(tool Tool) {
-	allTools.RegisterTool(tool)
+func (t *Tools) Init() {
+	t.mu = &sync.RWMutex{}
+	t.tools = make(map[string]Tool)
+	t.disabled = make(map[string]bool)
 }
 
-type Tools struct {
-	tools map[string]Tool
+// Disable prevents the named tools from being registered, and removes them if they already are.
+func (t *Tools) Disable(names ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range names {
+		t.disabled[name] = true
+		delete(t.tools, name)
+	}
 }
 
-func (t *Tools) Init() {
-	t.tools = make(map[string]Tool)
+// RegisterBuiltinTools registers the built-in tools. If enabled is non-empty, only the
+// listed built-in tools are registered. Tools disabled with Disable are skipped.
+func (t *Tools) RegisterBuiltinTools(enabled []string) error {
+	names := enabled
+	if len(names) == 0 {
+		names = BuiltinToolNames()
+	}
+	for _, name := range names {
+		newTool, ok := builtinTools[name]
+		if !ok {
+			return fmt.Errorf("unknown built-in tool %q (known tools: %s)", name, strings.Join(BuiltinToolNames(), ", "))
+		}
+		t.RegisterTool(newTool())
+	}
+	return nil
 }
 
 func (t *Tools) Lookup(name string) Tool {
+	if t.mu == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.tools[name]
 }
 
 func (t *Tools) AllTools() []Tool {
+	if t.mu == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return slices.Collect(maps.Values(t.tools))
 }
 
 func (t *Tools) Names() []string {
+	if t.mu == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	names := make([]string, 0, len(t.tools))
 	for name := range t.tools {
 		names = append(names, name)
@@ -80,25 +135,48 @@ func (t *Tools) Names() []string {
 	return names
 }
 
+// RegisterTool makes a tool available to the LLM.
+// Registering a tool with the same name as an existing tool replaces it,
+// so registration can safely be repeated when refreshing tools.
 func (t *Tools) RegisterTool(tool Tool) {
-	// if mcp tool use unique name
-	name := tool.Name()
-	// For MCP tools, we need to use a unique name to avoid conflicts
-	// with built-in tools or tools from other MCP servers.
-	if mcpTool, ok := tool.(*MCPTool); ok {
-		name = mcpTool.UniqueToolName()
-	}
+	name := toolKey(tool)
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.disabled[name] || t.disabled[tool.Name()] {
+		klog.V(2).Infof("not registering disabled tool %q", name)
+		return
+	}
 	if _, exists := t.tools[name]; exists {
-		panic("tool already registered: " + name)
+		klog.V(2).Infof("replacing already registered tool %q", name)
 	}
 	t.tools[name] = tool
 }
 
+// UnregisterTool removes a tool, if registered.
+func (t *Tools) UnregisterTool(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tools, name)
+}
+
+// toolKey returns the name a tool is registered under.
+func toolKey(tool Tool) string {
+	// For MCP tools, we need to use a unique name to avoid conflicts
+	// with built-in tools or tools from other MCP servers.
+	if mcpTool, ok := tool.(*MCPTool); ok {
+		return mcpTool.UniqueToolName()
+	}
+	return tool.Name()
+}
+
 type ToolCall struct {
 	tool      Tool
 	name      string
 	arguments map[string]any
+
+	// redactions counts the values masked in the output of the last invocation.
+	redactions redact.Counts
 }
 
 // Description returns a description of the tool call.
@@ -120,6 +198,11 @@ func (t *ToolCall) Description() string {
 		return fmt.Sprintf("[MCP: %s] %s(%s)", mcpTool.serverName, t.name, strings.Join(args, ", "))
 	}
 
+	// Tools with bulky arguments (e.g. file contents) describe their calls themselves
+	if describer, ok := t.tool.(callDescriber); ok {
+		return describer.describeCall(t.arguments)
+	}
+
 	// Default formatting for non-MCP tools
 	if command, ok := t.arguments["command"]; ok {
 		return command.(string)
@@ -151,6 +234,22 @@ type InvokeToolOptions struct {
 
 	// Kubeconfig is the path to the kubeconfig file.
 	Kubeconfig string
+
+	// KubeContext and Namespace, if set, are injected into kubectl commands
+	// that do not select a context or namespace themselves, and selected in the
+	// KUBECONFIG of the commands, for kubectl calls that cannot be rewritten.
+	KubeContext string
+	Namespace   string
+
+	// AllowedDirs are directories, in addition to WorkDir, that file tools may access.
+	AllowedDirs []string
+
+	// Redactor, if set, masks secrets in the tool output before it is recorded or returned.
+	Redactor *redact.Redactor
+
+	// Authorize, if set, is called before the tool runs, with the context it runs with.
+	// If it returns an error, the tool does not run, and the refusal is returned as the result of the call.
+	Authorize func(ctx context.Context, tool Tool, args map[string]any) error
 }
 
 type ToolRequestEvent struct {
@@ -181,9 +280,30 @@ func (t *ToolCall) InvokeTool(ctx context.Context, opt InvokeToolOptions) (any,
 	})
 
 	ctx = context.WithValue(ctx, KubeconfigKey, opt.Kubeconfig)
+	ctx = context.WithValue(ctx, KubeContextKey, opt.KubeContext)
+	ctx = context.WithValue(ctx, NamespaceKey, opt.Namespace)
 	ctx = context.WithValue(ctx, WorkDirKey, opt.WorkDir)
+	ctx = context.WithValue(ctx, AllowedDirsKey, opt.AllowedDirs)
+
+	var response any
+	var err error
+	if opt.Authorize != nil {
+		if refused := opt.Authorize(ctx, t.tool, t.arguments); refused != nil {
+			klog.Infof("refused to run %s: %v", t.name, refused)
+			response = fmt.Sprintf("Refused: %v", refused)
+		}
+	}
+	if response == nil {
+		response, err = t.tool.Run(ctx, t.arguments)
+	}
 
-	response, err := t.tool.Run(ctx, t.arguments)
+	t.redactions = nil
+	if opt.Redactor != nil && response != nil {
+		response, t.redactions = redactOutput(opt.Redactor, t.arguments, response)
+		if total := t.redactions.Total(); total > 0 {
+			klog.Infof("redacted %s from the output of %s", t.redactions, t.name)
+		}
+	}
 
 	{
 		ev := ToolResponseEvent{
@@ -203,6 +323,79 @@ func (t *ToolCall) InvokeTool(ctx context.Context, opt InvokeToolOptions) (any,
 	return response, err
 }
 
+// Redactions returns the number of values masked in the output of the last invocation, by kind.
+func (t *ToolCall) Redactions() redact.Counts {
+	return t.redactions
+}
+
+// redactOutput returns a copy of a tool's output with secrets masked.
+func redactOutput(r *redact.Redactor, args map[string]any, output any) (any, redact.Counts) {
+	counts := redact.Counts{}
+	text := func(s string) string {
+		redacted, c := r.RedactText(s)
+		counts.Add(c)
+		return redacted
+	}
+
+	switch out := output.(type) {
+	case string:
+		return text(out), counts
+	case *ExecResult:
+		if out == nil {
+			return out, counts
+		}
+		redacted := *out
+		command, _ := args["command"].(string)
+		if command == "" {
+			command = out.Command
+		}
+		stdout, c := r.RedactCommandOutput(command, out.Stdout)
+		counts.Add(c)
+		redacted.Stdout = stdout
+		redacted.Stderr = text(out.Stderr)
+		redacted.Error = text(out.Error)
+		return &redacted, counts
+	case *FileResult:
+		if out == nil {
+			return out, counts
+		}
+		redacted := *out
+		redacted.Content = text(out.Content)
+		redacted.Diff = text(out.Diff)
+		redacted.Error = text(out.Error)
+		return &redacted, counts
+	case *MCPResult:
+		if out == nil {
+			return out, counts
+		}
+		redacted := *out
+		redacted.Content = text(out.Content)
+		if out.StructuredContent != nil {
+			structured, c := redactOutput(r, args, out.StructuredContent)
+			counts.Add(c)
+			redacted.StructuredContent = structured
+		}
+		return &redacted, counts
+	default:
+		// Other results (e.g. from custom tools) are redacted in their JSON form.
+		b, err := json.Marshal(output)
+		if err != nil {
+			klog.Warningf("unable to redact tool output of type %T: %v", output, err)
+			return output, counts
+		}
+		redactedJSON := text(string(b))
+		if counts.Total() == 0 {
+			return output, counts
+		}
+		var redacted any
+		if err := json.Unmarshal([]byte(redactedJSON), &redacted); err != nil {
+			// Masking broke the JSON structure; fall back to the redacted text.
+			return redactedJSON, counts
+		}
+		return redacted, counts
+	}
+}
+
 // ToolResultToMap converts an arbitrary result to a map[string]any
 func ToolResultToMap(result any) (map[string]any, error) {
 	// Handle simple string results (common with MCP tools)
@@ -231,7 +424,7 @@ func ToolResultToMap(result any) (map[string]any, error) {
 
 // LoadAndRegisterCustomTools loads tool configurations from a YAML file
 // and registers them.
-func LoadAndRegisterCustomTools(configPath string) error {
+func (t *Tools) LoadAndRegisterCustomTools(configPath string) error {
 	pathInfo, err := os.Stat(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to describe config file %s: %w", configPath, err)
@@ -244,7 +437,7 @@ func LoadAndRegisterCustomTools(configPath string) error {
 		}
 
 		for _, entry := range configPaths {
-			if err := LoadAndRegisterCustomTools(filepath.Join(configPath, entry.Name())); err != nil {
+			if err := t.LoadAndRegisterCustomTools(filepath.Join(configPath, entry.Name())); err != nil {
 				return err
 			}
 		}
@@ -274,11 +467,11 @@ func LoadAndRegisterCustomTools(configPath string) error {
 			continue // Skip registration if creation failed
 		}
 		// Check for duplicate registration attempt
-		if _, exists := allTools.tools[tool.Name()]; exists {
+		if t.Lookup(tool.Name()) != nil {
 			registrationErrors = append(registrationErrors, fmt.Sprintf("tool %q already registered (possibly built-in), skipping custom definition", tool.Name()))
 			continue
 		}
-		RegisterTool(tool)
+		t.RegisterTool(tool)
 	}
 
 	if len(registrationErrors) > 0 {
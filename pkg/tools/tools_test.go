@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/redact"
+)
+
+func TestRegisterBuiltinTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  []string
+		disabled []string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "all by default", expected: []string{"bash", "kubectl", "list_files", "patch_file", "read_file", "write_file"}},
+		{name: "enable subset", enabled: []string{"kubectl"}, expected: []string{"kubectl"}},
+		{name: "disable bash", disabled: []string{"bash"}, expected: []string{"kubectl", "list_files", "patch_file", "read_file", "write_file"}},
+		{name: "disable wins over enable", enabled: []string{"kubectl", "bash"}, disabled: []string{"bash"}, expected: []string{"kubectl"}},
+		{name: "unknown tool", enabled: []string{"helm"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var toolset Tools
+			toolset.Init()
+			toolset.Disable(tt.disabled...)
+			err := toolset.RegisterBuiltinTools(tt.enabled)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegisterBuiltinTools() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := toolset.Names(); !slices.Equal(got, tt.expected) {
+				t.Errorf("Names() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToolsAreIndependentAndRefreshable(t *testing.T) {
+	var a, b Tools
+	a.Init()
+	b.Init()
+
+	a.RegisterTool(&BashTool{})
+	// Re-registering must replace rather than panic.
+	a.RegisterTool(&BashTool{})
+	b.RegisterTool(&Kubectl{})
+
+	if got := a.Names(); !slices.Equal(got, []string{"bash"}) {
+		t.Errorf("a.Names() = %v, want [bash]", got)
+	}
+	if got := b.Names(); !slices.Equal(got, []string{"kubectl"}) {
+		t.Errorf("b.Names() = %v, want [kubectl]", got)
+	}
+
+	a.UnregisterTool("bash")
+	if a.Lookup("bash") != nil {
+		t.Errorf("expected bash to be unregistered")
+	}
+}
+
+func TestLoadAndRegisterCustomToolsDoesNotOverrideBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "tools.yaml")
+	config := `
+- name: kubectl
+  description: "shadow kubectl"
+  command: "kubectl"
+- name: helm
+  description: "Helm"
+  command: "helm"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	var toolset Tools
+	toolset.Init()
+	if err := toolset.RegisterBuiltinTools(nil); err != nil {
+		t.Fatalf("RegisterBuiltinTools() error = %v", err)
+	}
+	if err := toolset.LoadAndRegisterCustomTools(configPath); err == nil {
+		t.Errorf("expected an error for the duplicate kubectl tool")
+	}
+
+	if _, ok := toolset.Lookup("kubectl").(*Kubectl); !ok {
+		t.Errorf("built-in kubectl tool was overridden by custom tool")
+	}
+	if toolset.Lookup("helm") == nil {
+		t.Errorf("expected custom helm tool to be registered")
+	}
+}
+
+func TestRedactOutput(t *testing.T) {
+	r, err := redact.New(nil)
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+
+	original := &ExecResult{
+		Command: "kubectl get secret db -o yaml",
+		Stdout:  "apiVersion: v1\nkind: Secret\ndata:\n  password: aHVudGVyMg==\n",
+	}
+	out, counts := redactOutput(r, map[string]any{"command": original.Command}, original)
+	result := out.(*ExecResult)
+	if strings.Contains(result.Stdout, "aHVudGVyMg==") || counts.Total() != 1 {
+		t.Errorf("expected secret data to be redacted, got %q (%v)", result.Stdout, counts)
+	}
+	if !strings.Contains(original.Stdout, "aHVudGVyMg==") {
+		t.Errorf("redaction must not modify the original result")
+	}
+
+	// Results of other types (e.g. MCP tools) are redacted in their JSON form.
+	out, counts = redactOutput(r, nil, map[string]any{"env": "API_KEY=abcdef123456"})
+	if got := out.(map[string]any)["env"]; got != "API_KEY=[REDACTED:password]" || counts.Total() != 1 {
+		t.Errorf("redactOutput() = %v (%v)", got, counts)
+	}
+}
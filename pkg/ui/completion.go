@@ -0,0 +1,85 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/chzyer/readline"
+)
+
+// commandCompleter completes the commands of the agent typed in readline.
+type commandCompleter struct {
+	agent *agent.Agent
+}
+
+var _ readline.AutoCompleter = &commandCompleter{}
+
+// Do returns the completions of the word before the cursor, as the text to add after it,
+// and the length of what was typed of the word.
+func (c *commandCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	partial := lastWord(typed)
+	var suffixes [][]rune
+	for _, completion := range c.agent.CompleteCommand(context.Background(), typed) {
+		suffixes = append(suffixes, []rune(strings.TrimPrefix(completion, partial)+" "))
+	}
+	return suffixes, len([]rune(partial))
+}
+
+// completeInput completes the last word of a command typed in the input of a UI. It returns the input
+// with the word completed as far as the completions agree, and the completions if there are several.
+func completeInput(a *agent.Agent, input string) (string, []string) {
+	completions := a.CompleteCommand(context.Background(), input)
+	if len(completions) == 0 {
+		return input, nil
+	}
+	prefix := strings.TrimSuffix(input, lastWord(input))
+	if len(completions) == 1 {
+		return prefix + completions[0] + " ", nil
+	}
+	return prefix + commonPrefix(completions), completions
+}
+
+// lastWord returns the word being typed at the end of line, which is empty after a space.
+func lastWord(line string) string {
+	return line[strings.LastIndexAny(line, " \t")+1:]
+}
+
+// commonPrefix returns the longest prefix of all the words.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// isClearCommand returns true if the query clears the conversation, after which UIs clear the screen too.
+func isClearCommand(a *agent.Agent, query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) != 1 {
+		return false
+	}
+	name, ok := strings.CutPrefix(fields[0], agent.CommandPrefix)
+	if !ok && !a.LegacyCommands {
+		return false
+	}
+	return name == "clear" || name == "reset"
+}
@@ -0,0 +1,547 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/klog/v2"
+)
+
+// User is an authenticated user of the web UI.
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator identifies the users of the web UI.
+type Authenticator interface {
+	// Authenticate returns the user making a request, or nil if the request is not authenticated.
+	Authenticate(r *http.Request) (*User, error)
+	// Challenge asks the client of an unauthenticated request to authenticate.
+	Challenge(w http.ResponseWriter, r *http.Request)
+	// RegisterRoutes registers the endpoints the authenticator needs, such as a login page.
+	// They are served to unauthenticated users.
+	RegisterRoutes(mux *http.ServeMux)
+}
+
+// logoutAuthenticator is implemented by the authenticators users can log out of, with POST /logout.
+type logoutAuthenticator interface {
+	supportsLogout() bool
+}
+
+type userKey struct{}
+
+// UserFromContext returns the authenticated user of a request, if any.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
+
+// Authorization restricts the web UI to some of the authenticated users.
+// If both lists are empty, every authenticated user is allowed.
+type Authorization struct {
+	AllowedUsers  []string
+	AllowedGroups []string
+	// AdminGroups are the groups whose members can use the sessions of all users,
+	// rather than only those they created.
+	AdminGroups []string
+}
+
+func (a Authorization) allows(user *User) bool {
+	if len(a.AllowedUsers) == 0 && len(a.AllowedGroups) == 0 {
+		return true
+	}
+	if slices.Contains(a.AllowedUsers, user.Name) {
+		return true
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(a.AllowedGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdmin reports whether the user can use the sessions of all users.
+func (a Authorization) isAdmin(user *User) bool {
+	for _, group := range user.Groups {
+		if slices.Contains(a.AdminGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAuth authenticates and authorizes the requests to a handler.
+func requireAuth(auth Authenticator, authz Authorization, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := auth.Authenticate(r)
+		if err != nil {
+			klog.FromContext(r.Context()).Error(err, "authenticating request")
+			http.Error(w, "authentication failed", http.StatusUnauthorized)
+			return
+		}
+		if user == nil {
+			auth.Challenge(w, r)
+			return
+		}
+		if !authz.allows(user) {
+			klog.Warningf("Web UI access denied to user %q (groups %v)", user.Name, user.Groups)
+			http.Error(w, fmt.Sprintf("user %q is not allowed to use kubectl-ai", user.Name), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// CSRF protection: the server gives browsers a random token in a cookie, and requests that change
+// anything must send it back in a header (or form field). Other sites cannot read the cookie.
+const (
+	csrfCookieName = "kubectl-ai-csrf"
+	csrfHeaderName = "X-CSRF-Token"
+	csrfFormField  = "csrf_token"
+)
+
+// csrfProtect rejects the requests that change anything without the CSRF token.
+// Requests with a bearer token are not sent by browsers on their own, so they do not need one.
+func csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(csrfCookieName)
+		if err != nil || cookie.Value == "" {
+			cookie = &http.Cookie{
+				Name:     csrfCookieName,
+				Value:    randomToken(),
+				Path:     "/",
+				SameSite: http.SameSiteStrictMode,
+				Secure:   r.TLS != nil,
+			}
+			http.SetCookie(w, cookie)
+			// Make the token available to the handlers of this request, e.g. the login page.
+			r.AddCookie(cookie)
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		token := r.Header.Get(csrfHeaderName)
+		if token == "" {
+			token = r.PostFormValue(csrfFormField)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) != 1 {
+			http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func csrfToken(r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// wantsHTML reports whether a request comes from a browser navigating to a page,
+// which should be sent to the login page rather than get an error.
+func wantsHTML(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// localRedirect returns the path to redirect to after logging in, refusing redirects to other sites.
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// loginSessionTTL is how long users stay logged in.
+const loginSessionTTL = 12 * time.Hour
+
+const loginCookieName = "kubectl-ai-login"
+
+// loginSessions remembers the users who logged in, by the random token in their login cookie.
+type loginSessions struct {
+	mu       sync.Mutex
+	sessions map[string]loginSession
+}
+
+type loginSession struct {
+	user    *User
+	expires time.Time
+}
+
+func newLoginSessions() *loginSessions {
+	return &loginSessions{sessions: map[string]loginSession{}}
+}
+
+// login starts a login session for a user, and sets its cookie.
+func (s *loginSessions) login(w http.ResponseWriter, r *http.Request, user *User) {
+	token := randomToken()
+	now := time.Now()
+
+	s.mu.Lock()
+	for k, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[token] = loginSession{user: user, expires: now.Add(loginSessionTTL)}
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  now.Add(loginSessionTTL),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	klog.Infof("Web UI user %q logged in", user.Name)
+}
+
+// user returns the user logged in with the cookie of a request.
+func (s *loginSessions) user(r *http.Request) *User {
+	cookie, err := r.Cookie(loginCookieName)
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[cookie.Value]
+	if !ok || time.Now().After(session.expires) {
+		return nil
+	}
+	return session.user
+}
+
+// handleLogout ends the login session of a request.
+func (s *loginSessions) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(loginCookieName); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookieName, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// challengeWithLogin sends browsers to the login page, and tells other clients to authenticate.
+func challengeWithLogin(w http.ResponseWriter, r *http.Request) {
+	if wantsHTML(r) {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="kubectl-ai"`)
+	http.Error(w, "authentication required", http.StatusUnauthorized)
+}
+
+//go:embed login.html
+var loginHTML string
+
+var loginTemplate = template.Must(template.New("login").Parse(loginHTML))
+
+// TokenAuthenticator authenticates users with static tokens, sent as bearer tokens by API clients,
+// or entered on the login page by browsers.
+type TokenAuthenticator struct {
+	// tokens maps the tokens to their users.
+	tokens   map[string]*User
+	sessions *loginSessions
+}
+
+var _ Authenticator = &TokenAuthenticator{}
+
+// NewTokenAuthenticator reads a token file in the format of the Kubernetes API server's static token file:
+// a CSV file with a token, a user name, a user ID (ignored), and optionally a quoted, comma-separated list of groups.
+func NewTokenAuthenticator(path string) (*TokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening token file: %w", err)
+	}
+	defer f.Close()
+
+	a := &TokenAuthenticator{tokens: map[string]*User{}, sessions: newLoginSessions()}
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing token file %s: %w", path, err)
+		}
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("token file %s, line %d: a token and a user name are required", path, line)
+		}
+		user := &User{Name: record[1]}
+		if len(record) > 3 && record[3] != "" {
+			user.Groups = strings.Split(record[3], ",")
+		}
+		a.tokens[record[0]] = user
+	}
+	if len(a.tokens) == 0 {
+		return nil, fmt.Errorf("token file %s has no tokens", path)
+	}
+	return a, nil
+}
+
+func (a *TokenAuthenticator) lookup(token string) *User {
+	for t, user := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return user
+		}
+	}
+	return nil
+}
+
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.lookup(strings.TrimSpace(token)), nil
+	}
+	return a.sessions.user(r), nil
+}
+
+func (a *TokenAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	challengeWithLogin(w, r)
+}
+
+func (a *TokenAuthenticator) supportsLogout() bool { return true }
+
+func (a *TokenAuthenticator) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		renderLogin(w, r, http.StatusOK, "")
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		user := a.lookup(r.PostFormValue("token"))
+		if user == nil {
+			klog.Warningf("Web UI login with an invalid token from %s", r.RemoteAddr)
+			renderLogin(w, r, http.StatusUnauthorized, "Invalid token.")
+			return
+		}
+		a.sessions.login(w, r, user)
+		http.Redirect(w, r, localRedirect(r.PostFormValue("next")), http.StatusSeeOther)
+	})
+	mux.HandleFunc("POST /logout", a.sessions.handleLogout)
+}
+
+func renderLogin(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	data := map[string]string{
+		"Next":      localRedirect(r.FormValue("next")),
+		"CSRFToken": csrfToken(r),
+		"Message":   message,
+	}
+	if err := loginTemplate.Execute(w, data); err != nil {
+		klog.Errorf("rendering login page: %v", err)
+	}
+}
+
+// BasicAuthenticator authenticates users with HTTP basic authentication, checking their passwords
+// against an htpasswd file with bcrypt hashes (htpasswd -B).
+type BasicAuthenticator struct {
+	hashes map[string][]byte
+}
+
+var _ Authenticator = &BasicAuthenticator{}
+
+// NewBasicAuthenticator reads an htpasswd file.
+func NewBasicAuthenticator(path string) (*BasicAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	a := &BasicAuthenticator{hashes: map[string][]byte{}}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("htpasswd file %s, line %d: expected user:hash", path, line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("htpasswd file %s, line %d: only bcrypt hashes are supported (htpasswd -B): %w", path, line, err)
+		}
+		a.hashes[user] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading htpasswd file %s: %w", path, err)
+	}
+	if len(a.hashes) == 0 {
+		return nil, fmt.Errorf("htpasswd file %s has no users", path)
+	}
+	return a, nil
+}
+
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	hash, ok := a.hashes[name]
+	if !ok || bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		klog.Warningf("Web UI basic authentication failed for user %q from %s", name, r.RemoteAddr)
+		return nil, nil
+	}
+	return &User{Name: name}, nil
+}
+
+func (a *BasicAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="kubectl-ai", charset="UTF-8"`)
+	http.Error(w, "authentication required", http.StatusUnauthorized)
+}
+
+func (a *BasicAuthenticator) RegisterRoutes(*http.ServeMux) {}
+
+// ProxyAuthenticator trusts a reverse proxy in front of the UI to authenticate users,
+// and to pass their identity in request headers.
+type ProxyAuthenticator struct {
+	// UserHeader and GroupsHeader are the headers with the user name, and their comma-separated groups.
+	UserHeader   string
+	GroupsHeader string
+	// TrustedProxies are the addresses of the proxies; the headers of other clients are ignored.
+	TrustedProxies []*net.IPNet
+}
+
+var _ Authenticator = &ProxyAuthenticator{}
+
+// NewProxyAuthenticator creates a ProxyAuthenticator trusting the proxies in the given CIDR ranges.
+func NewProxyAuthenticator(userHeader, groupsHeader string, trustedCIDRs []string) (*ProxyAuthenticator, error) {
+	if userHeader == "" {
+		return nil, fmt.Errorf("the user header is required")
+	}
+	if len(trustedCIDRs) == 0 {
+		return nil, fmt.Errorf("at least one trusted proxy address range is required")
+	}
+	a := &ProxyAuthenticator{UserHeader: userHeader, GroupsHeader: groupsHeader}
+	for _, cidr := range trustedCIDRs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy address range: %w", err)
+		}
+		a.TrustedProxies = append(a.TrustedProxies, ipNet)
+	}
+	return a, nil
+}
+
+func (a *ProxyAuthenticator) trusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range a.TrustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ProxyAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	if !a.trusted(r.RemoteAddr) {
+		klog.Warningf("Web UI request from %s, which is not a trusted proxy", r.RemoteAddr)
+		return nil, nil
+	}
+	name := strings.TrimSpace(r.Header.Get(a.UserHeader))
+	if name == "" {
+		return nil, nil
+	}
+	user := &User{Name: name}
+	if a.GroupsHeader != "" {
+		for _, value := range r.Header.Values(a.GroupsHeader) {
+			for _, group := range strings.Split(value, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					user.Groups = append(user.Groups, group)
+				}
+			}
+		}
+	}
+	return user, nil
+}
+
+func (a *ProxyAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "authentication required: access kubectl-ai through its authenticating proxy", http.StatusUnauthorized)
+}
+
+func (a *ProxyAuthenticator) RegisterRoutes(*http.ServeMux) {}
+
+// userName returns the name of a user, or "" for anonymous users.
+func userName(user *User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Name
+}
@@ -0,0 +1,436 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"k8s.io/klog/v2"
+)
+
+// oidcCallbackPath is the path of the endpoint the OpenID provider redirects users to after they log in.
+const oidcCallbackPath = "/oauth2/callback"
+
+// oidcLoginTimeout is how long users have to log in with the OpenID provider.
+const oidcLoginTimeout = 10 * time.Minute
+
+// OIDCConfig configures logging in with an OpenID Connect provider.
+type OIDCConfig struct {
+	// IssuerURL is the URL of the provider, where its discovery document is found.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL of the UI's callback endpoint (/oauth2/callback), as registered with the provider.
+	// If empty, it is derived from the requests, which is wrong behind a reverse proxy.
+	RedirectURL string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+	// UsernameClaim and GroupsClaim are the claims of the ID token with the user name and groups.
+	// They default to "email" and "groups".
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// OIDCAuthenticator logs users in with an OpenID Connect provider, using the authorization code flow with PKCE.
+type OIDCAuthenticator struct {
+	config     OIDCConfig
+	httpClient *http.Client
+	metadata   oidcMetadata
+	sessions   *loginSessions
+
+	keysMu      sync.Mutex
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+
+	pendingMu sync.Mutex
+	pending   map[string]pendingLogin
+}
+
+var _ Authenticator = &OIDCAuthenticator{}
+
+// oidcMetadata is the part of the provider's discovery document the UI uses.
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// pendingLogin is a login started on the provider, by its state parameter.
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	redirectURL  string
+	next         string
+	expires      time.Time
+}
+
+// NewOIDCAuthenticator fetches the discovery document of the provider.
+func NewOIDCAuthenticator(ctx context.Context, config OIDCConfig) (*OIDCAuthenticator, error) {
+	if config.IssuerURL == "" || config.ClientID == "" {
+		return nil, fmt.Errorf("the OIDC issuer URL and client ID are required")
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "email"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	a := &OIDCAuthenticator{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		sessions:   newLoginSessions(),
+		keys:       map[string]crypto.PublicKey{},
+		pending:    map[string]pendingLogin{},
+	}
+
+	discoveryURL := strings.TrimSuffix(config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := a.getJSON(ctx, discoveryURL, &a.metadata); err != nil {
+		return nil, fmt.Errorf("fetching the OIDC discovery document: %w", err)
+	}
+	if a.metadata.Issuer != config.IssuerURL {
+		return nil, fmt.Errorf("OIDC issuer %q does not match the issuer URL %q", a.metadata.Issuer, config.IssuerURL)
+	}
+	if a.metadata.AuthorizationEndpoint == "" || a.metadata.TokenEndpoint == "" || a.metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("the OIDC discovery document of %s lacks endpoints", config.IssuerURL)
+	}
+	return a, nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GET %s: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	return a.sessions.user(r), nil
+}
+
+func (a *OIDCAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	challengeWithLogin(w, r)
+}
+
+func (a *OIDCAuthenticator) supportsLogout() bool { return true }
+
+func (a *OIDCAuthenticator) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", a.handleLogin)
+	mux.HandleFunc("GET "+oidcCallbackPath, a.handleCallback)
+	mux.HandleFunc("POST /logout", a.sessions.handleLogout)
+}
+
+func (a *OIDCAuthenticator) redirectURL(r *http.Request) string {
+	if a.config.RedirectURL != "" {
+		return a.config.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + oidcCallbackPath
+}
+
+// handleLogin sends the user to the provider to log in.
+func (a *OIDCAuthenticator) handleLogin(w http.ResponseWriter, r *http.Request) {
+	state := randomToken()
+	login := pendingLogin{
+		nonce:        randomToken(),
+		codeVerifier: randomToken(),
+		redirectURL:  a.redirectURL(r),
+		next:         localRedirect(r.FormValue("next")),
+		expires:      time.Now().Add(oidcLoginTimeout),
+	}
+
+	a.pendingMu.Lock()
+	for k, p := range a.pending {
+		if time.Now().After(p.expires) {
+			delete(a.pending, k)
+		}
+	}
+	a.pending[state] = login
+	a.pendingMu.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.codeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.config.ClientID},
+		"redirect_uri":          {login.redirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, a.config.Scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {login.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(a.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, a.metadata.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// handleCallback completes a login: it exchanges the authorization code for an ID token, and verifies it.
+func (a *OIDCAuthenticator) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	a.pendingMu.Lock()
+	login, ok := a.pending[state]
+	delete(a.pending, state)
+	a.pendingMu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		http.Error(w, "the login expired or is unknown, please log in again", http.StatusBadRequest)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		klog.Warningf("OIDC login failed: %s: %s", errCode, query.Get("error_description"))
+		http.Error(w, "login failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := a.exchange(r.Context(), query.Get("code"), login)
+	if err != nil {
+		klog.Errorf("OIDC login failed: %v", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	a.sessions.login(w, r, user)
+	http.Redirect(w, r, login.next, http.StatusSeeOther)
+}
+
+func (a *OIDCAuthenticator) exchange(ctx context.Context, code string, login pendingLogin) (*User, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {login.redirectURL},
+		"code_verifier": {login.codeVerifier},
+		"client_id":     {a.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging the authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("exchanging the authorization code: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("parsing the token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("the token response has no ID token")
+	}
+	return a.verifyIDToken(ctx, token.IDToken, login.nonce)
+}
+
+// verifyIDToken checks the signature and claims of an ID token, and returns its user.
+func (a *OIDCAuthenticator) verifyIDToken(ctx context.Context, rawToken, nonce string) (*User, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return a.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(a.metadata.Issuer),
+		jwt.WithAudience(a.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("verifying the ID token: %w", err)
+	}
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("the ID token nonce does not match")
+	}
+
+	name, _ := claims[a.config.UsernameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("the ID token has no %q claim", a.config.UsernameClaim)
+	}
+	if a.config.UsernameClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return nil, fmt.Errorf("the email address %q is not verified", name)
+		}
+	}
+	user := &User{Name: name}
+	switch groups := claims[a.config.GroupsClaim].(type) {
+	case string:
+		user.Groups = []string{groups}
+	case []any:
+		for _, g := range groups {
+			if group, ok := g.(string); ok {
+				user.Groups = append(user.Groups, group)
+			}
+		}
+	}
+	return user, nil
+}
+
+// key returns the provider's signing key with the given ID, fetching the provider's keys
+// again if it is unknown, as providers rotate their keys.
+func (a *OIDCAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+
+	if key := a.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(a.keysFetched) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := a.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+	if key := a.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the key with the given ID, or the only key if the token does not say which it uses.
+func (a *OIDCAuthenticator) lookupKey(kid string) crypto.PublicKey {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key
+		}
+	}
+	return a.keys[kid]
+}
+
+func (a *OIDCAuthenticator) fetchKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(ctx, a.metadata.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("fetching the OIDC signing keys: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			klog.Warningf("ignoring OIDC signing key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	a.keys = keys
+	a.keysFetched = time.Now()
+	return nil
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set (RFC 7517).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// Elliptic curve keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding modulus: %w", err)
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,539 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// noRedirectClient returns redirects as responses, and keeps cookies.
+func noRedirectClient(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	tokenFile := writeFile(t, "tokens.csv", `# token,user,uid,groups
+alice-token,alice,1,"sre,dev"
+bob-token,bob,2
+`)
+	auth, err := NewTokenAuthenticator(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURL, _ := newTestUI(t, Options{Auth: auth, Authorization: Authorization{AllowedGroups: []string{"sre"}}})
+
+	tests := []struct {
+		name       string
+		path       string
+		header     http.Header
+		wantStatus int
+		wantHeader http.Header
+	}{
+		{
+			name:       "browser without login",
+			path:       "/",
+			header:     http.Header{"Accept": {"text/html"}},
+			wantStatus: http.StatusSeeOther,
+			wantHeader: http.Header{"Location": {"/login?next=%2F"}},
+		},
+		{
+			name:       "API client without token",
+			path:       "/sessions",
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: http.Header{"Www-Authenticate": {`Bearer realm="kubectl-ai"`}},
+		},
+		{
+			name:       "invalid token",
+			path:       "/sessions",
+			header:     http.Header{"Authorization": {"Bearer nope"}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "allowed user",
+			path:       "/sessions",
+			header:     http.Header{"Authorization": {"Bearer alice-token"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "user not in an allowed group",
+			path:       "/sessions",
+			header:     http.Header{"Authorization": {"Bearer bob-token"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "login page",
+			path:       "/login",
+			wantStatus: http.StatusOK,
+		},
+	}
+	client := noRedirectClient(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", baseURL+tt.path, nil)
+			for k, values := range tt.header {
+				for _, v := range values {
+					req.Header.Add(k, v)
+				}
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s = %s, want %d", tt.path, resp.Status, tt.wantStatus)
+			}
+			for k := range tt.wantHeader {
+				if got := resp.Header.Get(k); got != tt.wantHeader.Get(k) {
+					t.Errorf("header %s = %q, want %q", k, got, tt.wantHeader.Get(k))
+				}
+			}
+		})
+	}
+
+	// Log in on the login page, with the CSRF token it was served with.
+	login := func(token string) *http.Response {
+		t.Helper()
+		form := url.Values{"token": {token}, "next": {"/sessions"}, csrfFormField: {csrfCookie(t, client, baseURL)}}
+		resp, err := client.PostForm(baseURL+"/login", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	if resp := login("nope"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("logging in with an invalid token: %s", resp.Status)
+	}
+	if resp := login("alice-token"); resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/sessions" {
+		t.Fatalf("logging in: %s, redirected to %q", resp.Status, resp.Header.Get("Location"))
+	}
+
+	var whoami struct {
+		Name   string
+		Groups []string
+	}
+	resp, err := client.Get(baseURL + "/whoami")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	decode(t, resp, &whoami)
+	if whoami.Name != "alice" || !reflect.DeepEqual(whoami.Groups, []string{"sre", "dev"}) {
+		t.Errorf("logged in as %+v, want alice in groups sre and dev", whoami)
+	}
+}
+
+// csrfCookie returns the CSRF token the server gave a client.
+func csrfCookie(t *testing.T, client *http.Client, baseURL string) string {
+	t.Helper()
+	u, _ := url.Parse(baseURL)
+	for _, cookie := range client.Jar.Cookies(u) {
+		if cookie.Name == csrfCookieName {
+			return cookie.Value
+		}
+	}
+	t.Fatal("the server did not set a CSRF cookie")
+	return ""
+}
+
+func TestCSRFProtect(t *testing.T) {
+	handler := csrfProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		cookie string
+		header http.Header
+		form   url.Values
+		want   int
+	}{
+		{name: "GET without token", method: "GET", want: http.StatusNoContent},
+		{name: "POST without token", method: "POST", want: http.StatusForbidden},
+		{name: "POST without cookie", method: "POST", header: http.Header{csrfHeaderName: {"abc"}}, want: http.StatusForbidden},
+		{name: "POST with header", method: "POST", cookie: "abc", header: http.Header{csrfHeaderName: {"abc"}}, want: http.StatusNoContent},
+		{name: "POST with form field", method: "POST", cookie: "abc", form: url.Values{csrfFormField: {"abc"}}, want: http.StatusNoContent},
+		{name: "POST with wrong token", method: "POST", cookie: "abc", header: http.Header{csrfHeaderName: {"abd"}}, want: http.StatusForbidden},
+		{name: "DELETE without token", method: "DELETE", cookie: "abc", want: http.StatusForbidden},
+		{name: "POST with bearer token", method: "POST", header: http.Header{"Authorization": {"Bearer xyz"}}, want: http.StatusNoContent},
+		{
+			name:   "cross-origin POST",
+			method: "POST",
+			cookie: "abc",
+			header: http.Header{csrfHeaderName: {"abc"}, "Origin": {"https://evil.example.com"}},
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "same-origin POST",
+			method: "POST",
+			cookie: "abc",
+			header: http.Header{csrfHeaderName: {"abc"}, "Origin": {"http://example.com"}},
+			want:   http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/send-message", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for k, values := range tt.header {
+				for _, v := range values {
+					req.Header.Add(k, v)
+				}
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+			if setsCookie := strings.Contains(rec.Header().Get("Set-Cookie"), csrfCookieName); setsCookie != (tt.cookie == "") {
+				t.Errorf("Set-Cookie = %q", rec.Header().Get("Set-Cookie"))
+			}
+		})
+	}
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := NewBasicAuthenticator(writeFile(t, "htpasswd", "# users\nalice:"+string(hash)+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     *User
+	}{
+		{name: "valid password", user: "alice", password: "s3cret", want: &User{Name: "alice"}},
+		{name: "wrong password", user: "alice", password: "secret"},
+		{name: "unknown user", user: "bob", password: "s3cret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.SetBasicAuth(tt.user, tt.password)
+			got, err := auth.Authenticate(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewBasicAuthenticator(writeFile(t, "htpasswd", "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")); err == nil {
+		t.Error("NewBasicAuthenticator() accepted a SHA-1 hash")
+	}
+}
+
+func TestProxyAuthenticator(t *testing.T) {
+	auth, err := NewProxyAuthenticator("X-Forwarded-User", "X-Forwarded-Groups", []string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       *User
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:4567",
+			header:     http.Header{"X-Forwarded-User": {"alice"}, "X-Forwarded-Groups": {"sre, dev", "ops"}},
+			want:       &User{Name: "alice", Groups: []string{"sre", "dev", "ops"}},
+		},
+		{
+			name:       "trusted proxy address",
+			remoteAddr: "192.168.1.1:4567",
+			header:     http.Header{"X-Forwarded-User": {"bob"}},
+			want:       &User{Name: "bob"},
+		},
+		{
+			name:       "untrusted client",
+			remoteAddr: "192.168.1.2:4567",
+			header:     http.Header{"X-Forwarded-User": {"alice"}},
+		},
+		{
+			name:       "no user",
+			remoteAddr: "10.1.2.3:4567",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, values := range tt.header {
+				for _, v := range values {
+					req.Header.Add(k, v)
+				}
+			}
+			got, err := auth.Authenticate(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeIdP is an OpenID provider for tests, which logs in everyone who asks.
+type fakeIdP struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+	// claims can change the claims of the ID tokens, to test how they are verified.
+	claims func(jwt.MapClaims)
+
+	mu    sync.Mutex
+	codes map[string]fakeIdPCode
+}
+
+type fakeIdPCode struct {
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{key: key, clientID: "kubectl-ai", secret: "client-secret", codes: map[string]fakeIdPCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != idp.clientID || q.Get("code_challenge_method") != "S256" || !strings.Contains(q.Get("scope"), "openid") {
+			http.Error(w, "invalid authorization request", http.StatusBadRequest)
+			return
+		}
+		code := randomToken()
+		idp.mu.Lock()
+		idp.codes[code] = fakeIdPCode{nonce: q.Get("nonce"), codeChallenge: q.Get("code_challenge"), redirectURI: q.Get("redirect_uri")}
+		idp.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != idp.clientID || secret != idp.secret {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		idp.mu.Lock()
+		code, ok := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || code.redirectURI != r.FormValue("redirect_uri") || base64.RawURLEncoding.EncodeToString(verifier[:]) != code.codeChallenge {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":            idp.URL,
+			"sub":            "1234",
+			"aud":            idp.clientID,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          code.nonce,
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"groups":         []string{"sre"},
+		}
+		if idp.claims != nil {
+			idp.claims(claims)
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": signed})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func TestOIDCAuthentication(t *testing.T) {
+	idp := newFakeIdP(t)
+	auth, err := NewOIDCAuthenticator(context.Background(), OIDCConfig{
+		IssuerURL:    idp.URL,
+		ClientID:     idp.clientID,
+		ClientSecret: idp.secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURL, _ := newTestUI(t, Options{Auth: auth})
+
+	tests := []struct {
+		name   string
+		claims func(jwt.MapClaims)
+		// wantStatus is the status of the last response of the login, after following redirects.
+		wantStatus int
+		wantUser   *User
+	}{
+		{
+			name:       "valid ID token",
+			wantStatus: http.StatusOK,
+			wantUser:   &User{Name: "alice@example.com", Groups: []string{"sre"}},
+		},
+		{
+			name:       "other audience",
+			claims:     func(c jwt.MapClaims) { c["aud"] = "other-client" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "other issuer",
+			claims:     func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired",
+			claims:     func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "replayed nonce",
+			claims:     func(c jwt.MapClaims) { c["nonce"] = "old-nonce" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unverified email",
+			claims:     func(c jwt.MapClaims) { c["email_verified"] = false },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.claims = tt.claims
+			jar, _ := cookiejar.New(nil)
+			client := &http.Client{Jar: jar}
+
+			// The browser is sent to the provider, and back to the UI once logged in.
+			req, _ := http.NewRequest("GET", baseURL+"/", nil)
+			req.Header.Set("Accept", "text/html")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("logging in: %s, want %d", resp.Status, tt.wantStatus)
+			}
+
+			resp, err = client.Get(baseURL + "/whoami")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if tt.wantUser == nil {
+				if resp.StatusCode != http.StatusUnauthorized {
+					t.Errorf("GET /whoami after a failed login: %s", resp.Status)
+				}
+				return
+			}
+			var whoami struct {
+				Name   string
+				Groups []string
+			}
+			decode(t, resp, &whoami)
+			if got := (&User{Name: whoami.Name, Groups: whoami.Groups}); !reflect.DeepEqual(got, tt.wantUser) {
+				t.Errorf("logged in as %+v, want %+v", got, tt.wantUser)
+			}
+		})
+	}
+
+	// Callbacks of logins the UI did not start are refused.
+	resp, err := http.Get(baseURL + oidcCallbackPath + "?code=abc&state=forged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("callback with a forged state: %s", resp.Status)
+	}
+}
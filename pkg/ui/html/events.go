@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The events sent to browsers on the event stream of a session.
+const (
+	// eventMessageAdded carries a message added to the session.
+	eventMessageAdded = "message-added"
+	// eventMessageDelta carries a piece of the text of a message the model is streaming.
+	eventMessageDelta = "message-delta"
+	// eventStateChanged carries the state of the agent, and the Kubernetes context and namespace it targets.
+	eventStateChanged = "state-changed"
+	// eventReset tells browsers to forget the messages they have, as they are sent all of them again.
+	eventReset = "reset"
+)
+
+// sseEvent is an event of the event stream of a session.
+type sseEvent struct {
+	// id identifies the event for browsers to resume the stream after it, with the Last-Event-ID header.
+	// It is empty for the events of the snapshot sent to new browsers, except the last one.
+	id  string
+	seq uint64
+
+	name string
+	data []byte
+}
+
+func (e *sseEvent) write(w io.Writer) error {
+	var b strings.Builder
+	if e.id != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.id)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", e.name, e.data)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// maxEventLogSize is the number of recent events kept for browsers resuming their event stream.
+const maxEventLogSize = 1000
+
+// eventLog numbers the events of a session, and keeps the recent ones.
+//
+// Event IDs are "<epoch>-<sequence number>". The epoch is random, so that browsers that were
+// connected to a previous run of the UI do not resume from an event of this one.
+type eventLog struct {
+	epoch string
+
+	mu      sync.Mutex
+	lastSeq uint64
+	events  []*sseEvent
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{epoch: randomToken()[:8]}
+}
+
+// add numbers an event, and keeps it.
+func (l *eventLog) add(name string, data []byte) *sseEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastSeq++
+	e := &sseEvent{
+		id:   l.epoch + "-" + strconv.FormatUint(l.lastSeq, 10),
+		seq:  l.lastSeq,
+		name: name,
+		data: data,
+	}
+	if len(l.events) == maxEventLogSize {
+		copy(l.events, l.events[1:])
+		l.events = l.events[:len(l.events)-1]
+	}
+	l.events = append(l.events, e)
+	return e
+}
+
+// last returns the ID and sequence number of the last event.
+func (l *eventLog) last() (id string, seq uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.epoch + "-" + strconv.FormatUint(l.lastSeq, 10), l.lastSeq
+}
+
+// since returns the events after the event with the given ID. It returns false if the ID is
+// not one of this log, or if some of the events after it are not kept anymore.
+func (l *eventLog) since(id string) ([]*sseEvent, bool) {
+	seqString, ok := strings.CutPrefix(id, l.epoch+"-")
+	if !ok {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqString, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if seq > l.lastSeq {
+		return nil, false
+	}
+	if seq == l.lastSeq {
+		return nil, true
+	}
+	if len(l.events) == 0 || l.events[0].seq > seq+1 {
+		return nil, false
+	}
+	i := int(seq + 1 - l.events[0].seq)
+	return append([]*sseEvent(nil), l.events[i:]...), true
+}
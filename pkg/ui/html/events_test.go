@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestEventLogSince(t *testing.T) {
+	l := newEventLog()
+	for i := 0; i < maxEventLogSize+5; i++ {
+		l.add(eventMessageDelta, []byte(fmt.Sprintf(`{"text":"%d"}`, i)))
+	}
+	id := func(seq int) string { return fmt.Sprintf("%s-%d", l.epoch, seq) }
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		wantOK      bool
+		wantFirst   uint64
+		wantCount   int
+	}{
+		{name: "recent event", lastEventID: id(maxEventLogSize + 2), wantOK: true, wantFirst: maxEventLogSize + 3, wantCount: 3},
+		{name: "last event", lastEventID: id(maxEventLogSize + 5), wantOK: true},
+		{name: "oldest kept event", lastEventID: id(5), wantOK: true, wantFirst: 6, wantCount: maxEventLogSize},
+		{name: "evicted event", lastEventID: id(4)},
+		{name: "future event", lastEventID: id(maxEventLogSize + 6)},
+		{name: "other epoch", lastEventID: "other-3"},
+		{name: "invalid", lastEventID: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok := l.since(tt.lastEventID)
+			if ok != tt.wantOK || len(events) != tt.wantCount {
+				t.Fatalf("since(%q) = %d events, %v; want %d events, %v", tt.lastEventID, len(events), ok, tt.wantCount, tt.wantOK)
+			}
+			if len(events) > 0 && events[0].seq != tt.wantFirst {
+				t.Errorf("since(%q) starts at event %d, want %d", tt.lastEventID, events[0].seq, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestMessagesStreamResume(t *testing.T) {
+	baseURL, id := newTestUI(t, Options{})
+
+	// A new browser is sent the session, and the ID of the last event.
+	state := firstState(t, baseURL, id)
+	lastEventID, _ := state["lastEventId"].(string)
+	if lastEventID == "" {
+		t.Fatalf("the snapshot has no event ID: %v", state)
+	}
+
+	// "/model" is answered by the agent without calling the LLM.
+	do(t, "POST", baseURL+"/sessions/"+id+"/send-message", url.Values{"q": {"/model"}})
+
+	// A browser reconnecting is only sent the events it missed.
+	var names []string
+	sawQuery, sawAnswer := false, false
+	sseEvents(t, baseURL, id, lastEventID, func(name string, data map[string]any) bool {
+		names = append(names, name)
+		if name == eventMessageAdded && data["Source"] == "user" && data["Payload"] == "/model" {
+			sawQuery = true
+		}
+		if name == eventMessageAdded && data["Source"] == "agent" && data["Type"] == "text" {
+			sawAnswer = true
+		}
+		return sawQuery && sawAnswer
+	})
+	if names[0] == eventReset {
+		t.Errorf("resuming the stream reset it: %v", names)
+	}
+
+	// A browser with an unknown event ID is sent the whole session again.
+	sseEvents(t, baseURL, id, "unknown-1", func(name string, data map[string]any) bool {
+		if name != eventReset {
+			t.Errorf("first event of a stream that cannot be resumed = %s, want %s", name, eventReset)
+		}
+		return true
+	})
+}
@@ -16,6 +16,7 @@ package html
 
 import (
 	"context"
+	"crypto/tls"
 	_ "embed"
 	"encoding/json"
 	"errors"
@@ -23,13 +24,16 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
 	"github.com/charmbracelet/glamour"
 	"golang.org/x/sync/errgroup"
@@ -38,20 +42,20 @@ import (
 
 // Broadcaster manages a set of clients for Server-Sent Events.
 type Broadcaster struct {
-	clients   map[chan []byte]bool
-	newClient chan chan []byte
-	delClient chan chan []byte
-	messages  chan []byte
+	clients   map[chan *sseEvent]bool
+	newClient chan chan *sseEvent
+	delClient chan chan *sseEvent
+	messages  chan *sseEvent
 	mu        sync.Mutex
 }
 
 // NewBroadcaster creates a new Broadcaster instance.
 func NewBroadcaster() *Broadcaster {
 	b := &Broadcaster{
-		clients:   make(map[chan []byte]bool),
-		newClient: make(chan (chan []byte)),
-		delClient: make(chan (chan []byte)),
-		messages:  make(chan []byte, 10),
+		clients:   make(map[chan *sseEvent]bool),
+		newClient: make(chan (chan *sseEvent)),
+		delClient: make(chan (chan *sseEvent)),
+		messages:  make(chan *sseEvent, 10),
 	}
 	return b
 }
@@ -68,8 +72,10 @@ func (b *Broadcaster) Run(ctx context.Context) {
 			b.mu.Unlock()
 		case client := <-b.delClient:
 			b.mu.Lock()
-			delete(b.clients, client)
-			close(client)
+			if b.clients[client] {
+				delete(b.clients, client)
+				close(client)
+			}
 			b.mu.Unlock()
 		case msg := <-b.messages:
 			b.mu.Lock()
@@ -77,7 +83,11 @@ func (b *Broadcaster) Run(ctx context.Context) {
 				select {
 				case client <- msg:
 				default:
-					klog.Warning("SSE client buffer full, dropping message.")
+					// Rather than dropping events, disconnect the client: the browser
+					// reconnects, and resumes the stream after the last event it got.
+					klog.Warning("SSE client buffer full, disconnecting the client.")
+					delete(b.clients, client)
+					close(client)
 				}
 			}
 			b.mu.Unlock()
@@ -85,43 +95,140 @@ func (b *Broadcaster) Run(ctx context.Context) {
 	}
 }
 
-// Broadcast sends a message to all connected clients.
-func (b *Broadcaster) Broadcast(msg []byte) {
-	b.messages <- msg
+// AgentFactory creates and initializes the agent of a session, whose messages are kept in store.
+// The web UI runs the agent, and closes it when the session is deleted or the UI stops.
+type AgentFactory func(ctx context.Context, store api.ChatMessageStore) (*agent.Agent, error)
+
+// webSession is a session hosted by the web UI: an agent, and the browsers following it.
+type webSession struct {
+	id          string
+	agent       *agent.Agent
+	broadcaster *Broadcaster
+	events      *eventLog
+	// state is the last state sent to the browsers; only the goroutine publishing the events uses it.
+	state sessionState
+	// ownsAgent is set for the agents created by the UI, which it closes.
+	ownsAgent bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Options configures how the web UI is served.
+type Options struct {
+	ListenAddress string
+	// Auth authenticates the users of the UI. If nil, anyone who can reach the UI can use it.
+	Auth Authenticator
+	// Authorization restricts the UI to some of the authenticated users.
+	Authorization Authorization
+	// TLSCertFile and TLSKeyFile are the certificate and key to serve the UI over HTTPS with.
+	TLSCertFile string
+	TLSKeyFile  string
 }
 
+// HTMLUserInterface is a web UI hosting many sessions, each with its own agent.
 type HTMLUserInterface struct {
 	httpServer         *http.Server
 	httpServerListener net.Listener
+	useTLS             bool
+	// canLogOut is set if users log in to the UI, and can log out.
+	canLogOut bool
+	authz     Authorization
 
-	agent            *agent.Agent
 	journal          journal.Recorder
 	markdownRenderer *glamour.TermRenderer
-	broadcaster      *Broadcaster
+
+	sessionManager *sessions.SessionManager
+	newAgent       AgentFactory
+
+	// defaultAgent is the agent the UI was started with.
+	defaultAgent     *agent.Agent
+	defaultSessionID string
+
+	mu sync.Mutex
+	// runCtx is the context of Run, which the sessions run in.
+	runCtx   context.Context
+	sessions map[string]*webSession
+	// defaultClaimed is set once a browser opened the default session without asking for a new one.
+	defaultClaimed bool
+	// defaultOwner is the user who owns the default session, which the session manager may not keep.
+	defaultOwner string
+	wg           sync.WaitGroup
 }
 
 var _ ui.UI = &HTMLUserInterface{}
 
-func NewHTMLUserInterface(agent *agent.Agent, listenAddress string, journal journal.Recorder) (*HTMLUserInterface, error) {
+// NewHTMLUserInterface creates a web UI. The agent it is started with is its default session;
+// browsers create and resume other sessions of the session manager, with agents created by newAgent.
+func NewHTMLUserInterface(agent *agent.Agent, opts Options, journal journal.Recorder, sessionManager *sessions.SessionManager, newAgent AgentFactory) (*HTMLUserInterface, error) {
 	mux := http.NewServeMux()
 
 	u := &HTMLUserInterface{
+		journal:          journal,
+		sessionManager:   sessionManager,
+		newAgent:         newAgent,
+		defaultAgent:     agent,
+		defaultSessionID: agent.Session().ID,
+		sessions:         map[string]*webSession{},
+		authz:            opts.Authorization,
+	}
+	if store, ok := agent.ChatMessageStore.(*sessions.Session); ok {
+		if meta, err := store.LoadMetadata(); err == nil {
+			u.defaultOwner = meta.Owner
+		}
+	}
+	u.sessions[u.defaultSessionID] = &webSession{
+		id:          u.defaultSessionID,
 		agent:       agent,
-		journal:     journal,
 		broadcaster: NewBroadcaster(),
+		events:      newEventLog(),
 	}
 
-	httpServer := &http.Server{
-		Addr:    listenAddress,
-		Handler: mux,
+	mux.HandleFunc("GET /", u.serveIndex)
+	mux.HandleFunc("GET /whoami", u.handleGETWhoami)
+	mux.HandleFunc("GET /sessions", u.handleGETSessions)
+	mux.HandleFunc("POST /sessions", u.handlePOSTSessions)
+	mux.HandleFunc("PATCH /sessions/{id}", u.handlePATCHSession)
+	mux.HandleFunc("DELETE /sessions/{id}", u.handleDELETESession)
+	mux.HandleFunc("GET /sessions/{id}/messages-stream", u.serveMessagesStream)
+	mux.HandleFunc("POST /sessions/{id}/send-message", u.handlePOSTSendMessage)
+	mux.HandleFunc("POST /sessions/{id}/choose-option", u.handlePOSTChooseOption)
+	// The endpoints of the default session, from before the UI hosted several sessions.
+	mux.HandleFunc("GET /messages-stream", u.inDefaultSession(u.serveMessagesStream))
+	mux.HandleFunc("POST /send-message", u.inDefaultSession(u.handlePOSTSendMessage))
+	mux.HandleFunc("POST /choose-option", u.inDefaultSession(u.handlePOSTChooseOption))
+
+	var handler http.Handler = mux
+	if opts.Auth != nil {
+		// The login endpoints are served to everyone, the UI only to authorized users.
+		authMux := http.NewServeMux()
+		opts.Auth.RegisterRoutes(authMux)
+		authMux.Handle("/", requireAuth(opts.Auth, opts.Authorization, mux))
+		handler = authMux
+		if a, ok := opts.Auth.(logoutAuthenticator); ok {
+			u.canLogOut = a.supportsLogout()
+		}
 	}
 
-	mux.HandleFunc("GET /", u.serveIndex)
-	mux.HandleFunc("GET /messages-stream", u.serveMessagesStream)
-	mux.HandleFunc("POST /send-message", u.handlePOSTSendMessage)
-	mux.HandleFunc("POST /choose-option", u.handlePOSTChooseOption)
+	httpServer := &http.Server{
+		Addr:    opts.ListenAddress,
+		Handler: csrfProtect(handler),
+	}
+	scheme := "http"
+	if opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS certificate: %w", err)
+		}
+		httpServer.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		u.useTLS = true
+		scheme = "https"
+	}
 
-	httpServerListener, err := net.Listen("tcp", listenAddress)
+	httpServerListener, err := net.Listen("tcp", opts.ListenAddress)
 	if err != nil {
 		return nil, fmt.Errorf("starting http server network listener: %w", err)
 	}
@@ -129,7 +236,7 @@ func NewHTMLUserInterface(agent *agent.Agent, listenAddress string, journal jour
 	u.httpServerListener = httpServerListener
 	u.httpServer = httpServer
 
-	fmt.Fprintf(os.Stdout, "listening on http://%s\n", endpoint)
+	fmt.Fprintf(os.Stdout, "listening on %s://%s\n", scheme, endpoint)
 
 	mdRenderer, err := glamour.NewTermRenderer(
 		glamour.WithAutoStyle(),
@@ -147,38 +254,23 @@ func NewHTMLUserInterface(agent *agent.Agent, listenAddress string, journal jour
 func (u *HTMLUserInterface) Run(ctx context.Context) error {
 	g, gctx := errgroup.WithContext(ctx)
 
-	// Start the broadcaster
-	g.Go(func() error {
-		u.broadcaster.Run(gctx)
-		return nil
-	})
+	u.mu.Lock()
+	u.runCtx = gctx
+	for _, ws := range u.sessions {
+		ws.ctx, ws.cancel = context.WithCancel(gctx)
+		u.startSession(ws)
+	}
+	u.mu.Unlock()
 
-	// This goroutine listens to agent output and broadcasts it.
 	g.Go(func() error {
-		for {
-			select {
-			case <-gctx.Done():
-				return nil
-			case _, ok := <-u.agent.Output:
-				if !ok {
-					return nil // Channel closed
-				}
-				// We received a message from the agent. It's a signal that
-				// the state has changed. We fetch the entire current state and
-				// broadcast it to all connected clients.
-				jsonData, err := u.getCurrentStateJSON()
-				if err != nil {
-					// Don't return an error, just log it and continue
-					klog.Errorf("Error marshaling state for broadcast: %v", err)
-					continue
-				}
-				u.broadcaster.Broadcast(jsonData)
-			}
+		var err error
+		if u.useTLS {
+			// The certificate is in the TLS config.
+			err = u.httpServer.ServeTLS(u.httpServerListener, "", "")
+		} else {
+			err = u.httpServer.Serve(u.httpServerListener)
 		}
-	})
-
-	g.Go(func() error {
-		if err := u.httpServer.Serve(u.httpServerListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("error running http server: %w", err)
 		}
 		return nil
@@ -191,12 +283,207 @@ func (u *HTMLUserInterface) Run(ctx context.Context) error {
 		if err := u.httpServer.Shutdown(shutdownCtx); err != nil {
 			klog.Errorf("HTTP server shutdown error: %v", err)
 		}
+
+		u.mu.Lock()
+		for id, ws := range u.sessions {
+			u.stopSession(ws)
+			delete(u.sessions, id)
+		}
+		u.mu.Unlock()
+		u.wg.Wait()
 		return nil
 	})
 
 	return g.Wait()
 }
 
+// startSession starts broadcasting the state of a session to its browsers, until ws.ctx is done.
+// The caller must hold u.mu.
+func (u *HTMLUserInterface) startSession(ws *webSession) {
+	u.wg.Add(2)
+	go func() {
+		defer u.wg.Done()
+		ws.broadcaster.Run(ws.ctx)
+	}()
+
+	// This goroutine listens to agent output, and broadcasts what changed.
+	go func() {
+		defer u.wg.Done()
+		for {
+			select {
+			case <-ws.ctx.Done():
+				return
+			case output, ok := <-ws.agent.Output:
+				if !ok {
+					return // Channel closed
+				}
+				msg, _ := output.(*api.Message)
+				for _, event := range u.sessionEvents(ws, msg) {
+					select {
+					case ws.broadcaster.messages <- event:
+					case <-ws.ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// stopSession stops the agent of a session, and disconnects its browsers. The caller must hold u.mu.
+func (u *HTMLUserInterface) stopSession(ws *webSession) {
+	if ws.cancel != nil {
+		ws.cancel()
+	}
+	if ws.ownsAgent {
+		if err := ws.agent.Close(); err != nil {
+			klog.Warningf("error closing the agent of session %s: %v", ws.id, err)
+		}
+	}
+}
+
+// errSessionNotFound is returned for sessions that do not exist.
+var errSessionNotFound = errors.New("session not found")
+
+// errUINotRunning is returned when sessions are started before the UI runs, or after it stopped.
+var errUINotRunning = errors.New("the web UI is not running")
+
+// session returns a running session, resuming it from the session manager if needed.
+func (u *HTMLUserInterface) session(id string) (*webSession, error) {
+	u.mu.Lock()
+	ws, ok := u.sessions[id]
+	u.mu.Unlock()
+	if ok {
+		return ws, nil
+	}
+
+	store, err := u.sessionManager.FindSessionByID(id)
+	if err != nil {
+		return nil, errSessionNotFound
+	}
+	if err := store.UpdateLastAccessed(); err != nil {
+		klog.Warningf("Failed to update session last accessed time: %v", err)
+	}
+	return u.runSession(id, store)
+}
+
+// runSession creates and runs the agent of a session. The agent is created without holding u.mu,
+// as that can take a while; if another request started the session meanwhile, that one is returned.
+func (u *HTMLUserInterface) runSession(id string, store *sessions.Session) (*webSession, error) {
+	u.mu.Lock()
+	runCtx := u.runCtx
+	u.mu.Unlock()
+	if runCtx == nil || runCtx.Err() != nil {
+		return nil, errUINotRunning
+	}
+
+	a, err := u.newAgent(runCtx, store)
+	if err != nil {
+		return nil, fmt.Errorf("starting the agent of session %s: %w", id, err)
+	}
+	ws := &webSession{
+		id:          id,
+		agent:       a,
+		broadcaster: NewBroadcaster(),
+		events:      newEventLog(),
+		ownsAgent:   true,
+	}
+	ws.ctx, ws.cancel = context.WithCancel(runCtx)
+	if err := a.Run(ws.ctx, ""); err != nil {
+		ws.cancel()
+		a.Close()
+		return nil, fmt.Errorf("running the agent of session %s: %w", id, err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if existing, ok := u.sessions[id]; ok {
+		u.stopSession(ws)
+		return existing, nil
+	}
+	if runCtx.Err() != nil {
+		// The UI stopped, and will not stop this session.
+		u.stopSession(ws)
+		return nil, errUINotRunning
+	}
+	u.startSession(ws)
+	u.sessions[id] = ws
+	klog.Infof("Started the agent of session %s", id)
+	return ws, nil
+}
+
+// inDefaultSession serves a session endpoint for the default session.
+func (u *HTMLUserInterface) inDefaultSession(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		req.SetPathValue("id", u.defaultSessionID)
+		handler(w, req)
+	}
+}
+
+// sessionOwner returns the user who owns a session, "" if the UI does not authenticate its users.
+// The caller must hold u.mu for the default session.
+func (u *HTMLUserInterface) sessionOwner(id string) (string, error) {
+	if id == u.defaultSessionID {
+		return u.defaultOwner, nil
+	}
+	store, err := u.sessionManager.FindSessionByID(id)
+	if err != nil {
+		return "", errSessionNotFound
+	}
+	meta, err := store.LoadMetadata()
+	if err != nil {
+		return "", fmt.Errorf("loading the metadata of session %s: %w", id, err)
+	}
+	return meta.Owner, nil
+}
+
+// mayUseSession reports whether user can use a session owned by owner. If the UI authenticates
+// its users, they can only use their own sessions, unless they are in an admin group.
+func (u *HTMLUserInterface) mayUseSession(user *User, owner string) bool {
+	return user == nil || user.Name == owner || u.authz.isAdmin(user)
+}
+
+// authorizeSession checks that the user of a request can use a session, writing an error response
+// if not. Other users' sessions are reported as not found.
+func (u *HTMLUserInterface) authorizeSession(w http.ResponseWriter, req *http.Request, id string) bool {
+	user := UserFromContext(req.Context())
+	if user == nil {
+		return true
+	}
+	u.mu.Lock()
+	owner, err := u.sessionOwner(id)
+	u.mu.Unlock()
+	if errors.Is(err, errSessionNotFound) || (err == nil && !u.mayUseSession(user, owner)) {
+		http.Error(w, errSessionNotFound.Error(), http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		klog.FromContext(req.Context()).Error(err, "getting session owner")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// requestSession returns the session of a request, writing an error response if there is none
+// or the user of the request cannot use it.
+func (u *HTMLUserInterface) requestSession(w http.ResponseWriter, req *http.Request) (*webSession, bool) {
+	if !u.authorizeSession(w, req, req.PathValue("id")) {
+		return nil, false
+	}
+	ws, err := u.session(req.PathValue("id"))
+	if errors.Is(err, errSessionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		klog.FromContext(req.Context()).Error(err, "getting session")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return ws, true
+}
+
 //go:embed index.html
 var indexHTML []byte
 
@@ -205,10 +492,247 @@ func (u *HTMLUserInterface) serveIndex(w http.ResponseWriter, req *http.Request)
 	w.Write(indexHTML)
 }
 
+// handleGETWhoami returns the authenticated user, for the UI to show who is logged in.
+func (u *HTMLUserInterface) handleGETWhoami(w http.ResponseWriter, req *http.Request) {
+	user := UserFromContext(req.Context())
+	if user == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"authenticated": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"authenticated": true,
+		"name":          user.Name,
+		"groups":        user.Groups,
+		"canLogOut":     u.canLogOut,
+	})
+}
+
+// sessionInfo describes a session in the session list.
+type sessionInfo struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ModelID      string    `json:"modelID,omitempty"`
+	ProviderID   string    `json:"providerID,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	LastAccessed time.Time `json:"lastAccessed"`
+	// Active is set for the sessions whose agent is running.
+	Active bool `json:"active"`
+	// Default is set for the session the UI was started with.
+	Default bool `json:"default,omitempty"`
+}
+
+func (u *HTMLUserInterface) handleGETSessions(w http.ResponseWriter, req *http.Request) {
+	list, err := u.sessionManager.ListSessions()
+	if err != nil {
+		klog.FromContext(req.Context()).Error(err, "listing sessions")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	u.mu.Lock()
+	active := map[string]bool{}
+	for id := range u.sessions {
+		active[id] = true
+	}
+	defaultOwner := u.defaultOwner
+	u.mu.Unlock()
+
+	user := UserFromContext(req.Context())
+	infos := []sessionInfo{}
+	for _, s := range list {
+		meta, err := s.LoadMetadata()
+		if err != nil {
+			klog.Warningf("could not load metadata for session %s: %v", s.ID, err)
+			continue
+		}
+		owner := meta.Owner
+		if s.ID == u.defaultSessionID {
+			owner = defaultOwner
+		}
+		if !u.mayUseSession(user, owner) {
+			continue
+		}
+		name := meta.Name
+		if name == "" {
+			name = sessionTitle(s.ChatMessages())
+		}
+		infos = append(infos, sessionInfo{
+			ID:           s.ID,
+			Name:         name,
+			ModelID:      meta.ModelID,
+			ProviderID:   meta.ProviderID,
+			CreatedAt:    meta.CreatedAt,
+			LastAccessed: meta.LastAccessed,
+			Active:       active[s.ID],
+			Default:      s.ID == u.defaultSessionID,
+		})
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].LastAccessed.After(infos[j].LastAccessed)
+	})
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// maxSessionTitleLength is the length of the titles derived from the first query of unnamed sessions.
+const maxSessionTitleLength = 60
+
+// sessionTitle names an unnamed session after its first query.
+func sessionTitle(messages []*api.Message) string {
+	for _, m := range messages {
+		text, ok := m.Payload.(string)
+		if m.Source != api.MessageSourceUser || m.Type != api.MessageTypeText || !ok {
+			continue
+		}
+		text = strings.Join(strings.Fields(text), " ")
+		if runes := []rune(text); len(runes) > maxSessionTitleLength {
+			text = strings.TrimSpace(string(runes[:maxSessionTitleLength])) + "…"
+		}
+		return text
+	}
+	return "New session"
+}
+
+// handlePOSTSessions creates a session, owned by the user of the request. Browsers opening the UI
+// for the first time pass claimDefault=true, so the first of them continues the session the UI was
+// started with, if its user can use it; the default session then belongs to that user.
+func (u *HTMLUserInterface) handlePOSTSessions(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	owner := ""
+	user := UserFromContext(req.Context())
+	if user != nil {
+		owner = user.Name
+	}
+
+	u.mu.Lock()
+	if req.FormValue("claimDefault") == "true" && !u.defaultClaimed && (u.defaultOwner == "" || u.mayUseSession(user, u.defaultOwner)) {
+		if _, ok := u.sessions[u.defaultSessionID]; ok {
+			u.defaultClaimed = true
+			if u.defaultOwner == "" && owner != "" {
+				u.claimDefaultSession(owner)
+			}
+			u.mu.Unlock()
+			writeJSON(w, http.StatusOK, map[string]string{"id": u.defaultSessionID})
+			return
+		}
+	}
+	running := u.runCtx != nil && u.runCtx.Err() == nil
+	u.mu.Unlock()
+	if !running {
+		http.Error(w, errUINotRunning.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	store, err := u.sessionManager.NewSession(sessions.Metadata{
+		Name:       strings.TrimSpace(req.FormValue("name")),
+		Owner:      owner,
+		ProviderID: u.defaultAgent.Provider,
+		ModelID:    u.defaultAgent.Model,
+	})
+	if err != nil {
+		klog.FromContext(req.Context()).Error(err, "creating session")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := u.runSession(store.ID, store); err != nil {
+		klog.FromContext(req.Context()).Error(err, "starting session")
+		if err := u.sessionManager.DeleteSession(store.ID); err != nil {
+			klog.Warningf("failed to delete session %s: %v", store.ID, err)
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": store.ID})
+}
+
+// claimDefaultSession makes owner the owner of the default session. The caller must hold u.mu.
+func (u *HTMLUserInterface) claimDefaultSession(owner string) {
+	u.defaultOwner = owner
+	if store, ok := u.defaultAgent.ChatMessageStore.(*sessions.Session); ok {
+		claimed, err := store.Claim(owner)
+		if err != nil {
+			klog.Warningf("failed to record the owner of session %s: %v", store.ID, err)
+			return
+		}
+		u.defaultOwner = claimed
+	}
+}
+
+// handlePATCHSession renames a session.
+func (u *HTMLUserInterface) handlePATCHSession(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !u.authorizeSession(w, req, req.PathValue("id")) {
+		return
+	}
+	store, err := u.sessionManager.FindSessionByID(req.PathValue("id"))
+	if err != nil {
+		http.Error(w, errSessionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err := store.Rename(strings.TrimSpace(req.FormValue("name"))); err != nil {
+		klog.FromContext(req.Context()).Error(err, "renaming session")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDELETESession stops the agent of a session, and deletes the session.
+// The session the UI was started with runs until the UI stops, so it cannot be deleted.
+func (u *HTMLUserInterface) handleDELETESession(w http.ResponseWriter, req *http.Request) {
+	id := req.PathValue("id")
+	if !u.authorizeSession(w, req, id) {
+		return
+	}
+	if id == u.defaultSessionID {
+		http.Error(w, "the session kubectl-ai was started with cannot be deleted", http.StatusConflict)
+		return
+	}
+
+	u.mu.Lock()
+	if ws, ok := u.sessions[id]; ok {
+		u.stopSession(ws)
+		delete(u.sessions, id)
+	}
+	u.mu.Unlock()
+
+	if err := u.sessionManager.DeleteSession(id); err != nil {
+		if ws, _ := u.sessionManager.FindSessionByID(id); ws == nil {
+			http.Error(w, errSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		klog.FromContext(req.Context()).Error(err, "deleting session")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("writing JSON response: %v", err)
+	}
+}
+
+// serveMessagesStream sends the events of a session to a browser. A new browser is sent all the
+// messages of the session first; a browser reconnecting with the Last-Event-ID header is only sent
+// the events it missed, if they are still kept.
 func (u *HTMLUserInterface) serveMessagesStream(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	log := klog.FromContext(ctx)
 
+	ws, ok := u.requestSession(w, req)
+	if !ok {
+		return
+	}
+
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
@@ -219,35 +743,78 @@ func (u *HTMLUserInterface) serveMessagesStream(w http.ResponseWriter, req *http
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	clientChan := make(chan []byte, 10)
-	u.broadcaster.newClient <- clientChan
+	// Subscribe before looking at the session, so that no event is missed.
+	clientChan := make(chan *sseEvent, 100)
+	select {
+	case ws.broadcaster.newClient <- clientChan:
+	case <-ws.ctx.Done():
+		return
+	}
 	defer func() {
-		u.broadcaster.delClient <- clientChan
+		select {
+		case ws.broadcaster.delClient <- clientChan:
+		case <-ws.ctx.Done():
+		}
 	}()
 
-	log.Info("SSE client connected")
+	var events []*sseEvent
+	var lastSeq uint64
+	resumed := false
+	if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+		events, resumed = ws.events.since(lastEventID)
+		if len(events) > 0 {
+			lastSeq = events[len(events)-1].seq
+		} else if resumed {
+			_, lastSeq = ws.events.last()
+		}
+	}
+	if !resumed {
+		events, lastSeq = u.snapshot(ws)
+	}
+	log.Info("SSE client connected", "session", ws.id, "resumed", resumed, "events", len(events))
 
-	// Immediately send the current state to the new client
-	initialData, err := u.getCurrentStateJSON()
-	if err != nil {
-		log.Error(err, "getting initial state for SSE client")
-	} else {
-		fmt.Fprintf(w, "data: %s\n\n", initialData)
-		flusher.Flush()
+	for _, event := range events {
+		if err := event.write(w); err != nil {
+			return
+		}
 	}
+	flusher.Flush()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("SSE client disconnected")
+			log.Info("SSE client disconnected", "session", ws.id)
+			return
+		case <-ws.ctx.Done():
+			// The session was deleted, or the UI is stopping.
 			return
-		case msg := <-clientChan:
-			fmt.Fprintf(w, "data: %s\n\n", msg)
+		case event, ok := <-clientChan:
+			if !ok {
+				// The browser fell behind; it reconnects and resumes.
+				return
+			}
+			if event.seq <= lastSeq {
+				// Sent already.
+				continue
+			}
+			if err := event.write(w); err != nil {
+				return
+			}
 			flusher.Flush()
 		}
 	}
 }
 
+// sendInput passes user input to the agent of a session.
+func sendInput(w http.ResponseWriter, ws *webSession, input any) {
+	select {
+	case ws.agent.Input <- input:
+		w.WriteHeader(http.StatusOK)
+	case <-ws.ctx.Done():
+		http.Error(w, "the session was closed", http.StatusGone)
+	}
+}
+
 func (u *HTMLUserInterface) handlePOSTSendMessage(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	log := klog.FromContext(ctx)
@@ -258,7 +825,7 @@ func (u *HTMLUserInterface) handlePOSTSendMessage(w http.ResponseWriter, req *ht
 		return
 	}
 
-	log.Info("got request", "values", req.Form)
+	log.Info("got request", "session", req.PathValue("id"), "values", req.Form, "user", userName(UserFromContext(ctx)))
 
 	q := req.FormValue("q")
 	if q == "" {
@@ -266,30 +833,100 @@ func (u *HTMLUserInterface) handlePOSTSendMessage(w http.ResponseWriter, req *ht
 		return
 	}
 
+	ws, ok := u.requestSession(w, req)
+	if !ok {
+		return
+	}
 	// Send the message to the agent
-	u.agent.Input <- &api.UserInputResponse{Query: q}
+	sendInput(w, ws, &api.UserInputResponse{Query: q})
+}
 
-	w.WriteHeader(http.StatusOK)
+// sessionState is the state of a session sent in state-changed events.
+type sessionState struct {
+	SessionID   string         `json:"sessionId"`
+	AgentState  api.AgentState `json:"agentState"`
+	KubeContext string         `json:"kubeContext"`
+	Namespace   string         `json:"namespace"`
 }
 
-func (u *HTMLUserInterface) getCurrentStateJSON() ([]byte, error) {
-	allMessages := u.agent.Session().AllMessages()
-	// Create a copy of the messages to avoid race conditions
-	var messages []*api.Message
-	for _, message := range allMessages {
-		if message.Type == api.MessageTypeUserInputRequest && message.Payload == ">>>" {
-			continue
+func currentState(ws *webSession) sessionState {
+	session := ws.agent.Session()
+	return sessionState{
+		SessionID:   ws.id,
+		AgentState:  session.AgentState,
+		KubeContext: session.KubeContext,
+		Namespace:   session.Namespace,
+	}
+}
+
+// messageDelta is the data of message-delta events.
+type messageDelta struct {
+	MessageID string `json:"messageId"`
+	Text      string `json:"text"`
+}
+
+// hiddenMessage reports whether a message is not shown in the UI: the prompts for a query,
+// as the UI always has an input box.
+func hiddenMessage(message *api.Message) bool {
+	return message.Type == api.MessageTypeUserInputRequest && message.Payload == ">>>"
+}
+
+// sessionEvents records the events for an output message of the agent of a session,
+// and for the state change that came with it.
+func (u *HTMLUserInterface) sessionEvents(ws *webSession, msg *api.Message) []*sseEvent {
+	var events []*sseEvent
+	add := func(name string, v any) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			// Don't return an error, just log it and continue
+			klog.Errorf("Error marshaling %s event: %v", name, err)
+			return
 		}
-		messages = append(messages, message)
+		events = append(events, ws.events.add(name, data))
 	}
 
-	agentState := u.agent.Session().AgentState
+	switch {
+	case msg == nil || hiddenMessage(msg):
+	case msg.Type == api.MessageTypeProgress:
+		// The page shows the agent state from state-changed events.
+	case msg.Type == api.MessageTypeTextDelta:
+		text, _ := msg.Payload.(string)
+		add(eventMessageDelta, messageDelta{MessageID: msg.ID, Text: text})
+	default:
+		add(eventMessageAdded, msg)
+	}
+	if state := currentState(ws); state != ws.state {
+		ws.state = state
+		add(eventStateChanged, state)
+	}
+	return events
+}
+
+// snapshot returns the events that send a new browser the messages and state of a session,
+// and the sequence number of the last event they include.
+func (u *HTMLUserInterface) snapshot(ws *webSession) ([]*sseEvent, uint64) {
+	// Events that come after the last one are sent after the snapshot,
+	// even if the snapshot includes them: browsers ignore the messages they have.
+	lastID, lastSeq := ws.events.last()
 
-	data := map[string]interface{}{
-		"messages":   messages,
-		"agentState": agentState,
+	events := []*sseEvent{{name: eventReset, data: []byte("{}")}}
+	for _, message := range ws.agent.Session().AllMessages() {
+		if hiddenMessage(message) {
+			continue
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			klog.Errorf("Error marshaling message %s: %v", message.ID, err)
+			continue
+		}
+		events = append(events, &sseEvent{name: eventMessageAdded, data: data})
+	}
+	data, err := json.Marshal(currentState(ws))
+	if err != nil {
+		klog.Errorf("Error marshaling session state: %v", err)
 	}
-	return json.Marshal(data)
+	events = append(events, &sseEvent{id: lastID, seq: lastSeq, name: eventStateChanged, data: data})
+	return events, lastSeq
 }
 
 func (u *HTMLUserInterface) handlePOSTChooseOption(w http.ResponseWriter, req *http.Request) {
@@ -302,7 +939,8 @@ func (u *HTMLUserInterface) handlePOSTChooseOption(w http.ResponseWriter, req *h
 		return
 	}
 
-	log.Info("got request", "values", req.Form)
+	user := UserFromContext(ctx)
+	log.Info("got request", "session", req.PathValue("id"), "values", req.Form, "user", userName(user))
 
 	choice := req.FormValue("choice")
 	if choice == "" {
@@ -316,10 +954,13 @@ func (u *HTMLUserInterface) handlePOSTChooseOption(w http.ResponseWriter, req *h
 		return
 	}
 
-	// Send the choice to the agent
-	u.agent.Input <- &api.UserChoiceResponse{Choice: choiceIndex}
-
-	w.WriteHeader(http.StatusOK)
+	ws, ok := u.requestSession(w, req)
+	if !ok {
+		return
+	}
+	// Send the choice to the agent, which records who made it in the journal.
+	// The tool calls edited by the user, if they chose to edit them, come as edit values.
+	sendInput(w, ws, &api.UserChoiceResponse{Choice: choiceIndex, User: userName(user), Edits: req.Form["edit"]})
 }
 
 func (u *HTMLUserInterface) Close() error {
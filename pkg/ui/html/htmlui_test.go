@@ -0,0 +1,416 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestUI starts a web UI whose agents never get to call the LLM.
+func newTestUI(t *testing.T, opts Options) (baseURL string, defaultSessionID string) {
+	return newTestUIWithAgents(t, opts, nil)
+}
+
+// newTestUIWithAgents starts a web UI whose agents never get to call the LLM. If wrap is set,
+// the UI creates the agents of the sessions other than the default one with wrap(newAgent).
+func newTestUIWithAgents(t *testing.T, opts Options, wrap func(AgentFactory) AgentFactory) (baseURL string, defaultSessionID string) {
+	// Agents resume their sessions from the session manager in the home directory.
+	t.Setenv("HOME", t.TempDir())
+	manager, err := sessions.NewSessionManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), gomock.Any()).Return(chat).AnyTimes()
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil).AnyTimes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var newAgent AgentFactory = func(ctx context.Context, store api.ChatMessageStore) (*agent.Agent, error) {
+		var toolset tools.Tools
+		toolset.Init()
+		a := &agent.Agent{
+			Model:            "test-model",
+			Provider:         "test-provider",
+			LLM:              client,
+			MaxIterations:    5,
+			Tools:            toolset,
+			RemoveWorkDir:    true,
+			ChatMessageStore: store,
+		}
+		if err := a.Init(ctx); err != nil {
+			return nil, err
+		}
+		return a, nil
+	}
+
+	store, err := manager.NewSession(sessions.Metadata{ProviderID: "test-provider", ModelID: "test-model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultAgent, err := newAgent(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := defaultAgent.Run(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if wrap != nil {
+		newAgent = wrap(newAgent)
+	}
+	opts.ListenAddress = "127.0.0.1:0"
+	u, err := NewHTMLUserInterface(defaultAgent, opts, &journal.LogRecorder{}, manager, newAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- u.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+		defaultAgent.Close()
+	})
+	return "http://" + u.httpServerListener.Addr().String(), store.ID
+}
+
+func do(t *testing.T, method, url string, form url.Values) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// The CSRF token is any value sent in both the cookie and the header.
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "test-csrf-token"})
+	req.Header.Set(csrfHeaderName, "test-csrf-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+}
+
+// sseEvents connects to the event stream of a session, and returns the events it receives
+// until stop returns true.
+func sseEvents(t *testing.T, baseURL, id, lastEventID string, stop func(name string, data map[string]any) bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", baseURL+"/sessions/"+id+"/messages-stream", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("opening the event stream of session %s: %s", id, resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	name := ""
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			name = v
+		}
+		if v, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+			lastEventID = v
+		}
+		if v, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var data map[string]any
+			if err := json.Unmarshal([]byte(v), &data); err != nil {
+				t.Fatal(err)
+			}
+			data["lastEventId"] = lastEventID
+			if stop(name, data) {
+				return
+			}
+		}
+	}
+	t.Fatalf("event stream of session %s ended: %v", id, scanner.Err())
+}
+
+// firstState reads the first state the server sends on the event stream of a session.
+func firstState(t *testing.T, baseURL, id string) map[string]any {
+	t.Helper()
+	var state map[string]any
+	sseEvents(t, baseURL, id, "", func(name string, data map[string]any) bool {
+		state = data
+		return name == eventStateChanged
+	})
+	return state
+}
+
+func TestHTMLUserInterfaceSessions(t *testing.T) {
+	baseURL, defaultID := newTestUI(t, Options{})
+
+	// The first browser continues the session the UI was started with; the next ones get sessions of their own.
+	var created struct{ ID string }
+	decode(t, do(t, "POST", baseURL+"/sessions", url.Values{"claimDefault": {"true"}}), &created)
+	if created.ID != defaultID {
+		t.Errorf("first browser got session %q, want the default session %q", created.ID, defaultID)
+	}
+	resp := do(t, "POST", baseURL+"/sessions", url.Values{"claimDefault": {"true"}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("creating a session: %s", resp.Status)
+	}
+	decode(t, resp, &created)
+	if created.ID == "" || created.ID == defaultID {
+		t.Fatalf("second browser got session %q, want a new session", created.ID)
+	}
+
+	if state := firstState(t, baseURL, created.ID); state["sessionId"] != created.ID {
+		t.Errorf("event stream of session %s sent state %v", created.ID, state)
+	}
+	if state := firstState(t, baseURL, defaultID); state["sessionId"] != defaultID {
+		t.Errorf("event stream of the default session sent state %v", state)
+	}
+
+	if resp := do(t, "PATCH", baseURL+"/sessions/"+created.ID, url.Values{"name": {"debugging web"}}); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("renaming a session: %s", resp.Status)
+	}
+
+	var list []sessionInfo
+	decode(t, do(t, "GET", baseURL+"/sessions", nil), &list)
+	names := map[string]sessionInfo{}
+	for _, s := range list {
+		names[s.ID] = s
+	}
+	if s := names[created.ID]; s.Name != "debugging web" || !s.Active || s.ModelID != "test-model" {
+		t.Errorf("listed session %+v, want the renamed, active session", s)
+	}
+	if s := names[defaultID]; !s.Default || s.Name != "New session" {
+		t.Errorf("listed default session %+v", s)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: "DELETE", path: "/sessions/" + defaultID, want: http.StatusConflict},
+		{method: "DELETE", path: "/sessions/" + created.ID, want: http.StatusNoContent},
+		{method: "DELETE", path: "/sessions/" + created.ID, want: http.StatusNotFound},
+		{method: "GET", path: "/sessions/" + created.ID + "/messages-stream", want: http.StatusNotFound},
+		{method: "POST", path: "/sessions/unknown/send-message?q=hi", want: http.StatusNotFound},
+		{method: "PATCH", path: "/sessions/unknown", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := do(t, tt.method, baseURL+tt.path, nil); resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %s, want %d", tt.method, tt.path, resp.Status, tt.want)
+		}
+	}
+}
+
+func TestSessionStartDoesNotBlockOtherRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	baseURL, defaultID := newTestUIWithAgents(t, Options{}, func(newAgent AgentFactory) AgentFactory {
+		return func(ctx context.Context, store api.ChatMessageStore) (*agent.Agent, error) {
+			close(started)
+			<-release
+			return newAgent(ctx, store)
+		}
+	})
+
+	created := make(chan *http.Response, 1)
+	go func() {
+		created <- do(t, "POST", baseURL+"/sessions", nil)
+	}()
+	<-started
+
+	// While the agent of the new session starts, the other sessions are served.
+	listed := make(chan *http.Response, 1)
+	go func() {
+		listed <- do(t, "GET", baseURL+"/sessions", nil)
+	}()
+	select {
+	case resp := <-listed:
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("listing sessions: %s", resp.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listing sessions waited for the agent of the new session")
+	}
+	if state := firstState(t, baseURL, defaultID); state["sessionId"] != defaultID {
+		t.Errorf("event stream of the default session sent state %v", state)
+	}
+
+	close(release)
+	if resp := <-created; resp.StatusCode != http.StatusCreated {
+		t.Errorf("creating a session: %s", resp.Status)
+	}
+}
+
+func TestSessionsAreOwnedByTheirUsers(t *testing.T) {
+	tokenFile := writeFile(t, "tokens.csv", `alice-token,alice,1
+bob-token,bob,2
+carol-token,carol,3,"admins"
+`)
+	auth, err := NewTokenAuthenticator(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURL, defaultID := newTestUI(t, Options{Auth: auth, Authorization: Authorization{AdminGroups: []string{"admins"}}})
+
+	// as makes a request authenticated with a bearer token, which needs no CSRF token.
+	as := func(token, method, path string, form url.Values) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, baseURL+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	create := func(token string) string {
+		t.Helper()
+		var created struct{ ID string }
+		decode(t, as(token, "POST", "/sessions", url.Values{"claimDefault": {"true"}}), &created)
+		return created.ID
+	}
+	listed := func(token string) []string {
+		t.Helper()
+		var list []sessionInfo
+		decode(t, as(token, "GET", "/sessions", nil), &list)
+		var ids []string
+		for _, s := range list {
+			ids = append(ids, s.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	sorted := func(ids ...string) []string {
+		sort.Strings(ids)
+		return ids
+	}
+
+	// Alice opens the UI first, and continues the default session.
+	if id := create("alice-token"); id != defaultID {
+		t.Fatalf("alice got session %q, want the default session %q", id, defaultID)
+	}
+	aliceID := create("alice-token")
+	bobID := create("bob-token")
+	if bobID == defaultID || bobID == aliceID {
+		t.Fatalf("bob got session %q, want a session of his own", bobID)
+	}
+
+	if got, want := listed("alice-token"), sorted(defaultID, aliceID); !reflect.DeepEqual(got, want) {
+		t.Errorf("alice lists sessions %v, want %v", got, want)
+	}
+	if got, want := listed("bob-token"), []string{bobID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bob lists sessions %v, want %v", got, want)
+	}
+	if got, want := listed("carol-token"), sorted(defaultID, aliceID, bobID); !reflect.DeepEqual(got, want) {
+		t.Errorf("the admin lists sessions %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{token: "bob-token", method: "GET", path: "/sessions/" + aliceID + "/messages-stream", want: http.StatusNotFound},
+		{token: "bob-token", method: "POST", path: "/sessions/" + aliceID + "/send-message?q=hi", want: http.StatusNotFound},
+		{token: "bob-token", method: "POST", path: "/sessions/" + aliceID + "/choose-option?choice=1", want: http.StatusNotFound},
+		{token: "bob-token", method: "PATCH", path: "/sessions/" + aliceID, want: http.StatusNotFound},
+		{token: "bob-token", method: "DELETE", path: "/sessions/" + aliceID, want: http.StatusNotFound},
+		{token: "bob-token", method: "GET", path: "/sessions/" + defaultID + "/messages-stream", want: http.StatusNotFound},
+		{token: "bob-token", method: "GET", path: "/messages-stream", want: http.StatusNotFound},
+		{token: "bob-token", method: "POST", path: "/choose-option?choice=1", want: http.StatusNotFound},
+		{token: "bob-token", method: "PATCH", path: "/sessions/" + bobID, want: http.StatusNoContent},
+		{token: "alice-token", method: "PATCH", path: "/sessions/" + aliceID, want: http.StatusNoContent},
+		{token: "carol-token", method: "PATCH", path: "/sessions/" + aliceID, want: http.StatusNoContent},
+		{token: "carol-token", method: "DELETE", path: "/sessions/" + bobID, want: http.StatusNoContent},
+		{token: "alice-token", method: "DELETE", path: "/sessions/" + aliceID, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if resp := as(tt.token, tt.method, tt.path, nil); resp.StatusCode != tt.want {
+			t.Errorf("%s %s by %s = %s, want %d", tt.method, tt.path, strings.TrimSuffix(tt.token, "-token"), resp.Status, tt.want)
+		}
+	}
+}
+
+func TestSessionTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*api.Message
+		want     string
+	}{
+		{name: "empty", want: "New session"},
+		{
+			name: "first query",
+			messages: []*api.Message{
+				{Source: api.MessageSourceAgent, Type: api.MessageTypeText, Payload: "Hey there"},
+				{Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "why is\n  web crashing?"},
+				{Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: "and db?"},
+			},
+			want: "why is web crashing?",
+		},
+		{
+			name:     "long query",
+			messages: []*api.Message{{Source: api.MessageSourceUser, Type: api.MessageTypeText, Payload: strings.Repeat("a", 100)}},
+			want:     strings.Repeat("a", maxSessionTitleLength) + "…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionTitle(tt.messages); got != tt.want {
+				t.Errorf("sessionTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -36,12 +36,14 @@ const (
 	UITypeTerminal Type = "terminal"
 	UITypeWeb      Type = "web"
 	UITypeTUI      Type = "tui"
+	// UITypeJSON writes the messages of a single, non-interactive run as JSON lines.
+	UITypeJSON Type = "json"
 )
 
 // Implement pflag.Value for UIType
 func (u *Type) Set(s string) error {
 	switch s {
-	case "terminal", "web", "tui":
+	case "terminal", "web", "tui", "json":
 		*u = Type(s)
 		return nil
 	default:
@@ -0,0 +1,266 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"k8s.io/klog/v2"
+)
+
+// RunStatus is how a non-interactive run of the agent ended.
+type RunStatus string
+
+const (
+	// RunStatusSuccess is a run that answered the query.
+	RunStatusSuccess RunStatus = "success"
+	// RunStatusError is a run that failed, such as when the LLM could not be reached.
+	RunStatusError RunStatus = "error"
+	// RunStatusToolError is a run in which some tool calls failed.
+	RunStatusToolError RunStatus = "tool-error"
+	// RunStatusPermissionRequired is a run that stopped because tool calls needed the user's approval.
+	RunStatusPermissionRequired RunStatus = "permission-required"
+	// RunStatusMaxIterations is a run that stopped because it reached the maximum number of iterations.
+	RunStatusMaxIterations RunStatus = "max-iterations"
+)
+
+// ExitCode returns the exit code of the process for a run that ended with the status.
+func (s RunStatus) ExitCode() int {
+	switch s {
+	case RunStatusSuccess:
+		return 0
+	case RunStatusToolError:
+		return 2
+	case RunStatusPermissionRequired:
+		return 3
+	case RunStatusMaxIterations:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// ExitError is returned by UIs whose run of the agent did not succeed, for the process to exit with its code.
+type ExitError struct {
+	Status RunStatus
+	// Err is the error the agent ran into, if any.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("agent run ended with status %s: %v", e.Status, e.Err)
+	}
+	return fmt.Sprintf("agent run ended with status %s", e.Status)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the process.
+func (e *ExitError) ExitCode() int {
+	return e.Status.ExitCode()
+}
+
+// JSONUI is a non-interactive UI for scripts: it writes every message of the agent as a line of JSON,
+// and a summary of the run once the agent exits.
+type JSONUI struct {
+	agent *agent.Agent
+	enc   *json.Encoder
+}
+
+var _ UI = &JSONUI{}
+
+// NewJSONUI creates a JSON UI writing to out. The agent must run in RunOnce mode.
+func NewJSONUI(agent *agent.Agent, out io.Writer) *JSONUI {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return &JSONUI{
+		agent: agent,
+		enc:   enc,
+	}
+}
+
+// jsonEvent is a line of the output of the JSON UI, for a message of the agent.
+type jsonEvent struct {
+	// Type is one of text, error, tool-call, tool-result and choice-request.
+	Type      string            `json:"type"`
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Source    api.MessageSource `json:"source"`
+
+	// Text is the text of text, error and choice-request events.
+	Text string `json:"text,omitempty"`
+
+	// ToolCall is the tool call of tool-call and tool-result events.
+	ToolCall *jsonToolCall `json:"toolCall,omitempty"`
+	// Description is how the tool call of a tool-call event is shown to users.
+	Description string `json:"description,omitempty"`
+	// Result is the output of the tool of a tool-result event.
+	Result any `json:"result,omitempty"`
+}
+
+type jsonToolCall struct {
+	ID        string         `json:"id,omitempty"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// jsonSummary is the last line of the output of the JSON UI.
+type jsonSummary struct {
+	// Type is always summary.
+	Type       string    `json:"type"`
+	Status     RunStatus `json:"status"`
+	ExitCode   int       `json:"exitCode"`
+	Error      string    `json:"error,omitempty"`
+	Iterations int       `json:"iterations"`
+	ToolCalls  int       `json:"toolCalls"`
+	ToolErrors int       `json:"toolErrors"`
+	// Usage is the token usage reported by the LLM for each request, in the format of the provider.
+	Usage []any `json:"usage,omitempty"`
+}
+
+func (u *JSONUI) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-u.agent.Output:
+			if !ok {
+				return u.finish()
+			}
+			if err := u.handleMessage(msg); err != nil {
+				return err
+			}
+		case <-u.agent.Done():
+			// The agent sent all its messages before it returned, so write the ones not read yet.
+			for {
+				select {
+				case msg, ok := <-u.agent.Output:
+					if !ok {
+						return u.finish()
+					}
+					if err := u.handleMessage(msg); err != nil {
+						return err
+					}
+				default:
+					return u.finish()
+				}
+			}
+		}
+	}
+}
+
+func (u *JSONUI) handleMessage(msg any) error {
+	message, ok := msg.(*api.Message)
+	if !ok {
+		klog.Warningf("unexpected agent output %T", msg)
+		return nil
+	}
+	event := jsonEvent{
+		ID:        message.ID,
+		Timestamp: message.Timestamp,
+		Source:    message.Source,
+	}
+	if message.ToolCall != nil {
+		event.ToolCall = &jsonToolCall{
+			ID:        message.ToolCall.ID,
+			Name:      message.ToolCall.Name,
+			Arguments: message.ToolCall.Arguments,
+		}
+	}
+	switch message.Type {
+	case api.MessageTypeText:
+		event.Type = "text"
+		event.Text = fmt.Sprint(message.Payload)
+	case api.MessageTypeError:
+		event.Type = "error"
+		event.Text = strings.TrimSpace(fmt.Sprint(message.Payload))
+	case api.MessageTypeToolCallRequest:
+		event.Type = "tool-call"
+		event.Description = fmt.Sprint(message.Payload)
+	case api.MessageTypeToolCallResponse:
+		event.Type = "tool-result"
+		event.Result = message.Payload
+	case api.MessageTypeUserChoiceRequest:
+		event.Type = "choice-request"
+		if choiceRequest, ok := message.Payload.(*api.UserChoiceRequest); ok {
+			event.Text = choiceRequest.Prompt
+		}
+	default:
+		// Text deltas are followed by the whole text, progress is summarized at the end of the run,
+		// and the agent does not ask for input in RunOnce mode.
+		return nil
+	}
+	if err := u.enc.Encode(event); err != nil {
+		return fmt.Errorf("writing JSON output: %w", err)
+	}
+	return nil
+}
+
+// finish writes the summary of the run, and returns an *ExitError if it did not succeed.
+func (u *JSONUI) finish() error {
+	stats := u.agent.Stats()
+	lastErr := u.agent.LastErr()
+	status := runStatus(stats, lastErr)
+
+	summary := jsonSummary{
+		Type:       "summary",
+		Status:     status,
+		ExitCode:   status.ExitCode(),
+		Iterations: stats.Iterations,
+		ToolCalls:  stats.ToolCalls,
+		ToolErrors: stats.ToolErrors,
+		Usage:      stats.Usage,
+	}
+	if lastErr != nil {
+		summary.Error = lastErr.Error()
+	}
+	if err := u.enc.Encode(summary); err != nil {
+		return fmt.Errorf("writing JSON output: %w", err)
+	}
+	if status == RunStatusSuccess {
+		return nil
+	}
+	return &ExitError{Status: status, Err: lastErr}
+}
+
+// runStatus returns the status of a run of the agent, from what it did and the last error it ran into.
+func runStatus(stats agent.RunStats, lastErr error) RunStatus {
+	switch {
+	case stats.PermissionRequired:
+		return RunStatusPermissionRequired
+	case stats.MaxIterationsReached:
+		return RunStatusMaxIterations
+	case stats.ToolErrors > 0:
+		return RunStatusToolError
+	case lastErr != nil:
+		return RunStatusError
+	default:
+		return RunStatusSuccess
+	}
+}
+
+func (u *JSONUI) ClearScreen() {
+	// Not applicable for JSON output.
+}
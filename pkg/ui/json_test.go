@@ -0,0 +1,222 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"go.uber.org/mock/gomock"
+)
+
+type fakePart struct {
+	text  string
+	calls []gollm.FunctionCall
+}
+
+func (p fakePart) AsText() (string, bool) { return p.text, p.text != "" }
+
+func (p fakePart) AsFunctionCalls() ([]gollm.FunctionCall, bool) { return p.calls, p.calls != nil }
+
+type fakeCandidate []gollm.Part
+
+func (c fakeCandidate) String() string      { return "" }
+func (c fakeCandidate) Parts() []gollm.Part { return c }
+
+type fakeChatResponse struct{ candidate gollm.Candidate }
+
+func (r fakeChatResponse) UsageMetadata() any            { return nil }
+func (r fakeChatResponse) Candidates() []gollm.Candidate { return []gollm.Candidate{r.candidate} }
+
+// llmResponse returns a streamed LLM response made of the given parts.
+func llmResponse(parts ...gollm.Part) gollm.ChatResponseIterator {
+	return func(yield func(gollm.ChatResponse, error) bool) {
+		yield(fakeChatResponse{candidate: fakeCandidate(parts)}, nil)
+	}
+}
+
+// llmStreamError returns a streamed LLM response that fails.
+func llmStreamError(err error) gollm.ChatResponseIterator {
+	return func(yield func(gollm.ChatResponse, error) bool) {
+		yield(nil, err)
+	}
+}
+
+func TestJSONUI(t *testing.T) {
+	toolCall := llmResponse(fakePart{calls: []gollm.FunctionCall{{ID: "1", Name: "mocktool", Arguments: map[string]any{"command": "kubectl get pods"}}}})
+	answer := llmResponse(fakePart{text: "All pods are running."})
+
+	tests := []struct {
+		name string
+		// responses are the responses of the LLM, or the errors of the requests.
+		responses []any
+		// toolResult is what the tool returns, if the LLM calls it.
+		toolResult *tools.ExecResult
+		// wantTypes are the types of the records written, in order.
+		wantTypes  []string
+		wantStatus RunStatus
+		wantCode   int
+	}{
+		{
+			name:       "tool call and answer",
+			responses:  []any{toolCall, answer},
+			toolResult: &tools.ExecResult{Command: "kubectl get pods", Stdout: "web-0 Running"},
+			wantTypes:  []string{"text", "tool-call", "tool-result", "text", "summary"},
+			wantStatus: RunStatusSuccess,
+			wantCode:   0,
+		},
+		{
+			name:       "failed tool call",
+			responses:  []any{toolCall, answer},
+			toolResult: &tools.ExecResult{Command: "kubectl get pods", Stderr: "kubectl: not found", ExitCode: 127},
+			wantTypes:  []string{"text", "tool-call", "tool-result", "text", "summary"},
+			wantStatus: RunStatusToolError,
+			wantCode:   2,
+		},
+		{
+			name:       "llm stream error",
+			responses:  []any{llmStreamError(errors.New("quota exceeded"))},
+			wantTypes:  []string{"text", "error", "summary"},
+			wantStatus: RunStatusError,
+			wantCode:   1,
+		},
+		{
+			// The error is only reported in the summary.
+			name:       "llm request error",
+			responses:  []any{errors.New("quota exceeded")},
+			wantTypes:  []string{"text", "summary"},
+			wantStatus: RunStatusError,
+			wantCode:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			client := mocks.NewMockClient(ctrl)
+			chat := mocks.NewMockChat(ctrl)
+			client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+			chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+			chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+			var calls []any
+			for _, response := range tt.responses {
+				call := chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any())
+				if err, ok := response.(error); ok {
+					call.Return(nil, err)
+				} else {
+					call.Return(response.(gollm.ChatResponseIterator), nil)
+				}
+				calls = append(calls, call)
+			}
+			gomock.InOrder(calls...)
+
+			tool := mocks.NewMockTool(ctrl)
+			tool.EXPECT().Name().Return("mocktool").AnyTimes()
+			tool.EXPECT().Description().Return("mock tool").AnyTimes()
+			tool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "mocktool"}).AnyTimes()
+			tool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+			tool.EXPECT().CheckModifiesResource(gomock.Any()).Return("no").AnyTimes()
+			if tt.toolResult != nil {
+				tool.EXPECT().Run(gomock.Any(), gomock.Any()).Return(tt.toolResult, nil)
+			}
+			var toolset tools.Tools
+			toolset.Init()
+			toolset.RegisterTool(tool)
+
+			query := "are the pods running?"
+			a := &agent.Agent{
+				ChatMessageStore: sessions.NewInMemoryChatStore(),
+				LLM:              client,
+				Model:            "test-model",
+				Tools:            toolset,
+				MaxIterations:    4,
+				RunOnce:          true,
+				InitialQuery:     query,
+			}
+			if err := a.Init(ctx); err != nil {
+				t.Fatalf("init: %v", err)
+			}
+			defer a.Close()
+			if err := a.Run(ctx, query); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			var out bytes.Buffer
+			err := NewJSONUI(a, &out).Run(ctx)
+
+			var exitErr *ExitError
+			switch {
+			case tt.wantCode == 0 && err != nil:
+				t.Errorf("Run() error = %v, want nil", err)
+			case tt.wantCode != 0 && (!errors.As(err, &exitErr) || exitErr.ExitCode() != tt.wantCode):
+				t.Errorf("Run() error = %v, want an *ExitError with exit code %d", err, tt.wantCode)
+			}
+
+			var records []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("output line %q is not JSON: %v", line, err)
+				}
+				records = append(records, record)
+			}
+			var types []string
+			for _, record := range records {
+				types = append(types, record["type"].(string))
+			}
+			if strings.Join(types, ",") != strings.Join(tt.wantTypes, ",") {
+				t.Fatalf("record types = %v, want %v\n%s", types, tt.wantTypes, out.String())
+			}
+
+			for _, record := range records {
+				switch record["type"] {
+				case "tool-call", "tool-result":
+					call, _ := record["toolCall"].(map[string]any)
+					args, _ := call["arguments"].(map[string]any)
+					if call["name"] != "mocktool" || args["command"] != "kubectl get pods" {
+						t.Errorf("%s record has tool call %v, want the call of mocktool", record["type"], record["toolCall"])
+					}
+				case "text":
+					if record["source"] == "user" && record["text"] != query || record["source"] == "model" && record["text"] != "All pods are running." {
+						t.Errorf("text record = %v, want the query or the answer of the model", record)
+					}
+				case "error":
+					if !strings.Contains(record["text"].(string), "quota exceeded") {
+						t.Errorf("error record = %v, want the LLM error", record)
+					}
+				}
+			}
+			summary := records[len(records)-1]
+			if summary["status"] != string(tt.wantStatus) || summary["exitCode"] != float64(tt.wantCode) {
+				t.Errorf("summary = %v, want status %s and exit code %d", summary, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantStatus == RunStatusError && summary["error"] != "quota exceeded" {
+				t.Errorf("summary error = %v, want the LLM error", summary["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/charmbracelet/x/ansi"
+	"golang.org/x/term"
+)
+
+// statusFrames are the frames of the spinner of the status line.
+var statusFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// statusInterval is how often the status line is redrawn.
+const statusInterval = 100 * time.Millisecond
+
+// statusLine shows what the agent is doing on the last line of the terminal while it works on a query,
+// from its progress messages: the iteration, the tool call running and for how long, and the tokens used.
+// The line is erased before anything else is printed.
+type statusLine struct {
+	agent *agent.Agent
+	out   io.Writer
+
+	mu sync.Mutex
+	// progress is what the agent is doing, since the time of the message. It is nil when the agent
+	// does not work on a query, or while it streams text or waits for input.
+	progress *api.Progress
+	since    time.Time
+	frame    int
+	// shown is set while the line is on the terminal, and paused while a message is printed.
+	shown  bool
+	paused bool
+}
+
+func newStatusLine(agent *agent.Agent, out io.Writer) *statusLine {
+	return &statusLine{agent: agent, out: out}
+}
+
+// run redraws the line until ctx is done.
+func (s *statusLine) run(ctx context.Context) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.erase()
+			return
+		case <-ticker.C:
+			// The agent may wait for its output to be read, with its state locked: it is read
+			// before locking the line, which its output goes through.
+			state, usage := s.agent.AgentState(), s.agent.Stats().Usage
+			s.mu.Lock()
+			s.draw(state, usage)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// update erases the line before msg is shown, and follows what the agent does from it.
+// The line is not drawn again until resume is called, once msg is shown.
+func (s *statusLine) update(msg *api.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eraseLocked()
+	s.paused = true
+
+	switch msg.Type {
+	case api.MessageTypeProgress:
+		if progress, ok := msg.Payload.(*api.Progress); ok && progress.State == api.AgentStateRunning {
+			s.progress, s.since = progress, msg.Timestamp
+		}
+	case api.MessageTypeToolCallResponse:
+		// The tool call is done, the agent goes on with the next one or asks the LLM.
+		if s.progress != nil {
+			progress := *s.progress
+			progress.Tool = ""
+			s.progress, s.since = &progress, msg.Timestamp
+		}
+	case api.MessageTypeTextDelta, api.MessageTypeUserInputRequest, api.MessageTypeUserChoiceRequest:
+		// The line would get in the way of the streamed text and of the input.
+		s.progress = nil
+	}
+}
+
+// resume lets the line be drawn again.
+func (s *statusLine) resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+// erase removes the line from the terminal.
+func (s *statusLine) erase() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eraseLocked()
+}
+
+func (s *statusLine) eraseLocked() {
+	if s.shown {
+		fmt.Fprint(s.out, "\r\033[K")
+		s.shown = false
+	}
+}
+
+// draw shows the line, if the agent works on a query. The caller holds the lock.
+func (s *statusLine) draw(state api.AgentState, usage []any) {
+	if s.progress == nil || s.paused {
+		return
+	}
+	if state != api.AgentStateRunning {
+		// The agent is done, and asks for input next.
+		s.eraseLocked()
+		return
+	}
+	s.frame = (s.frame + 1) % len(statusFrames)
+
+	parts := []string{statusFrames[s.frame]}
+	if s.progress.MaxIterations > 0 {
+		parts = append(parts, fmt.Sprintf("step %d/%d", s.progress.Iteration, s.progress.MaxIterations))
+	}
+	elapsed := time.Since(s.since).Truncate(time.Second)
+	if tool := strings.TrimSpace(s.progress.Tool); tool != "" {
+		tool, _, _ = strings.Cut(tool, "\n")
+		parts = append(parts, fmt.Sprintf("running %s (%s)", tool, elapsed))
+	} else {
+		parts = append(parts, fmt.Sprintf("thinking (%s)", elapsed))
+	}
+	if tokens, ok := totalTokens(usage); ok {
+		parts = append(parts, formatTokens(tokens)+" tokens")
+	}
+	line := strings.Join(parts, " · ")
+
+	// The line must not wrap, or erasing it would leave its first rows behind.
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 1 {
+		line = ansi.Truncate(line, width-1, "…")
+	}
+	fmt.Fprintf(s.out, "\r\033[K\033[2m%s\033[0m", line)
+	s.shown = true
+}
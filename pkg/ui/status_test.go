@@ -0,0 +1,160 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+)
+
+func progressMessage(iteration int, tool string) *api.Message {
+	return &api.Message{
+		Type:      api.MessageTypeProgress,
+		Timestamp: time.Now(),
+		Payload:   &api.Progress{State: api.AgentStateRunning, Iteration: iteration, MaxIterations: 4, Tool: tool},
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	tests := []struct {
+		name string
+		// messages are shown one after the other before the line is drawn.
+		messages []*api.Message
+		state    api.AgentState
+		usage    []any
+		// want are the parts of the line drawn, or nil if it is not drawn.
+		want []string
+	}{
+		{
+			name:     "thinking",
+			messages: []*api.Message{progressMessage(1, "")},
+			state:    api.AgentStateRunning,
+			want:     []string{"step 1/4", "thinking (0s)"},
+		},
+		{
+			name:     "running a tool",
+			messages: []*api.Message{progressMessage(2, "kubectl get pods\n-n default")},
+			state:    api.AgentStateRunning,
+			usage:    []any{map[string]any{"totalTokenCount": 1200}},
+			want:     []string{"step 2/4", "running kubectl get pods (0s)", "1.2k tokens"},
+		},
+		{
+			name: "tool call done",
+			messages: []*api.Message{
+				progressMessage(2, "kubectl get pods"),
+				{Type: api.MessageTypeToolCallResponse, Timestamp: time.Now()},
+			},
+			state: api.AgentStateRunning,
+			want:  []string{"step 2/4", "thinking (0s)"},
+		},
+		{
+			name: "streaming text",
+			messages: []*api.Message{
+				progressMessage(1, ""),
+				{Type: api.MessageTypeTextDelta, Timestamp: time.Now()},
+			},
+			state: api.AgentStateRunning,
+		},
+		{
+			name: "asking for a choice",
+			messages: []*api.Message{
+				progressMessage(1, "kubectl delete pod web-0"),
+				{Type: api.MessageTypeUserChoiceRequest, Timestamp: time.Now()},
+			},
+			state: api.AgentStateWaitingForInput,
+		},
+		{
+			name:     "agent done",
+			messages: []*api.Message{progressMessage(3, "")},
+			state:    api.AgentStateDone,
+		},
+		{
+			name: "not working on a query",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := newStatusLine(&agent.Agent{}, &out)
+			for _, msg := range tt.messages {
+				s.update(msg)
+				s.resume()
+			}
+			s.draw(tt.state, tt.usage)
+
+			got := out.String()
+			if tt.want == nil {
+				if got != "" {
+					t.Errorf("draw() wrote %q, want nothing", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, "\r\033[K\033[2m") || !strings.HasSuffix(got, "\033[0m") {
+				t.Fatalf("draw() wrote %q, want a dimmed line that replaces the current one", got)
+			}
+			for _, part := range tt.want {
+				if !strings.Contains(got, part) {
+					t.Errorf("draw() wrote %q, want it to contain %q", got, part)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusLineErasedBeforeMessages(t *testing.T) {
+	var out bytes.Buffer
+	s := newStatusLine(&agent.Agent{}, &out)
+	s.update(progressMessage(1, "kubectl get pods"))
+	s.resume()
+	s.draw(api.AgentStateRunning, nil)
+	if !s.shown {
+		t.Fatalf("the line is not shown after draw()")
+	}
+
+	// The line is erased before the response is shown, and not drawn until it is.
+	out.Reset()
+	s.update(&api.Message{Type: api.MessageTypeToolCallResponse, Timestamp: time.Now()})
+	s.draw(api.AgentStateRunning, nil)
+	if got := out.String(); got != "\r\033[K" {
+		t.Errorf("update() and draw() while paused wrote %q, want the line erased", got)
+	}
+
+	out.Reset()
+	s.resume()
+	s.draw(api.AgentStateRunning, nil)
+	if got := out.String(); !strings.Contains(got, "thinking") {
+		t.Errorf("draw() after resume() wrote %q, want the line drawn again", got)
+	}
+
+	// Once the agent is done, the line is erased and not drawn again.
+	out.Reset()
+	s.draw(api.AgentStateDone, nil)
+	s.draw(api.AgentStateDone, nil)
+	if got := out.String(); got != "\r\033[K" {
+		t.Errorf("draw() once done wrote %q, want the line erased once", got)
+	}
+
+	// Erasing a line that is not shown writes nothing.
+	out.Reset()
+	s.erase()
+	if got := out.String(); got != "" {
+		t.Errorf("erase() of a hidden line wrote %q, want nothing", got)
+	}
+}
@@ -16,11 +16,13 @@ package ui
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -31,6 +33,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/chzyer/readline"
 	"golang.org/x/term"
 	"k8s.io/klog/v2"
@@ -80,6 +83,14 @@ type TerminalUI struct {
 	// showToolOutput disables truncation of tool output.
 	showToolOutput bool
 
+	// liveText is set when stdout is a terminal, which shows the model's text as it streams.
+	liveText bool
+	// streamID is the ID of the message the model is streaming, and streamedText the text shown so far.
+	streamID     string
+	streamedText string
+	// status shows what the agent does while it works, when stdout is a terminal and the agent is interactive.
+	status *statusLine
+
 	agent *agent.Agent
 }
 
@@ -135,6 +146,10 @@ func NewTerminalUI(agent *agent.Agent, useTTYForInput bool, showToolOutput bool,
 		useTTYForInput:   useTTYForInput, // Store this flag
 		agent:            agent,
 		showToolOutput:   showToolOutput,
+		liveText:         term.IsTerminal(int(os.Stdout.Fd())),
+	}
+	if u.liveText && !agent.RunOnce {
+		u.status = newStatusLine(agent, os.Stdout)
 	}
 
 	return u, nil
@@ -144,6 +159,10 @@ func (u *TerminalUI) Run(ctx context.Context) error {
 	// Channel to signal when the agent has exited
 	agentExited := make(chan struct{})
 
+	if u.status != nil {
+		go u.status.run(ctx)
+	}
+
 	// Start a goroutine to handle agent output
 	go func() {
 		for {
@@ -190,19 +209,24 @@ func (u *TerminalUI) ttyReader() (*bufio.Reader, error) {
 	return u.ttyReaderInstance, nil
 }
 
+// historyFilePath returns the path of the file keeping the queries typed in the terminal UIs.
+func historyFilePath() string {
+	return filepath.Join(os.TempDir(), "kubectl-ai-history")
+}
+
 func (u *TerminalUI) readlineInstance() (*readline.Instance, error) {
 	if u.rlInstance != nil {
 		return u.rlInstance, nil
 	}
 	// Initialize readline input
-	historyPath := filepath.Join(os.TempDir(), "kubectl-ai-history")
 	rl, err := readline.NewEx(&readline.Config{
 		Prompt:      ">>> ", // Default prompt for main input
 		Stdin:       os.Stdin,
 		Stdout:      os.Stdout,
 		Stderr:      os.Stderr,
-		HistoryFile: historyPath,
+		HistoryFile: historyFilePath(),
 		// History enabled by default
+		AutoComplete: &commandCompleter{agent: u.agent},
 	})
 	if err != nil {
 		// Log warning or fallback if readline init fails?
@@ -236,6 +260,21 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 	text := ""
 	var styleOptions []styleOption
 
+	if u.status != nil {
+		u.status.update(msg)
+		defer u.status.resume()
+	}
+	switch msg.Type {
+	case api.MessageTypeProgress:
+		return
+	case api.MessageTypeTextDelta:
+		u.showDelta(msg)
+		return
+	}
+	if !u.endStream(msg) {
+		return
+	}
+
 	switch msg.Type {
 	case api.MessageTypeText:
 		text = msg.Payload.(string)
@@ -285,7 +324,7 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 			// keep reading input until we get a non-empty query
 			for {
 				var err error
-				fmt.Print("\n>>> ") // Print prompt manually
+				fmt.Print("\n" + inputPrompt(u.agent.Session())) // Print prompt manually
 				query, err = tReader.ReadString('\n')
 				if err != nil {
 					klog.Infof("TTY read error: %v", err)
@@ -314,7 +353,7 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 			}
 			// keep reading input until we get a non-empty query
 			for {
-				rlInstance.SetPrompt(">>> ") // Ensure correct prompt
+				rlInstance.SetPrompt(inputPrompt(u.agent.Session())) // Ensure correct prompt
 				query, err = rlInstance.Readline()
 				if err != nil {
 					klog.Infof("Readline error: %v", err)
@@ -336,7 +375,7 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 				break
 			}
 		}
-		if query == "clear" || query == "reset" {
+		if isClearCommand(u.agent, query) {
 			u.ClearScreen()
 		}
 		return
@@ -405,6 +444,11 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 			if input == "n" || input == "no" {
 				input = "3"
 			}
+			if input == "e" || input == "edit" {
+				if i := editOptionIndex(choiceRequest); i >= 0 {
+					input = strconv.Itoa(i + 1)
+				}
+			}
 
 			choiceIdx, err := strconv.Atoi(input)
 			if err == nil && choiceIdx > 0 && choiceIdx <= len(choiceRequest.Options) {
@@ -414,7 +458,23 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 
 			fmt.Println("Invalid choice. Please try again.")
 		}
-		u.agent.Input <- &api.UserChoiceResponse{Choice: choice}
+		response := &api.UserChoiceResponse{Choice: choice}
+		if choice-1 == editOptionIndex(choiceRequest) {
+			edits, err := u.readEdits(choiceRequest.ToolCalls)
+			if err != nil {
+				if errors.Is(err, io.EOF) || errors.Is(err, readline.ErrInterrupt) {
+					u.agent.Input <- io.EOF
+					return
+				}
+				u.agent.Input <- err
+				return
+			}
+			response.Edits = edits
+		}
+		u.agent.Input <- response
+		return
+	case api.MessageTypeUserChoiceResponse:
+		// The choice was typed in the terminal, there is nothing more to show.
 		return
 	default:
 		klog.Warningf("unsupported message type: %v", msg.Type)
@@ -456,6 +516,128 @@ func (u *TerminalUI) handleMessage(msg *api.Message) {
 	fmt.Printf("%s%s", printText, reset)
 }
 
+// readEdits lets the user edit the tool calls awaiting approval, one line each, starting from the calls as they are.
+func (u *TerminalUI) readEdits(toolCalls []api.ToolCall) ([]string, error) {
+	var edits []string
+	for _, call := range toolCalls {
+		text := call.EditText()
+		// Arguments are edited on one line.
+		var compact bytes.Buffer
+		if strings.Contains(text, "\n") && json.Compact(&compact, []byte(text)) == nil {
+			text = compact.String()
+		}
+
+		var line string
+		if u.useTTYForInput {
+			tReader, err := u.ttyReader()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("Edit %s (press enter to keep it):\n  %s\n> ", call.Name, text)
+			if line, err = tReader.ReadString('\n'); err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(line) == "" {
+				line = text
+			}
+		} else {
+			rlInstance, err := u.readlineInstance()
+			if err != nil {
+				return nil, fmt.Errorf("error creating readline instance: %w", err)
+			}
+			rlInstance.SetPrompt("Edit " + call.Name + ": ")
+			if line, err = rlInstance.ReadlineWithDefault(text); err != nil {
+				return nil, err
+			}
+		}
+		edits = append(edits, strings.TrimSpace(line))
+	}
+	return edits, nil
+}
+
+// editOptionIndex returns the index of the option of a choice request to edit the tool calls, or -1 if there is none.
+func editOptionIndex(request *api.UserChoiceRequest) int {
+	for i, option := range request.Options {
+		if option.Value == api.UserChoiceEdit && len(request.ToolCalls) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// showDelta prints a piece of the text the model is streaming, as is.
+// The whole text replaces it, rendered as markdown, once the model is done.
+func (u *TerminalUI) showDelta(msg *api.Message) {
+	if !u.liveText {
+		return
+	}
+	text, _ := msg.Payload.(string)
+	if msg.ID != u.streamID {
+		u.streamID = msg.ID
+		u.streamedText = ""
+	}
+	u.streamedText += text
+	fmt.Print(text)
+}
+
+// endStream finishes showing the streamed text before msg is shown. It erases the streamed text
+// if msg is the streamed message, for it to be rendered as markdown. If the text scrolled out of
+// view, it cannot be erased, so it returns false for msg not to be shown again.
+func (u *TerminalUI) endStream(msg *api.Message) bool {
+	if u.streamID == "" {
+		return true
+	}
+	streamID, streamedText := u.streamID, u.streamedText
+	u.streamID, u.streamedText = "", ""
+
+	if msg.ID == streamID {
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			if rows := terminalRows(streamedText, width); rows < height {
+				if rows > 1 {
+					// Move the cursor to the first line of the streamed text.
+					fmt.Printf("\033[%dF", rows-1)
+				}
+				fmt.Print("\r\033[J")
+				return true
+			}
+		}
+	}
+	fmt.Println()
+	return msg.ID != streamID
+}
+
+// terminalRows returns the number of rows text takes on a terminal of the given width.
+func terminalRows(text string, width int) int {
+	if width <= 0 {
+		return math.MaxInt
+	}
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		rows += max(1, (lipgloss.Width(line)+width-1)/width)
+	}
+	return rows
+}
+
+// kubeTargetLabel describes the Kubernetes context and namespace the agent targets, e.g. "prod/web".
+func kubeTargetLabel(session *api.Session) string {
+	switch {
+	case session.KubeContext == "":
+		return session.Namespace
+	case session.Namespace == "":
+		return session.KubeContext
+	default:
+		return session.KubeContext + "/" + session.Namespace
+	}
+}
+
+// inputPrompt returns the prompt for user input, showing the active kube target.
+func inputPrompt(session *api.Session) string {
+	if label := kubeTargetLabel(session); label != "" {
+		return "(" + label + ") >>> "
+	}
+	return ">>> "
+}
+
 func (u *TerminalUI) ClearScreen() {
 	fmt.Print("\033[H\033[2J")
 }
@@ -0,0 +1,40 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+)
+
+func TestKubeTargetLabel(t *testing.T) {
+	tests := []struct {
+		kubeContext string
+		namespace   string
+		want        string
+	}{
+		{want: ""},
+		{kubeContext: "prod", want: "prod"},
+		{kubeContext: "prod", namespace: "web", want: "prod/web"},
+		{namespace: "web", want: "web"},
+	}
+	for _, tt := range tests {
+		session := &api.Session{KubeContext: tt.kubeContext, Namespace: tt.namespace}
+		if got := kubeTargetLabel(session); got != tt.want {
+			t.Errorf("kubeTargetLabel(%q, %q) = %q, want %q", tt.kubeContext, tt.namespace, got, tt.want)
+		}
+	}
+}
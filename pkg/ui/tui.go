@@ -15,9 +15,11 @@
 package ui
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/user"
 	"strings"
@@ -25,60 +27,129 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
-	"github.com/charmbracelet/bubbles/list"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"k8s.io/klog/v2"
 )
 
-const listHeight = 5
+// maxInputHeight is the number of lines the input grows to before it scrolls.
+const maxInputHeight = 8
+
+// maxHistorySize is the number of queries kept in the input history.
+const maxHistorySize = 500
 
 var (
-	spinnerStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
-	helpStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Margin(1, 0)
-	dotStyle          = helpStyle.UnsetMargins()
-	durationStyle     = dotStyle
-	appStyle          = lipgloss.NewStyle().Margin(1, 2, 0, 2)
-	titleStyle        = lipgloss.NewStyle().MarginLeft(2)
-	listStyle         = lipgloss.NewStyle().MarginBottom(2)
-	itemStyle         = lipgloss.NewStyle().PaddingLeft(4)
-	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
-	paginationStyle   = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
-	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 2, 4)
+	senderStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+	errorStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	dimStyle            = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	successStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	spinnerStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+	paneHeaderStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	selectedPaneStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Bold(true)
+	paneBodyStyle       = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("241")).PaddingLeft(1).MarginLeft(2)
+	choiceTitleStyle    = lipgloss.NewStyle().Bold(true)
+	choiceStyle         = lipgloss.NewStyle().PaddingLeft(4)
+	selectedChoiceStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
+	statusBarStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Background(lipgloss.Color("236"))
+	noticeStyle         = statusBarStyle.Foreground(lipgloss.Color("214"))
+	currentMatchStyle   = lipgloss.NewStyle().Reverse(true)
+	otherMatchStyle     = lipgloss.NewStyle().Underline(true)
 )
 
-type item string
+// tuiKeyMap are the key bindings of the TUI.
+type tuiKeyMap struct {
+	Send        key.Binding
+	Newline     key.Binding
+	HistoryPrev key.Binding
+	HistoryNext key.Binding
+	Complete    key.Binding
 
-func (i item) FilterValue() string { return "" }
+	PageUp   key.Binding
+	PageDown key.Binding
+	LineUp   key.Binding
+	LineDown key.Binding
+	Search   key.Binding
 
-type itemDelegate struct{}
+	PrevPane       key.Binding
+	NextPane       key.Binding
+	TogglePane     key.Binding
+	ToggleAllPanes key.Binding
 
-func (d itemDelegate) Height() int                             { return 1 }
-func (d itemDelegate) Spacing() int                            { return 0 }
-func (d itemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
-func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	i, ok := listItem.(item)
-	if !ok {
-		return
-	}
+	ChoiceUp   key.Binding
+	ChoiceDown key.Binding
 
-	str := fmt.Sprintf("%d. %s", index+1, i)
+	Interrupt key.Binding
+	Quit      key.Binding
+	Help      key.Binding
+	Close     key.Binding
+}
 
-	fn := itemStyle.Render
-	if index == m.Index() {
-		fn = func(s ...string) string {
-			return selectedItemStyle.Render("> " + strings.Join(s, " "))
-		}
+var defaultTUIKeyMap = tuiKeyMap{
+	Send:        key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "send")),
+	Newline:     key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"), key.WithHelp("alt+enter", "new line")),
+	HistoryPrev: key.NewBinding(key.WithKeys("up"), key.WithHelp("↑", "previous query")),
+	HistoryNext: key.NewBinding(key.WithKeys("down"), key.WithHelp("↓", "next query")),
+	Complete:    key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete /command")),
+
+	PageUp:   key.NewBinding(key.WithKeys("pgup"), key.WithHelp("pgup", "scroll up")),
+	PageDown: key.NewBinding(key.WithKeys("pgdown"), key.WithHelp("pgdown", "scroll down")),
+	LineUp:   key.NewBinding(key.WithKeys("shift+up"), key.WithHelp("shift+↑", "scroll a line up")),
+	LineDown: key.NewBinding(key.WithKeys("shift+down"), key.WithHelp("shift+↓", "scroll a line down")),
+	Search:   key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "search")),
+
+	PrevPane:       key.NewBinding(key.WithKeys("alt+up"), key.WithHelp("alt+↑", "previous tool call")),
+	NextPane:       key.NewBinding(key.WithKeys("alt+down"), key.WithHelp("alt+↓", "next tool call")),
+	TogglePane:     key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "show/hide tool output")),
+	ToggleAllPanes: key.NewBinding(key.WithKeys("alt+o"), key.WithHelp("alt+o", "show/hide all tool output")),
+
+	ChoiceUp:   key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑", "previous option")),
+	ChoiceDown: key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓", "next option")),
+
+	Interrupt: key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "clear input")),
+	Quit:      key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "quit")),
+	Help:      key.NewBinding(key.WithKeys("f1"), key.WithHelp("f1", "help")),
+	Close:     key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+}
+
+func (k tuiKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Send, k.Search, k.TogglePane, k.Help}
+}
+
+func (k tuiKeyMap) FullHelp() [][]key.Binding {
+	var shortcuts []key.Binding
+	for _, shortcut := range metaQueryShortcuts {
+		shortcuts = append(shortcuts, shortcut.binding)
 	}
+	return [][]key.Binding{
+		{k.Send, k.Newline, k.HistoryPrev, k.HistoryNext, k.Complete, k.Interrupt, k.Quit, k.Help},
+		{k.PageUp, k.PageDown, k.LineUp, k.LineDown, k.Search, k.PrevPane, k.NextPane, k.TogglePane, k.ToggleAllPanes},
+		shortcuts,
+	}
+}
 
-	fmt.Fprint(w, fn(str))
+// metaQueryShortcut is a key binding that sends a command to the agent, as if it was typed.
+type metaQueryShortcut struct {
+	binding key.Binding
+	query   string
 }
 
-const gap = "\n\n"
+var metaQueryShortcuts = []metaQueryShortcut{
+	{key.NewBinding(key.WithKeys("ctrl+l"), key.WithHelp("ctrl+l", "clear the conversation")), "/clear"},
+	{key.NewBinding(key.WithKeys("alt+m"), key.WithHelp("alt+m", "list models")), "/models"},
+	{key.NewBinding(key.WithKeys("alt+t"), key.WithHelp("alt+t", "list tools")), "/tools"},
+	{key.NewBinding(key.WithKeys("alt+k"), key.WithHelp("alt+k", "list kube contexts")), "/context"},
+	{key.NewBinding(key.WithKeys("alt+n"), key.WithHelp("alt+n", "show namespace")), "/namespace"},
+	{key.NewBinding(key.WithKeys("alt+s"), key.WithHelp("alt+s", "list sessions")), "/session list"},
+}
 
 // getCurrentUsername returns the current user's username, caching it to avoid repeated calls
 func getCurrentUsername() string {
@@ -106,8 +177,12 @@ func NewTUI(agent *agent.Agent) *TUI {
 	}
 }
 
+// agentExitedMsg tells the model that the agent exited, and sent all its messages.
+type agentExitedMsg struct{}
+
 func (u *TUI) Run(ctx context.Context) error {
 	go func() {
+		defer u.program.Send(agentExitedMsg{})
 		for {
 			select {
 			case <-ctx.Done():
@@ -117,6 +192,18 @@ func (u *TUI) Run(ctx context.Context) error {
 					return
 				}
 				u.program.Send(msg)
+			case <-u.agent.Done():
+				for {
+					select {
+					case msg, ok := <-u.agent.Output:
+						if !ok {
+							return
+						}
+						u.program.Send(msg)
+					default:
+						return
+					}
+				}
 			}
 		}
 	}()
@@ -128,256 +215,890 @@ func (u *TUI) Run(ctx context.Context) error {
 func (u *TUI) ClearScreen() {
 }
 
-type resultMsg struct {
-	duration time.Duration
-	food     string
-}
+type model struct {
+	agent *agent.Agent
+	keys  tuiKeyMap
 
-func (r resultMsg) String() string {
-	if r.duration == 0 {
-		return dotStyle.Render(strings.Repeat(".", 30))
-	}
-	return fmt.Sprintf("🍔 Ate %s %s", r.food,
-		durationStyle.Render(r.duration.String()))
-}
+	viewport viewport.Model
+	textarea textarea.Model
+	search   textinput.Model
+	spinner  spinner.Model
+	help     help.Model
 
-type (
-	errMsg error
-)
+	width, height int
+	username      string
 
-type model struct {
-	viewport    viewport.Model
-	textarea    textarea.Model
-	senderStyle lipgloss.Style
-	err         error
+	// markdownStyle is the glamour style for the terminal's background, detected before the program starts.
+	markdownStyle string
+	renderer      *glamour.TermRenderer
+	// rendered caches the rendered text messages by ID, for the current width.
+	rendered map[string]string
 
-	agent    *agent.Agent
-	spinner  spinner.Model
-	results  []resultMsg
 	messages []*api.Message
-	quitting bool
+	// streamID is the ID of the message the model is streaming, and streamedText its text so far.
+	streamID     string
+	streamedText string
+	// progress is the last progress message of the agent, for the status bar to show the tool call running.
+	progress *api.Message
 
-	list     list.Model
-	choice   string
-	username string // cached username
+	// panes are the IDs of the tool call request messages, each shown in a pane with the result
+	// of the call, and paneLines the line of their header in the scrollback.
+	panes     []string
+	paneLines map[string]int
+	// expanded has the panes toggled by the user, other panes are expanded if expandAll is set.
+	expanded     map[string]bool
+	expandAll    bool
+	selectedPane int
+
+	// choiceID is the ID of the choice request shown, and choiceIndex the option selected.
+	// answeredChoiceID is the last choice request answered, while the agent processes the answer.
+	choiceID         string
+	choiceIndex      int
+	answeredChoiceID string
+	// editing is set while the user edits the tool calls of the choice request, in the input.
+	// edits holds the calls edited so far, and editDraft the query that was in the input.
+	editing   bool
+	edits     []string
+	editDraft string
+
+	// history holds the queries sent, and historyIndex the one shown in the input, or len(history)
+	// for draft, the query being written.
+	history      []string
+	historyIndex int
+	draft        string
+
+	// searching is set when the search bar is shown. matches are the lines of the scrollback
+	// matching the search, and matchIndex the one shown.
+	searching  bool
+	matches    []int
+	matchIndex int
+
+	showHelp bool
+	// notice is a hint shown in the status bar until the next key press.
+	notice string
 }
 
 func newModel(agent *agent.Agent) model {
 	ta := textarea.New()
-	ta.Placeholder = "Send a message..."
+	ta.Placeholder = "Ask anything, or press F1 for help"
 	ta.Focus()
-
 	ta.Prompt = "┃ "
-	ta.CharLimit = 280
-
-	ta.SetWidth(30)
-	ta.SetHeight(5)
-
+	ta.CharLimit = 0
+	ta.ShowLineNumbers = false
+	ta.SetHeight(1)
 	// Remove cursor line styling
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.KeyMap.InsertNewline = defaultTUIKeyMap.Newline
+	// ctrl+f searches the scrollback.
+	ta.KeyMap.CharacterForward.SetKeys("right")
 
-	ta.ShowLineNumbers = false
-
-	items := []list.Item{
-		item("Yes"),
-		item("Yes, and don't ask me again"),
-		item("No"),
-	}
-
-	const defaultWidth = 30
-
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
-	l.Title = "Do you want to proceed ?"
-	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
-	l.SetShowHelp(false)
-	l.SetShowPagination(false)
-	l.Styles.Title = titleStyle
+	search := textinput.New()
+	search.Prompt = "search: "
 
-	vp := viewport.New(30, 5)
-	vp.SetContent(`Welcome to the chat room!
-Type a message and press Enter to send.`)
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = spinnerStyle
 
-	ta.KeyMap.InsertNewline.SetEnabled(false)
+	markdownStyle := "light"
+	if lipgloss.HasDarkBackground() {
+		markdownStyle = "dark"
+	}
 
+	history := loadHistory()
 	return model{
-		agent:    agent,
-		textarea: ta,
-		viewport: vp,
-		list:     l,
-		// a lipgloss style for the sender
-		senderStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
-		username:    getCurrentUsername(),
-		err:         nil,
+		agent:         agent,
+		keys:          defaultTUIKeyMap,
+		viewport:      viewport.New(0, 0),
+		textarea:      ta,
+		search:        search,
+		spinner:       sp,
+		help:          help.New(),
+		username:      getCurrentUsername(),
+		markdownStyle: markdownStyle,
+		rendered:      make(map[string]string),
+		paneLines:     make(map[string]int),
+		expanded:      make(map[string]bool),
+		selectedPane:  -1,
+		history:       history,
+		historyIndex:  len(history),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return textarea.Blink
+	return tea.Batch(textarea.Blink, m.spinner.Tick)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-
-	var (
-		tiCmd   tea.Cmd
-		vpCmd   tea.Cmd
-		listCmd tea.Cmd
-	)
-
-	m.textarea, tiCmd = m.textarea.Update(msg)
-	m.viewport, vpCmd = m.viewport.Update(msg)
-	m.list, listCmd = m.list.Update(msg)
-
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.viewport.Width = msg.Width
-		m.textarea.SetWidth(msg.Width)
-		if m.agent.Session().AgentState == api.AgentStateWaitingForInput {
-			m.list.SetWidth(msg.Width)
-			// m.viewport.Height = msg.Height - m.list.Height() - lipgloss.Height(gap)
-			// TODO: keeping the height of the viewport the same as the height of the textarea for now to avoid jerky UI
-			m.viewport.Height = msg.Height - m.textarea.Height() - lipgloss.Height(gap)
-		} else {
-			m.viewport.Height = msg.Height - m.textarea.Height() - lipgloss.Height(gap)
-		}
-		if len(m.renderedMessages()) > 0 {
-			// Wrap content before setting it.
-			m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(strings.Join(m.renderedMessages(), "\n")))
+		if msg.Width != m.width {
+			m.width = msg.Width
+			m.setRenderer()
 		}
+		m.height = msg.Height
+		m.help.Width = msg.Width
+		m.layout()
+		m.refresh()
 		m.viewport.GotoBottom()
-	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc, tea.KeyCtrlD:
-			return m, tea.Quit
-		case tea.KeyEnter:
-			if m.agent.Session().AgentState == api.AgentStateWaitingForInput {
-				i, ok := m.list.SelectedItem().(item)
-				if ok {
-					m.choice = string(i)
-					choiceIndex := m.list.Index()
-					m.agent.Input <- &api.UserChoiceResponse{Choice: choiceIndex + 1}
-				}
-				return m, nil
+		return m, nil
+
+	case *api.Message:
+		if msg.Type == api.MessageTypeProgress {
+			m.progress = msg
+			return m, nil
+		}
+		if msg.Type == api.MessageTypeToolCallResponse {
+			m.progress = nil
+		}
+		if msg.Type == api.MessageTypeTextDelta {
+			if msg.ID != m.streamID {
+				m.streamID, m.streamedText = msg.ID, ""
 			}
+			text, _ := msg.Payload.(string)
+			m.streamedText += text
+		} else {
+			// The streamed message is complete, or the stream failed.
+			m.streamID, m.streamedText = "", ""
+		}
+		m.refresh()
+		m.layout()
+		return m, nil
 
-			m.messages = append(m.messages, &api.Message{
-				Source:  api.MessageSourceUser,
-				Type:    api.MessageTypeText,
-				Payload: m.textarea.Value(),
-			})
-			m.viewport.SetContent(strings.Join(m.renderedMessages(), "\n"))
-			m.agent.Input <- &api.UserInputResponse{Query: m.textarea.Value()}
-			m.textarea.Reset()
-			m.viewport.GotoBottom()
+	case agentExitedMsg:
+		return m, tea.Quit
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	// e.g. cursor blinks
+	var taCmd, searchCmd tea.Cmd
+	m.textarea, taCmd = m.textarea.Update(msg)
+	m.search, searchCmd = m.search.Update(msg)
+	return m, tea.Batch(taCmd, searchCmd)
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.notice = ""
+
+	if m.showHelp {
+		if key.Matches(msg, m.keys.Help, m.keys.Close) {
+			m.showHelp = false
+			m.layout()
 		}
-	case *api.Message:
-		m.messages = m.agent.Session().AllMessages()
-		m.viewport.SetContent(strings.Join(m.renderedMessages(), "\n"))
-		m.viewport.GotoBottom()
+		return m, nil
+	}
+	if m.searching {
+		return m.handleSearchKey(msg)
+	}
 
-	// We handle errors just like any other message
-	case errMsg:
-		m.err = msg
+	switch {
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+		m.layout()
+		return m, nil
+	case key.Matches(msg, m.keys.PageUp):
+		m.viewport.PageUp()
+		return m, nil
+	case key.Matches(msg, m.keys.PageDown):
+		m.viewport.PageDown()
+		return m, nil
+	case key.Matches(msg, m.keys.LineUp):
+		m.viewport.ScrollUp(1)
+		return m, nil
+	case key.Matches(msg, m.keys.LineDown):
+		m.viewport.ScrollDown(1)
+		return m, nil
+	case key.Matches(msg, m.keys.PrevPane):
+		m.selectPane(-1)
+		return m, nil
+	case key.Matches(msg, m.keys.NextPane):
+		m.selectPane(1)
+		return m, nil
+	case key.Matches(msg, m.keys.TogglePane):
+		m.togglePane()
+		return m, nil
+	case key.Matches(msg, m.keys.ToggleAllPanes):
+		m.expandAll = !m.expandAll
+		m.expanded = make(map[string]bool)
+		m.refresh()
 		return m, nil
+	case key.Matches(msg, m.keys.Search):
+		m.searching = true
+		m.search.SetValue("")
+		cmd := m.search.Focus()
+		m.layout()
+		return m, cmd
 	}
 
-	return m, tea.Batch(tiCmd, vpCmd, listCmd)
+	if choiceRequest := m.pendingChoice(); choiceRequest != nil {
+		if m.editing {
+			return m.handleEditKey(msg, choiceRequest)
+		}
+		return m.handleChoiceKey(msg, choiceRequest)
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Interrupt):
+		if m.textarea.Value() == "" {
+			m.notice = "Press Ctrl+D to quit"
+			return m, nil
+		}
+		m.textarea.Reset()
+		m.historyIndex = len(m.history)
+		m.layout()
+		return m, nil
+	case key.Matches(msg, m.keys.Quit) && m.textarea.Value() == "":
+		select {
+		case m.agent.Input <- io.EOF:
+		default:
+		}
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Send):
+		return m.sendQuery(m.textarea.Value(), true)
+	case key.Matches(msg, m.keys.HistoryPrev) && m.textarea.Line() == 0:
+		m.recallHistory(-1)
+		return m, nil
+	case key.Matches(msg, m.keys.HistoryNext) && m.textarea.Line() == m.textarea.LineCount()-1:
+		m.recallHistory(1)
+		return m, nil
+	case key.Matches(msg, m.keys.Complete) && m.textarea.LineCount() == 1:
+		input, completions := completeInput(m.agent, m.textarea.Value())
+		m.textarea.SetValue(input)
+		if len(completions) > 0 {
+			m.notice = strings.Join(completions, "  ")
+		}
+		return m, nil
+	}
+	for _, shortcut := range metaQueryShortcuts {
+		if key.Matches(msg, shortcut.binding) {
+			return m.sendQuery(shortcut.query, false)
+		}
+	}
 
+	// The input has room for a new line, as it does not scroll back when it grows.
+	m.textarea.SetHeight(min(m.textarea.LineCount()+1, maxInputHeight))
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	m.layout()
+	return m, cmd
 }
 
-func (m model) renderedMessages() []string {
-	allMessages := m.agent.Session().AllMessages()
+// sendQuery sends a query to the agent. typed is set for the query of the input, which is added to the history.
+func (m model) sendQuery(query string, typed bool) (tea.Model, tea.Cmd) {
+	if strings.TrimSpace(query) == "" {
+		return m, nil
+	}
+	if state := m.agent.AgentState(); state != api.AgentStateIdle && state != api.AgentStateDone {
+		m.notice = "The agent is busy, wait for it to finish"
+		return m, nil
+	}
+	if typed {
+		m.addHistory(query)
+		m.textarea.Reset()
+		m.layout()
+	}
+	m.viewport.GotoBottom()
+	m.agent.Input <- &api.UserInputResponse{Query: query}
+	return m, nil
+}
 
-	var messages []string
-	for _, message := range allMessages {
-		if message.Type == api.MessageTypeUserInputRequest && message.Payload == ">>>" {
-			continue
-		}
-		messages = append(messages, m.renderMessage(message))
+func (m model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		m.refresh()
+		m.layout()
+		return m, nil
+	case "enter", "up", "ctrl+p":
+		m.moveMatch(-1)
+		return m, nil
+	case "down", "ctrl+n":
+		m.moveMatch(1)
+		return m, nil
+	}
+
+	previous := m.search.Value()
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if m.search.Value() != previous {
+		// Start from the most recent match.
+		m.matchIndex = math.MaxInt
+		m.refresh()
+		m.scrollToMatch()
 	}
-	return messages
+	return m, cmd
 }
 
-func (m model) View() string {
-	if m.quitting {
-		return quitTextStyle.Render("Not safe to quit yet.")
+// moveMatch shows the previous (older) or next match of the search, wrapping around.
+func (m *model) moveMatch(delta int) {
+	if len(m.matches) == 0 {
+		return
 	}
-	mainView := fmt.Sprintf(
-		"%s%s",
-		m.viewport.View(),
-		gap,
-	)
-	if m.agent.Session().AgentState == api.AgentStateWaitingForInput {
-		var choiceRequest *api.UserChoiceRequest
-		if len(m.messages) > 0 {
-			if lastMsg := m.messages[len(m.messages)-1]; lastMsg.Type == api.MessageTypeUserChoiceRequest {
-				choiceRequest = lastMsg.Payload.(*api.UserChoiceRequest)
-			}
+	m.matchIndex = (m.matchIndex + delta + len(m.matches)) % len(m.matches)
+	m.refresh()
+	m.scrollToMatch()
+}
+
+func (m *model) scrollToMatch() {
+	if len(m.matches) == 0 {
+		return
+	}
+	m.viewport.SetYOffset(m.matches[m.matchIndex] - m.viewport.Height/2)
+}
+
+func (m model) handleChoiceKey(msg tea.KeyMsg, choiceRequest *api.UserChoiceRequest) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ChoiceUp):
+		if m.choiceIndex > 0 {
+			m.choiceIndex--
+		}
+	case key.Matches(msg, m.keys.ChoiceDown):
+		if m.choiceIndex < len(choiceRequest.Options)-1 {
+			m.choiceIndex++
+		}
+	case key.Matches(msg, m.keys.Send):
+		return m.choose(m.choiceIndex)
+	case key.Matches(msg, m.keys.Interrupt):
+		m.notice = "Choose an option to continue"
+	case len(msg.Runes) == 1 && msg.Runes[0] == 'e' && editOptionIndex(choiceRequest) >= 0:
+		return m.choose(editOptionIndex(choiceRequest))
+	case len(msg.Runes) == 1 && msg.Runes[0] >= '1' && msg.Runes[0] <= '9':
+		if i := int(msg.Runes[0] - '1'); i < len(choiceRequest.Options) {
+			return m.choose(i)
 		}
+	}
+	return m, nil
+}
 
-		if choiceRequest != nil {
-			items := make([]list.Item, len(choiceRequest.Options))
-			for i, option := range choiceRequest.Options {
-				items[i] = item(option.Label)
-			}
-			m.list.SetItems(items)
-			m.list.Title = "Select an option:"
-			mainView += listStyle.Render(m.list.View())
-		} else {
-			mainView += m.textarea.View()
+// choose answers the choice request shown with the option at index i, or starts editing
+// its tool calls if it is the option to edit them.
+func (m model) choose(i int) (tea.Model, tea.Cmd) {
+	if choiceRequest := m.pendingChoice(); choiceRequest != nil && i == editOptionIndex(choiceRequest) {
+		m.editing, m.edits, m.editDraft = true, nil, m.textarea.Value()
+		m.textarea.SetValue(choiceRequest.ToolCalls[0].EditText())
+		m.layout()
+		return m, nil
+	}
+	m.answeredChoiceID = m.choiceID
+	m.agent.Input <- &api.UserChoiceResponse{Choice: i + 1}
+	m.layout()
+	m.viewport.GotoBottom()
+	return m, nil
+}
+
+// handleEditKey handles the keys while the user edits the tool calls of a choice request, one after the other.
+// Once they are all edited, they are sent to the agent as the answer.
+func (m model) handleEditKey(msg tea.KeyMsg, choiceRequest *api.UserChoiceRequest) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Close, m.keys.Interrupt):
+		// Back to the options.
+		m.stopEditing()
+		return m, nil
+	case key.Matches(msg, m.keys.Send):
+		m.edits = append(m.edits, strings.TrimSpace(m.textarea.Value()))
+		if len(m.edits) < len(choiceRequest.ToolCalls) {
+			m.textarea.SetValue(choiceRequest.ToolCalls[len(m.edits)].EditText())
+			m.layout()
+			return m, nil
 		}
+		edits := m.edits
+		m.stopEditing()
+		m.answeredChoiceID = m.choiceID
+		m.agent.Input <- &api.UserChoiceResponse{Choice: editOptionIndex(choiceRequest) + 1, Edits: edits}
+		m.viewport.GotoBottom()
+		return m, nil
+	}
+	m.textarea.SetHeight(min(m.textarea.LineCount()+1, maxInputHeight))
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	m.layout()
+	return m, cmd
+}
+
+// stopEditing puts back the query that was in the input before the user edited tool calls.
+func (m *model) stopEditing() {
+	m.editing, m.edits = false, nil
+	m.textarea.SetValue(m.editDraft)
+	m.editDraft = ""
+	m.layout()
+}
+
+// pendingChoice returns the choice request the agent waits for the user to answer, if any.
+func (m model) pendingChoice() *api.UserChoiceRequest {
+	if len(m.messages) == 0 || m.agent.AgentState() != api.AgentStateWaitingForInput {
+		return nil
+	}
+	last := m.messages[len(m.messages)-1]
+	if last.Type != api.MessageTypeUserChoiceRequest || last.ID != m.choiceID || last.ID == m.answeredChoiceID {
+		return nil
+	}
+	choiceRequest, _ := last.Payload.(*api.UserChoiceRequest)
+	return choiceRequest
+}
+
+// selectPane selects the previous or next tool call pane, and scrolls to it.
+func (m *model) selectPane(delta int) {
+	if len(m.panes) == 0 {
+		return
+	}
+	switch {
+	case m.selectedPane < 0 && delta < 0:
+		m.selectedPane = len(m.panes) - 1
+	case m.selectedPane < 0:
+		m.selectedPane = 0
+	default:
+		m.selectedPane = max(0, min(len(m.panes)-1, m.selectedPane+delta))
+	}
+	m.refresh()
+	m.scrollToPane(m.panes[m.selectedPane])
+}
+
+// togglePane shows or hides the output of the selected tool call, or of the last one if none is selected.
+func (m *model) togglePane() {
+	if len(m.panes) == 0 {
+		return
+	}
+	id := m.panes[len(m.panes)-1]
+	if m.selectedPane >= 0 && m.selectedPane < len(m.panes) {
+		id = m.panes[m.selectedPane]
+	}
+	m.expanded[id] = !m.paneExpanded(id)
+	m.refresh()
+	m.scrollToPane(id)
+}
+
+func (m *model) paneExpanded(id string) bool {
+	if expanded, ok := m.expanded[id]; ok {
+		return expanded
+	}
+	return m.expandAll
+}
+
+// scrollToPane scrolls to the header of a pane, unless it is shown already.
+func (m *model) scrollToPane(id string) {
+	line := m.paneLines[id]
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height/3)
+	}
+}
+
+func (m *model) addHistory(query string) {
+	if n := len(m.history); n == 0 || m.history[n-1] != query {
+		m.history = append(m.history, query)
+		appendHistory(query)
+	}
+	m.historyIndex = len(m.history)
+	m.draft = ""
+}
+
+// recallHistory shows the previous or next query of the history in the input.
+func (m *model) recallHistory(delta int) {
+	i := m.historyIndex + delta
+	if i < 0 || i > len(m.history) {
+		return
+	}
+	if m.historyIndex == len(m.history) {
+		m.draft = m.textarea.Value()
+	}
+	m.historyIndex = i
+	if i == len(m.history) {
+		m.textarea.SetValue(m.draft)
 	} else {
-		mainView += m.textarea.View()
+		m.textarea.SetValue(m.history[i])
 	}
-	return mainView
+	m.layout()
 }
 
-func (m model) renderMessage(message *api.Message) string {
-	sourceDisplayName := ""
-	switch message.Source {
-	case api.MessageSourceUser:
-		sourceDisplayName = m.username
-	case api.MessageSourceModel, api.MessageSourceAgent:
-		sourceDisplayName = "AI"
+// loadHistory reads the history of queries shared with the terminal UI.
+func loadHistory() []string {
+	f, err := os.Open(historyFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("reading input history: %v", err)
+		}
+		return nil
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > maxHistorySize {
+		history = history[len(history)-maxHistorySize:]
+	}
+	return history
+}
+
+// appendHistory adds a query to the history file. The file has a query per line, so queries
+// of many lines are only kept in memory.
+func appendHistory(query string) {
+	if strings.Contains(query, "\n") {
+		return
+	}
+	f, err := os.OpenFile(historyFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		klog.Warningf("writing input history: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, query); err != nil {
+		klog.Warningf("writing input history: %v", err)
 	}
-	text := m.senderStyle.Render(fmt.Sprintf("%s: ", sourceDisplayName))
-	glamourRenderWidth := m.viewport.Width - m.viewport.Style.GetHorizontalFrameSize() - lipgloss.Width(text)
+}
 
+// setRenderer creates the markdown renderer for the width of the terminal.
+func (m *model) setRenderer() {
+	m.rendered = make(map[string]string)
 	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(glamourRenderWidth),
+		glamour.WithStandardStyle(m.markdownStyle),
+		glamour.WithWordWrap(max(m.width-lipgloss.Width("AI: ")-2, 20)),
 	)
 	if err != nil {
 		klog.Errorf("failed to create glamour renderer: %v", err)
-		return fmt.Sprintf("error rendering message: %v", err)
+		m.renderer = nil
+		return
+	}
+	m.renderer = renderer
+}
+
+// layout sizes the input and the scrollback for the terminal and the panel shown below the scrollback.
+func (m *model) layout() {
+	if m.width == 0 {
+		return
+	}
+	atBottom := m.viewport.AtBottom()
+	m.textarea.SetWidth(m.width)
+	m.textarea.SetHeight(max(1, min(m.textarea.LineCount(), maxInputHeight)))
+	m.search.Width = m.width - lipgloss.Width(m.search.Prompt) - 1
+
+	m.viewport.Width = m.width
+	m.viewport.Height = max(1, m.height-lipgloss.Height(m.bottomView())-1)
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// refresh renders the messages of the session into the scrollback.
+func (m *model) refresh() {
+	atBottom := m.viewport.AtBottom()
+	m.messages = m.agent.Session().AllMessages()
+
+	// A new choice request starts with its first option selected.
+	if n := len(m.messages); n > 0 && m.messages[n-1].Type == api.MessageTypeUserChoiceRequest && m.messages[n-1].ID != m.choiceID {
+		m.choiceID = m.messages[n-1].ID
+		m.choiceIndex = 0
+	}
+
+	lines := strings.Split(m.renderMessages(), "\n")
+
+	m.matches = nil
+	if query := strings.ToLower(m.search.Value()); m.searching && query != "" {
+		for i, line := range lines {
+			if strings.Contains(strings.ToLower(ansi.Strip(line)), query) {
+				m.matches = append(m.matches, i)
+			}
+		}
+		m.matchIndex = max(0, min(m.matchIndex, len(m.matches)-1))
+		for i, line := range m.matches {
+			style := otherMatchStyle
+			if i == m.matchIndex {
+				style = currentMatchStyle
+			}
+			lines[line] = style.Render(ansi.Strip(lines[line]))
+		}
+	}
+
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// renderMessages renders the messages, grouping each tool call with its result in a pane.
+func (m *model) renderMessages() string {
+	var b strings.Builder
+	line := 0
+	write := func(s string) {
+		s = strings.Trim(s, "\n")
+		b.WriteString(s)
+		b.WriteString("\n\n")
+		line += strings.Count(s, "\n") + 2
+	}
+
+	responses := toolCallResponses(m.messages)
+	m.panes = nil
+	m.paneLines = make(map[string]int)
+	for _, message := range m.messages {
+		switch message.Type {
+		case api.MessageTypeUserInputRequest, api.MessageTypeToolCallResponse:
+			// Results are shown with their tool call.
+			continue
+		case api.MessageTypeToolCallRequest:
+			m.paneLines[message.ID] = line
+			m.panes = append(m.panes, message.ID)
+			write(m.renderPane(message, responses[message.ID], len(m.panes)-1 == m.selectedPane))
+		default:
+			if rendered := m.renderMessage(message); rendered != "" {
+				write(rendered)
+			}
+		}
+	}
+	if m.streamedText != "" {
+		write(m.renderMessage(&api.Message{
+			Source:  api.MessageSourceModel,
+			Type:    api.MessageTypeText,
+			Payload: m.streamedText,
+		}))
 	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
 
-	var renderedText string
-	var contentToRender string
+// toolCallResponses returns the result messages of the tool calls, by the ID of their request message.
+func toolCallResponses(messages []*api.Message) map[string]*api.Message {
+	responses := make(map[string]*api.Message)
+	var waiting []*api.Message
+	for _, message := range messages {
+		switch message.Type {
+		case api.MessageTypeToolCallRequest:
+			waiting = append(waiting, message)
+		case api.MessageTypeToolCallResponse:
+			// Tool calls run one after the other, so a result is of the first call waiting for one.
+			// The call is matched by ID too when messages have it.
+			for i, request := range waiting {
+				if request.ToolCall != nil && message.ToolCall != nil && request.ToolCall.ID != message.ToolCall.ID {
+					continue
+				}
+				responses[request.ID] = message
+				waiting = append(waiting[:i], waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	return responses
+}
+
+func (m *model) sender(message *api.Message) string {
+	if message.Source == api.MessageSourceUser {
+		return senderStyle.Render(m.username + ": ")
+	}
+	return senderStyle.Render("AI: ")
+}
 
+func (m *model) renderMessage(message *api.Message) string {
+	var text string
 	switch p := message.Payload.(type) {
 	case string:
-		contentToRender = p
+		text = p
 	case *api.UserChoiceRequest:
-		contentToRender = p.Prompt
+		text = p.Prompt
 	default:
 		return "" // Don't render unknown payload types
 	}
 
-	switch message.Type {
-	case api.MessageTypeToolCallRequest:
-		contentToRender = fmt.Sprintf("Running: `%s`", contentToRender)
-	case api.MessageTypeError:
-		contentToRender = fmt.Sprintf("Error: %s", contentToRender)
-	case api.MessageTypeToolCallResponse:
-		return "" // Or a summary
+	if message.Type == api.MessageTypeError {
+		return m.sender(message) + errorStyle.Width(max(m.width-lipgloss.Width(m.sender(message)), 1)).Render(strings.TrimSpace(text))
+	}
+
+	if rendered, ok := m.rendered[message.ID]; ok && message.ID != "" {
+		return rendered
+	}
+	rendered := m.sender(message) + text
+	if m.renderer != nil {
+		markdown, err := m.renderer.Render(text)
+		if err != nil {
+			klog.Errorf("failed to render markdown: %v", err)
+		} else {
+			rendered = m.sender(message) + markdown
+		}
+	}
+	if message.ID != "" {
+		m.rendered[message.ID] = rendered
+	}
+	return rendered
+}
+
+// renderPane renders a tool call, and its result when the pane is expanded.
+func (m *model) renderPane(request, response *api.Message, selected bool) string {
+	description := strings.TrimSpace(fmt.Sprint(request.Payload))
+	title, details, _ := strings.Cut(description, "\n")
+
+	expanded := m.paneExpanded(request.ID)
+	arrow := "▸"
+	if expanded {
+		arrow = "▾"
 	}
 
-	renderedText, err = renderer.Render(contentToRender)
+	var status, output string
+	var failed bool
+	switch {
+	case response == nil:
+		status = dimStyle.Render("…")
+	default:
+		output, failed = toolOutput(response.Payload)
+		status = successStyle.Render("✓")
+		if failed {
+			status = errorStyle.Render("✗")
+		}
+	}
+
+	headerStyle := paneHeaderStyle
+	if selected {
+		headerStyle = selectedPaneStyle
+	}
+	header := headerStyle.Render(arrow+" Running: ") + status + " " + headerStyle.Render(title)
+	if !expanded && output != "" {
+		header += dimStyle.Render(fmt.Sprintf(" (%d lines)", strings.Count(output, "\n")+1))
+	}
+	header = ansi.Truncate(header, m.width, "…")
+	if !expanded {
+		return header
+	}
+
+	body := strings.TrimSpace(details)
+	if output != "" {
+		if body != "" {
+			body += "\n\n"
+		}
+		body += output
+	}
+	if body == "" {
+		return header
+	}
+	return header + "\n" + paneBodyStyle.Width(max(m.width-4, 10)).Render(body)
+}
+
+// toolOutput returns the text of the result of a tool call, and whether the call failed.
+func toolOutput(payload any) (string, bool) {
+	result, err := tools.ToolResultToMap(payload)
 	if err != nil {
-		klog.Errorf("failed to render markdown: %v", err)
-		return text + contentToRender // Fallback to non-rendered
+		return fmt.Sprint(payload), false
+	}
+	failed := false
+	if exitCode, ok := result["exit_code"].(float64); ok && exitCode != 0 {
+		failed = true
+	}
+	if errorText, ok := result["error"]; ok && fmt.Sprint(errorText) != "" {
+		failed = true
 	}
 
-	return text + renderedText
+	parts := []string{formatToolCallResponse(result)}
+	if _, ok := result["stdout"]; ok {
+		for _, name := range []string{"stderr", "error"} {
+			if v, ok := result[name]; ok && fmt.Sprint(v) != "" {
+				parts = append(parts, fmt.Sprint(v))
+			}
+		}
+	}
+	return strings.TrimRight(strings.Join(parts, "\n"), "\n"), failed
+}
+
+func (m model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	return m.viewport.View() + "\n" + m.bottomView() + "\n" + m.statusBar()
+}
+
+// bottomView renders the panel below the scrollback: the input, the options of a choice
+// request, the search bar or the help.
+func (m model) bottomView() string {
+	if m.showHelp {
+		return m.help.FullHelpView(m.keys.FullHelp())
+	}
+	if m.searching {
+		info := dimStyle.Render(" no matches")
+		if len(m.matches) > 0 {
+			info = dimStyle.Render(fmt.Sprintf(" %d/%d", m.matchIndex+1, len(m.matches)))
+		}
+		if m.search.Value() == "" {
+			info = ""
+		}
+		return m.search.View() + info
+	}
+	if choiceRequest := m.pendingChoice(); choiceRequest != nil && m.editing {
+		call := choiceRequest.ToolCalls[len(m.edits)]
+		title := fmt.Sprintf("Edit the %s call (enter to confirm, esc to go back):", call.Name)
+		if n := len(choiceRequest.ToolCalls); n > 1 {
+			title = fmt.Sprintf("Edit the %s call, %d of %d (enter to confirm, esc to go back):", call.Name, len(m.edits)+1, n)
+		}
+		return choiceTitleStyle.Render(title) + "\n" + m.textarea.View()
+	}
+	if choiceRequest := m.pendingChoice(); choiceRequest != nil {
+		lines := []string{choiceTitleStyle.Render(fmt.Sprintf("Select an option (↑/↓ and enter, or 1-%d):", len(choiceRequest.Options)))}
+		for i, option := range choiceRequest.Options {
+			text := fmt.Sprintf("%d. %s", i+1, option.Label)
+			if i == m.choiceIndex {
+				lines = append(lines, selectedChoiceStyle.Render("> "+text))
+			} else {
+				lines = append(lines, choiceStyle.Render(text))
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return m.textarea.View()
+}
+
+// statusBar renders the model, kube target, agent state and token usage, and a hint.
+func (m model) statusBar() string {
+	session := m.agent.Session()
+
+	var state string
+	switch session.AgentState {
+	case api.AgentStateRunning:
+		state = m.spinner.View() + "running"
+		if m.progress == nil {
+			break
+		}
+		if progress, ok := m.progress.Payload.(*api.Progress); ok {
+			if progress.MaxIterations > 0 {
+				state += fmt.Sprintf(" step %d/%d", progress.Iteration, progress.MaxIterations)
+			}
+			if tool, _, _ := strings.Cut(strings.TrimSpace(progress.Tool), "\n"); tool != "" {
+				state += fmt.Sprintf(" · %s (%s)", tool, time.Since(m.progress.Timestamp).Truncate(time.Second))
+			}
+		}
+	case api.AgentStateWaitingForInput:
+		state = "waiting for approval"
+	case api.AgentStateIdle, api.AgentStateDone:
+		state = "ready"
+	default:
+		state = string(session.AgentState)
+	}
+	parts := []string{state}
+	if provider, modelName := m.agent.LLMTarget(); modelName != "" {
+		if provider != "" {
+			modelName = provider + "/" + modelName
+		}
+		parts = append(parts, modelName)
+	}
+	if label := kubeTargetLabel(session); label != "" {
+		parts = append(parts, label)
+	}
+	if tokens, ok := totalTokens(m.agent.Stats().Usage); ok {
+		parts = append(parts, formatTokens(tokens)+" tokens")
+	}
+	left := " " + strings.Join(parts, " · ")
+
+	right := "F1 help "
+	style := statusBarStyle
+	if m.notice != "" {
+		right, style = m.notice+" ", noticeStyle
+	}
+	gap := m.width - lipgloss.Width(left) - lipgloss.Width(right)
+	if gap < 1 {
+		left = ansi.Truncate(left, max(m.width-lipgloss.Width(right)-1, 0), "…")
+		gap = m.width - lipgloss.Width(left) - lipgloss.Width(right)
+	}
+	return statusBarStyle.Render(left+strings.Repeat(" ", max(gap, 0))) + style.Render(right)
 }
@@ -0,0 +1,217 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/internal/mocks"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/api"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/sessions"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"go.uber.org/mock/gomock"
+)
+
+// waitForMessage reads the output of the agent until a message of type messageType.
+func waitForMessage(t *testing.T, ctx context.Context, a *agent.Agent, messageType api.MessageType) *api.Message {
+	t.Helper()
+	for {
+		select {
+		case v := <-a.Output:
+			if msg, ok := v.(*api.Message); ok && msg.Type == messageType {
+				return msg
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for a %s message", messageType)
+			return nil
+		}
+	}
+}
+
+// startApprovalAgent starts an agent whose LLM asks to run a command that needs the approval of the user,
+// and waits for the agent to ask for it. The command the tool runs is stored in ran.
+func startApprovalAgent(t *testing.T, ctx context.Context, ran *string) *agent.Agent {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	client := mocks.NewMockClient(ctrl)
+	chat := mocks.NewMockChat(ctrl)
+	client.EXPECT().StartChat(gomock.Any(), "test-model").Return(chat)
+	chat.EXPECT().Initialize(gomock.Any()).Return(nil)
+	chat.EXPECT().SetFunctionDefinitions(gomock.Any()).Return(nil)
+	gomock.InOrder(
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(fakePart{calls: []gollm.FunctionCall{
+			{ID: "1", Name: "mocktool", Arguments: map[string]any{"command": "kubectl delete pod web-0"}},
+		}}), nil),
+		chat.EXPECT().SendStreaming(gomock.Any(), gomock.Any()).Return(llmResponse(fakePart{text: "Done."}), nil),
+	)
+
+	tool := mocks.NewMockTool(ctrl)
+	tool.EXPECT().Name().Return("mocktool").AnyTimes()
+	tool.EXPECT().Description().Return("mock tool").AnyTimes()
+	tool.EXPECT().FunctionDefinition().Return(&gollm.FunctionDefinition{Name: "mocktool"}).AnyTimes()
+	tool.EXPECT().IsInteractive(gomock.Any()).Return(false, nil).AnyTimes()
+	tool.EXPECT().CheckModifiesResource(gomock.Any()).Return("yes").AnyTimes()
+	tool.EXPECT().Run(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, args map[string]any) (any, error) {
+		*ran, _ = args["command"].(string)
+		return &tools.ExecResult{Command: *ran}, nil
+	}).MaxTimes(1)
+	var toolset tools.Tools
+	toolset.Init()
+	toolset.RegisterTool(tool)
+
+	a := &agent.Agent{
+		ChatMessageStore: sessions.NewInMemoryChatStore(),
+		LLM:              client,
+		Model:            "test-model",
+		Tools:            toolset,
+		MaxIterations:    4,
+	}
+	if err := a.Init(ctx); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	if err := a.Run(ctx, ""); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waitForMessage(t, ctx, a, api.MessageTypeUserInputRequest)
+	a.Input <- &api.UserInputResponse{Query: "delete web-0"}
+	waitForMessage(t, ctx, a, api.MessageTypeUserChoiceRequest)
+	return a
+}
+
+// newTestModel returns the model of the TUI for a, with the messages of its session shown.
+func newTestModel(a *agent.Agent) model {
+	// The renderer would query the terminal for its background color.
+	lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(io.Discard))
+	m := newModel(a)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return updated.(model)
+}
+
+// press sends the keys to the model, one after the other.
+func press(m model, keys ...tea.KeyMsg) model {
+	for _, k := range keys {
+		updated, _ := m.Update(k)
+		m = updated.(model)
+	}
+	return m
+}
+
+var (
+	keyEnter = tea.KeyMsg{Type: tea.KeyEnter}
+	keyEsc   = tea.KeyMsg{Type: tea.KeyEsc}
+	keyDown  = tea.KeyMsg{Type: tea.KeyDown}
+)
+
+func keyRune(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+}
+
+func TestTUIApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []tea.KeyMsg
+		wantRan string
+	}{
+		{
+			name:    "enter on the first option",
+			keys:    []tea.KeyMsg{keyEnter},
+			wantRan: "kubectl delete pod web-0",
+		},
+		{
+			name:    "number of the option",
+			keys:    []tea.KeyMsg{keyRune('1')},
+			wantRan: "kubectl delete pod web-0",
+		},
+		{
+			name: "declined",
+			keys: []tea.KeyMsg{keyDown, keyDown, keyEnter},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var ran string
+			a := startApprovalAgent(t, ctx, &ran)
+
+			m := newTestModel(a)
+			if m.pendingChoice() == nil {
+				t.Fatalf("the model shows no pending choice")
+			}
+			m = press(m, tt.keys...)
+			if m.pendingChoice() != nil {
+				t.Errorf("the choice is still pending after the keys were pressed")
+			}
+
+			// The agent asks for the next query once the LLM answered.
+			waitForMessage(t, ctx, a, api.MessageTypeUserInputRequest)
+			if ran != tt.wantRan {
+				t.Errorf("tool ran %q, want %q", ran, tt.wantRan)
+			}
+		})
+	}
+}
+
+func TestTUIEditToolCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ran string
+	a := startApprovalAgent(t, ctx, &ran)
+
+	m := newTestModel(a)
+	m.textarea.SetValue("draft query")
+
+	// 'e' puts the command in the input, and esc goes back to the options with the draft.
+	m = press(m, keyRune('e'))
+	if !m.editing || m.textarea.Value() != "kubectl delete pod web-0" {
+		t.Fatalf("after 'e': editing = %v, input = %q, want the command to edit", m.editing, m.textarea.Value())
+	}
+	m = press(m, keyEsc)
+	if m.editing || m.textarea.Value() != "draft query" || m.pendingChoice() == nil {
+		t.Fatalf("after esc: editing = %v, input = %q, want the options and the draft back", m.editing, m.textarea.Value())
+	}
+
+	m = press(m, keyRune('e'))
+	m.textarea.SetValue("kubectl delete pod web-1")
+	m = press(m, keyEnter)
+	if m.editing || m.textarea.Value() != "draft query" {
+		t.Errorf("after sending the edit: editing = %v, input = %q, want the draft back", m.editing, m.textarea.Value())
+	}
+	if m.pendingChoice() != nil {
+		t.Errorf("the choice is still pending after the edit was sent")
+	}
+
+	// The edited command still needs to be approved.
+	msg := waitForMessage(t, ctx, a, api.MessageTypeUserChoiceRequest)
+	if toolCalls := msg.Payload.(*api.UserChoiceRequest).ToolCalls; len(toolCalls) != 1 || toolCalls[0].EditText() != "kubectl delete pod web-1" {
+		t.Fatalf("the agent asks to approve %+v, want the edited command", toolCalls)
+	}
+	updated, _ := m.Update(msg)
+	m = press(updated.(model), keyEnter)
+
+	waitForMessage(t, ctx, a, api.MessageTypeUserInputRequest)
+	if ran != "kubectl delete pod web-1" {
+		t.Errorf("tool ran %q, want the edited command", ran)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// totalTokens adds up the tokens of usage reports of LLM requests, as returned by
+// gollm.ChatResponse.UsageMetadata. It returns false if none of the reports has a known format.
+func totalTokens(usages []any) (int, bool) {
+	total, known := 0, false
+	for _, usage := range usages {
+		if tokens, ok := usageTokens(usage); ok {
+			total += tokens
+			known = true
+		}
+	}
+	return total, known
+}
+
+// usageTokens returns the total tokens of the usage report of an LLM request.
+// Providers report usage in their own format, so their fields are looked up by name.
+func usageTokens(usage any) (int, bool) {
+	b, err := json.Marshal(usage)
+	if err != nil {
+		return 0, false
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return 0, false
+	}
+	counts := make(map[string]int)
+	for name, value := range fields {
+		if n, ok := value.(float64); ok {
+			// e.g. totalTokenCount, total_tokens and TotalTokens are all totaltokens.
+			counts[strings.ReplaceAll(strings.ToLower(name), "_", "")] = int(n)
+		}
+	}
+
+	for _, name := range []string{"totaltokens", "totaltokencount"} {
+		if n, ok := counts[name]; ok {
+			return n, true
+		}
+	}
+	for _, names := range [][2]string{
+		{"inputtokens", "outputtokens"},
+		{"prompttokens", "completiontokens"},
+		{"prompttokencount", "candidatestokencount"},
+		{"promptevalcount", "evalcount"},
+	} {
+		input, hasInput := counts[names[0]]
+		output, hasOutput := counts[names[1]]
+		if hasInput || hasOutput {
+			return input + output, true
+		}
+	}
+	return 0, false
+}
+
+// formatTokens formats a number of tokens for the status bar, e.g. 950 or 12.3k.
+func formatTokens(n int) string {
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}